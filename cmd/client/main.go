@@ -0,0 +1,263 @@
+// Package main is a command-line client for exercising the MCP Subfinder Server
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultServerURL  = "http://localhost:8080/mcp"
+	protocolVersion   = "0.3"
+	clientName        = "mcp-subfinder-client"
+	clientVersion     = "1.0.0"
+	sessionIDHeader   = "X-Session-Id"
+	defaultReqTimeout = 5 * time.Minute
+)
+
+// options holds the parsed command-line flags
+type options struct {
+	server         string
+	method         string
+	tool           string
+	domain         string
+	timeout        int
+	maxDepth       int
+	recursive      bool
+	sources        string
+	excludeSources string
+	extraArgs      string
+	session        string
+	trace          bool
+	requestTimeout time.Duration
+}
+
+// rpcRequest is a JSON-RPC 2.0 request envelope
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcResponse is the part of a JSON-RPC 2.0 response the client inspects
+type rpcResponse struct {
+	Result *struct {
+		IsError bool `json:"isError"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// client sends JSON-RPC requests to an MCP server within one session
+type client struct {
+	server    string
+	sessionID string
+	http      *http.Client
+	trace     io.Writer
+	nextID    int
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.server, "server", defaultServerURL, "MCP endpoint URL")
+	flag.StringVar(&opts.method, "method", "tools.list", "Method to call: initialize, tools.list or tools.call")
+	flag.StringVar(&opts.tool, "tool", "enumerateSubdomains", "Tool to run for tools.call")
+	flag.StringVar(&opts.domain, "domain", "", "Domain argument for tools.call")
+	flag.IntVar(&opts.timeout, "timeout", 0, "Enumeration timeout in seconds (server default if unset)")
+	flag.IntVar(&opts.maxDepth, "max-depth", 0, "Maximum recursion depth (server default if unset)")
+	flag.BoolVar(&opts.recursive, "recursive", false, "Enable recursive enumeration")
+	flag.StringVar(&opts.sources, "sources", "", "Comma-separated sources to use")
+	flag.StringVar(&opts.excludeSources, "exclude-sources", "", "Comma-separated sources to exclude")
+	flag.StringVar(&opts.extraArgs, "args", "", "Additional tool arguments as a JSON object")
+	flag.StringVar(&opts.session, "session", "", "Session ID to send (random if unset)")
+	flag.BoolVar(&opts.trace, "trace", false, "Log request and response bodies to stderr")
+	flag.DurationVar(&opts.requestTimeout, "request-timeout", defaultReqTimeout, "HTTP request timeout")
+	flag.Parse()
+
+	code, err := run(opts, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+	os.Exit(code)
+}
+
+// run executes the requested method and pretty-prints the response to out.
+// It returns exit code 1 when the call fails or the server reports an error.
+func run(opts options, out, traceOut io.Writer) (int, error) {
+	sessionID := opts.session
+	if sessionID == "" {
+		sessionID = newSessionID()
+	}
+
+	c := &client{
+		server:    opts.server,
+		sessionID: sessionID,
+		http:      &http.Client{Timeout: opts.requestTimeout},
+	}
+	if opts.trace {
+		c.trace = traceOut
+	}
+
+	var params interface{}
+	switch opts.method {
+	case "initialize":
+		params = initializeParams()
+	case "tools.list":
+	case "tools.call":
+		args, err := buildArguments(opts)
+		if err != nil {
+			return 1, err
+		}
+		params = map[string]interface{}{
+			"name":      opts.tool,
+			"arguments": args,
+		}
+
+		// The server only accepts tool calls from initialized sessions
+		if _, err := c.call("initialize", initializeParams()); err != nil {
+			return 1, fmt.Errorf("initialize failed: %w", err)
+		}
+	default:
+		return 1, fmt.Errorf("unsupported method %q", opts.method)
+	}
+
+	body, err := c.call(opts.method, params)
+	if err != nil {
+		return 1, err
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		out.Write(body)
+	} else {
+		pretty.WriteTo(out)
+	}
+	fmt.Fprintln(out)
+
+	if failed(body) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// initializeParams returns the params sent with initialize
+func initializeParams() map[string]interface{} {
+	return map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"clientInfo": map[string]string{
+			"name":    clientName,
+			"version": clientVersion,
+		},
+	}
+}
+
+// buildArguments assembles tool arguments from the flags, with -args applied last
+func buildArguments(opts options) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if opts.domain != "" {
+		args["domain"] = opts.domain
+	}
+	if opts.timeout > 0 {
+		args["timeout"] = opts.timeout
+	}
+	if opts.maxDepth > 0 {
+		args["maxDepth"] = opts.maxDepth
+	}
+	if opts.recursive {
+		args["recursive"] = true
+	}
+	if opts.sources != "" {
+		args["sourcesFilter"] = opts.sources
+	}
+	if opts.excludeSources != "" {
+		args["excludeSourcesFilter"] = opts.excludeSources
+	}
+
+	if strings.TrimSpace(opts.extraArgs) != "" {
+		var extra map[string]interface{}
+		if err := json.Unmarshal([]byte(opts.extraArgs), &extra); err != nil {
+			return nil, fmt.Errorf("invalid -args JSON: %w", err)
+		}
+		for name, value := range extra {
+			args[name] = value
+		}
+	}
+
+	return args, nil
+}
+
+// call sends one JSON-RPC request and returns the raw response body
+func (c *client) call(method string, params interface{}) ([]byte, error) {
+	c.nextID++
+	payload, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	c.tracef("--> %s\n", payload)
+
+	req, err := http.NewRequest(http.MethodPost, c.server, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sessionIDHeader, c.sessionID)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	c.tracef("<-- %d %s\n", resp.StatusCode, bytes.TrimSpace(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// tracef writes to the trace output when tracing is enabled
+func (c *client) tracef(format string, args ...interface{}) {
+	if c.trace != nil {
+		fmt.Fprintf(c.trace, format, args...)
+	}
+}
+
+// failed reports whether a response carries a JSON-RPC error or an error tool result
+func failed(body []byte) bool {
+	var resp rpcResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return true
+	}
+	return resp.Error != nil || (resp.Result != nil && resp.Result.IsError)
+}
+
+// newSessionID returns a random session ID so each run gets its own session
+func newSessionID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("client-%d", time.Now().UnixNano())
+	}
+	return "client-" + hex.EncodeToString(id)
+}
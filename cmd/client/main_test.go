@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-subfinder-server/internal/server"
+)
+
+func TestBuildArguments(t *testing.T) {
+	args, err := buildArguments(options{
+		domain:    "example.com",
+		timeout:   30,
+		recursive: true,
+		sources:   "crtsh",
+		extraArgs: `{"minSources": 2, "timeout": 45}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected arguments to build, got %v", err)
+	}
+
+	if args["domain"] != "example.com" || args["recursive"] != true || args["sourcesFilter"] != "crtsh" {
+		t.Errorf("Unexpected arguments: %v", args)
+	}
+	// -args is applied last and overrides individual flags
+	if args["timeout"] != float64(45) || args["minSources"] != float64(2) {
+		t.Errorf("Expected -args to override flags, got %v", args)
+	}
+
+	if _, err := buildArguments(options{extraArgs: "not json"}); err == nil {
+		t.Error("Expected an error for invalid -args JSON")
+	}
+}
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(server.MCPHandler))
+	defer ts.Close()
+
+	t.Run("tools.list", func(t *testing.T) {
+		var out, trace bytes.Buffer
+		code, err := run(options{server: ts.URL, method: "tools.list", trace: true}, &out, &trace)
+		if err != nil || code != 0 {
+			t.Fatalf("Expected success, got code %d, err %v", code, err)
+		}
+		if !strings.Contains(out.String(), `"enumerateSubdomains"`) {
+			t.Errorf("Expected tools in output, got %s", out.String())
+		}
+		if !strings.Contains(trace.String(), "-->") || !strings.Contains(trace.String(), "<--") {
+			t.Errorf("Expected traced request and response, got %s", trace.String())
+		}
+	})
+
+	t.Run("tools.call error", func(t *testing.T) {
+		var out bytes.Buffer
+		// Missing domain is rejected after the automatic initialize
+		code, err := run(options{server: ts.URL, method: "tools.call", tool: "enumerateSubdomains"}, &out, &out)
+		if err != nil {
+			t.Fatalf("Expected a response, got %v", err)
+		}
+		if code != 1 {
+			t.Errorf("Expected exit code 1 for an error response, got %d", code)
+		}
+		if !strings.Contains(out.String(), "Invalid params") {
+			t.Errorf("Expected invalid params error, got %s", out.String())
+		}
+	})
+
+	t.Run("unsupported method", func(t *testing.T) {
+		var out bytes.Buffer
+		if code, err := run(options{server: ts.URL, method: "bogus"}, &out, &out); err == nil || code != 1 {
+			t.Errorf("Expected an error for an unsupported method, got code %d, err %v", code, err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package classify
+
+import (
+	"net/netip"
+	"strings"
+
+	"mcp-subfinder-server/internal/cloud"
+)
+
+// Asset types returned by ClassifyAssetType
+const (
+	API        = "api"
+	Mail       = "mail"
+	CDN        = "cdn"
+	AWSManaged = "aws-managed"
+	Auth       = "auth"
+	VPN        = "vpn"
+	Staging    = "staging"
+	DevOps     = "devops"
+	Storage    = "storage"
+	Web        = "web"
+)
+
+// nameRules map subdomain labels to the asset type they usually indicate, checked in order
+var nameRules = []struct {
+	assetType string
+	labels    []string
+}{
+	{API, []string{"api", "apis", "gateway", "graphql", "rest", "rpc", "ws"}},
+	{Mail, []string{"mail", "smtp", "mx", "imap", "pop", "pop3", "webmail", "autodiscover", "exchange"}},
+	{Auth, []string{"auth", "sso", "login", "idp", "oauth", "adfs", "okta"}},
+	{VPN, []string{"vpn", "remote", "citrix", "anyconnect", "gp"}},
+	{Staging, []string{"dev", "staging", "stage", "test", "qa", "uat", "sandbox", "preprod"}},
+	{DevOps, []string{"git", "gitlab", "jenkins", "ci", "jira", "confluence", "grafana", "kibana", "sonar"}},
+}
+
+// cdnSuffixes are CNAME target domains operated by CDNs
+var cdnSuffixes = []string{
+	"cloudfront.net", "akamaiedge.net", "akamaized.net", "edgekey.net", "edgesuite.net",
+	"fastly.net", "fastlylb.net", "cdn.cloudflare.net", "azureedge.net", "azurefd.net",
+	"b-cdn.net", "cdn77.org", "stackpathdns.com", "llnwd.net", "edgecastcdn.net",
+}
+
+// awsManagedSuffixes are names of AWS services that terminate traffic on the customer's behalf
+var awsManagedSuffixes = []string{
+	"acm.amazonaws.com", "elb.amazonaws.com", "elasticbeanstalk.com", "awsglobalaccelerator.com",
+}
+
+// storageSuffixes are object storage endpoints
+var storageSuffixes = []string{
+	"s3.amazonaws.com", "blob.core.windows.net", "storage.googleapis.com", "digitaloceanspaces.com",
+}
+
+// ClassifyAssetType guesses what kind of asset a subdomain is from its name, the
+// names and addresses it resolved to (CNAME targets, certificate names, IPs) and
+// the HTTP Server header it returned, if any. It returns "" when nothing matches.
+func ClassifyAssetType(subdomain string, resolved []string, serverHeader string) string {
+	var names []string
+	var cdnHosted bool
+	for _, value := range resolved {
+		value = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(value, "*."), "."))
+		if _, err := netip.ParseAddr(value); err == nil {
+			if provider := cloud.Classify(value); provider == cloud.Cloudflare || provider == cloud.Fastly {
+				cdnHosted = true
+			}
+			continue
+		}
+		names = append(names, value)
+	}
+
+	// The subdomain's own labels describe its purpose best
+	labels := nameLabels(subdomain)
+	for _, rule := range nameRules {
+		for _, label := range rule.labels {
+			if _, ok := labels[label]; ok {
+				return rule.assetType
+			}
+		}
+	}
+
+	// Otherwise infer it from where the subdomain points
+	switch {
+	case matchesAny(names, cdnSuffixes):
+		return CDN
+	case matchesAny(names, awsManagedSuffixes):
+		return AWSManaged
+	case matchesAny(names, storageSuffixes):
+		return Storage
+	case cdnHosted:
+		return CDN
+	}
+
+	server := strings.ToLower(serverHeader)
+	switch {
+	case server == "":
+		return ""
+	case strings.Contains(server, "amazons3"):
+		return Storage
+	case strings.Contains(server, "awselb"):
+		return AWSManaged
+	case strings.Contains(server, "cloudflare") || strings.Contains(server, "akamai") || strings.Contains(server, "cloudfront"):
+		return CDN
+	default:
+		return Web
+	}
+}
+
+// nameLabels splits the labels left of the registered domain into words,
+// so "api-v2.eu.example.com" yields api, v2 and eu. Trailing digits are
+// dropped so that mail2 and mx1 match their base words.
+func nameLabels(subdomain string) map[string]struct{} {
+	parts := strings.Split(strings.ToLower(strings.TrimSuffix(subdomain, ".")), ".")
+	if len(parts) > 2 {
+		parts = parts[:len(parts)-2]
+	}
+
+	labels := make(map[string]struct{})
+	for _, part := range parts {
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '_' }) {
+			labels[word] = struct{}{}
+			labels[strings.TrimRight(word, "0123456789")] = struct{}{}
+		}
+	}
+	return labels
+}
+
+// matchesAny reports whether any name equals or falls under one of suffixes
+func matchesAny(names, suffixes []string) bool {
+	for _, name := range names {
+		for _, suffix := range suffixes {
+			if name == suffix || strings.HasSuffix(name, "."+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package classify
+
+import "testing"
+
+func TestClassifyAssetType(t *testing.T) {
+	tests := []struct {
+		subdomain    string
+		resolved     []string
+		serverHeader string
+		expected     string
+	}{
+		{"api.example.com", nil, "", API},
+		{"api-v2.example.com", nil, "nginx", API},
+		{"gateway.eu.example.com", nil, "", API},
+		{"api2.example.com", nil, "", API},
+		{"mail.example.com", nil, "", Mail},
+		{"smtp.example.com", nil, "", Mail},
+		{"mx1.example.com", nil, "", Mail},
+		{"sso.example.com", nil, "", Auth},
+		{"vpn.example.com", nil, "", VPN},
+		{"staging.example.com", []string{"d111111abcdef8.cloudfront.net"}, "", Staging},
+		{"jenkins.example.com", nil, "", DevOps},
+		{"static.example.com", []string{"d111111abcdef8.cloudfront.net."}, "", CDN},
+		{"www.example.com", []string{"www.example.com.cdn.cloudflare.net"}, "", CDN},
+		{"assets.example.com", []string{"104.16.1.1"}, "", CDN},
+		{"shop.example.com", []string{"*.acm.amazonaws.com"}, "", AWSManaged},
+		{"app.example.com", []string{"my-lb-123.us-east-1.elb.amazonaws.com"}, "", AWSManaged},
+		{"files.example.com", []string{"files.example.com.s3.amazonaws.com"}, "", Storage},
+		{"media.example.com", nil, "AmazonS3", Storage},
+		{"edge.example.com", nil, "cloudflare", CDN},
+		{"www.example.com", []string{"192.0.2.10"}, "Apache/2.4.41", Web},
+		{"www.example.com", []string{"192.0.2.10"}, "", ""},
+		// Words in the registered domain itself are not evidence
+		{"www.mailchimp-api.com", nil, "", ""},
+		{"capital.example.com", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyAssetType(tt.subdomain, tt.resolved, tt.serverHeader); got != tt.expected {
+			t.Errorf("ClassifyAssetType(%q, %v, %q): expected %q, got %q", tt.subdomain, tt.resolved, tt.serverHeader, tt.expected, got)
+		}
+	}
+}
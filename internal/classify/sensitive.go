@@ -0,0 +1,144 @@
+package classify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sensitivePattern flags subdomain names that usually belong to internal services.
+// A prefix matches a first label that is the prefix itself or continues with a
+// hyphen or digit, so "admin" matches admin, admin-eu and admin2 but not
+// administration. A regex is matched against the lowercase labels left of the
+// target domain, followed by a dot.
+type sensitivePattern struct {
+	prefix string
+	regex  *regexp.Regexp
+	reason string
+}
+
+// Reasons reported for sensitive subdomains
+const (
+	ReasonAdminPanel    = "admin panel indicator"
+	ReasonInternal      = "internal network indicator"
+	ReasonRemoteAccess  = "remote access indicator"
+	ReasonCICD          = "CI/CD system indicator"
+	ReasonMonitoring    = "monitoring dashboard indicator"
+	ReasonSourceControl = "source control indicator"
+	ReasonDatabase      = "database indicator"
+	ReasonNonProduction = "non-production environment indicator"
+	ReasonBackup        = "backup indicator"
+	ReasonAuth          = "identity provider indicator"
+)
+
+// sensitivePatterns are checked in order; the first match gives the reason
+var sensitivePatterns = []sensitivePattern{
+	{prefix: "admin", reason: ReasonAdminPanel},
+	{prefix: "administrator", reason: ReasonAdminPanel},
+	{prefix: "cpanel", reason: ReasonAdminPanel},
+	{prefix: "whm", reason: ReasonAdminPanel},
+	{prefix: "plesk", reason: ReasonAdminPanel},
+	{prefix: "phpmyadmin", reason: ReasonAdminPanel},
+	{prefix: "pma", reason: ReasonAdminPanel},
+	{prefix: "manage", reason: ReasonAdminPanel},
+	{prefix: "manager", reason: ReasonAdminPanel},
+	{prefix: "console", reason: ReasonAdminPanel},
+	{prefix: "dashboard", reason: ReasonAdminPanel},
+	{prefix: "backoffice", reason: ReasonAdminPanel},
+	{prefix: "portainer", reason: ReasonAdminPanel},
+	{regex: regexp.MustCompile(`(^|[.-])(wp-admin|webadmin|sysadmin)([.-]|$)`), reason: ReasonAdminPanel},
+
+	{prefix: "internal", reason: ReasonInternal},
+	{prefix: "intranet", reason: ReasonInternal},
+	{prefix: "corp", reason: ReasonInternal},
+	{prefix: "private", reason: ReasonInternal},
+	{prefix: "lan", reason: ReasonInternal},
+	{prefix: "ldap", reason: ReasonInternal},
+	{prefix: "ad", reason: ReasonInternal},
+	{prefix: "dc", reason: ReasonInternal},
+	{regex: regexp.MustCompile(`(^|\.)(int|internal|corp)\.`), reason: ReasonInternal},
+
+	{prefix: "vpn", reason: ReasonRemoteAccess},
+	{prefix: "rdp", reason: ReasonRemoteAccess},
+	{prefix: "ssh", reason: ReasonRemoteAccess},
+	{prefix: "citrix", reason: ReasonRemoteAccess},
+	{prefix: "remote", reason: ReasonRemoteAccess},
+	{prefix: "bastion", reason: ReasonRemoteAccess},
+	{prefix: "jump", reason: ReasonRemoteAccess},
+	{prefix: "owa", reason: ReasonRemoteAccess},
+	{regex: regexp.MustCompile(`^(sslvpn|anyconnect|globalprotect|fortigate)`), reason: ReasonRemoteAccess},
+
+	{prefix: "jenkins", reason: ReasonCICD},
+	{prefix: "ci", reason: ReasonCICD},
+	{prefix: "teamcity", reason: ReasonCICD},
+	{prefix: "bamboo", reason: ReasonCICD},
+	{prefix: "argocd", reason: ReasonCICD},
+	{prefix: "drone", reason: ReasonCICD},
+	{prefix: "nexus", reason: ReasonCICD},
+	{prefix: "artifactory", reason: ReasonCICD},
+	{prefix: "registry", reason: ReasonCICD},
+
+	{prefix: "grafana", reason: ReasonMonitoring},
+	{prefix: "kibana", reason: ReasonMonitoring},
+	{prefix: "prometheus", reason: ReasonMonitoring},
+	{prefix: "nagios", reason: ReasonMonitoring},
+	{prefix: "zabbix", reason: ReasonMonitoring},
+	{prefix: "splunk", reason: ReasonMonitoring},
+	{prefix: "elastic", reason: ReasonMonitoring},
+	{prefix: "metrics", reason: ReasonMonitoring},
+
+	{prefix: "git", reason: ReasonSourceControl},
+	{prefix: "gitlab", reason: ReasonSourceControl},
+	{prefix: "bitbucket", reason: ReasonSourceControl},
+	{prefix: "svn", reason: ReasonSourceControl},
+	{prefix: "gerrit", reason: ReasonSourceControl},
+
+	{prefix: "db", reason: ReasonDatabase},
+	{prefix: "mysql", reason: ReasonDatabase},
+	{prefix: "postgres", reason: ReasonDatabase},
+	{prefix: "mongo", reason: ReasonDatabase},
+	{prefix: "redis", reason: ReasonDatabase},
+	{prefix: "sql", reason: ReasonDatabase},
+
+	{prefix: "staging", reason: ReasonNonProduction},
+	{prefix: "dev", reason: ReasonNonProduction},
+	{prefix: "uat", reason: ReasonNonProduction},
+	{regex: regexp.MustCompile(`(^|[.-])(preprod|pre-prod|sandbox)([.-]|$)`), reason: ReasonNonProduction},
+
+	{prefix: "backup", reason: ReasonBackup},
+	{prefix: "bak", reason: ReasonBackup},
+	{regex: regexp.MustCompile(`(^|[.-])(old|archive|legacy)[.-]`), reason: ReasonBackup},
+
+	{prefix: "sso", reason: ReasonAuth},
+	{prefix: "adfs", reason: ReasonAuth},
+	{prefix: "keycloak", reason: ReasonAuth},
+	{prefix: "vault", reason: ReasonAuth},
+}
+
+// Sensitive reports whether subdomain of domain looks like it exposes an internal
+// service, returning why. Only the labels left of domain are checked, so a target
+// such as corp.example.com does not mark every subdomain internal; a name outside
+// domain is checked whole.
+func Sensitive(subdomain, domain string) (string, bool) {
+	name := strings.ToLower(strings.TrimSuffix(subdomain, "."))
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if name == domain {
+		return "", false
+	}
+	labels := name
+	if domain != "" {
+		labels = strings.TrimSuffix(name, "."+domain)
+	}
+	first, _, _ := strings.Cut(labels, ".")
+	for _, pattern := range sensitivePatterns {
+		if pattern.regex != nil {
+			if pattern.regex.MatchString(labels + ".") {
+				return pattern.reason, true
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(first, pattern.prefix); ok && (rest == "" || rest[0] == '-' || (rest[0] >= '0' && rest[0] <= '9')) {
+			return pattern.reason, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package classify
+
+import "testing"
+
+func TestSensitive(t *testing.T) {
+	tests := []struct {
+		subdomain string
+		reason    string
+	}{
+		{"admin.example.com", ReasonAdminPanel},
+		{"admin-eu.example.com", ReasonAdminPanel},
+		{"Admin2.Example.com.", ReasonAdminPanel},
+		{"shop.wp-admin.example.com", ReasonAdminPanel},
+		{"vpn.example.com", ReasonRemoteAccess},
+		{"sslvpn01.example.com", ReasonRemoteAccess},
+		{"jenkins.example.com", ReasonCICD},
+		{"grafana.example.com", ReasonMonitoring},
+		{"api.internal.example.com", ReasonInternal},
+		{"db-replica.example.com", ReasonDatabase},
+		{"old-shop.example.com", ReasonBackup},
+		{"www.example.com", ""},
+		{"administration.example.com", ""},
+		{"development.example.com", ""},
+		{"api.example.com", ""},
+		{"example.com", ""},
+	}
+
+	for _, tc := range tests {
+		reason, ok := Sensitive(tc.subdomain, "example.com")
+		if reason != tc.reason || ok != (tc.reason != "") {
+			t.Errorf("%s: expected %q, got %q (%v)", tc.subdomain, tc.reason, reason, ok)
+		}
+	}
+}
+
+func TestSensitiveApex(t *testing.T) {
+	// Apex labels that look sensitive do not mark every subdomain
+	tests := []struct {
+		subdomain string
+		domain    string
+		reason    string
+	}{
+		{"www.old-navy.com", "old-navy.com", ""},
+		{"shop.old-navy.com", "old-navy.com", ""},
+		{"legacy-shop.old-navy.com", "old-navy.com", ReasonBackup},
+		{"www.corp.acme.com", "corp.acme.com", ""},
+		{"api.int.corp.acme.com", "corp.acme.com", ReasonInternal},
+		{"vpn.corp.acme.com", "corp.acme.com", ReasonRemoteAccess},
+		{"admin.other.com", "corp.acme.com", ReasonAdminPanel},
+	}
+
+	for _, tc := range tests {
+		reason, ok := Sensitive(tc.subdomain, tc.domain)
+		if reason != tc.reason || ok != (tc.reason != "") {
+			t.Errorf("%s under %s: expected %q, got %q (%v)", tc.subdomain, tc.domain, tc.reason, reason, ok)
+		}
+	}
+}
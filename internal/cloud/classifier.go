@@ -0,0 +1,109 @@
+package cloud
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen.go
+
+// Provider names reported by Classify
+const (
+	AWS        = "aws"
+	GCP        = "gcp"
+	Azure      = "azure"
+	Cloudflare = "cloudflare"
+	Fastly     = "fastly"
+)
+
+// rangesData holds one "provider prefix" pair per line, as written by gen.go
+//
+//go:embed ranges.txt
+var rangesData string
+
+// Classifier maps addresses to the cloud provider whose published ranges contain them
+type Classifier struct {
+	// prefixes is keyed by prefix length so a lookup masks the address once per length
+	prefixes map[int]map[netip.Prefix]string
+	// lengths lists the prefix lengths present, longest first
+	lengths []int
+}
+
+// Parse builds a Classifier from "provider prefix" lines; blank lines and # comments are skipped
+func Parse(data string) (*Classifier, error) {
+	c := &Classifier{prefixes: make(map[int]map[netip.Prefix]string)}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected provider and prefix, got %q", line, text)
+		}
+		prefix, err := netip.ParsePrefix(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		prefix = prefix.Masked()
+		bits := prefix.Bits()
+		if _, ok := c.prefixes[bits]; !ok {
+			c.prefixes[bits] = make(map[netip.Prefix]string)
+			c.lengths = append(c.lengths, bits)
+		}
+		c.prefixes[bits][prefix] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(c.lengths)))
+	return c, nil
+}
+
+// Classify returns the provider hosting ip, or "" when ip is invalid or in no known range.
+// The most specific matching prefix wins.
+func (c *Classifier) Classify(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	for _, bits := range c.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if provider, ok := c.prefixes[bits][prefix]; ok {
+			return provider
+		}
+	}
+	return ""
+}
+
+// defaultClassifier parses the embedded ranges on first use
+var defaultClassifier = sync.OnceValue(func() *Classifier {
+	c, err := Parse(rangesData)
+	if err != nil {
+		panic(fmt.Sprintf("cloud: invalid embedded ranges: %v", err))
+	}
+	return c
+})
+
+// Classify returns the provider hosting ip according to the embedded ranges
+func Classify(ip string) string {
+	return defaultClassifier().Classify(ip)
+}
@@ -0,0 +1,50 @@
+package cloud
+
+import "testing"
+
+func TestClassifierClassify(t *testing.T) {
+	c, err := Parse(`# comment
+aws 52.0.0.0/10
+cloudflare 52.10.0.0/16
+
+gcp 2600:1900::/28
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"52.1.2.3":        AWS,
+		"52.10.1.1":       Cloudflare, // the more specific prefix wins
+		"::ffff:52.1.2.3": AWS,
+		"2600:1900::1":    GCP,
+		"8.8.8.8":         "",
+		"2001:db8::1":     "",
+		"not-an-ip":       "",
+	}
+	for ip, expected := range tests {
+		if got := c.Classify(ip); got != expected {
+			t.Errorf("Classify(%q): expected %q, got %q", ip, expected, got)
+		}
+	}
+
+	if _, err := Parse("aws"); err == nil {
+		t.Error("Expected an error for a line without a prefix")
+	}
+	if _, err := Parse("aws 300.0.0.0/8"); err == nil {
+		t.Error("Expected an error for an invalid prefix")
+	}
+}
+
+func TestEmbeddedRanges(t *testing.T) {
+	tests := map[string]string{
+		"104.16.1.1":   Cloudflare,
+		"151.101.1.69": Fastly,
+		"54.230.1.1":   AWS,
+	}
+	for ip, expected := range tests {
+		if got := Classify(ip); got != expected {
+			t.Errorf("Classify(%q): expected %q, got %q", ip, expected, got)
+		}
+	}
+}
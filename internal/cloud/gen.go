@@ -0,0 +1,207 @@
+//go:build ignore
+
+// gen.go downloads the published IP ranges of each supported cloud provider
+// and writes them to ranges.txt. Run it with go generate ./internal/cloud.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	awsURL         = "https://ip-ranges.amazonaws.com/ip-ranges.json"
+	gcpURL         = "https://www.gstatic.com/ipranges/cloud.json"
+	azurePageURL   = "https://www.microsoft.com/en-us/download/details.aspx?id=56519"
+	cloudflareV4   = "https://www.cloudflare.com/ips-v4"
+	cloudflareV6   = "https://www.cloudflare.com/ips-v6"
+	fastlyURL      = "https://api.fastly.com/public-ip-list"
+	outputPath     = "ranges.txt"
+	requestTimeout = 60 * time.Second
+)
+
+// azureDownloadPattern finds the weekly service tags file linked from the download page
+var azureDownloadPattern = regexp.MustCompile(`https://download\.microsoft\.com/download/[^"]+/ServiceTags_Public_\d+\.json`)
+
+var client = &http.Client{Timeout: requestTimeout}
+
+func main() {
+	fetchers := []struct {
+		provider string
+		fetch    func() ([]string, error)
+	}{
+		{"aws", fetchAWS},
+		{"gcp", fetchGCP},
+		{"azure", fetchAzure},
+		{"cloudflare", fetchCloudflare},
+		{"fastly", fetchFastly},
+	}
+
+	var lines []string
+	for _, f := range fetchers {
+		prefixes, err := f.fetch()
+		if err != nil {
+			log.Fatalf("%s: %v", f.provider, err)
+		}
+		seen := make(map[netip.Prefix]struct{}, len(prefixes))
+		for _, p := range prefixes {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(p))
+			if err != nil {
+				log.Fatalf("%s: %v", f.provider, err)
+			}
+			prefix = prefix.Masked()
+			if _, ok := seen[prefix]; ok {
+				continue
+			}
+			seen[prefix] = struct{}{}
+			lines = append(lines, f.provider+" "+prefix.String())
+		}
+		log.Printf("%s: %d prefixes", f.provider, len(seen))
+	}
+	sort.Strings(lines)
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "# Code generated by gen.go; DO NOT EDIT.\n# Published cloud provider IP ranges, fetched %s\n", time.Now().UTC().Format("2006-01-02"))
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// get returns the body of url, failing on non-200 responses
+func get(url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// getJSON decodes the JSON body of url into v
+func getJSON(url string, v interface{}) error {
+	body, err := get(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func fetchAWS() ([]string, error) {
+	var data struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := getJSON(awsURL, &data); err != nil {
+		return nil, err
+	}
+	var prefixes []string
+	for _, p := range data.Prefixes {
+		prefixes = append(prefixes, p.IPPrefix)
+	}
+	for _, p := range data.IPv6Prefixes {
+		prefixes = append(prefixes, p.IPv6Prefix)
+	}
+	return prefixes, nil
+}
+
+func fetchGCP() ([]string, error) {
+	var data struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+		} `json:"prefixes"`
+	}
+	if err := getJSON(gcpURL, &data); err != nil {
+		return nil, err
+	}
+	var prefixes []string
+	for _, p := range data.Prefixes {
+		if p.IPv4Prefix != "" {
+			prefixes = append(prefixes, p.IPv4Prefix)
+		}
+		if p.IPv6Prefix != "" {
+			prefixes = append(prefixes, p.IPv6Prefix)
+		}
+	}
+	return prefixes, nil
+}
+
+func fetchAzure() ([]string, error) {
+	page, err := get(azurePageURL)
+	if err != nil {
+		return nil, err
+	}
+	url := azureDownloadPattern.Find(page)
+	if url == nil {
+		return nil, fmt.Errorf("no service tags download link on %s", azurePageURL)
+	}
+
+	var data struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := getJSON(string(url), &data); err != nil {
+		return nil, err
+	}
+	// AzureCloud is the union of every public Azure region
+	for _, v := range data.Values {
+		if v.Name == "AzureCloud" {
+			return v.Properties.AddressPrefixes, nil
+		}
+	}
+	return nil, fmt.Errorf("no AzureCloud service tag in %s", url)
+}
+
+func fetchCloudflare() ([]string, error) {
+	var prefixes []string
+	for _, url := range []string{cloudflareV4, cloudflareV6} {
+		body, err := get(url)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, strings.Fields(string(body))...)
+	}
+	return prefixes, nil
+}
+
+func fetchFastly() ([]string, error) {
+	var data struct {
+		Addresses     []string `json:"addresses"`
+		IPv6Addresses []string `json:"ipv6_addresses"`
+	}
+	if err := getJSON(fastlyURL, &data); err != nil {
+		return nil, err
+	}
+	return append(data.Addresses, data.IPv6Addresses...), nil
+}
@@ -0,0 +1,153 @@
+// Package compat converts subfinder configuration for other recon tools
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// amassSource is the Amass data source a subfinder source's keys carry over to
+type amassSource struct {
+	// Name is the data source name in Amass datasources.yaml
+	Name string
+	// Fields name the Amass credential each colon-separated part of a subfinder
+	// key goes to; an empty field drops that part
+	Fields []string
+}
+
+// amassSources maps every subfinder source with an Amass counterpart that takes
+// the same credentials. Sources whose keys do not carry over, such as zoomeyeapi
+// (an API key in subfinder, a username and password in Amass), are left out.
+var amassSources = map[string]amassSource{
+	"bevigil":        {Name: "BeVigil", Fields: []string{"apikey"}},
+	"binaryedge":     {Name: "BinaryEdge", Fields: []string{"apikey"}},
+	"bufferover":     {Name: "BufferOver", Fields: []string{"apikey"}},
+	"builtwith":      {Name: "BuiltWith", Fields: []string{"apikey"}},
+	"c99":            {Name: "C99", Fields: []string{"apikey"}},
+	"censys":         {Name: "Censys", Fields: []string{"apikey", "secret"}},
+	"certspotter":    {Name: "Certspotter", Fields: []string{"apikey"}},
+	"chaos":          {Name: "Chaos", Fields: []string{"apikey"}},
+	"dnsdb":          {Name: "DNSDB", Fields: []string{"apikey"}},
+	"facebook":       {Name: "FacebookCT", Fields: []string{"apikey", "secret"}},
+	"fofa":           {Name: "FOFA", Fields: []string{"username", "apikey"}},
+	"fullhunt":       {Name: "FullHunt", Fields: []string{"apikey"}},
+	"github":         {Name: "GitHub", Fields: []string{"apikey"}},
+	"hunter":         {Name: "Hunter", Fields: []string{"apikey"}},
+	"intelx":         {Name: "IntelX", Fields: []string{"", "apikey"}},
+	"leakix":         {Name: "LeakIX", Fields: []string{"apikey"}},
+	"netlas":         {Name: "Netlas", Fields: []string{"apikey"}},
+	"quake":          {Name: "Quake", Fields: []string{"apikey"}},
+	"securitytrails": {Name: "SecurityTrails", Fields: []string{"apikey"}},
+	"shodan":         {Name: "Shodan", Fields: []string{"apikey"}},
+	"threatbook":     {Name: "ThreatBook", Fields: []string{"apikey"}},
+	"virustotal":     {Name: "VirusTotal", Fields: []string{"apikey"}},
+	"whoisxmlapi":    {Name: "WhoisXMLAPI", Fields: []string{"apikey"}},
+}
+
+// amassMinimumTTL is the global_options.minimum_ttl written to the config, in
+// minutes, matching the Amass default
+const amassMinimumTTL = 1440
+
+// ErrNoMappableSources is returned when no source in the subfinder config has
+// both an Amass counterpart and a usable key
+var ErrNoMappableSources = errors.New("no subfinder source in the config has an Amass counterpart with a usable key")
+
+// amassConfig is the layout of Amass datasources.yaml
+type amassConfig struct {
+	Datasources   []amassDatasource  `yaml:"datasources"`
+	GlobalOptions amassGlobalOptions `yaml:"global_options"`
+}
+
+type amassDatasource struct {
+	Name  string                       `yaml:"name"`
+	Creds map[string]map[string]string `yaml:"creds"`
+}
+
+type amassGlobalOptions struct {
+	MinimumTTL int `yaml:"minimum_ttl"`
+}
+
+// AmassDatasources converts a subfinder provider-config.yaml into an Amass
+// datasources.yaml. Each key of a source becomes one Amass credential set,
+// named account, account2 and so on. skipped lists the configured sources that
+// have no Amass counterpart or only keys not in the expected format.
+func AmassDatasources(subfinderConfig []byte) (datasources []byte, skipped []string, err error) {
+	var providers map[string][]string
+	if err := yaml.Unmarshal(subfinderConfig, &providers); err != nil {
+		return nil, nil, fmt.Errorf("invalid subfinder provider config: %w", err)
+	}
+
+	config := amassConfig{GlobalOptions: amassGlobalOptions{MinimumTTL: amassMinimumTTL}}
+	for name, keys := range providers {
+		source, ok := amassSources[strings.ToLower(name)]
+		if !ok {
+			if len(keys) > 0 {
+				skipped = append(skipped, name)
+			}
+			continue
+		}
+
+		creds := make(map[string]map[string]string)
+		for _, key := range keys {
+			account, ok := amassCredentials(source, key)
+			if !ok {
+				continue
+			}
+			label := "account"
+			if len(creds) > 0 {
+				label = fmt.Sprintf("account%d", len(creds)+1)
+			}
+			creds[label] = account
+		}
+		if len(creds) == 0 {
+			if len(keys) > 0 {
+				skipped = append(skipped, name)
+			}
+			continue
+		}
+		config.Datasources = append(config.Datasources, amassDatasource{Name: source.Name, Creds: creds})
+	}
+	sort.Strings(skipped)
+
+	if len(config.Datasources) == 0 {
+		return nil, skipped, ErrNoMappableSources
+	}
+	sort.Slice(config.Datasources, func(i, j int) bool {
+		return config.Datasources[i].Name < config.Datasources[j].Name
+	})
+
+	datasources, err = yaml.Marshal(config)
+	if err != nil {
+		return nil, skipped, err
+	}
+	return datasources, skipped, nil
+}
+
+// amassCredentials splits a subfinder key into the source's Amass fields. A key
+// for a single field is used whole; otherwise it must have one part per field.
+func amassCredentials(source amassSource, key string) (map[string]string, bool) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, false
+	}
+
+	parts := []string{key}
+	if len(source.Fields) > 1 {
+		parts = strings.SplitN(key, ":", len(source.Fields))
+		if len(parts) != len(source.Fields) {
+			return nil, false
+		}
+	}
+
+	account := make(map[string]string, len(parts))
+	for i, field := range source.Fields {
+		if field != "" {
+			account[field] = parts[i]
+		}
+	}
+	return account, true
+}
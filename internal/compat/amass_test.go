@@ -0,0 +1,71 @@
+package compat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAmassDatasources(t *testing.T) {
+	subfinderConfig := []byte(`
+shodan:
+  - SHODAN_KEY_1
+  - SHODAN_KEY_2
+censys:
+  - CENSYS_ID:CENSYS_SECRET
+  - malformed
+intelx:
+  - 2.intelx.io:INTELX_KEY
+zoomeyeapi:
+  - api.zoomeye.ai:ZOOMEYE_KEY
+crtsh: []
+`)
+
+	data, skipped, err := AmassDatasources(subfinderConfig)
+	if err != nil {
+		t.Fatalf("AmassDatasources failed: %v", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"zoomeyeapi"}) {
+		t.Errorf("Expected only zoomeyeapi to be skipped, got %v", skipped)
+	}
+
+	var config amassConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Generated config is not valid YAML: %v\n%s", err, data)
+	}
+	if config.GlobalOptions.MinimumTTL != amassMinimumTTL {
+		t.Errorf("Expected minimum_ttl %d, got %d", amassMinimumTTL, config.GlobalOptions.MinimumTTL)
+	}
+
+	expected := []amassDatasource{
+		{Name: "Censys", Creds: map[string]map[string]string{
+			"account": {"apikey": "CENSYS_ID", "secret": "CENSYS_SECRET"},
+		}},
+		{Name: "IntelX", Creds: map[string]map[string]string{
+			"account": {"apikey": "INTELX_KEY"},
+		}},
+		{Name: "Shodan", Creds: map[string]map[string]string{
+			"account":  {"apikey": "SHODAN_KEY_1"},
+			"account2": {"apikey": "SHODAN_KEY_2"},
+		}},
+	}
+	if !reflect.DeepEqual(config.Datasources, expected) {
+		t.Errorf("Expected datasources %+v, got %+v", expected, config.Datasources)
+	}
+}
+
+func TestAmassDatasourcesErrors(t *testing.T) {
+	if _, _, err := AmassDatasources([]byte("shodan: [unterminated")); err == nil || errors.Is(err, ErrNoMappableSources) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+
+	_, skipped, err := AmassDatasources([]byte("zoomeyeapi:\n  - host:key\ncensys:\n  - no-secret\n"))
+	if !errors.Is(err, ErrNoMappableSources) {
+		t.Errorf("Expected ErrNoMappableSources, got %v", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"censys", "zoomeyeapi"}) {
+		t.Errorf("Expected censys and zoomeyeapi to be skipped, got %v", skipped)
+	}
+}
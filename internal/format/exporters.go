@@ -0,0 +1,206 @@
+// Package format converts enumeration results into formats other security tools consume
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Supported export formats
+const (
+	Plain       = "plain"
+	NmapXML     = "nmap-xml"
+	MasscanJSON = "masscan-json"
+	AmassJSON   = "amass-json"
+)
+
+// Formats lists every supported export format
+var Formats = []string{Plain, NmapXML, MasscanJSON, AmassJSON}
+
+// Record is one discovered subdomain with whatever is known about it
+type Record struct {
+	Name      string
+	Domain    string
+	Addresses []string
+	Sources   []string
+}
+
+// IsSupported reports whether name is a known export format
+func IsSupported(name string) bool {
+	for _, f := range Formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Export renders records in the named format and returns the bytes with their MIME type
+func Export(name string, records []Record) ([]byte, string, error) {
+	switch name {
+	case Plain, "":
+		return exportPlain(records), "text/plain", nil
+	case NmapXML:
+		data, err := exportNmapXML(records)
+		return data, "application/xml", err
+	case MasscanJSON:
+		data, err := exportMasscanJSON(records, time.Now())
+		return data, "application/json", err
+	case AmassJSON:
+		data, err := exportAmassJSON(records)
+		return data, "application/x-ndjson", err
+	default:
+		return nil, "", fmt.Errorf("unsupported export format %q", name)
+	}
+}
+
+// exportPlain writes one subdomain per line
+func exportPlain(records []Record) []byte {
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.WriteString(record.Name)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// nmapRun is the root of an Nmap XML document
+type nmapRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Scanner string     `xml:"scanner,attr"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+// nmapHost is a single <host> element
+type nmapHost struct {
+	Addresses []nmapAddress `xml:"address"`
+	Hostnames nmapHostnames `xml:"hostnames"`
+}
+
+// nmapAddress is a host <address> element
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+// nmapHostnames wraps the hostnames of a host
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+// nmapHostname is a <hostname> element
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// exportNmapXML emits one <host> per subdomain with its resolved addresses
+func exportNmapXML(records []Record) ([]byte, error) {
+	run := nmapRun{Scanner: "mcp-subfinder-server", Hosts: make([]nmapHost, 0, len(records))}
+	for _, record := range records {
+		host := nmapHost{
+			Hostnames: nmapHostnames{Hostnames: []nmapHostname{{Name: record.Name, Type: "user"}}},
+		}
+		for _, addr := range record.Addresses {
+			host.Addresses = append(host.Addresses, nmapAddress{Addr: addr, AddrType: addressType(addr)})
+		}
+		run.Hosts = append(run.Hosts, host)
+	}
+
+	data, err := xml.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode nmap XML: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// addressType returns the Nmap addrtype for an IP address
+func addressType(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// masscanEntry is one element of masscan's JSON output
+type masscanEntry struct {
+	IP        string        `json:"ip"`
+	Timestamp string        `json:"timestamp"`
+	Ports     []interface{} `json:"ports"`
+}
+
+// exportMasscanJSON emits masscan's JSON array with one entry per unique resolved
+// address, since masscan works on IPs; subdomains without addresses are skipped
+func exportMasscanJSON(records []Record, now time.Time) ([]byte, error) {
+	seen := make(map[string]struct{})
+	var addrs []string
+	for _, record := range records {
+		for _, addr := range record.Addresses {
+			if _, ok := seen[addr]; ok {
+				continue
+			}
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+
+	timestamp := fmt.Sprintf("%d", now.Unix())
+	entries := make([]masscanEntry, 0, len(addrs))
+	for _, addr := range addrs {
+		entries = append(entries, masscanEntry{IP: addr, Timestamp: timestamp, Ports: []interface{}{}})
+	}
+
+	data, err := jsoniter.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode masscan JSON: %w", err)
+	}
+	return data, nil
+}
+
+// amassAddress is an address in Amass JSON output
+type amassAddress struct {
+	IP string `json:"ip"`
+}
+
+// amassEntry is one line of Amass JSON output
+type amassEntry struct {
+	Name      string         `json:"name"`
+	Domain    string         `json:"domain"`
+	Addresses []amassAddress `json:"addresses"`
+	Sources   []string       `json:"sources"`
+}
+
+// exportAmassJSON emits one Amass JSON object per line
+func exportAmassJSON(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, record := range records {
+		entry := amassEntry{
+			Name:      record.Name,
+			Domain:    strings.ToLower(record.Domain),
+			Addresses: make([]amassAddress, 0, len(record.Addresses)),
+			Sources:   record.Sources,
+		}
+		if entry.Sources == nil {
+			entry.Sources = []string{}
+		}
+		for _, addr := range record.Addresses {
+			entry.Addresses = append(entry.Addresses, amassAddress{IP: addr})
+		}
+
+		line, err := jsoniter.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode amass JSON: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRecords = []Record{
+	{Name: "api.example.com", Domain: "example.com", Addresses: []string{"93.184.216.34", "2606:2800:220:1::1"}, Sources: []string{"crtsh"}},
+	{Name: "www.example.com", Domain: "example.com", Addresses: []string{"93.184.216.34"}},
+	{Name: "old.example.com", Domain: "example.com"},
+}
+
+func TestExportPlain(t *testing.T) {
+	data, mimeType, err := Export(Plain, testRecords)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if mimeType != "text/plain" {
+		t.Errorf("Expected text/plain, got %s", mimeType)
+	}
+	if string(data) != "api.example.com\nwww.example.com\nold.example.com\n" {
+		t.Errorf("Unexpected plain output %q", data)
+	}
+}
+
+func TestExportNmapXML(t *testing.T) {
+	data, mimeType, err := Export(NmapXML, testRecords)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if mimeType != "application/xml" {
+		t.Errorf("Expected application/xml, got %s", mimeType)
+	}
+
+	out := string(data)
+	for _, fragment := range []string{
+		`<nmaprun scanner="mcp-subfinder-server">`,
+		`<hostname name="api.example.com" type="user"></hostname>`,
+		`<address addr="2606:2800:220:1::1" addrtype="ipv6"></address>`,
+		`<address addr="93.184.216.34" addrtype="ipv4"></address>`,
+	} {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("Expected nmap XML to contain %s, got:\n%s", fragment, out)
+		}
+	}
+	if got := strings.Count(out, "<host>"); got != len(testRecords) {
+		t.Errorf("Expected %d hosts, got %d", len(testRecords), got)
+	}
+}
+
+func TestExportMasscanJSON(t *testing.T) {
+	data, err := exportMasscanJSON(testRecords, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	expected := `[{"ip":"2606:2800:220:1::1","timestamp":"1700000000","ports":[]},{"ip":"93.184.216.34","timestamp":"1700000000","ports":[]}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestExportAmassJSON(t *testing.T) {
+	data, mimeType, err := Export(AmassJSON, testRecords)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if mimeType != "application/x-ndjson" {
+		t.Errorf("Expected application/x-ndjson, got %s", mimeType)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(testRecords) {
+		t.Fatalf("Expected %d lines, got %d", len(testRecords), len(lines))
+	}
+	expected := `{"name":"api.example.com","domain":"example.com","addresses":[{"ip":"93.184.216.34"},{"ip":"2606:2800:220:1::1"}],"sources":["crtsh"]}`
+	if lines[0] != expected {
+		t.Errorf("Expected %s, got %s", expected, lines[0])
+	}
+	if lines[2] != `{"name":"old.example.com","domain":"example.com","addresses":[],"sources":[]}` {
+		t.Errorf("Unexpected line for unresolved subdomain: %s", lines[2])
+	}
+}
+
+func TestExportUnsupported(t *testing.T) {
+	if IsSupported("csv") {
+		t.Error("Expected csv to be unsupported")
+	}
+	if _, _, err := Export("csv", testRecords); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,58 @@
+package format
+
+// Field names accepted by outputFields. Most mirror the JSON resource's own keys;
+// ip4, ip6 and sources are derived from the resolved addresses and source attribution.
+const (
+	FieldSubdomain       = "subdomain"
+	FieldIPs             = "ips"
+	FieldIP4             = "ip4"
+	FieldIP6             = "ip6"
+	FieldSources         = "sources"
+	FieldParked          = "parked"
+	FieldCert            = "cert"
+	FieldCloudProvider   = "cloudProvider"
+	FieldAssetType       = "assetType"
+	FieldInScope         = "inScope"
+	FieldShodan          = "shodan"
+	FieldAliases         = "aliases"
+	FieldTrustScore      = "trustScore"
+	FieldStatus          = "status"
+	FieldSensitive       = "sensitive"
+	FieldSensitiveReason = "sensitiveReason"
+	FieldTLSVersions     = "tlsVersions"
+	FieldVirusTotal      = "virusTotal"
+)
+
+// Fields lists every field that can be selected for JSON output
+var Fields = []string{
+	FieldSubdomain, FieldIPs, FieldIP4, FieldIP6, FieldSources, FieldParked,
+	FieldCert, FieldCloudProvider, FieldAssetType, FieldInScope, FieldShodan,
+	FieldAliases, FieldTrustScore, FieldStatus, FieldSensitive, FieldSensitiveReason,
+	FieldTLSVersions, FieldVirusTotal,
+}
+
+// IsField reports whether name is a selectable output field
+func IsField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectFields returns copies of rows holding only the named fields. Fields a
+// row does not have are left out rather than written as null.
+func SelectFields(rows []map[string]interface{}, fields []string) []map[string]interface{} {
+	selected := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		kept := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, ok := row[field]; ok {
+				kept[field] = value
+			}
+		}
+		selected = append(selected, kept)
+	}
+	return selected
+}
@@ -0,0 +1,26 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectFields(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"subdomain": "www.example.com", "ip4": []string{"192.0.2.1"}, "sources": []string{"crtsh"}, "cloudProvider": "aws"},
+		{"subdomain": "old.example.com", "sources": []string{"crtsh"}},
+	}
+
+	got := SelectFields(rows, []string{"subdomain", "ip4"})
+	expected := []map[string]interface{}{
+		{"subdomain": "www.example.com", "ip4": []string{"192.0.2.1"}},
+		{"subdomain": "old.example.com"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if !IsField("ip4") || IsField("hostname") {
+		t.Error("Expected ip4 to be a field and hostname not to be")
+	}
+}
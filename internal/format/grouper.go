@@ -0,0 +1,83 @@
+package format
+
+import (
+	"strings"
+
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// Keys accepted by outputGroupBy
+const (
+	GroupNone          = "none"
+	GroupCloudProvider = "cloudProvider"
+	GroupSource        = "source"
+	GroupTLD           = "tld"
+	GroupASN           = "asn"
+)
+
+// GroupUnknown collects the entries that have no value for the grouping key
+const GroupUnknown = "unknown"
+
+// GroupKeys lists every key JSON output can be grouped by
+var GroupKeys = []string{GroupNone, GroupCloudProvider, GroupSource, GroupTLD, GroupASN}
+
+// IsGroupKey reports whether key is a supported grouping key
+func IsGroupKey(key string) bool {
+	for _, k := range GroupKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupBy sorts entries into groups by key, keeping their order within each
+// group. Grouping by source uses sources, the passive sources that reported each
+// subdomain; as with asn, an entry can land in more than one group. Entries
+// without a value for key are grouped under GroupUnknown.
+func GroupBy(entries []subfinder.SubdomainEntry, sources map[string][]string, key string) map[string][]subfinder.SubdomainEntry {
+	groups := make(map[string][]subfinder.SubdomainEntry)
+	for _, entry := range entries {
+		keys := groupKeys(entry, sources[entry.Subdomain], key)
+		if len(keys) == 0 {
+			keys = []string{GroupUnknown}
+		}
+		for _, k := range keys {
+			groups[k] = append(groups[k], entry)
+		}
+	}
+	return groups
+}
+
+// groupKeys returns the distinct groups entry belongs to
+func groupKeys(entry subfinder.SubdomainEntry, sources []string, key string) []string {
+	var keys []string
+	add := func(k string) {
+		if k == "" {
+			return
+		}
+		for _, existing := range keys {
+			if existing == k {
+				return
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	switch key {
+	case GroupCloudProvider:
+		add(entry.CloudProvider)
+	case GroupSource:
+		for _, source := range sources {
+			add(source)
+		}
+	case GroupTLD:
+		name := strings.TrimSuffix(entry.Subdomain, ".")
+		add(strings.ToLower(name[strings.LastIndex(name, ".")+1:]))
+	case GroupASN:
+		for _, host := range entry.Shodan {
+			add(host.ASN)
+		}
+	}
+	return keys
+}
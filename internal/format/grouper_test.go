@@ -0,0 +1,64 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"mcp-subfinder-server/internal/shodan"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+func TestGroupBy(t *testing.T) {
+	entries := []subfinder.SubdomainEntry{
+		{Subdomain: "www.example.com", CloudProvider: "aws", Shodan: []shodan.Host{{IP: "192.0.2.1", ASN: "AS64496"}, {IP: "192.0.2.2", ASN: "AS64497"}}},
+		{Subdomain: "api.example.co.uk", CloudProvider: "aws", Shodan: []shodan.Host{{IP: "192.0.2.3", ASN: "AS64496"}}},
+		{Subdomain: "old.example.com"},
+	}
+	sources := map[string][]string{
+		"www.example.com":   {"crtsh", "hackertarget"},
+		"api.example.co.uk": {"crtsh"},
+	}
+
+	names := func(groups map[string][]subfinder.SubdomainEntry) map[string][]string {
+		out := make(map[string][]string, len(groups))
+		for key, group := range groups {
+			for _, entry := range group {
+				out[key] = append(out[key], entry.Subdomain)
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		key      string
+		expected map[string][]string
+	}{
+		{GroupCloudProvider, map[string][]string{
+			"aws":     {"www.example.com", "api.example.co.uk"},
+			"unknown": {"old.example.com"},
+		}},
+		{GroupSource, map[string][]string{
+			"crtsh":        {"www.example.com", "api.example.co.uk"},
+			"hackertarget": {"www.example.com"},
+			"unknown":      {"old.example.com"},
+		}},
+		{GroupTLD, map[string][]string{
+			"com": {"www.example.com", "old.example.com"},
+			"uk":  {"api.example.co.uk"},
+		}},
+		{GroupASN, map[string][]string{
+			"AS64496": {"www.example.com", "api.example.co.uk"},
+			"AS64497": {"www.example.com"},
+			"unknown": {"old.example.com"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := names(GroupBy(entries, sources, tt.key)); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("GroupBy %s: expected %v, got %v", tt.key, tt.expected, got)
+		}
+	}
+
+	if !IsGroupKey("tld") || IsGroupKey("country") {
+		t.Error("Expected tld to be a group key and country not to be")
+	}
+}
@@ -0,0 +1,161 @@
+// Package jobs tracks deferred tool calls until their results are collected
+package jobs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a deferred job can be polled before it is forgotten
+	DefaultTTL = 24 * time.Hour
+	// DefaultMaxJobs caps the unexpired jobs a store keeps, results included
+	DefaultMaxJobs = 1000
+)
+
+// ErrStoreFull is returned by Create when the store already holds its maximum of jobs
+var ErrStoreFull = errors.New("job store is full")
+
+// Job states reported to callers polling a job
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	// StatusFailed is a finished job whose result is an error
+	StatusFailed = "failed"
+)
+
+// Job is the state of one deferred call
+type Job struct {
+	ID     string
+	Domain string
+	// Owner identifies the session that created the job; only it may collect the result
+	Owner  string
+	Status string
+	// StartedAt is when the job was created
+	StartedAt time.Time
+	// CompletedAt is when the job finished, zero until then
+	CompletedAt time.Time
+	// Found counts the results discovered so far while the job is running
+	Found int
+	// Result is the finished call's result, once Status is StatusDone
+	Result interface{}
+
+	expires time.Time
+}
+
+// Store keeps deferred jobs in memory until they expire
+type Store struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	ttl     time.Duration
+	maxJobs int
+}
+
+// NewStore creates an empty store whose jobs expire ttl after their last update
+// and which holds at most maxJobs unexpired jobs
+func NewStore(ttl time.Duration, maxJobs int) *Store {
+	return &Store{jobs: make(map[string]*Job), ttl: ttl, maxJobs: maxJobs}
+}
+
+// Create adds a pending job for domain owned by owner, evicting any expired ones.
+// It returns ErrStoreFull, storing nothing, when the store holds maxJobs jobs.
+func (s *Store) Create(id, domain, owner string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, job := range s.jobs {
+		if !now.Before(job.expires) {
+			delete(s.jobs, k)
+		}
+	}
+	if len(s.jobs) >= s.maxJobs {
+		return ErrStoreFull
+	}
+	s.jobs[id] = &Job{ID: id, Domain: domain, Owner: owner, Status: StatusPending, StartedAt: now, expires: now.Add(s.ttl)}
+	return nil
+}
+
+// Delete forgets a job, such as one that could not be started
+func (s *Store) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+// Start marks a job as running
+func (s *Store) Start(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		job.Status = StatusRunning
+	}
+}
+
+// AddFound adds n to the number of results a running job has found
+func (s *Store) AddFound(id string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		job.Found += n
+	}
+}
+
+// Finish records a job's result, marking the job failed when the result is an
+// error; it can be polled for another ttl from now
+func (s *Store) Finish(id string, result interface{}, failed bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		job.Status = StatusDone
+		if failed {
+			job.Status = StatusFailed
+		}
+		job.Result = result
+		job.CompletedAt = now
+		job.expires = now.Add(s.ttl)
+	}
+}
+
+// Get returns a copy of the unexpired job stored under id
+func (s *Store) Get(id string, now time.Time) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	if !now.Before(job.expires) {
+		delete(s.jobs, id)
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns copies of the unexpired jobs, most recently started first
+func (s *Store) List(now time.Time) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Job, 0, len(s.jobs))
+	for id, job := range s.jobs {
+		if !now.Before(job.expires) {
+			delete(s.jobs, id)
+			continue
+		}
+		list = append(list, *job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].StartedAt.Equal(list[j].StartedAt) {
+			return list[i].StartedAt.After(list[j].StartedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
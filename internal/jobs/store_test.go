@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreLifecycle(t *testing.T) {
+	store := NewStore(time.Hour, DefaultMaxJobs)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := store.Get("missing", now); ok {
+		t.Fatal("Expected no job for an unknown ID")
+	}
+
+	store.Create("job-1", "example.com", "", now)
+	if job, ok := store.Get("job-1", now); !ok || job.Status != StatusPending {
+		t.Fatalf("Expected a pending job, got %+v", job)
+	}
+
+	store.Start("job-1")
+	store.AddFound("job-1", 2)
+	store.AddFound("job-1", 3)
+	if job, _ := store.Get("job-1", now); job.Status != StatusRunning || job.Found != 5 {
+		t.Errorf("Expected a running job with 5 results, got %+v", job)
+	}
+
+	// Finishing restarts the expiry so the result stays available
+	finished := now.Add(50 * time.Minute)
+	store.Finish("job-1", "result", false, finished)
+	job, ok := store.Get("job-1", now.Add(90*time.Minute))
+	if !ok || job.Status != StatusDone || job.Result != "result" || !job.CompletedAt.Equal(finished) {
+		t.Errorf("Expected a finished job, got %+v", job)
+	}
+
+	if _, ok := store.Get("job-1", finished.Add(time.Hour)); ok {
+		t.Error("Expected the job to expire")
+	}
+}
+
+func TestStoreCreateEvictsExpired(t *testing.T) {
+	store := NewStore(time.Hour, DefaultMaxJobs)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store.Create("old", "example.com", "", now)
+	store.Create("new", "example.com", "", now.Add(2*time.Hour))
+
+	store.mu.Lock()
+	_, ok := store.jobs["old"]
+	store.mu.Unlock()
+	if ok {
+		t.Error("Expected the expired job to be evicted")
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	store := NewStore(time.Hour, DefaultMaxJobs)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store.Create("first", "example.com", "", now)
+	store.Create("second", "example.org", "", now.Add(time.Minute))
+	store.Finish("first", "error", true, now.Add(2*time.Minute))
+
+	list := store.List(now.Add(3 * time.Minute))
+	if len(list) != 2 || list[0].ID != "second" || list[1].ID != "first" {
+		t.Fatalf("Expected both jobs, newest first, got %+v", list)
+	}
+	if list[1].Status != StatusFailed || list[1].Domain != "example.com" {
+		t.Errorf("Expected a failed job for example.com, got %+v", list[1])
+	}
+
+	// The pending job expires an hour after it was created
+	if list := store.List(now.Add(61 * time.Minute)); len(list) != 1 || list[0].ID != "first" {
+		t.Errorf("Expected only the finished job to remain, got %+v", list)
+	}
+}
+
+func TestStoreLimit(t *testing.T) {
+	store := NewStore(time.Hour, 2)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, id := range []string{"first", "second"} {
+		if err := store.Create(id, "example.com", "session", now); err != nil {
+			t.Fatalf("Expected job %s to be stored, got %v", id, err)
+		}
+	}
+	if err := store.Create("third", "example.com", "session", now); !errors.Is(err, ErrStoreFull) {
+		t.Errorf("Expected ErrStoreFull, got %v", err)
+	}
+	if _, ok := store.Get("third", now); ok {
+		t.Error("Expected the rejected job not to be stored")
+	}
+
+	// Deleted and expired jobs make room again
+	store.Delete("first")
+	if err := store.Create("third", "example.com", "session", now); err != nil {
+		t.Errorf("Expected room after a delete, got %v", err)
+	}
+	if err := store.Create("fourth", "example.com", "session", now.Add(2*time.Hour)); err != nil {
+		t.Errorf("Expected room once the jobs expired, got %v", err)
+	}
+}
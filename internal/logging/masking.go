@@ -0,0 +1,99 @@
+// Package logging provides slog handlers used to keep results out of shared logs
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Mask returns the placeholder logged instead of subdomain: [REDACTED-{hash}],
+// where hash is the first 8 hex digits of the name's SHA-256
+func Mask(subdomain string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(subdomain)))
+	return "[REDACTED-" + hex.EncodeToString(sum[:])[:8] + "]"
+}
+
+// maskingHandler masks every subdomain of a domain in the records it passes on
+type maskingHandler struct {
+	next    slog.Handler
+	pattern *regexp.Regexp
+}
+
+// NewMaskingLogger returns a logger writing through logger's handler with every
+// subdomain of domain in messages and attribute values replaced by Mask. The
+// domain itself is logged as is.
+func NewMaskingLogger(logger *slog.Logger, domain string) *slog.Logger {
+	pattern := regexp.MustCompile(`(?i)[a-z0-9_*-][a-z0-9_*.-]*\.` + regexp.QuoteMeta(strings.TrimSuffix(domain, ".")) + `\b`)
+	return slog.New(&maskingHandler{next: logger.Handler(), pattern: pattern})
+}
+
+// Enabled defers to the wrapped handler
+func (h *maskingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle masks the record's message and attributes before passing it on
+func (h *maskingHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, h.maskString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.maskAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, masked)
+}
+
+// WithAttrs masks attrs once, as they are bound to the handler
+func (h *maskingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.maskAttr(a)
+	}
+	return &maskingHandler{next: h.next.WithAttrs(masked), pattern: h.pattern}
+}
+
+// WithGroup defers to the wrapped handler
+func (h *maskingHandler) WithGroup(name string) slog.Handler {
+	return &maskingHandler{next: h.next.WithGroup(name), pattern: h.pattern}
+}
+
+// maskString replaces every subdomain in s
+func (h *maskingHandler) maskString(s string) string {
+	return h.pattern.ReplaceAllStringFunc(s, Mask)
+}
+
+// maskAttr masks an attribute's value. Values other than strings, string slices
+// and groups are formatted as text when that text contains a subdomain.
+func (h *maskingHandler) maskAttr(a slog.Attr) slog.Attr {
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.maskString(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		masked := make([]any, len(group))
+		for i, member := range group {
+			masked[i] = h.maskAttr(member)
+		}
+		return slog.Group(a.Key, masked...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case []string:
+			masked := make([]string, len(v))
+			for i, s := range v {
+				masked[i] = h.maskString(s)
+			}
+			return slog.Any(a.Key, masked)
+		default:
+			text := fmt.Sprintf("%+v", v)
+			if h.pattern.MatchString(text) {
+				return slog.String(a.Key, h.maskString(text))
+			}
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: value}
+}
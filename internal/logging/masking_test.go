@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	masked := Mask("www.example.com")
+	if !strings.HasPrefix(masked, "[REDACTED-") || len(masked) != len("[REDACTED-12345678]") {
+		t.Errorf("Unexpected mask %q", masked)
+	}
+	if Mask("WWW.Example.com") != masked {
+		t.Error("Expected masking to ignore case")
+	}
+	if Mask("api.example.com") == masked {
+		t.Error("Expected different subdomains to get different masks")
+	}
+}
+
+func TestMaskingLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewTextHandler(&buf, nil))
+	logger := NewMaskingLogger(base, "example.com").With("subdomain", "bound.example.com")
+
+	logger.Info("Resolved www.example.com",
+		"domain", "example.com",
+		"subdomains", []string{"api.example.com", "mail.example.com"},
+		"error", errors.New("lookup dev.example.com: no such host"),
+		slog.Group("result", "name", "deep.example.com"),
+		"count", 3)
+
+	out := buf.String()
+	for _, name := range []string{"bound.example.com", "www.example.com", "api.example.com", "mail.example.com", "dev.example.com", "deep.example.com"} {
+		if strings.Contains(out, name) {
+			t.Errorf("Expected %s to be masked in %q", name, out)
+		}
+		if !strings.Contains(out, Mask(name)) {
+			t.Errorf("Expected the mask of %s in %q", name, out)
+		}
+	}
+	for _, kept := range []string{"domain=example.com", "count=3"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Expected %q in %q", kept, out)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"mcp-subfinder-server/internal/compat"
+)
+
+// amassConfigTool returns the definition of the generateAmassConfig tool
+func amassConfigTool() Tool {
+	return Tool{
+		Name:        "generateAmassConfig",
+		Title:       "Generate Amass Config",
+		Description: "Converts a subfinder provider-config.yaml into an Amass datasources.yaml, carrying over the API keys of every source both tools support",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"subfinderConfigBase64": map[string]interface{}{
+					"type":        "string",
+					"description": "Base64-encoded subfinder provider-config.yaml",
+				},
+			},
+			"required": []string{"subfinderConfigBase64"},
+		},
+	}
+}
+
+// handleGenerateAmassConfig runs the generateAmassConfig tool. It never runs an
+// enumeration; the converted config is returned as a YAML resource.
+func handleGenerateAmassConfig(ctx context.Context, req *Request, params ToolCallParams, logger *slog.Logger) Response {
+	encoded, ok := requiredStringArgument(params.Arguments, "subfinderConfigBase64", logger)
+	if !ok {
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	subfinderConfig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		logger.Warn("Invalid subfinderConfigBase64 parameter", "error", err)
+		return invalidAmassConfigResponse(req, "subfinderConfigBase64 is not valid base64")
+	}
+
+	// Keys are never logged, only which sources were left behind
+	datasources, skipped, err := compat.AmassDatasources(subfinderConfig)
+	if err != nil {
+		logger.Warn("Failed to convert subfinder config", "error", err, "skippedSources", skipped)
+		return invalidAmassConfigResponse(req, err.Error())
+	}
+	logger.Info("Generated Amass config", "skippedSources", skipped)
+
+	summary := "Converted the subfinder provider config to an Amass datasources.yaml"
+	if len(skipped) > 0 {
+		summary = fmt.Sprintf("%s; skipped sources without an Amass counterpart or a usable key: %v", summary, skipped)
+	}
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: formatToolCallResult(ToolCallResult{
+			Content: []interface{}{
+				ContentItem{
+					Type: "text",
+					Text: summary,
+				},
+				ResourceItem{
+					Type:     "resource",
+					MimeType: "application/yaml",
+					Blob:     base64.StdEncoding.EncodeToString(datasources),
+				},
+			},
+		}, protocolVersionFromContext(ctx)),
+	}
+}
+
+// invalidAmassConfigResponse rejects a subfinder config that cannot be converted
+func invalidAmassConfigResponse(req *Request, detail string) Response {
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error:   NewInvalidParamsError(detail),
+	}
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// generateAmassConfig calls the generateAmassConfig tool with a subfinder config
+func generateAmassConfig(subfinderConfig string) Response {
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params: jsoniter.RawMessage(`{"name": "generateAmassConfig", "arguments": {"subfinderConfigBase64": "` +
+			base64.StdEncoding.EncodeToString([]byte(subfinderConfig)) + `"}}`),
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return HandleToolsCall(context.Background(), req, "", logger)
+}
+
+func TestGenerateAmassConfig(t *testing.T) {
+	if !listsTool("generateAmassConfig") {
+		t.Error("Expected generateAmassConfig to be listed")
+	}
+
+	response := generateAmassConfig("shodan:\n  - SHODAN_KEY\nzoomeyeapi:\n  - host:key\n")
+	result, ok := response.Result.(ToolCallResult)
+	if !ok || result.IsError || len(result.Content) != 2 {
+		t.Fatalf("Expected a summary and a resource, got %+v", response)
+	}
+	if summary := result.Content[0].(ContentItem).Text; !strings.Contains(summary, "zoomeyeapi") {
+		t.Errorf("Expected the summary to name the skipped source, got %q", summary)
+	}
+	resource := result.Content[1].(ResourceItem)
+	data, _ := base64.StdEncoding.DecodeString(resource.Blob)
+	if resource.MimeType != "application/yaml" || !strings.Contains(string(data), "name: Shodan") || !strings.Contains(string(data), "apikey: SHODAN_KEY") {
+		t.Errorf("Unexpected resource %s:\n%s", resource.MimeType, data)
+	}
+}
+
+func TestGenerateAmassConfigInvalid(t *testing.T) {
+	for _, config := range []string{"shodan: [unterminated", "zoomeyeapi:\n  - host:key\n"} {
+		response := generateAmassConfig(config)
+		if response.Error == nil || response.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %q, got %+v", config, response)
+		}
+	}
+}
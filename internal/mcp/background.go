@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMaxBackgroundJobs caps the enumerations running in the background at once
+const DefaultMaxBackgroundJobs = 4
+
+// backgroundRunner runs enumerations that outlive their request on a capped
+// number of goroutines, and cancels them all on shutdown
+type backgroundRunner struct {
+	mu      sync.Mutex
+	running int
+	stopped bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// newBackgroundRunner creates a runner with nothing running
+func newBackgroundRunner() *backgroundRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundRunner{ctx: ctx, cancel: cancel}
+}
+
+// backgroundJobs runs every callbackURL and deferred enumeration
+var backgroundJobs = newBackgroundRunner()
+
+// start runs job in a goroutine with a context that keeps ctx's values but not
+// its cancellation or deadline, and is cancelled by stop instead. It returns
+// false without running job when limit jobs are already running or the runner
+// has stopped.
+func (r *backgroundRunner) start(ctx context.Context, limit int, job func(ctx context.Context)) bool {
+	r.mu.Lock()
+	if r.stopped || r.running >= limit {
+		r.mu.Unlock()
+		return false
+	}
+	r.running++
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopCancel := context.AfterFunc(r.ctx, cancel)
+	go func() {
+		defer func() {
+			stopCancel()
+			cancel()
+			r.mu.Lock()
+			r.running--
+			r.mu.Unlock()
+			r.wg.Done()
+		}()
+		job(jobCtx)
+	}()
+	return true
+}
+
+// stop cancels the running jobs, refuses new ones and waits for them to return
+func (r *backgroundRunner) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	r.cancel()
+	r.wg.Wait()
+}
+
+// maxBackgroundJobs returns the configured background job cap, or the default
+func maxBackgroundJobs() int {
+	if limit := currentSettings().MaxBackgroundJobs; limit > 0 {
+		return limit
+	}
+	return DefaultMaxBackgroundJobs
+}
+
+// StopBackgroundJobs cancels the running callbackURL and deferred enumerations
+// and waits for them to return
+func StopBackgroundJobs() {
+	backgroundJobs.stop()
+}
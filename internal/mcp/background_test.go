@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackgroundRunner(t *testing.T) {
+	runner := newBackgroundRunner()
+	type key struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+
+	started := make(chan struct{})
+	cancelled := make(chan string, 1)
+	ok := runner.start(ctx, 1, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		value, _ := ctx.Value(key{}).(string)
+		cancelled <- value
+	})
+	if !ok {
+		t.Fatal("Expected the first job to start")
+	}
+	<-started
+
+	// The request ending does not cancel the job
+	cancel()
+	select {
+	case <-cancelled:
+		t.Fatal("Expected the job to outlive its request")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if runner.start(context.Background(), 1, func(context.Context) {}) {
+		t.Error("Expected a job over the limit to be refused")
+	}
+
+	runner.stop()
+	select {
+	case value := <-cancelled:
+		if value != "value" {
+			t.Errorf("Expected the job context to keep the request's values, got %q", value)
+		}
+	default:
+		t.Fatal("Expected stop to cancel the running job and wait for it")
+	}
+	if runner.start(context.Background(), 1, func(context.Context) {}) {
+		t.Error("Expected jobs to be refused after stop")
+	}
+}
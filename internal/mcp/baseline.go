@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseBaseline decodes a base64-encoded, newline-separated list of subdomains.
+// Names are lowercased, blank lines and # comments are skipped, and duplicates removed.
+func parseBaseline(encoded string) ([]string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("baselineBase64 is not valid base64: %w", err)
+	}
+
+	seen := make(map[string]struct{})
+	var baseline []string
+	for _, line := range strings.Split(string(decoded), "\n") {
+		name := strings.ToLower(strings.TrimSpace(line))
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		baseline = append(baseline, name)
+	}
+
+	return baseline, nil
+}
+
+// diffBaseline compares found subdomains to a baseline. Names are compared
+// case-insensitively and every list is sorted and non-nil.
+func diffBaseline(found, baseline []string) BaselineDiff {
+	diff := BaselineDiff{
+		Added:     []string{},
+		Removed:   []string{},
+		Unchanged: []string{},
+	}
+
+	known := make(map[string]struct{}, len(baseline))
+	for _, name := range baseline {
+		known[strings.ToLower(name)] = struct{}{}
+	}
+
+	current := make(map[string]struct{}, len(found))
+	for _, subdomain := range found {
+		name := strings.ToLower(subdomain)
+		if _, ok := current[name]; ok {
+			continue
+		}
+		current[name] = struct{}{}
+		if _, ok := known[name]; ok {
+			diff.Unchanged = append(diff.Unchanged, name)
+		} else {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for name := range known {
+		if _, ok := current[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Unchanged)
+	return diff
+}
@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestParseBaseline(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("# last scan\nWWW.example.com\n\napi.example.com\r\nwww.example.com\n"))
+
+	baseline, err := parseBaseline(encoded)
+	if err != nil {
+		t.Fatalf("Expected baseline to parse, got %v", err)
+	}
+	expected := []string{"www.example.com", "api.example.com"}
+	if !reflect.DeepEqual(baseline, expected) {
+		t.Errorf("Expected %v, got %v", expected, baseline)
+	}
+
+	if _, err := parseBaseline("not base64!"); err == nil {
+		t.Error("Expected an error for invalid base64")
+	}
+}
+
+func TestDiffBaseline(t *testing.T) {
+	found := []string{"api.example.com", "new.example.com", "WWW.example.com"}
+	baseline := []string{"www.example.com", "api.example.com", "old.example.com"}
+
+	diff := diffBaseline(found, baseline)
+
+	expected := BaselineDiff{
+		Added:     []string{"new.example.com"},
+		Removed:   []string{"old.example.com"},
+		Unchanged: []string{"api.example.com", "www.example.com"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diff)
+	}
+
+	empty := diffBaseline(nil, nil)
+	if empty.Added == nil || empty.Removed == nil || empty.Unchanged == nil {
+		t.Errorf("Expected non-nil lists, got %+v", empty)
+	}
+}
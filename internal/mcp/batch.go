@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	jsoniter "github.com/json-iterator/go"
+)
+
+// BatchStatsHeader is the HTTP response header carrying a batch's BatchStats as JSON.
+// JSON-RPC batch responses are bare arrays, so the summary cannot go in the body.
+const BatchStatsHeader = "X-Batch-Stats"
+
+// BatchStats counts how many items of a batch request succeeded and failed
+type BatchStats struct {
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	Total   int `json:"total"`
+}
+
+// ProcessBatch runs every item of a batch request through process and returns the
+// responses to send along with the batch's stats. Items are decoded one at a time so
+// a malformed item fails alone, and every error carries the item's batchIndex in its
+// data. Notifications produce no response unless they fail.
+func ProcessBatch(items []jsoniter.RawMessage, process func(Request) Response) ([]Response, BatchStats) {
+	responses := make([]Response, 0, len(items))
+	stats := BatchStats{Total: len(items)}
+
+	for i, item := range items {
+		var resp Response
+		var req Request
+		if err := jsoniter.Unmarshal(item, &req); err != nil {
+			resp = Response{
+				JSONRPC: "2.0",
+				Error:   ErrInvalidRequest,
+			}
+		} else {
+			resp = process(req)
+		}
+
+		if resp.Error != nil {
+			stats.Invalid++
+			resp.Error = withBatchIndex(resp.Error, i)
+		} else {
+			stats.Valid++
+		}
+
+		// Only include non-empty responses (important for notifications)
+		if resp.ID != nil || resp.Error != nil {
+			responses = append(responses, resp)
+		}
+	}
+
+	return responses, stats
+}
+
+// withBatchIndex returns a copy of err whose data records the failing item's
+// position in the batch. Object data keeps its fields; other data moves to "detail".
+func withBatchIndex(err *RPCError, index int) *RPCError {
+	data := map[string]interface{}{"batchIndex": index}
+	switch existing := err.Data.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, value := range existing {
+			data[key] = value
+		}
+		data["batchIndex"] = index
+	default:
+		data["detail"] = existing
+	}
+
+	indexed := *err
+	indexed.Data = data
+	return &indexed
+}
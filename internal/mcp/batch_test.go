@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestProcessBatch(t *testing.T) {
+	items := []jsoniter.RawMessage{
+		jsoniter.RawMessage(`{"jsonrpc": "2.0", "id": 1, "method": "tools.list"}`),
+		jsoniter.RawMessage(`"not a request"`),
+		jsoniter.RawMessage(`{"jsonrpc": "2.0", "method": "notifications/initialized"}`),
+		jsoniter.RawMessage(`{"jsonrpc": "2.0", "id": 4, "method": "busy"}`),
+		jsoniter.RawMessage(`{"jsonrpc": "2.0", "id": 5, "method": "missing"}`),
+	}
+	process := func(req Request) Response {
+		switch req.Method {
+		case "tools.list":
+			return Response{JSONRPC: "2.0", ID: req.ID, Result: "ok"}
+		case "notifications/initialized":
+			return Response{}
+		case "busy":
+			return Response{JSONRPC: "2.0", ID: req.ID, Error: enumerationInProgressError(5 * time.Second)}
+		default:
+			return Response{JSONRPC: "2.0", ID: req.ID, Error: ErrMethodNotFound}
+		}
+	}
+
+	responses, stats := ProcessBatch(items, process)
+	if stats != (BatchStats{Valid: 2, Invalid: 3, Total: 5}) {
+		t.Errorf("Unexpected batch stats %+v", stats)
+	}
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses without the notification, got %d", len(responses))
+	}
+
+	if responses[0].Error != nil || responses[0].Result != "ok" {
+		t.Errorf("Expected the valid item to succeed, got %+v", responses[0])
+	}
+	expected := []map[string]interface{}{
+		{"batchIndex": 1},
+		{"batchIndex": 3, "retryAfterSeconds": 5},
+		{"batchIndex": 4},
+	}
+	for i, resp := range responses[1:] {
+		if resp.Error == nil || !reflect.DeepEqual(resp.Error.Data, expected[i]) {
+			t.Errorf("Response %d: expected error data %v, got %+v", i+1, expected[i], resp.Error)
+		}
+	}
+
+	// Shared error values must not be modified
+	if ErrMethodNotFound.Data != nil || ErrInvalidRequest.Data != nil {
+		t.Error("Expected the shared error values to be left unchanged")
+	}
+}
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"mcp-subfinder-server/internal/plugin"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// bruteForceTool returns the definition of the wildcardSubdomainBrute tool
+func bruteForceTool() Tool {
+	return Tool{
+		Name:        "wildcardSubdomainBrute",
+		Title:       "Brute Force Subdomains",
+		Description: "Actively resolves {word}.{domain} for every word in a server-side or downloaded wordlist and returns the names that resolve, ignoring wildcard DNS matches",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"domain": map[string]interface{}{
+					"type":        "string",
+					"description": "The base domain to brute force (e.g., example.com)",
+				},
+				"wordlistPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of a wordlist with one label per line, relative to the server's wordlist directory; give this or wordlistURL",
+				},
+				"wordlistURL": map[string]interface{}{
+					"type":        "string",
+					"description": "HTTPS URL of a text/plain wordlist of at most 100MB, such as one from SecLists, downloaded once and cached on the server; give this or wordlistPath",
+				},
+				"concurrency": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Number of concurrent DNS lookups (default: %d)", subfinder.DefaultBruteConcurrency),
+					"default":     subfinder.DefaultBruteConcurrency,
+					"minimum":     1,
+				},
+			},
+			"required": []string{"domain"},
+		},
+	}
+}
+
+// handleWildcardSubdomainBrute runs the wildcardSubdomainBrute tool
+func handleWildcardSubdomainBrute(ctx context.Context, req *Request, params ToolCallParams, logger *slog.Logger) Response {
+	if !currentSettings().AllowBruteForce {
+		logger.Warn("Brute force tool called while disabled")
+		return toolErrorResponse(req, "wildcardSubdomainBrute is disabled; start the server with --allow-brute-force to enable it")
+	}
+
+	domain, ok := requiredStringArgument(params.Arguments, "domain", logger)
+	if !ok {
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	// The wordlist is either on the server or downloaded over HTTPS, never both
+	wordlistURL, urlErr := webhookURLArgument(params.Arguments, "wordlistURL")
+	_, hasPath := params.Arguments["wordlistPath"]
+	if urlErr != nil || hasPath == (wordlistURL != "") {
+		detail := "exactly one of wordlistPath and wordlistURL is required"
+		if urlErr != nil {
+			detail = urlErr.Error()
+		}
+		logger.Warn("Invalid wordlist parameters", "error", detail)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError(detail),
+		}
+	}
+	wordlistPath := ""
+	if hasPath {
+		name, ok := requiredStringArgument(params.Arguments, "wordlistPath", logger)
+		if !ok {
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+		// Callers pick among the operator's wordlists, never an arbitrary server file
+		path, err := subfinder.WordlistInDir(currentSettings().WordlistDir, name)
+		if errors.Is(err, subfinder.ErrWordlistOutsideDir) {
+			logger.Warn("Rejected wordlistPath outside the wordlist directory", "wordlistPath", name)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   NewInvalidParamsError("wordlistPath must be a relative path inside the server's --wordlist-dir"),
+			}
+		}
+		if err != nil {
+			logger.Error("Failed to open wordlist", "wordlistPath", name, "error", err)
+			return toolErrorResponse(req, "Brute force enumeration failed: wordlist not found")
+		}
+		wordlistPath = path
+	}
+
+	// Extract concurrency if provided
+	concurrency := subfinder.DefaultBruteConcurrency
+	if concurrencyVal, ok := params.Arguments["concurrency"]; ok {
+		if v, ok := concurrencyVal.(float64); ok && v >= 1 {
+			concurrency = int(v)
+			logger.Debug("Using custom concurrency", "concurrency", concurrency)
+		} else {
+			logger.Warn("Invalid concurrency parameter, using default", "providedConcurrency", concurrencyVal)
+		}
+	}
+
+	if wordlistURL != "" {
+		path, err := subfinder.DownloadWordlist(ctx, wordlistURL)
+		if err != nil {
+			// Only the reason is logged, the URL may carry a token
+			logger.Error("Failed to download wordlist", "error", err)
+			return toolErrorResponse(req, fmt.Sprintf("Brute force enumeration failed: %v", err))
+		}
+		wordlistPath = path
+	}
+
+	words, err := subfinder.LoadWordlist(wordlistPath)
+	if err != nil {
+		logger.Error("Failed to load wordlist", "path", wordlistPath, "error", err)
+		return toolErrorResponse(req, fmt.Sprintf("Brute force enumeration failed: %v", err))
+	}
+
+	clientInfo := clientInfoFromContext(ctx)
+	logger.Info("Running brute force enumeration",
+		"domain", domain,
+		"wordlistPath", wordlistPath,
+		"words", len(words),
+		"concurrency", concurrency,
+		"clientName", clientInfo.Name,
+		"clientVersion", clientInfo.Version)
+	// Plugins run around brute forcing as around passive enumeration; there is no
+	// subfinder run, so OnPreEnumerate gets an empty config
+	if err := plugin.RunPreEnumerate(ctx, domain, subfinder.SubfinderConfig{}); err != nil {
+		logger.Error("Brute force enumeration failed", "error", err)
+		return toolErrorResponse(req, fmt.Sprintf("Brute force enumeration failed: %v", err))
+	}
+	subdomains := subfinder.BruteForceSubdomains(ctx, domain, words, concurrency, logger)
+	if subdomains, err = plugin.RunPostEnumerate(ctx, domain, subdomains); err != nil {
+		logger.Error("Brute force enumeration failed", "error", err)
+		return toolErrorResponse(req, fmt.Sprintf("Brute force enumeration failed: %v", err))
+	}
+	logger.Info("Brute force enumeration complete", "domain", domain, "subdomainsFound", len(subdomains))
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: formatToolCallResult(ToolCallResult{
+			IsError: false,
+			Content: subdomainListContent(domain, subdomains, defaultLineDelimiter),
+		}, protocolVersionFromContext(ctx)),
+	}
+}
+
+// toolErrorResponse wraps a failure message in an IsError tool result
+func toolErrorResponse(req *Request, message string) Response {
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: ToolCallResult{
+			IsError: true,
+			Content: []interface{}{
+				ContentItem{
+					Type: "text",
+					Text: message,
+				},
+			},
+		},
+	}
+}
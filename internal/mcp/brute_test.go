@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// listsTool reports whether tools.list currently advertises the named tool
+func listsTool(name string) bool {
+	result := HandleToolsList(&Request{JSONRPC: "2.0", Method: "tools.list", ID: rawMessagePtr("1")}).Result.(ToolsListResult)
+	for _, tool := range result.Tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWildcardSubdomainBruteGate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	defer Configure(ServerSettings{})
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("7"),
+		Params:  jsoniter.RawMessage(`{"name": "wildcardSubdomainBrute", "arguments": {"domain": "example.com", "wordlistPath": "wordlist.txt"}}`),
+	}
+
+	Configure(ServerSettings{AllowBruteForce: false})
+	if listsTool("wildcardSubdomainBrute") {
+		t.Error("Expected wildcardSubdomainBrute to be hidden when brute force is disabled")
+	}
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	result, ok := response.Result.(ToolCallResult)
+	if !ok || !result.IsError {
+		t.Fatalf("Expected an error result when brute force is disabled, got %+v", response)
+	}
+
+	Configure(ServerSettings{AllowBruteForce: true, WordlistDir: t.TempDir()})
+	if !listsTool("wildcardSubdomainBrute") {
+		t.Error("Expected wildcardSubdomainBrute to be listed when brute force is enabled")
+	}
+
+	// Paths outside the wordlist directory are invalid params, before anything is opened
+	for _, path := range []string{"/etc/passwd", "../wordlist.txt", "lists/../../wordlist.txt"} {
+		req.Params = jsoniter.RawMessage(`{"name": "wildcardSubdomainBrute", "arguments": {"domain": "example.com", "wordlistPath": "` + path + `"}}`)
+		response = HandleToolsCall(context.Background(), req, "", logger)
+		if response.Error == nil || response.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for wordlistPath %s, got %+v", path, response.Error)
+		}
+	}
+
+	// A missing wordlist is reported as a tool error rather than a protocol error
+	req.Params = jsoniter.RawMessage(`{"name": "wildcardSubdomainBrute", "arguments": {"domain": "example.com", "wordlistPath": "missing.txt"}}`)
+	response = HandleToolsCall(context.Background(), req, "", logger)
+	result, ok = response.Result.(ToolCallResult)
+	if !ok || !result.IsError {
+		t.Fatalf("Expected an error result for a missing wordlist, got %+v", response)
+	}
+
+	// Missing required parameters are invalid params
+	req.Params = jsoniter.RawMessage(`{"name": "wildcardSubdomainBrute", "arguments": {"domain": "example.com"}}`)
+	response = HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params for a missing wordlistPath, got %+v", response.Error)
+	}
+
+	// A wordlist URL must be https and cannot be combined with a path
+	for _, args := range []string{
+		`{"domain": "example.com", "wordlistURL": "http://example.com/words.txt"}`,
+		`{"domain": "example.com", "wordlistURL": "https://example.com/words.txt", "wordlistPath": "words.txt"}`,
+	} {
+		req.Params = jsoniter.RawMessage(`{"name": "wildcardSubdomainBrute", "arguments": ` + args + `}`)
+		response = HandleToolsCall(context.Background(), req, "", logger)
+		if response.Error == nil || response.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %s, got %+v", args, response.Error)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/netguard"
+	"mcp-subfinder-server/internal/useragent"
+)
+
+const (
+	// callbackRetries is how many times a failed webhook delivery is retried
+	callbackRetries = 3
+	// webhookLookupTimeout bounds resolving a webhook URL's host when it is checked
+	webhookLookupTimeout = 5 * time.Second
+)
+
+var (
+	// callbackBaseDelay is the wait before the first retry; it doubles after each one
+	callbackBaseDelay = time.Second
+	// callbackClient delivers async results to callback URLs, only ever connecting
+	// to public addresses however the host resolves by then
+	callbackClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: useragent.NewTransport(netguard.NewTransport(), useragent.Default),
+	}
+	// lookupWebhookHost resolves a webhook URL's host when it is checked
+	lookupWebhookHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	}
+)
+
+// callbackURLArgument returns the call's callbackURL, or an error when it is not
+// an absolute https URL. It is empty when the call is synchronous.
+func callbackURLArgument(args map[string]interface{}) (string, error) {
+	return webhookURLArgument(args, "callbackURL")
+}
+
+// webhookURLArgument returns the named webhook URL argument, or an error when it
+// is not an absolute https URL or its host resolves to an address that is not
+// public, so callers cannot reach the server's internal network or cloud metadata.
+// It is empty when the argument is absent.
+func webhookURLArgument(args map[string]interface{}, name string) (string, error) {
+	val, ok := args[name]
+	if !ok {
+		return "", nil
+	}
+	raw, ok := val.(string)
+	if !ok {
+		return "", errors.New(name + " must be a string")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return "", errors.New(name + " must be an absolute https URL")
+	}
+
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+		defer cancel()
+		if addrs, err = lookupWebhookHost(ctx, parsed.Hostname()); err != nil || len(addrs) == 0 {
+			return "", errors.New(name + " host could not be resolved")
+		}
+	}
+	for _, addr := range addrs {
+		if !netguard.IsPublic(addr) {
+			return "", errors.New(name + " must not point to a loopback, private or link-local address")
+		}
+	}
+	return raw, nil
+}
+
+// newJobID returns a random identifier for an async job
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "async-" + hex.EncodeToString(b), nil
+}
+
+// startAsyncEnumeration runs an enumerateSubdomains call in the background and
+// acknowledges it at once; the result is POSTed to callbackURL when it completes.
+// It fails with ErrServerOverloaded when the background job cap is reached.
+func startAsyncEnumeration(ctx context.Context, req *Request, params ToolCallParams, callbackURL, providerConfigPath string, logger *slog.Logger) Response {
+	jobID, err := newJobID()
+	if err != nil {
+		logger.Error("Failed to create async job ID", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInternal,
+		}
+	}
+
+	// The background call must not loop back here, and outlives the request, so it
+	// drops the request's cancellation, deadline and streaming writer
+	args := make(map[string]interface{}, len(params.Arguments))
+	for name, value := range params.Arguments {
+		if name != "callbackURL" {
+			args[name] = value
+		}
+	}
+	params.Arguments = args
+	jobLogger := logger.With("jobId", jobID)
+
+	started := backgroundJobs.start(WithStreamWriter(ctx, nil), maxBackgroundJobs(), func(jobCtx context.Context) {
+		resp := handleEnumerateSubdomains(jobCtx, req, params, providerConfigPath, jobLogger)
+		result := asyncResult(resp)
+		result.Annotations = params.Annotations
+		if err := deliverCallback(jobCtx, callbackURL, jobID, result, jobLogger); err != nil {
+			jobLogger.Error("Failed to deliver async result", "error", err)
+			return
+		}
+		jobLogger.Info("Delivered async result")
+	})
+	if !started {
+		logger.Warn("Too many background enumerations, rejecting async call", "maxBackgroundJobs", maxBackgroundJobs())
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrServerOverloaded,
+		}
+	}
+
+	logger.Info("Started async enumeration", "jobId", jobID)
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  AsyncToolCallResult{Async: true, JobID: jobID},
+	}
+}
+
+// asyncResult turns a finished call into the ToolCallResult delivered to the
+// callback, reporting JSON-RPC errors as failed results
+func asyncResult(resp Response) ToolCallResult {
+	if result, ok := resp.Result.(ToolCallResult); ok {
+		return result
+	}
+	message := "Subdomain enumeration failed"
+	if resp.Error != nil {
+		message = fmt.Sprintf("Subdomain enumeration failed: %s", resp.Error.Message)
+	}
+	return ToolCallResult{
+		IsError: true,
+		Content: []interface{}{
+			ContentItem{
+				Type: "text",
+				Text: message,
+			},
+		},
+	}
+}
+
+// deliverCallback POSTs result to callbackURL with an X-Job-ID header, retrying
+// failures up to callbackRetries times with exponential backoff
+func deliverCallback(ctx context.Context, callbackURL, jobID string, result ToolCallResult, logger *slog.Logger) error {
+	body, err := jsoniter.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	delay := callbackBaseDelay
+	for attempt := 0; ; attempt++ {
+		err = postCallback(ctx, callbackURL, jobID, body)
+		if err == nil || attempt == callbackRetries {
+			return err
+		}
+		logger.Warn("Callback delivery failed, retrying", "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postCallback makes one delivery attempt; any non-2xx status is a failure
+func postCallback(ctx context.Context, callbackURL, jobID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Job-ID", jobID)
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
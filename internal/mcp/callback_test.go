@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestCallbackURLArgument(t *testing.T) {
+	useFakeWebhookDNS(t, map[string]string{"hooks.example.com": "93.184.216.34", "internal.example.com": "10.0.0.5"})
+	tests := []struct {
+		args    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{args: map[string]interface{}{}, want: ""},
+		{args: map[string]interface{}{"callbackURL": "https://hooks.example.com/done?token=x"}, want: "https://hooks.example.com/done?token=x"},
+		{args: map[string]interface{}{"callbackURL": "http://hooks.example.com/done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https:///done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "hooks.example.com"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": 42.0}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://93.184.216.34/done"}, want: "https://93.184.216.34/done"},
+		// Requests must not reach the server's own network or cloud metadata
+		{args: map[string]interface{}{"callbackURL": "https://127.0.0.1:8080/done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://[::1]/done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://192.168.1.10/done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://169.254.169.254/latest/meta-data/"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://internal.example.com/done"}, wantErr: true},
+		{args: map[string]interface{}{"callbackURL": "https://unresolvable.example.com/done"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := callbackURLArgument(tt.args)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("callbackURLArgument(%v) = %q, %v; want %q, error %v", tt.args, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDeliverCallbackRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var mu sync.Mutex
+	attempts, failAll := 0, false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if r.Header.Get("X-Job-ID") != "async-test" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected callback headers %v", r.Header)
+		}
+		if attempts < 3 || failAll {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	useTestCallbackServer(t, server)
+
+	result := ToolCallResult{Content: []interface{}{ContentItem{Type: "text", Text: "done"}}}
+	if err := deliverCallback(context.Background(), server.URL, "async-test", result, logger); err != nil {
+		t.Fatalf("Expected delivery to succeed on the third attempt, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	// One initial attempt plus callbackRetries retries, then give up
+	attempts, failAll = 0, true
+	if err := deliverCallback(context.Background(), server.URL, "async-test", result, logger); err == nil {
+		t.Error("Expected delivery to fail after exhausting retries")
+	}
+	if attempts != callbackRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", callbackRetries+1, attempts)
+	}
+}
+
+func TestAsyncEnumeration(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	delivered := make(chan *http.Request, 1)
+	bodies := make(chan ToolCallResult, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result ToolCallResult
+		if err := jsoniter.NewDecoder(r.Body).Decode(&result); err != nil {
+			t.Errorf("Failed to decode callback body: %v", err)
+		}
+		delivered <- r
+		bodies <- result
+	}))
+	defer server.Close()
+	useTestCallbackServer(t, server)
+
+	// A CT-only run with no CT sources fails before any source is queried
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("8"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"certTransparencyOnly": true, "sourcesFilter": "hackertarget", "callbackURL": "https://example.com/hook"},
+			"annotations": {"projectId": "abc123"}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	ack, ok := response.Result.(AsyncToolCallResult)
+	if !ok || !ack.Async || !strings.HasPrefix(ack.JobID, "async-") {
+		t.Fatalf("Expected an async acknowledgement, got %+v", response)
+	}
+
+	select {
+	case r := <-delivered:
+		if r.Header.Get("X-Job-ID") != ack.JobID || r.URL.Path != "/hook" {
+			t.Errorf("Expected delivery to /hook for job %s, got %s with %q", ack.JobID, r.URL.Path, r.Header.Get("X-Job-ID"))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the callback")
+	}
+	result := <-bodies
+	if !result.IsError || result.Annotations["projectId"] != "abc123" {
+		t.Errorf("Expected the failed result with annotations, got %+v", result)
+	}
+
+	// Plain http callbacks are rejected before anything runs
+	req.Params = jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "callbackURL": "http://hooks.example.com"}}`)
+	if response := HandleToolsCall(context.Background(), req, "", logger); response.Error != ErrInvalidParams {
+		t.Errorf("Expected invalid params for an http callback, got %+v", response)
+	}
+}
+
+// useTestCallbackServer points callback delivery at server for the duration of
+// the test. https://example.com, which the test certificate is valid for, resolves
+// to a public address when checked and is delivered to server.
+func useTestCallbackServer(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	useFakeWebhookDNS(t, map[string]string{"example.com": "93.184.216.34"})
+	client, delay := callbackClient, callbackBaseDelay
+	testClient := server.Client()
+	transport := testClient.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	testClient.Transport = transport
+	callbackClient, callbackBaseDelay = testClient, time.Millisecond
+	t.Cleanup(func() {
+		callbackClient, callbackBaseDelay = client, delay
+	})
+}
+
+// useFakeWebhookDNS resolves webhook hosts from records for the duration of the test
+func useFakeWebhookDNS(t *testing.T, records map[string]string) {
+	t.Helper()
+	lookup := lookupWebhookHost
+	lookupWebhookHost = func(_ context.Context, host string) ([]netip.Addr, error) {
+		addr, ok := records[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		return []netip.Addr{netip.MustParseAddr(addr)}, nil
+	}
+	t.Cleanup(func() {
+		lookupWebhookHost = lookup
+	})
+}
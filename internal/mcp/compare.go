@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/plugin"
+)
+
+// handleCompareEnumerations runs the compareEnumerations tool
+func handleCompareEnumerations(ctx context.Context, req *Request, params ToolCallParams, providerConfigPath string, logger *slog.Logger) Response {
+	// Extract and validate both required domains
+	domain1, ok1 := requiredStringArgument(params.Arguments, "domain1", logger)
+	domain2, ok2 := requiredStringArgument(params.Arguments, "domain2", logger)
+	if !ok1 || !ok2 {
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	config := parseEnumerationConfig(params.Arguments, providerConfigPath, logger)
+	enumCtx, cancelEnumeration, timeoutWarning := fitTimeoutToDeadline(ctx, &config, logger)
+	defer cancelEnumeration()
+
+	// Run both enumerations concurrently
+	domains := [2]string{domain1, domain2}
+	var results [2][]string
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i, domain := range domains {
+		wg.Add(1)
+		go func(i int, domain string) {
+			defer wg.Done()
+			logger.Info("Running subdomain enumeration for comparison", "domain", domain, "config", config)
+			results[i], errs[i] = plugin.RunEnumeration(enumCtx, domain, config, logger)
+		}(i, domain)
+	}
+	wg.Wait()
+
+	// Either failure makes the comparison meaningless
+	for i, err := range errs {
+		if err != nil {
+			logger.Error("Subdomain enumeration failed", "domain", domains[i], "error", err)
+			result := ToolCallResult{
+				IsError: true,
+				Content: []interface{}{
+					ContentItem{
+						Type: "text",
+						Text: fmt.Sprintf("Subdomain enumeration failed for %s: %v", domains[i], err),
+					},
+				},
+			}
+			if timeoutWarning != "" {
+				result.Meta = &ToolCallMeta{Warning: timeoutWarning}
+			}
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Result:  result,
+			}
+		}
+	}
+
+	comparison := compareSubdomains(domain1, results[0], domain2, results[1])
+
+	comparisonJSON, err := jsoniter.Marshal(comparison)
+	if err != nil {
+		logger.Error("Failed to encode comparison", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInternal,
+		}
+	}
+
+	logger.Info("Comparison complete",
+		"domain1", domain1,
+		"domain2", domain2,
+		"common", len(comparison.Common),
+		"overlapPercent", comparison.OverlapPercent)
+
+	result := ToolCallResult{
+		Content: []interface{}{
+			ContentItem{
+				Type: "text",
+				Text: string(comparisonJSON),
+			},
+		},
+	}
+	if timeoutWarning != "" {
+		result.Meta = &ToolCallMeta{Warning: timeoutWarning}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// compareSubdomains compares two result sets by their labels relative to each base domain.
+// overlapPercent is the share of all distinct labels that both domains have in common.
+func compareSubdomains(domain1 string, subdomains1 []string, domain2 string, subdomains2 []string) ComparisonResult {
+	labels1 := relativeLabels(domain1, subdomains1)
+	labels2 := relativeLabels(domain2, subdomains2)
+
+	result := ComparisonResult{
+		Domain1:     domain1,
+		Domain2:     domain2,
+		Domain1Only: []string{},
+		Domain2Only: []string{},
+		Common:      []string{},
+	}
+
+	for label := range labels1 {
+		if _, ok := labels2[label]; ok {
+			result.Common = append(result.Common, label)
+		} else {
+			result.Domain1Only = append(result.Domain1Only, label)
+		}
+	}
+	for label := range labels2 {
+		if _, ok := labels1[label]; !ok {
+			result.Domain2Only = append(result.Domain2Only, label)
+		}
+	}
+
+	sort.Strings(result.Domain1Only)
+	sort.Strings(result.Domain2Only)
+	sort.Strings(result.Common)
+
+	if union := len(result.Domain1Only) + len(result.Domain2Only) + len(result.Common); union > 0 {
+		percent := float64(len(result.Common)) / float64(union) * 100
+		result.OverlapPercent = math.Round(percent*100) / 100
+	}
+
+	return result
+}
+
+// relativeLabels strips the base domain from each subdomain, keeping foreign names whole
+func relativeLabels(domain string, subdomains []string) map[string]struct{} {
+	suffix := "." + strings.ToLower(domain)
+	labels := make(map[string]struct{}, len(subdomains))
+	for _, subdomain := range subdomains {
+		name := strings.ToLower(subdomain)
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			name = trimmed
+		}
+		labels[name] = struct{}{}
+	}
+	return labels
+}
@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareSubdomains(t *testing.T) {
+	result := compareSubdomains(
+		"example.com", []string{"www.example.com", "api.example.com", "dev.example.com"},
+		"acquired.com", []string{"WWW.acquired.com", "api.acquired.com", "shop.acquired.com"},
+	)
+
+	if !reflect.DeepEqual(result.Common, []string{"api", "www"}) {
+		t.Errorf("Expected common [api www], got %v", result.Common)
+	}
+	if !reflect.DeepEqual(result.Domain1Only, []string{"dev"}) {
+		t.Errorf("Expected domain1Only [dev], got %v", result.Domain1Only)
+	}
+	if !reflect.DeepEqual(result.Domain2Only, []string{"shop"}) {
+		t.Errorf("Expected domain2Only [shop], got %v", result.Domain2Only)
+	}
+	if result.OverlapPercent != 50 {
+		t.Errorf("Expected overlapPercent 50, got %v", result.OverlapPercent)
+	}
+}
+
+func TestCompareSubdomainsEmpty(t *testing.T) {
+	result := compareSubdomains("example.com", nil, "acquired.com", nil)
+
+	if result.OverlapPercent != 0 {
+		t.Errorf("Expected overlapPercent 0 for empty inputs, got %v", result.OverlapPercent)
+	}
+	if result.Common == nil || result.Domain1Only == nil || result.Domain2Only == nil {
+		t.Errorf("Expected empty lists rather than nil so JSON renders []")
+	}
+}
@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/jobs"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// deferredJobs holds the state of every deferred tools.call until it expires
+var deferredJobs = jobs.NewStore(jobs.DefaultTTL, jobs.DefaultMaxJobs)
+
+// deferredArgument reports whether the call asked to be deferred
+func deferredArgument(args map[string]interface{}, logger *slog.Logger) bool {
+	val, ok := args["deferred"]
+	if !ok {
+		return false
+	}
+	deferred, ok := val.(bool)
+	if !ok {
+		logger.Warn("Invalid deferred parameter, using default", "providedDeferred", val)
+		return false
+	}
+	return deferred
+}
+
+// startDeferredEnumeration runs an enumerateSubdomains call in the background and
+// returns its job ID at once; the result is collected later with jobs.get by the
+// same session. It fails with ErrServerOverloaded when the job store is full or
+// the background job cap is reached.
+func startDeferredEnumeration(ctx context.Context, req *Request, params ToolCallParams, providerConfigPath string, logger *slog.Logger) Response {
+	jobID, err := newJobID()
+	if err != nil {
+		logger.Error("Failed to create deferred job ID", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInternal,
+		}
+	}
+	domain, _ := params.Arguments["domain"].(string)
+	if err := deferredJobs.Create(jobID, domain, sessionIDFromContext(ctx), time.Now()); err != nil {
+		logger.Warn("Too many deferred jobs stored, rejecting deferred call", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrServerOverloaded,
+		}
+	}
+
+	// As with callbacks, the background call must not loop back here and
+	// outlives the request; its stream only feeds the job's progress count
+	args := make(map[string]interface{}, len(params.Arguments))
+	for name, value := range params.Arguments {
+		if name != "deferred" {
+			args[name] = value
+		}
+	}
+	params.Arguments = args
+	jobLogger := logger.With("jobId", jobID)
+
+	started := backgroundJobs.start(WithStreamWriter(ctx, jobProgressWriter(jobID)), maxBackgroundJobs(), func(jobCtx context.Context) {
+		deferredJobs.Start(jobID)
+		resp := handleEnumerateSubdomains(jobCtx, req, params, providerConfigPath, jobLogger)
+		result := asyncResult(resp)
+		result.Annotations = params.Annotations
+		deferredJobs.Finish(jobID, result, result.IsError, time.Now())
+		jobLogger.Info("Deferred enumeration finished")
+	})
+	if !started {
+		deferredJobs.Delete(jobID)
+		logger.Warn("Too many background enumerations, rejecting deferred call", "maxBackgroundJobs", maxBackgroundJobs())
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrServerOverloaded,
+		}
+	}
+
+	logger.Info("Started deferred enumeration", "jobId", jobID)
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  JobStatusResult{JobID: jobID, Status: jobs.StatusPending},
+	}
+}
+
+// jobProgressWriter counts the subdomain events of a job's result stream
+type jobProgressWriter string
+
+// Write is called once per NDJSON event by the result stream
+func (id jobProgressWriter) Write(p []byte) (int, error) {
+	found := 0
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		var event subfinder.StreamEvent
+		if jsoniter.Unmarshal(line, &event) == nil && event.Type == subfinder.StreamEventSubdomain {
+			found++
+		}
+	}
+	if found > 0 {
+		deferredJobs.AddFound(string(id), found)
+	}
+	return len(p), nil
+}
+
+// HandleJobsGet processes a jobs.get request, returning the job's status until it
+// finishes and its ToolCallResult afterwards
+func HandleJobsGet(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+
+	var params JobsGetParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse jobs.get params", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+
+	// Another session's job is reported as missing rather than forbidden
+	job, ok := deferredJobs.Get(params.JobID, time.Now())
+	if !ok || job.Owner != sessionIDFromContext(ctx) {
+		logger.Warn("Deferred job not found", "jobId", params.JobID)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	var result interface{}
+	switch job.Status {
+	case jobs.StatusDone, jobs.StatusFailed:
+		result = job.Result
+	case jobs.StatusRunning:
+		found := job.Found
+		result = JobStatusResult{Status: job.Status, SubdomainsFoundSoFar: &found}
+	default:
+		result = JobStatusResult{Status: job.Status}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// JobStatusCompleted is how GET /mcp/jobs reports a job that finished successfully
+const JobStatusCompleted = "completed"
+
+// JobListStatuses are the statuses GET /mcp/jobs reports and filters on
+var JobListStatuses = []string{jobs.StatusPending, jobs.StatusRunning, JobStatusCompleted, jobs.StatusFailed}
+
+// listedStatus maps a stored job status to the one GET /mcp/jobs reports
+func listedStatus(status string) string {
+	if status == jobs.StatusDone {
+		return JobStatusCompleted
+	}
+	return status
+}
+
+// ListJobs returns the unexpired deferred jobs, newest first, keeping only those
+// whose listed status is status unless it is empty
+func ListJobs(status string) []JobSummary {
+	summaries := make([]JobSummary, 0)
+	for _, job := range deferredJobs.List(time.Now()) {
+		summary := JobSummary{
+			JobID:           job.ID,
+			Domain:          job.Domain,
+			Status:          listedStatus(job.Status),
+			StartedAt:       job.StartedAt,
+			SubdomainsFound: job.Found,
+		}
+		if status != "" && summary.Status != status {
+			continue
+		}
+		if !job.CompletedAt.IsZero() {
+			completedAt := job.CompletedAt
+			summary.CompletedAt = &completedAt
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// JobResult returns the ToolCallResult of a finished deferred job along with the
+// job's listed status; result is nil while the job is still pending or running
+func JobResult(jobID string) (result interface{}, status string, ok bool) {
+	job, ok := deferredJobs.Get(jobID, time.Now())
+	if !ok {
+		return nil, "", false
+	}
+	return job.Result, listedStatus(job.Status), true
+}
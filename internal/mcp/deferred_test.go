@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/jobs"
+)
+
+func TestDeferredEnumeration(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// A CT-only run with no CT sources fails before any source is queried
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("9"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"certTransparencyOnly": true, "sourcesFilter": "hackertarget", "deferred": true},
+			"annotations": {"projectId": "abc123"}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	ack, ok := response.Result.(JobStatusResult)
+	if !ok || ack.Status != jobs.StatusPending || !strings.HasPrefix(ack.JobID, "async-") {
+		t.Fatalf("Expected a pending job, got %+v", response)
+	}
+
+	poll := &Request{
+		JSONRPC: "2.0",
+		Method:  "jobs.get",
+		ID:      rawMessagePtr("10"),
+		Params:  jsoniter.RawMessage(`{"jobId": "` + ack.JobID + `"}`),
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		response = HandleJobsGet(context.Background(), poll, logger)
+		if response.Error != nil {
+			t.Fatalf("Unexpected error polling the job: %+v", response.Error)
+		}
+		if result, ok := response.Result.(ToolCallResult); ok {
+			if !result.IsError || result.Annotations["projectId"] != "abc123" {
+				t.Errorf("Expected the failed result with annotations, got %+v", result)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the job, last status %+v", response.Result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Only the session that created the job may collect it
+	other := NewSession()
+	other.SetState(SessionReady)
+	if response := HandleJobsGet(WithSession(context.Background(), other), poll, logger); response.Error != ErrInvalidParams {
+		t.Errorf("Expected another session's job to be hidden, got %+v", response)
+	}
+
+	poll.Params = jsoniter.RawMessage(`{"jobId": "async-unknown"}`)
+	if response := HandleJobsGet(context.Background(), poll, logger); response.Error != ErrInvalidParams {
+		t.Errorf("Expected invalid params for an unknown job, got %+v", response)
+	}
+
+	// Delivering to a callback and polling are exclusive
+	useFakeWebhookDNS(t, map[string]string{"hooks.example.com": "93.184.216.34"})
+	req.Params = jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "deferred": true, "callbackURL": "https://hooks.example.com/done"}}`)
+	if response := HandleToolsCall(context.Background(), req, "", logger); response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params for callbackURL with deferred, got %+v", response)
+	}
+}
+
+func TestDeferredEnumerationOverloaded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	original := deferredJobs
+	deferredJobs = jobs.NewStore(jobs.DefaultTTL, 1)
+	defer func() { deferredJobs = original }()
+	deferredJobs.Create("async-occupied", "example.com", "", time.Now())
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("12"),
+		Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "deferred": true}}`),
+	}
+	if response := HandleToolsCall(context.Background(), req, "", logger); response.Error != ErrServerOverloaded {
+		t.Errorf("Expected ErrServerOverloaded with the job store full, got %+v", response)
+	}
+}
+
+func TestJobProgressWriter(t *testing.T) {
+	jobID := "async-progress-test"
+	deferredJobs.Create(jobID, "example.com", "", time.Now())
+	deferredJobs.Start(jobID)
+
+	w := jobProgressWriter(jobID)
+	w.Write([]byte(`{"type":"subdomain","subdomain":"www.example.com"}` + "\n"))
+	w.Write([]byte(`{"type":"subdomain","subdomain":"api.example.com"}` + "\n"))
+	w.Write([]byte(`{"type":"stats","subdomainsFound":2}` + "\n"))
+
+	poll := &Request{
+		JSONRPC: "2.0",
+		Method:  "jobs.get",
+		ID:      rawMessagePtr("11"),
+		Params:  jsoniter.RawMessage(`{"jobId": "` + jobID + `"}`),
+	}
+	response := HandleJobsGet(context.Background(), poll, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	status, ok := response.Result.(JobStatusResult)
+	if !ok || status.Status != jobs.StatusRunning || status.SubdomainsFoundSoFar == nil || *status.SubdomainsFoundSoFar != 2 {
+		t.Errorf("Expected a running job with 2 subdomains, got %+v", response.Result)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	deferredJobs.Create("async-list-running", "example.com", "", time.Now())
+	deferredJobs.Start("async-list-running")
+	deferredJobs.Create("async-list-failed", "example.org", "", time.Now())
+	deferredJobs.Finish("async-list-failed", ToolCallResult{IsError: true}, true, time.Now())
+
+	listed := func(status string) map[string]JobSummary {
+		summaries := make(map[string]JobSummary)
+		for _, summary := range ListJobs(status) {
+			summaries[summary.JobID] = summary
+		}
+		return summaries
+	}
+
+	all := listed("")
+	if running, ok := all["async-list-running"]; !ok || running.Status != jobs.StatusRunning || running.CompletedAt != nil {
+		t.Errorf("Expected the running job without completedAt, got %+v", running)
+	}
+	if failed, ok := all["async-list-failed"]; !ok || failed.Domain != "example.org" || failed.CompletedAt == nil {
+		t.Errorf("Expected the failed job with completedAt, got %+v", failed)
+	}
+
+	if failed := listed(jobs.StatusFailed); len(failed) == 0 || failed["async-list-running"].JobID != "" {
+		t.Errorf("Expected only failed jobs, got %+v", failed)
+	}
+
+	if result, status, ok := JobResult("async-list-failed"); !ok || status != jobs.StatusFailed || result == nil {
+		t.Errorf("Expected the failed job's result, got %v %q %v", result, status, ok)
+	}
+	if _, _, ok := JobResult("async-list-unknown"); ok {
+		t.Error("Expected no result for an unknown job")
+	}
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLockWaitTimeout is how long an enumeration waits for a running one on the same domain
+const defaultLockWaitTimeout = 5 * time.Second
+
+// domainLock serializes enumerations of one domain
+type domainLock struct {
+	sem chan struct{}
+	// refs counts holders and waiters so idle locks can be dropped
+	refs int
+	// expectedEnd is when the current holder's enumeration should finish
+	expectedEnd time.Time
+}
+
+// domainLocks hands out per-domain soft locks so concurrent calls for the same
+// domain wait for each other instead of all running subfinder at once
+type domainLocks struct {
+	mu    sync.Mutex
+	locks map[string]*domainLock
+}
+
+// newDomainLocks creates an empty lock table
+func newDomainLocks() *domainLocks {
+	return &domainLocks{locks: make(map[string]*domainLock)}
+}
+
+// enumerationLocks is the process-wide lock table shared by every enumerateSubdomains call
+var enumerationLocks = newDomainLocks()
+
+// acquire waits up to wait for the domain's lock. On success it returns a release
+// function; otherwise it returns how long until the running enumeration should end.
+// expected is how long the caller's own enumeration is expected to take.
+func (l *domainLocks) acquire(ctx context.Context, domain string, wait, expected time.Duration) (func(), time.Duration, bool) {
+	key := strings.ToLower(strings.TrimSpace(domain))
+
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &domainLock{sem: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case lock.sem <- struct{}{}:
+		l.mu.Lock()
+		lock.expectedEnd = time.Now().Add(expected)
+		l.mu.Unlock()
+		return func() {
+			<-lock.sem
+			l.unref(key, lock)
+		}, 0, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	retryAfter := time.Until(lock.expectedEnd)
+	l.mu.Unlock()
+	l.unref(key, lock)
+	return nil, retryAfter, false
+}
+
+// unref drops a reference to lock, removing it from the table once unused
+func (l *domainLocks) unref(key string, lock *domainLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 && l.locks[key] == lock {
+		delete(l.locks, key)
+	}
+}
+
+// lockWaitTimeout returns the configured wait for a busy domain, or the default
+func lockWaitTimeout() time.Duration {
+	if wait := currentSettings().LockWaitTimeout; wait > 0 {
+		return wait
+	}
+	return defaultLockWaitTimeout
+}
+
+// enumerationInProgressError builds the error returned when a domain stays locked,
+// suggesting a retry interval of at least one second
+func enumerationInProgressError(retryAfter time.Duration) *RPCError {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &RPCError{
+		Code:    EnumerationInProgressCode,
+		Message: "domain enumeration already in progress",
+		Data:    map[string]interface{}{"retryAfterSeconds": seconds},
+	}
+}
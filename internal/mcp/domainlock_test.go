@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDomainLocks(t *testing.T) {
+	locks := newDomainLocks()
+
+	release, _, ok := locks.acquire(context.Background(), "Example.com", time.Second, time.Minute)
+	if !ok {
+		t.Fatal("Expected the first acquire to succeed")
+	}
+
+	// The same domain in any case is busy; the retry hint reflects the holder's timeout
+	_, retryAfter, ok := locks.acquire(context.Background(), "example.com", 10*time.Millisecond, time.Minute)
+	if ok {
+		t.Fatal("Expected a second acquire of the same domain to time out")
+	}
+	if retryAfter <= 50*time.Second || retryAfter > time.Minute {
+		t.Errorf("Expected retryAfter close to one minute, got %v", retryAfter)
+	}
+
+	// Other domains are independent
+	otherRelease, _, ok := locks.acquire(context.Background(), "example.org", 10*time.Millisecond, time.Minute)
+	if !ok {
+		t.Fatal("Expected a different domain to be acquirable")
+	}
+	otherRelease()
+
+	// A waiter gets the lock once the holder releases it
+	acquired := make(chan bool)
+	go func() {
+		next, _, ok := locks.acquire(context.Background(), "example.com", time.Second, time.Minute)
+		if ok {
+			next()
+		}
+		acquired <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release()
+	if !<-acquired {
+		t.Error("Expected the waiter to acquire the lock after release")
+	}
+
+	locks.mu.Lock()
+	remaining := len(locks.locks)
+	locks.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("Expected idle locks to be removed, %d left", remaining)
+	}
+}
+
+func TestEnumerationInProgressError(t *testing.T) {
+	err := enumerationInProgressError(2500 * time.Millisecond)
+	if err.Code != EnumerationInProgressCode || err.Message != "domain enumeration already in progress" {
+		t.Errorf("Unexpected error %+v", err)
+	}
+	if data, ok := err.Data.(map[string]interface{}); !ok || data["retryAfterSeconds"] != 3 {
+		t.Errorf("Expected retryAfterSeconds 3, got %v", err.Data)
+	}
+
+	if data := enumerationInProgressError(-time.Second).Data.(map[string]interface{}); data["retryAfterSeconds"] != 1 {
+		t.Errorf("Expected a minimum retryAfterSeconds of 1, got %v", data["retryAfterSeconds"])
+	}
+}
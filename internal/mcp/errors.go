@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewToolNotFoundError is returned for a tools.call naming a tool the server does
+// not have; Data names the tool and lists the available ones
+func NewToolNotFoundError(name string) *RPCError {
+	tools := availableTools()
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return &RPCError{
+		Code:    MethodNotFoundCode,
+		Message: ErrMethodNotFound.Message,
+		Data:    map[string]interface{}{"tool": name, "availableTools": names},
+	}
+}
+
+// NewDomainValidationError is returned when a domain argument is not a valid host
+// name; Data holds the domain and why it was rejected
+func NewDomainValidationError(domain, reason string) *RPCError {
+	return &RPCError{
+		Code:    InvalidParamsCode,
+		Message: ErrInvalidParams.Message,
+		Data:    map[string]interface{}{"domain": domain, "reason": reason},
+	}
+}
+
+// NewTimeoutError is returned when an enumeration ran out of its timeout, given
+// in seconds, before producing a result
+func NewTimeoutError(timeout int) *RPCError {
+	return &RPCError{
+		Code:    EnumerationTimeoutCode,
+		Message: "Enumeration timed out",
+		Data:    map[string]interface{}{"timeoutSeconds": timeout},
+	}
+}
+
+// NewRateLimitError is returned when a client has to slow down; Data says how
+// many seconds to wait, at least one
+func NewRateLimitError(retryAfterSeconds int) *RPCError {
+	return &RPCError{
+		Code:    RateLimitedCode,
+		Message: "Rate limit exceeded",
+		Data:    map[string]interface{}{"retryAfterSeconds": max(retryAfterSeconds, 1)},
+	}
+}
+
+// NewInvalidParamsError is ErrInvalidParams with detail saying which parameter
+// was wrong and how
+func NewInvalidParamsError(detail string) *RPCError {
+	return &RPCError{
+		Code:    InvalidParamsCode,
+		Message: ErrInvalidParams.Message,
+		Data:    detail,
+	}
+}
+
+// NewUnsupportedProtocolVersionError is returned by initialize for a protocol
+// version outside SupportedProtocolVersions
+func NewUnsupportedProtocolVersionError(version string) *RPCError {
+	return &RPCError{
+		Code:    InvalidParamsCode,
+		Message: fmt.Sprintf("Unsupported protocol version: %s. Server supports: %s", version, strings.Join(SupportedProtocolVersions, ", ")),
+	}
+}
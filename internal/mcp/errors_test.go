@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestErrorConstructors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *RPCError
+		expected *RPCError
+	}{
+		{
+			name: "tool not found",
+			err:  NewToolNotFoundError("scanPorts"),
+			expected: &RPCError{
+				Code:    MethodNotFoundCode,
+				Message: "Method not found",
+				Data: map[string]interface{}{
+					"tool":           "scanPorts",
+					"availableTools": []string{"enumerateSubdomains", "compareEnumerations", "generateAmassConfig"},
+				},
+			},
+		},
+		{
+			name: "domain validation",
+			err:  NewDomainValidationError("exa_mple.com", "invalid character"),
+			expected: &RPCError{
+				Code:    InvalidParamsCode,
+				Message: "Invalid params",
+				Data:    map[string]interface{}{"domain": "exa_mple.com", "reason": "invalid character"},
+			},
+		},
+		{
+			name: "timeout",
+			err:  NewTimeoutError(120),
+			expected: &RPCError{
+				Code:    EnumerationTimeoutCode,
+				Message: "Enumeration timed out",
+				Data:    map[string]interface{}{"timeoutSeconds": 120},
+			},
+		},
+		{
+			name: "rate limit",
+			err:  NewRateLimitError(30),
+			expected: &RPCError{
+				Code:    RateLimitedCode,
+				Message: "Rate limit exceeded",
+				Data:    map[string]interface{}{"retryAfterSeconds": 30},
+			},
+		},
+		{
+			name: "rate limit rounds up to a second",
+			err:  NewRateLimitError(0),
+			expected: &RPCError{
+				Code:    RateLimitedCode,
+				Message: "Rate limit exceeded",
+				Data:    map[string]interface{}{"retryAfterSeconds": 1},
+			},
+		},
+		{
+			name:     "invalid params",
+			err:      NewInvalidParamsError("resolveIPs must be a boolean"),
+			expected: &RPCError{Code: InvalidParamsCode, Message: "Invalid params", Data: "resolveIPs must be a boolean"},
+		},
+		{
+			name: "unsupported protocol version",
+			err:  NewUnsupportedProtocolVersionError("0.2"),
+			expected: &RPCError{
+				Code:    InvalidParamsCode,
+				Message: "Unsupported protocol version: 0.2. Server supports: 0.3, 2024-11-05",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.err, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidateDomainArgument(t *testing.T) {
+	for _, domain := range []string{"example.com", "Example.com.", "bücher.example", "xn--bcher-kva.example"} {
+		if err := validateDomainArgument(domain); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", domain, err)
+		}
+	}
+	for _, domain := range []string{"localhost", "exa_mple.com", "-example.com", "example..com"} {
+		if err := validateDomainArgument(domain); err == nil {
+			t.Errorf("Expected %q to be rejected", domain)
+		}
+	}
+}
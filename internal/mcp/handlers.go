@@ -2,20 +2,49 @@ package mcp
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
 	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/net/idna"
+	"mcp-subfinder-server/internal/classify"
+	"mcp-subfinder-server/internal/cloud"
+	"mcp-subfinder-server/internal/format"
+	"mcp-subfinder-server/internal/logging"
+	"mcp-subfinder-server/internal/parked"
+	"mcp-subfinder-server/internal/permutation"
+	"mcp-subfinder-server/internal/plugin"
+	"mcp-subfinder-server/internal/probe"
+	"mcp-subfinder-server/internal/report"
+	"mcp-subfinder-server/internal/shodan"
+	"mcp-subfinder-server/internal/sources"
+	"mcp-subfinder-server/internal/stats"
 	"mcp-subfinder-server/internal/subfinder"
+	"mcp-subfinder-server/internal/useragent"
+	"mcp-subfinder-server/internal/validation"
+	"mcp-subfinder-server/internal/virustotal"
+	"mcp-subfinder-server/internal/weights"
 )
 
 // HandleInitialize processes an initialize request
-func HandleInitialize(req *Request) Response {
+func HandleInitialize(ctx context.Context, req *Request, logger *slog.Logger) Response {
 	// Parse and validate params
 	var params InitializeParams
 	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse initialize params", "error", err)
 		return Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -23,18 +52,27 @@ func HandleInitialize(req *Request) Response {
 		}
 	}
 
+	logger.Info("Client initializing",
+		"clientName", params.ClientInfo.Name,
+		"clientVersion", params.ClientInfo.Version,
+		"protocolVersion", params.ProtocolVersion)
+
 	// Validate protocol version
-	if params.ProtocolVersion != SupportedProtocolVersion {
+	if !isSupportedProtocolVersion(params.ProtocolVersion) {
 		return Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error: &RPCError{
-				Code:    InvalidParamsCode,
-				Message: fmt.Sprintf("Unsupported protocol version: %s. Server supports: %s", params.ProtocolVersion, SupportedProtocolVersion),
-			},
+			Error:   NewUnsupportedProtocolVersionError(params.ProtocolVersion),
 		}
 	}
 
+	// Remember the client identity and negotiated version for later calls
+	if session := SessionFromContext(ctx); session != nil {
+		session.SetClientInfo(params.ClientInfo)
+		session.SetProtocolVersion(params.ProtocolVersion)
+		session.SetState(SessionInitializing)
+	}
+
 	// Return server capabilities
 	return Response{
 		JSONRPC: "2.0",
@@ -42,13 +80,42 @@ func HandleInitialize(req *Request) Response {
 		Result: InitializeResult{
 			Name:            "MCP Subfinder Server",
 			Version:         "1.0.0",
-			ProtocolVersion: SupportedProtocolVersion,
+			ProtocolVersion: params.ProtocolVersion,
+			Capabilities:    ServerCapabilities{Resources: ResourcesCapability{Subscribe: false}},
 		},
 	}
 }
 
+// HandleResourcesSubscribe processes a resources.subscribe request. Subscriptions
+// are not implemented, so clients get an explicit error instead of Method not found.
+func HandleResourcesSubscribe(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	logger.Warn("Resource subscription requested but not supported")
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error:   ErrSubscriptionsNotSupported,
+	}
+}
+
 // HandleToolsList processes a tools.list request
 func HandleToolsList(req *Request) Response {
+	// Return the list of tools
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ToolsList(),
+	}
+}
+
+// ToolsList returns the tools advertised under the current server settings
+func ToolsList() ToolsListResult {
+	return ToolsListResult{
+		Tools: availableTools(),
+	}
+}
+
+// availableTools returns the definitions of every tool exposed by the server
+func availableTools() []Tool {
 	// Define the enumerateSubdomains tool with its input schema
 	subdomainTool := Tool{
 		Name:        "enumerateSubdomains",
@@ -56,105 +123,679 @@ func HandleToolsList(req *Request) Response {
 		Description: "Discovers subdomains for a given domain using the subfinder tool",
 		InputSchema: map[string]interface{}{
 			"type": "object",
-			"properties": map[string]interface{}{
+			"properties": withEnumerationOptions(map[string]interface{}{
 				"domain": map[string]interface{}{
 					"type":        "string",
 					"description": "The base domain to enumerate subdomains for (e.g., example.com)",
 				},
-				"timeout": map[string]interface{}{
-					"type":        "integer",
-					"description": "Maximum time in seconds to run enumeration (default: 60)",
-					"default":     60,
+				"callbackURL": map[string]interface{}{
+					"type":        "string",
+					"description": "https URL to POST the result to; the call then returns {\"async\": true, \"jobId\": ...} immediately and the delivery carries an X-Job-ID header",
+				},
+				"notifyWebhookURL": map[string]interface{}{
+					"type":        "string",
+					"description": "https URL to POST each subdomain to as it is discovered, as {\"subdomain\", \"sources\", \"discoveredAt\", \"domain\", \"jobId\"}; the jobId is echoed as _meta.notifyJobId",
+				},
+				"deferred": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Return {\"jobId\": ..., \"status\": \"pending\"} immediately and enumerate in the background; poll jobs.get with the jobId for the result, kept for 24 hours (default: false)",
+					"default":     false,
+				},
+				"maskResults": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Replace subdomain names in server logs with [REDACTED-{hash}]; results returned to the caller are unchanged (default: false)",
+					"default":     false,
+				},
+				"domainAlias": map[string]interface{}{
+					"type":        "string",
+					"description": "Human-friendly name for the target, such as a bug bounty program name, echoed back as _meta.alias",
 				},
-				"maxDepth": map[string]interface{}{
+				"minSources": map[string]interface{}{
 					"type":        "integer",
-					"description": "Maximum depth to explore for subdomain enumeration (default: 1)",
+					"description": "Only return subdomains reported by at least this many passive sources (default: 1)",
 					"default":     1,
+					"minimum":     1,
+				},
+				"minimumResultThreshold": map[string]interface{}{
+					"type":        "integer",
+					"description": "Fail the call with isError when the sources find fewer than this many subdomains, counted before this call's filters, which usually means missing API keys or the wrong domain; 0 disables the check (default: 0)",
+					"default":     0,
+					"minimum":     0,
+				},
+				"minTrustScore": map[string]interface{}{
+					"type":        "number",
+					"description": "Only return subdomains with at least this trust score, which is 0.2 per distinct source that reported them, capped at 1 (default: 0)",
+					"default":     0,
+					"minimum":     0,
+					"maximum":     1,
+				},
+				"limitToTLD": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return subdomains ending in this TLD, e.g. \"com\" (default: all TLDs)",
+				},
+				"limitToRegisteredDomain": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Drop results that do not end in .{domain}, catching cross-domain pollution from sources (default: false)",
+					"default":     false,
+				},
+				"subdomainPrefix": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Keep only subdomains starting with one of these strings, e.g. dev-",
+				},
+				"subdomainSuffix": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Keep only subdomains ending with one of these strings, e.g. .internal.example.com",
+				},
+				"subdomainContains": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Keep only subdomains containing one of these strings, e.g. -staging",
+				},
+				"minSubdomainLength": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Keep only subdomains whose first label, e.g. cdn in cdn.example.com, has at least this many characters (default: no limit)",
+				},
+				"maxSubdomainLength": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Keep only subdomains whose first label has at most this many characters (default: no limit)",
 				},
-				"sourcesFilter": map[string]interface{}{
+				"sensitivePatternAlert": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Tag subdomains whose names suggest admin panels, VPNs, CI systems and other internal services with sensitive and sensitiveReason in the JSON resource (default: false)",
+				},
+				"filterByRegex": map[string]interface{}{
+					"type":        "string",
+					"description": "Keep only subdomains matching this Go regular expression, e.g. ^dev-; an invalid expression fails the call",
+				},
+				"excludeByRegex": map[string]interface{}{
 					"type":        "string",
-					"description": "Comma-separated list of sources to use (default: all sources)",
+					"description": "Drop subdomains matching this Go regular expression, e.g. -staging\\.; an invalid expression fails the call",
 				},
-				"excludeSourcesFilter": map[string]interface{}{
+				"recursionExcludePatterns": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "path.Match globs such as *.cloudfront.net; matching subdomains are still returned but not enumerated recursively",
+				},
+				"customDNSSeed": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Subdomains already known from earlier scans, returned alongside new discoveries with source custom-seed; names outside the domain are ignored",
+				},
+				"baselineBase64": map[string]interface{}{
 					"type":        "string",
-					"description": "Comma-separated list of sources to exclude",
+					"description": "Base64-encoded newline-separated list of known subdomains; the result adds a JSON diff with added, removed and unchanged lists",
 				},
-				"recursive": map[string]interface{}{
+				"includeProviderStatus": map[string]interface{}{
 					"type":        "boolean",
-					"description": "Enable recursive subdomain discovery (default: false)",
+					"description": "Report in _meta.providerStatus how many results each passive source returned and whether it had errors (default: false)",
 					"default":     false,
 				},
-			},
+				"resolveIPs": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Resolve the A/AAAA records of each discovered subdomain (default: false)",
+					"default":     false,
+				},
+				"exportFormat": map[string]interface{}{
+					"type":        "string",
+					"description": "Format of the subdomain list resource: plain, nmap-xml, masscan-json or amass-json; masscan-json lists resolved addresses only, so combine it with resolveIPs (default: plain)",
+					"enum":        format.Formats,
+					"default":     format.Plain,
+				},
+				"generateReport": map[string]interface{}{
+					"type":        "string",
+					"description": "Add a human-readable summary resource: none, markdown or html. It lists subdomains with their sources and, with resolveIPs, cloud providers and CNAMEs whose target no longer resolves (default: none)",
+					"enum":        report.Formats,
+					"default":     report.None,
+				},
+				"outputTemplate": map[string]interface{}{
+					"type":        "string",
+					"description": "Go text/template rendered against {Domain, Subdomains, Stats, Timestamp} and added as a text item; Subdomains are the result entries and Stats the _meta values. Only the template builtins are available and output is limited to 10MB",
+				},
+				"retainRawOutput": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add the result lines subfinder wrote for the enumeration as a final text content item, only for the names the call returns and truncated to 100KB (default: false)",
+					"default":     false,
+				},
+				"followCNAME": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also enumerate the apex domains of discovered subdomains' CNAME targets, up to 3 hops and 5 derived domains (default: false)",
+					"default":     false,
+				},
+				"probeTLSVersion": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Handshake with port 443 of each resolved subdomain once per TLS version and list the versions it accepts as tlsVersions in the JSON resource (requires resolveIPs, default: false)",
+					"default":     false,
+				},
+				"onlyTLSVersions": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": probe.TLSVersions},
+					"description": "Only return subdomains accepting at least one of these TLS versions, e.g. [\"1.0\", \"1.1\"] to find legacy servers (requires probeTLSVersion)",
+				},
+				"excludeTLSVersions": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": probe.TLSVersions},
+					"description": "Drop subdomains accepting any of these TLS versions (requires probeTLSVersion)",
+				},
+				"autoExpandWildcard": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Check the domain for a wildcard DNS record first and, if it has one, drop results that resolve only to the wildcard addresses; subfinder's passive sources do no wildcard filtering of their own (default: false)",
+					"default":     false,
+				},
+				"autoDetectWildcardSources": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Before enumerating, query every source for a random UUID subdomain that cannot exist and exclude the sources that report anything for it, since they inflate results on wildcard domains (default: false)",
+					"default":     false,
+				},
+				"permutations": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Resolve up to 1000 permutations of the discovered names, such as dev-{name} and {name}-prod, and add those that resolve (default: false)",
+					"default":     false,
+				},
+				"probeTLS": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Connect to port 443 of resolved subdomains, report their certificate and guessed asset type in the JSON resource and add certificate names missing from the results; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"scopePatterns": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Bug bounty scope as path.Match globs such as *.example.com; a leading ! excludes. Each entry of the JSON resource gets inScope true or false",
+				},
+				"outputLineDelimiter": map[string]interface{}{
+					"type":        "string",
+					"description": "Separator between subdomains in the plain-text list, e.g. \",\" or \" \" for legacy scripts; at most 5 characters (default: newline)",
+					"default":     defaultLineDelimiter,
+					"maxLength":   maxLineDelimiterLength,
+				},
+				"outputFields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": format.Fields},
+					"description": "Only include these fields in each entry of the JSON resource, e.g. subdomain, ip4 and sources; the resource is returned even without resolveIPs",
+				},
+				"outputGroupBy": map[string]interface{}{
+					"type":        "string",
+					"enum":        format.GroupKeys,
+					"description": "Return the JSON resource as an object of entry arrays grouped by this key instead of a flat array; cloudProvider requires resolveIPs, and asn requires enrichWithShodan (default: none)",
+					"default":     format.GroupNone,
+				},
+				"networkTimeout": map[string]interface{}{
+					"type":        "number",
+					"description": "Seconds allowed to establish each probeTLS, excludeParked and httpProbe connection, separate from the enumeration timeout (default: 5)",
+					"default":     probe.DefaultNetworkTimeout.Seconds(),
+				},
+				"excludeParked": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Probe resolved subdomains over HTTP and drop those serving registrar parking pages; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"httpProbe": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Probe resolved subdomains over HTTPS and HTTP and mark each entry of the JSON resource with status live or idle; idle ones are also listed in _meta.idleSubdomains. Requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"injectConsentCookies": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Send the accepted-consent cookies of common consent management platforms, such as CookieConsent=yes, with httpProbe requests so GDPR consent walls do not hide the real response. Requires httpProbe (default: false)",
+					"default":     false,
+				},
+				"excludeIdle": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Drop idle subdomains from the list; they stay in the JSON resource and _meta.idleSubdomains. Requires httpProbe (default: false)",
+					"default":     false,
+				},
+				"enrichWithShodan": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Look up each unique resolved IP in Shodan and add its open ports, tags, org and ISP to the JSON resource, at 1 request per second; requires resolveIPs and an API key (default: false)",
+					"default":     false,
+				},
+				"shodanApiKey": map[string]interface{}{
+					"type":        "string",
+					"description": "Shodan API key for enrichWithShodan (default: the server's SHODAN_API_KEY environment variable)",
+				},
+				"enrichWithVirusTotal": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Look up each listed subdomain in VirusTotal and add its malicious and suspicious detections, categories and last analysis date to the JSON resource, at 4 requests per minute and within the call's timeout; requires an API key (default: false)",
+					"default":     false,
+				},
+				"virusTotalApiKey": map[string]interface{}{
+					"type":        "string",
+					"description": "VirusTotal API key for enrichWithVirusTotal (default: the server's VIRUSTOTAL_API_KEY environment variable)",
+				},
+				"uniqueByIP": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Return only the alphabetically first subdomain of each group sharing a resolved IP, directly or through other members, listing the others as aliases in the JSON resource; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"excludePrivateIPs": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Drop subdomains whose resolved IPs are all private (RFC1918) or link-local; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"excludeLoopback": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Drop subdomains whose resolved IPs are all loopback; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"excludeMulticast": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Drop subdomains whose resolved IPs are all multicast; requires resolveIPs (default: false)",
+					"default":     false,
+				},
+				"limitByCloudProvider": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": cloudProviders},
+					"description": "Only return subdomains hosted on these cloud providers; requires resolveIPs, and the call fails without it",
+				},
+				"excludeCloudProvider": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": cloudProviders},
+					"description": "Drop subdomains hosted on these cloud providers; requires resolveIPs, and the call fails without it",
+				},
+			}),
 			"required": []string{"domain"},
 		},
 		RequiresAPIKeys: true,
 	}
 
-	// Return the list of tools
-	return Response{
-		JSONRPC: "2.0",
-		ID:      req.ID,
-		Result: ToolsListResult{
-			Tools: []Tool{subdomainTool},
+	// Define the compareEnumerations tool that diffs two domains' footprints
+	compareTool := Tool{
+		Name:        "compareEnumerations",
+		Title:       "Compare Enumerations",
+		Description: "Enumerates two domains concurrently and compares their subdomain labels (the part left of each base domain)",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": withEnumerationOptions(map[string]interface{}{
+				"domain1": map[string]interface{}{
+					"type":        "string",
+					"description": "The first base domain to enumerate (e.g., example.com)",
+				},
+				"domain2": map[string]interface{}{
+					"type":        "string",
+					"description": "The second base domain to enumerate (e.g., acquired.com)",
+				},
+			}),
+			"required": []string{"domain1", "domain2"},
+		},
+		RequiresAPIKeys: true,
+	}
+
+	tools := []Tool{subdomainTool, compareTool, amassConfigTool()}
+
+	// Custom CAs for probeTLS are only advertised when the operator opted in
+	if currentSettings().AllowCustomTrustAnchors {
+		properties := subdomainTool.InputSchema.(map[string]interface{})["properties"].(map[string]interface{})
+		properties["trustAnchorsBase64"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Base64-encoded PEM bundle of CA certificates trusted in addition to the system pool; probeTLS then reports whether each certificate chain verifies",
+		}
+	}
+
+	// Active brute forcing is only advertised when the operator opted in
+	if currentSettings().AllowBruteForce {
+		tools = append(tools, bruteForceTool())
+	}
+
+	// Tool calls can be deduplicated with an idempotency key, unless their results hold secrets
+	for _, tool := range tools {
+		properties := tool.InputSchema.(map[string]interface{})["properties"].(map[string]interface{})
+		if !uncachedTools[tool.Name] {
+			properties["idempotencyKey"] = map[string]interface{}{
+				"type":        "string",
+				"description": "Arbitrary key; repeating it with the same arguments in the same session within the idempotency window returns the earlier successful result without running the tool again",
+			}
+			properties["cacheKey"] = map[string]interface{}{
+				"type":        "string",
+				"pattern":     cacheKeyPattern.String(),
+				"description": "Cache group for idempotencyKey within the session, such as a project name; calls only replay results stored under the same cacheKey",
+			}
+		}
+		properties["comment"] = map[string]interface{}{
+			"type":        "string",
+			"maxLength":   maxCommentLength,
+			"description": "Why the call was made, for audit logs; it is logged and otherwise ignored",
+		}
+	}
+
+	return tools
+}
+
+// withEnumerationOptions adds the subfinder options shared by every enumeration tool to properties
+func withEnumerationOptions(properties map[string]interface{}) map[string]interface{} {
+	shared := map[string]interface{}{
+		"timeout": map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("Maximum time in seconds to run enumeration, at most %d (default: 60)", MaxEnumerationTimeout),
+			"default":     60,
+			"maximum":     MaxEnumerationTimeout,
+		},
+		"maxDepth": map[string]interface{}{
+			"type":        "integer",
+			"description": "Maximum depth to explore for subdomain enumeration, capped by the server's -max-recursive-depth (default: 1)",
+			"default":     1,
+		},
+		"sourcesFilter": map[string]interface{}{
+			"type":        "string",
+			"description": "Comma-separated list of sources to use (default: all sources)",
+		},
+		"excludeSourcesFilter": map[string]interface{}{
+			"type":        "string",
+			"description": "Comma-separated list of sources to exclude",
+		},
+		"prioritizeSources": map[string]interface{}{
+			"type":        "array",
+			"description": "Sources to query first, on their own with two thirds of the timeout; the remaining sources then run within what is left of it, and the priority results are returned if they fail or run out of time. Unknown source names are rejected",
+			"items":       map[string]interface{}{"type": "string"},
 		},
+		"sourceWeights": map[string]interface{}{
+			"type":        "object",
+			"description": "Preference for individual sources, e.g. {\"crtsh\": 2}; sources weighted above 1 run first as with prioritizeSources, which takes precedence. Sources not named here use the weights learned from DNS-confirmed results, relative to the average source, so {} applies the learned weights alone",
+			"additionalProperties": map[string]interface{}{
+				"type":    "number",
+				"minimum": 0,
+			},
+		},
+		"recursive": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Enable recursive subdomain discovery (default: false)",
+			"default":     false,
+		},
+		"maxPerSource": map[string]interface{}{
+			"type":        "integer",
+			"description": "Maximum number of subdomains a single source may contribute on its own; corroborated results are never capped (default: unlimited)",
+		},
+		"userAgent": map[string]interface{}{
+			"type":        "string",
+			"description": "User-Agent for HTTP requests made by the server itself; subfinder's passive sources choose their own (default: mcp-subfinder/1.0.0)",
+			"default":     useragent.Default,
+		},
+		"certTransparencyOnly": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Only query certificate transparency log sources and skip anything that resolves against the target's DNS (default: false)",
+			"default":     false,
+		},
+		"normalizeUnicode": map[string]interface{}{
+			"type":        "string",
+			"description": "Encoding internationalized subdomains are normalized to before deduplication, so xn--wgv71a119e.example.com and 日本語.example.com are one result: punycode, unicode or none (default: punycode)",
+			"enum":        subfinder.IDNEncodings,
+			"default":     subfinder.IDNPunycode,
+		},
+		"verbose": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Run subfinder verbosely and log its raw per-source output at debug level (default: false)",
+			"default":     false,
+		},
+		"maxRetries": map[string]interface{}{
+			"type":        "integer",
+			"description": "Maximum enumeration attempts, overriding retryStrategy.maxAttempts; 0 and 1 both make a single attempt and fail fast on any error (default: 3)",
+			"minimum":     0,
+			"maximum":     5,
+			"default":     subfinder.DefaultRetryConfig.MaxAttempts,
+		},
+		"rateLimitPerSource": map[string]interface{}{
+			"type":        "object",
+			"description": "Maximum requests per second for individual sources, e.g. {\"shodan\": 1, \"securitytrails\": 2}; other sources are not throttled",
+			"additionalProperties": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
+			},
+		},
+		"retryStrategy": map[string]interface{}{
+			"type":        "object",
+			"description": "How failed or empty enumerations are retried; the delay before retry n is baseDelaySeconds * backoffMultiplier^(n-1)",
+			"properties": map[string]interface{}{
+				"maxAttempts": map[string]interface{}{
+					"type":    "integer",
+					"minimum": 1,
+					"maximum": 5,
+					"default": subfinder.DefaultRetryConfig.MaxAttempts,
+				},
+				"baseDelaySeconds": map[string]interface{}{
+					"type":    "number",
+					"minimum": 1,
+					"maximum": 30,
+					"default": subfinder.DefaultRetryConfig.BaseDelay.Seconds(),
+				},
+				"backoffMultiplier": map[string]interface{}{
+					"type":    "number",
+					"minimum": 1.0,
+					"maximum": 3.0,
+					"default": subfinder.DefaultRetryConfig.BackoffMultiplier,
+				},
+			},
+		},
+	}
+
+	for name, schema := range shared {
+		properties[name] = schema
 	}
+	return properties
 }
 
-// HandleToolsCall processes a tools.call request
+// HandleToolsCall processes a tools.call request, running it through the
+// built-in middleware and any registered with RegisterToolMiddleware
 func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath string, logger *slog.Logger) Response {
-	// Parse and validate params
-	var params ToolCallParams
-	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
-		logger.Error("Failed to parse tools.call params", "error", err)
+	chain := append([]ToolMiddleware{
+		sessionMiddleware(logger),
+		paramsMiddleware(providerConfigPath, logger),
+		idempotencyMiddleware(logger),
+	}, registeredMiddleware()...)
+
+	handler := chainToolMiddleware(chain, func(ctx context.Context, req *Request, _ subfinder.SubfinderConfig) Response {
+		params, _ := ToolCallParamsFromContext(ctx)
+
+		stats.Default.EnumerationStarted()
+		started := time.Now()
+		resp := callTool(ctx, req, params, providerConfigPath, logger)
+		failed := resp.Error != nil
+		if result, ok := resp.Result.(ToolCallResult); ok && result.IsError {
+			failed = true
+		}
+		stats.Default.EnumerationFinished(time.Since(started), failed)
+
+		// Echo caller annotations so orchestrators can correlate the result
+		if result, ok := resp.Result.(ToolCallResult); ok && params.Annotations != nil {
+			result.Annotations = params.Annotations
+			resp.Result = result
+		}
+		return resp
+	})
+	return handler(ctx, req, subfinder.SubfinderConfig{})
+}
+
+// maxCommentLength is the longest audit comment logged, in characters
+const maxCommentLength = 500
+
+// commentArgument returns the call's comment, truncated to maxCommentLength characters
+func commentArgument(params ToolCallParams, logger *slog.Logger) string {
+	val, ok := params.Arguments["comment"]
+	if !ok {
+		return ""
+	}
+	comment, ok := val.(string)
+	if !ok {
+		logger.Warn("Invalid comment parameter, ignoring it", "providedComment", val)
+		return ""
+	}
+	if runes := []rune(comment); len(runes) > maxCommentLength {
+		logger.Warn("Comment too long, truncating it", "length", len(runes), "maxLength", maxCommentLength)
+		comment = string(runes[:maxCommentLength])
+	}
+	return comment
+}
+
+// requestIDString renders a JSON-RPC request ID for logs; it is empty for notifications
+func requestIDString(id *jsoniter.RawMessage) string {
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
+
+// requireInitialized rejects requests from sessions that have not completed initialize
+func requireInitialized(ctx context.Context, req *Request, logger *slog.Logger) (Response, bool) {
+	if session := SessionFromContext(ctx); session != nil && session.State() == SessionUninitialized {
+		logger.Warn("Method called before initialize", "method", req.Method)
 		return Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error:   ErrParse,
-		}
+			Error:   ErrServerNotInitialized,
+		}, false
 	}
+	return Response{}, true
+}
 
-	// Check if the requested tool is supported
-	if params.Name != "enumerateSubdomains" {
+// callTool routes a parsed tools.call request to the requested tool
+func callTool(ctx context.Context, req *Request, params ToolCallParams, providerConfigPath string, logger *slog.Logger) Response {
+	switch params.Name {
+	case "enumerateSubdomains":
+		return handleEnumerateSubdomains(ctx, req, params, providerConfigPath, logger)
+	case "compareEnumerations":
+		return handleCompareEnumerations(ctx, req, params, providerConfigPath, logger)
+	case "wildcardSubdomainBrute":
+		return handleWildcardSubdomainBrute(ctx, req, params, logger)
+	case "generateAmassConfig":
+		return handleGenerateAmassConfig(ctx, req, params, logger)
+	default:
 		logger.Warn("Tool not found", "requestedTool", params.Name)
 		return Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error:   ErrMethodNotFound,
+			Error:   NewToolNotFoundError(params.Name),
 		}
 	}
+}
 
-	// Extract and validate required domain parameter
-	domainVal, ok := params.Arguments["domain"]
+// regexArgument compiles an optional regular expression argument. It is nil when
+// the argument is absent and an error when it is not a valid expression.
+func regexArgument(args map[string]interface{}, name string) (*regexp.Regexp, error) {
+	val, ok := args[name]
 	if !ok {
-		logger.Warn("Missing required domain parameter")
-		return Response{
-			JSONRPC: "2.0",
-			ID:      req.ID,
-			Error:   ErrInvalidParams,
+		return nil, nil
+	}
+	pattern, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", name)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return re, nil
+}
+
+// stringArrayArgument extracts an optional array of non-empty strings, ignoring
+// it entirely when any element is not one
+func stringArrayArgument(args map[string]interface{}, name string, logger *slog.Logger) []string {
+	val, ok := args[name]
+	if !ok {
+		return nil
+	}
+
+	values, isArray := val.([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok || str == "" {
+			isArray = false
+			break
 		}
+		strs = append(strs, str)
+	}
+	if !isArray {
+		logger.Warn("Invalid parameter, ignoring it", "parameter", name, "value", val)
+		return nil
 	}
 
-	domain, ok := domainVal.(string)
-	if !ok || domain == "" {
-		logger.Warn("Invalid domain parameter", "domain", domainVal)
-		return Response{
-			JSONRPC: "2.0",
-			ID:      req.ID,
-			Error:   ErrInvalidParams,
+	logger.Debug("Using custom "+name, name, strs)
+	return strs
+}
+
+// validateSourceArguments checks that the sources an enumeration is asked to
+// prefer exist, since subfinder exits the process when it is left with none
+func validateSourceArguments(args map[string]interface{}) error {
+	names, _ := args["prioritizeSources"].([]interface{})
+	var unknown []string
+	for _, name := range names {
+		if source, ok := name.(string); ok && source != "" && !sources.IsKnown(source) {
+			unknown = append(unknown, source)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("prioritizeSources: unknown sources %s", strings.Join(unknown, ", "))
+	}
+
+	weightMap, _ := args["sourceWeights"].(map[string]interface{})
+	for source := range weightMap {
+		if source != "" && !sources.IsKnown(source) {
+			unknown = append(unknown, source)
 		}
 	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("sourceWeights: unknown sources %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// requiredStringArgument extracts a non-empty string argument, logging why it is unusable
+func requiredStringArgument(args map[string]interface{}, name string, logger *slog.Logger) (string, bool) {
+	val, ok := args[name]
+	if !ok {
+		logger.Warn("Missing required parameter", "parameter", name)
+		return "", false
+	}
+
+	str, ok := val.(string)
+	if !ok || str == "" {
+		logger.Warn("Invalid required parameter", "parameter", name, "value", val)
+		return "", false
+	}
+
+	return str, true
+}
+
+// validateDomainArgument checks a domain argument, accepting IDNs in Unicode or
+// Punycode form and a trailing dot
+func validateDomainArgument(domain string) error {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return err
+	}
+	return validation.ValidateDomain(ascii)
+}
 
+// cloudProviders lists the providers reported as cloudProvider
+var cloudProviders = []string{cloud.AWS, cloud.GCP, cloud.Azure, cloud.Cloudflare, cloud.Fastly}
+
+// DefaultEnumerationConfig returns the options an enumeration runs with when a
+// call sets none of them
+func DefaultEnumerationConfig(providerConfigPath string) subfinder.SubfinderConfig {
+	return parseEnumerationConfig(nil, providerConfigPath, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// parseEnumerationConfig extracts the subfinder options shared by every enumeration tool
+func parseEnumerationConfig(args map[string]interface{}, providerConfigPath string, logger *slog.Logger) subfinder.SubfinderConfig {
 	// Parse optional parameters with sensible defaults
 	config := subfinder.SubfinderConfig{
 		ProviderConfigPath: providerConfigPath,
 		Timeout:            60, // Default timeout of 60 seconds
 		MaxDepth:           1,  // Default max depth of 1
+		UserAgent:          useragent.Default,
+		SilentMode:         true,
+		NormalizeUnicode:   subfinder.IDNPunycode,
 	}
 
 	// Extract timeout if provided
-	if timeoutVal, ok := params.Arguments["timeout"]; ok {
+	if timeoutVal, ok := args["timeout"]; ok {
 		if timeout, ok := timeoutVal.(float64); ok && timeout > 0 {
-			config.Timeout = int(timeout)
+			config.Timeout = int(min(timeout, MaxEnumerationTimeout))
+			if timeout > MaxEnumerationTimeout {
+				logger.Warn("timeout exceeds the server limit, clamping it", "requestedTimeout", timeout, "timeout", config.Timeout)
+			}
 			logger.Debug("Using custom timeout", "timeout", config.Timeout)
 		} else {
 			logger.Warn("Invalid timeout parameter, using default", "providedTimeout", timeoutVal)
@@ -162,7 +803,7 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 	}
 
 	// Extract maxDepth if provided
-	if maxDepthVal, ok := params.Arguments["maxDepth"]; ok {
+	if maxDepthVal, ok := args["maxDepth"]; ok {
 		if maxDepth, ok := maxDepthVal.(float64); ok && maxDepth > 0 {
 			config.MaxDepth = int(maxDepth)
 			logger.Debug("Using custom maxDepth", "maxDepth", config.MaxDepth)
@@ -171,8 +812,14 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 		}
 	}
 
+	// Whatever the caller asks for, recursion stops at the server's cap
+	if limit := maxRecursiveDepth(); config.MaxDepth > limit {
+		logger.Warn("maxDepth exceeds the server limit, clamping it", "requestedMaxDepth", config.MaxDepth, "maxDepth", limit)
+		config.MaxDepth = limit
+	}
+
 	// Extract sourcesFilter if provided
-	if sourcesFilterVal, ok := params.Arguments["sourcesFilter"]; ok {
+	if sourcesFilterVal, ok := args["sourcesFilter"]; ok {
 		if sourcesFilter, ok := sourcesFilterVal.(string); ok && sourcesFilter != "" {
 			config.SourcesFilter = sourcesFilter
 			logger.Debug("Using custom sourcesFilter", "sourcesFilter", config.SourcesFilter)
@@ -182,7 +829,7 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 	}
 
 	// Extract excludeSourcesFilter if provided
-	if excludeSourcesFilterVal, ok := params.Arguments["excludeSourcesFilter"]; ok {
+	if excludeSourcesFilterVal, ok := args["excludeSourcesFilter"]; ok {
 		if excludeSourcesFilter, ok := excludeSourcesFilterVal.(string); ok && excludeSourcesFilter != "" {
 			config.ExcludeSourcesFilter = excludeSourcesFilter
 			logger.Debug("Using custom excludeSourcesFilter", "excludeSourcesFilter", config.ExcludeSourcesFilter)
@@ -191,8 +838,45 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 		}
 	}
 
+	// Extract prioritizeSources if provided
+	if prioritySources := stringArrayArgument(args, "prioritizeSources", logger); len(prioritySources) > 0 {
+		for _, source := range prioritySources {
+			config.PrioritySources = append(config.PrioritySources, strings.ToLower(strings.TrimSpace(source)))
+		}
+		logger.Debug("Using custom prioritizeSources", "prioritizeSources", config.PrioritySources)
+	}
+
+	// Extract sourceWeights if provided; sources weighted above neutral run first,
+	// and the caller's weights override the learned ones
+	if weightsVal, ok := args["sourceWeights"]; ok {
+		if weightMap, ok := weightsVal.(map[string]interface{}); ok {
+			// Learned weights can name sources subfinder no longer has
+			sourceWeights := make(map[string]float64, len(weightMap))
+			for source, weight := range weights.Default.Weights() {
+				if sources.IsKnown(source) {
+					sourceWeights[source] = weight
+				}
+			}
+			for source, weightVal := range weightMap {
+				if v, ok := weightVal.(float64); ok && v >= 0 && source != "" {
+					sourceWeights[strings.ToLower(source)] = v
+				} else {
+					logger.Warn("Invalid sourceWeights entry, ignoring it", "source", source, "providedWeight", weightVal)
+				}
+			}
+			logger.Debug("Using custom sourceWeights", "sourceWeights", sourceWeights)
+			if len(config.PrioritySources) > 0 {
+				logger.Warn("sourceWeights is not used with prioritizeSources, ignoring it")
+			} else {
+				config.PrioritySources = subfinder.PrioritySourcesByWeight(sourceWeights)
+			}
+		} else {
+			logger.Warn("Invalid sourceWeights parameter, using default", "providedSourceWeights", weightsVal)
+		}
+	}
+
 	// Extract recursive if provided
-	if recursiveVal, ok := params.Arguments["recursive"]; ok {
+	if recursiveVal, ok := args["recursive"]; ok {
 		if recursive, ok := recursiveVal.(bool); ok {
 			config.Recursive = recursive
 			logger.Debug("Using custom recursive setting", "recursive", config.Recursive)
@@ -201,12 +885,1210 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 		}
 	}
 
+	// Extract maxPerSource if provided
+	if maxPerSourceVal, ok := args["maxPerSource"]; ok {
+		if maxPerSource, ok := maxPerSourceVal.(float64); ok && maxPerSource > 0 {
+			config.MaxPerSource = int(maxPerSource)
+			logger.Debug("Using custom maxPerSource", "maxPerSource", config.MaxPerSource)
+		} else {
+			logger.Warn("Invalid maxPerSource parameter, using default", "providedMaxPerSource", maxPerSourceVal)
+		}
+	}
+
+	// Extract userAgent if provided
+	if userAgentVal, ok := args["userAgent"]; ok {
+		if userAgent, ok := userAgentVal.(string); ok && userAgent != "" {
+			config.UserAgent = userAgent
+			logger.Debug("Using custom userAgent", "userAgent", config.UserAgent)
+		} else {
+			logger.Warn("Invalid userAgent parameter, using default", "providedUserAgent", userAgentVal)
+		}
+	}
+
+	// Extract certTransparencyOnly if provided
+	if ctOnlyVal, ok := args["certTransparencyOnly"]; ok {
+		if ctOnly, ok := ctOnlyVal.(bool); ok {
+			config.CertTransparencyOnly = ctOnly
+			logger.Debug("Using custom certTransparencyOnly setting", "certTransparencyOnly", ctOnly)
+		} else {
+			logger.Warn("Invalid certTransparencyOnly parameter, using default", "providedCertTransparencyOnly", ctOnlyVal)
+		}
+	}
+
+	// Extract normalizeUnicode if provided
+	if normalizeVal, ok := args["normalizeUnicode"]; ok {
+		if v, ok := normalizeVal.(string); ok && slices.Contains(subfinder.IDNEncodings, v) {
+			config.NormalizeUnicode = v
+			logger.Debug("Using custom normalizeUnicode setting", "normalizeUnicode", v)
+		} else {
+			logger.Warn("Invalid normalizeUnicode parameter, using default", "providedNormalizeUnicode", normalizeVal)
+		}
+	}
+
+	// Extract verbose if provided; verbose runs are never silent
+	if verboseVal, ok := args["verbose"]; ok {
+		if verbose, ok := verboseVal.(bool); ok {
+			config.VerboseMode = verbose
+			config.SilentMode = !verbose
+			logger.Debug("Using custom verbose setting", "verbose", verbose)
+		} else {
+			logger.Warn("Invalid verbose parameter, using default", "providedVerbose", verboseVal)
+		}
+	}
+
+	// Extract retryStrategy if provided; out-of-range fields keep their default
+	config.RetryConfig = subfinder.DefaultRetryConfig
+	if retryVal, ok := args["retryStrategy"]; ok {
+		if strategy, ok := retryVal.(map[string]interface{}); ok {
+			if v, ok := strategy["maxAttempts"].(float64); ok && v >= 1 && v <= 5 && v == math.Trunc(v) {
+				config.RetryConfig.MaxAttempts = int(v)
+			} else if _, set := strategy["maxAttempts"]; set {
+				logger.Warn("Invalid retryStrategy.maxAttempts, using default", "providedMaxAttempts", strategy["maxAttempts"])
+			}
+			if v, ok := strategy["baseDelaySeconds"].(float64); ok && v >= 1 && v <= 30 {
+				config.RetryConfig.BaseDelay = time.Duration(v * float64(time.Second))
+			} else if _, set := strategy["baseDelaySeconds"]; set {
+				logger.Warn("Invalid retryStrategy.baseDelaySeconds, using default", "providedBaseDelaySeconds", strategy["baseDelaySeconds"])
+			}
+			if v, ok := strategy["backoffMultiplier"].(float64); ok && v >= 1 && v <= 3 {
+				config.RetryConfig.BackoffMultiplier = v
+			} else if _, set := strategy["backoffMultiplier"]; set {
+				logger.Warn("Invalid retryStrategy.backoffMultiplier, using default", "providedBackoffMultiplier", strategy["backoffMultiplier"])
+			}
+			logger.Debug("Using custom retryStrategy", "retryStrategy", config.RetryConfig)
+		} else {
+			logger.Warn("Invalid retryStrategy parameter, using default", "providedRetryStrategy", retryVal)
+		}
+	}
+
+	// Extract maxRetries if provided; zero still makes the one required attempt
+	if maxRetriesVal, ok := args["maxRetries"]; ok {
+		if v, ok := maxRetriesVal.(float64); ok && v >= 0 && v <= 5 && v == math.Trunc(v) {
+			config.RetryConfig.MaxAttempts = max(int(v), 1)
+			logger.Debug("Using custom maxRetries", "maxRetries", int(v))
+		} else {
+			logger.Warn("Invalid maxRetries parameter, using default", "providedMaxRetries", maxRetriesVal)
+		}
+	}
+
+	// Extract rateLimitPerSource if provided; invalid entries are skipped
+	if rateLimitsVal, ok := args["rateLimitPerSource"]; ok {
+		if limits, ok := rateLimitsVal.(map[string]interface{}); ok {
+			config.RateLimits = make(map[string]int, len(limits))
+			for source, limitVal := range limits {
+				if v, ok := limitVal.(float64); ok && v >= 1 && v == math.Trunc(v) && source != "" {
+					config.RateLimits[strings.ToLower(source)] = int(v)
+				} else {
+					logger.Warn("Invalid rateLimitPerSource entry, ignoring it", "source", source, "providedRateLimit", limitVal)
+				}
+			}
+			logger.Debug("Using custom rateLimitPerSource", "rateLimitPerSource", config.RateLimits)
+		} else {
+			logger.Warn("Invalid rateLimitPerSource parameter, using default", "providedRateLimitPerSource", rateLimitsVal)
+		}
+	}
+
+	return config
+}
+
+// providerStatus summarizes per-source statistics for the caller
+func providerStatus(stats []subfinder.SourceStatistic) []ProviderStatus {
+	status := make([]ProviderStatus, 0, len(stats))
+	for _, stat := range stats {
+		status = append(status, ProviderStatus{
+			Name:         stat.Source,
+			ResultsCount: stat.Results,
+			HadErrors:    stat.Errors > 0,
+		})
+	}
+	return status
+}
+
+// markParked probes the resolved entries over HTTP and flags those serving parking pages
+func markParked(ctx context.Context, entries []subfinder.SubdomainEntry, userAgent string, networkTimeout time.Duration, logger *slog.Logger) {
+	var hosts []string
+	for _, entry := range entries {
+		if len(entry.IPs) > 0 {
+			hosts = append(hosts, entry.Subdomain)
+		}
+	}
+
+	checker := parked.NewChecker(userAgent, parked.DefaultProbeTimeout, networkTimeout, logger)
+	providers := checker.Check(ctx, hosts)
+	for i := range entries {
+		if provider, ok := providers[entries[i].Subdomain]; ok {
+			entries[i].Parked = true
+			logger.Debug("Subdomain is parked", "subdomain", entries[i].Subdomain, "provider", provider)
+		}
+	}
+	logger.Info("Checked subdomains for parking pages", "checked", len(hosts), "parked", len(providers))
+}
+
+// learnSourceWeights records which sources reported the entries that resolved.
+// Seeds and permutations are the caller's and the server's own guesses, not
+// sources, so they are left out.
+func learnSourceWeights(entries []subfinder.SubdomainEntry, sources map[string][]string) {
+	reported := make(map[string][]string, len(entries))
+	resolved := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		var entrySources []string
+		for _, source := range sources[entry.Subdomain] {
+			if source != subfinder.SeedSource && source != permutation.Source {
+				entrySources = append(entrySources, source)
+			}
+		}
+		if len(entrySources) > 0 {
+			reported[entry.Subdomain] = entrySources
+			resolved[entry.Subdomain] = len(entry.IPs) > 0
+		}
+	}
+	weights.Default.Record(reported, resolved)
+}
+
+// markIdle probes the resolved entries over HTTPS and HTTP and records whether
+// each answered. Entries without addresses cannot answer and are marked idle.
+func markIdle(ctx context.Context, entries []subfinder.SubdomainEntry, userAgent string, networkTimeout time.Duration, injectConsentCookies bool, logger *slog.Logger) {
+	var hosts []string
+	for _, entry := range entries {
+		if len(entry.IPs) > 0 {
+			hosts = append(hosts, entry.Subdomain)
+		}
+	}
+
+	client := probe.NewLivenessClient(probe.DefaultLivenessTimeout, networkTimeout, userAgent)
+	if injectConsentCookies {
+		client = probe.WithConsentCookies(client)
+	}
+	responsive := probe.Responsive(ctx, client, hosts)
+	for i := range entries {
+		if _, ok := responsive[entries[i].Subdomain]; ok {
+			entries[i].Status = subfinder.StatusLive
+		} else {
+			entries[i].Status = subfinder.StatusIdle
+		}
+	}
+	logger.Info("Probed subdomains over HTTP", "probed", len(hosts), "live", len(responsive))
+}
+
+// tagTLSVersions records which TLS versions port 443 of each resolved entry supports
+func tagTLSVersions(ctx context.Context, entries []subfinder.SubdomainEntry, networkTimeout time.Duration, logger *slog.Logger) {
+	var hosts []string
+	for _, entry := range entries {
+		if len(entry.IPs) > 0 {
+			hosts = append(hosts, entry.Subdomain)
+		}
+	}
+
+	supported := probe.SupportedTLSVersions(ctx, hosts, networkTimeout)
+	for i := range entries {
+		entries[i].TLSVersions = supported[entries[i].Subdomain]
+	}
+	logger.Info("Probed subdomains for TLS versions", "probed", len(hosts), "tlsEnabled", len(supported))
+}
+
+// enrichShodan looks up every unique resolved address in Shodan and attaches the
+// records found to the entries that resolve to them
+func enrichShodan(ctx context.Context, entries []subfinder.SubdomainEntry, apiKey, userAgent string, networkTimeout time.Duration, logger *slog.Logger) {
+	seen := make(map[string]struct{})
+	var ips []string
+	for _, entry := range entries {
+		for _, addr := range entry.IPs {
+			if _, ok := seen[addr.IP]; !ok {
+				seen[addr.IP] = struct{}{}
+				ips = append(ips, addr.IP)
+			}
+		}
+	}
+
+	client := shodan.NewClient(apiKey, userAgent, networkTimeout, logger)
+	hosts := client.Hosts(ctx, ips)
+	for i := range entries {
+		for _, addr := range entries[i].IPs {
+			if host, ok := hosts[addr.IP]; ok {
+				entries[i].Shodan = append(entries[i].Shodan, *host)
+			}
+		}
+	}
+	logger.Info("Enriched resolved addresses with Shodan", "queried", len(ips), "found", len(hosts))
+}
+
+// enrichVirusTotal looks up the listed subdomains in VirusTotal and attaches the
+// reports found to their entries. The free API's rate limit, shared by every call
+// using the key, makes this slow, so lookups stop once budget has passed.
+func enrichVirusTotal(ctx context.Context, entries []subfinder.SubdomainEntry, listed []string, apiKey, userAgent string, networkTimeout, budget time.Duration, logger *slog.Logger) {
+	var domains []string
+	for _, entry := range entries {
+		if slices.Contains(listed, entry.Subdomain) {
+			domains = append(domains, entry.Subdomain)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	client := virustotal.NewClient(apiKey, userAgent, networkTimeout, logger)
+	reports := client.Domains(ctx, domains)
+	for i := range entries {
+		entries[i].VirusTotal = reports[entries[i].Subdomain]
+	}
+	logger.Info("Enriched subdomains with VirusTotal", "listed", len(domains), "found", len(reports))
+}
+
+// tagAssetTypes classifies each resolved entry from its name, CNAME target,
+// certificate names and addresses. probeTLS makes no HTTP request, so no
+// Server header is available.
+func tagAssetTypes(ctx context.Context, entries []subfinder.SubdomainEntry, logger *slog.Logger) {
+	var hosts []string
+	for _, entry := range entries {
+		if len(entry.IPs) > 0 {
+			hosts = append(hosts, entry.Subdomain)
+		}
+	}
+	cnames := subfinder.ResolveCNAMEs(ctx, hosts, logger)
+
+	for i := range entries {
+		if len(entries[i].IPs) == 0 {
+			continue
+		}
+		var resolved []string
+		if target, ok := cnames[entries[i].Subdomain]; ok {
+			resolved = append(resolved, target)
+		}
+		if cert := entries[i].Cert; cert != nil {
+			resolved = append(resolved, cert.CommonName)
+			resolved = append(resolved, cert.SubjectAlternativeNames...)
+		}
+		for _, ip := range entries[i].IPs {
+			resolved = append(resolved, ip.IP)
+		}
+		entries[i].AssetType = classify.ClassifyAssetType(entries[i].Subdomain, resolved, "")
+	}
+}
+
+// deadlineBuffer is kept free before the request deadline for building and
+// writing the response once the enumeration stops
+const deadlineBuffer = 5 * time.Second
+
+// fitTimeoutToDeadline returns the context to enumerate with, which ends
+// deadlineBuffer before ctx does, so the enumeration finishes cleanly and leaves
+// time to build the result instead of failing with a context error. When that is
+// sooner than config.Timeout, the timeout is shortened to match and a warning for
+// the caller is returned.
+func fitTimeoutToDeadline(ctx context.Context, config *subfinder.SubfinderConfig, logger *slog.Logger) (context.Context, context.CancelFunc, string) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		enumCtx, cancel := context.WithCancel(ctx)
+		return enumCtx, cancel, ""
+	}
+
+	// An enumeration always gets at least a second, even past the buffer
+	now := time.Now()
+	stop := deadline.Add(-deadlineBuffer)
+	if stop.Before(now.Add(time.Second)) {
+		stop = now.Add(time.Second)
+	}
+	enumCtx, cancel := context.WithDeadline(ctx, stop)
+
+	if remaining := stop.Sub(now); remaining < time.Duration(config.Timeout)*time.Second {
+		requested := config.Timeout
+		config.Timeout = max(int(remaining.Seconds()), 1)
+		logger.Debug("Reducing enumeration timeout to fit the request deadline",
+			"requestedTimeout", requested,
+			"effectiveTimeout", config.Timeout)
+		return enumCtx, cancel, fmt.Sprintf("Effective timeout reduced to %ds (requested %ds) because the server deadline for this request expires sooner", config.Timeout, requested)
+	}
+	return enumCtx, cancel, ""
+}
+
+// enumerateDomain runs the enumerations of enumerateSubdomains; tests replace it
+var enumerateDomain = plugin.Enumerate
+
+// handleEnumerateSubdomains runs the enumerateSubdomains tool
+func handleEnumerateSubdomains(ctx context.Context, req *Request, params ToolCallParams, providerConfigPath string, logger *slog.Logger) Response {
+	// Extract and validate required domain parameter
+	domain, ok := requiredStringArgument(params.Arguments, "domain", logger)
+	if !ok {
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+	if err := validateDomainArgument(domain); err != nil {
+		logger.Warn("Invalid domain parameter", "providedDomain", domain, "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewDomainValidationError(domain, err.Error()),
+		}
+	}
+
+	// Keep the results out of shared server logs, before anything about them is
+	// logged; the domain itself is still logged
+	maskResults := false
+	if maskVal, ok := params.Arguments["maskResults"]; ok {
+		if v, ok := maskVal.(bool); ok {
+			maskResults = v
+			if v {
+				logger = logging.NewMaskingLogger(logger, domain)
+				logger.Debug("Masking subdomains in logs")
+			}
+		} else {
+			logger.Warn("Invalid maskResults parameter, using default", "providedMaskResults", maskVal)
+		}
+	}
+
+	// Compile the regex filters first so a bad pattern fails before anything runs
+	filterRegex, regexErr := regexArgument(params.Arguments, "filterByRegex")
+	var excludeRegex *regexp.Regexp
+	if regexErr == nil {
+		excludeRegex, regexErr = regexArgument(params.Arguments, "excludeByRegex")
+	}
+	if regexErr != nil {
+		logger.Warn("Invalid regex parameter", "error", regexErr)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError(regexErr.Error()),
+		}
+	}
+
+	// Long enumerations can run in the background and deliver to a webhook
+	callbackURL, callbackErr := callbackURLArgument(params.Arguments)
+	if callbackErr != nil {
+		// Webhook URLs often embed secrets, so only the reason is logged
+		logger.Warn("Invalid callbackURL parameter", "error", callbackErr)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+	notifyWebhookURL, notifyErr := webhookURLArgument(params.Arguments, "notifyWebhookURL")
+	if notifyErr != nil {
+		logger.Warn("Invalid notifyWebhookURL parameter", "error", notifyErr)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+	// A call is delivered to a callback or polled for, never both
+	deferred := deferredArgument(params.Arguments, logger)
+	if callbackURL != "" && deferred {
+		logger.Warn("callbackURL and deferred given together")
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError("callbackURL and deferred cannot be combined"),
+		}
+	}
+	if callbackURL != "" {
+		return startAsyncEnumeration(ctx, req, params, callbackURL, providerConfigPath, logger)
+	}
+
+	// Or run in the background and be polled with jobs.get
+	if deferred {
+		return startDeferredEnumeration(ctx, req, params, providerConfigPath, logger)
+	}
+
+	config := parseEnumerationConfig(params.Arguments, providerConfigPath, logger)
+	config.ResultWriter = streamWriterFromContext(ctx)
+	// subfinder prints every name it finds to the server's output when verbose
+	if maskResults && config.VerboseMode {
+		logger.Warn("verbose is not allowed with maskResults, ignoring it")
+		config.VerboseMode = false
+		config.SilentMode = true
+	}
+
+	// POST each subdomain to the notification webhook as it is discovered
+	notifyJobID := ""
+	if notifyWebhookURL != "" {
+		var err error
+		if notifyJobID, err = newJobID(); err != nil {
+			logger.Error("Failed to create notification job ID", "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInternal,
+			}
+		}
+		notifier := newSubdomainNotifier(config.ResultWriter, notifyWebhookURL, domain, notifyJobID, logger)
+		defer notifier.Close()
+		config.ResultWriter = notifier
+	}
+	enumCtx, cancelEnumeration, timeoutWarning := fitTimeoutToDeadline(ctx, &config, logger)
+	defer cancelEnumeration()
+
+	// Extract domainAlias if provided
+	domainAlias := ""
+	if aliasVal, ok := params.Arguments["domainAlias"]; ok {
+		if v, ok := aliasVal.(string); ok {
+			domainAlias = strings.TrimSpace(v)
+			logger.Debug("Using domain alias", "domainAlias", domainAlias)
+		} else {
+			logger.Warn("Invalid domainAlias parameter, ignoring it", "providedDomainAlias", aliasVal)
+		}
+	}
+
+	// Extract minSources if provided
+	minSources := 1
+	if minSourcesVal, ok := params.Arguments["minSources"]; ok {
+		if v, ok := minSourcesVal.(float64); ok && v >= 1 {
+			minSources = int(v)
+			logger.Debug("Using custom minSources", "minSources", minSources)
+		} else {
+			logger.Warn("Invalid minSources parameter, using default", "providedMinSources", minSourcesVal)
+		}
+	}
+
+	// Extract minimumResultThreshold if provided
+	minimumResultThreshold := 0
+	if thresholdVal, ok := params.Arguments["minimumResultThreshold"]; ok {
+		if v, ok := thresholdVal.(float64); ok && v >= 0 && v == float64(int(v)) {
+			minimumResultThreshold = int(v)
+			logger.Debug("Using custom minimumResultThreshold", "minimumResultThreshold", minimumResultThreshold)
+		} else {
+			logger.Warn("Invalid minimumResultThreshold parameter, using default", "providedMinimumResultThreshold", thresholdVal)
+		}
+	}
+
+	// Extract retainRawOutput if provided
+	retainRawOutput := false
+	if retainVal, ok := params.Arguments["retainRawOutput"]; ok {
+		if v, ok := retainVal.(bool); ok {
+			retainRawOutput = v
+			logger.Debug("Using custom retainRawOutput setting", "retainRawOutput", retainRawOutput)
+		} else {
+			logger.Warn("Invalid retainRawOutput parameter, using default", "providedRetainRawOutput", retainVal)
+		}
+	}
+
+	// Extract minTrustScore if provided
+	minTrustScore := 0.0
+	if minTrustScoreVal, ok := params.Arguments["minTrustScore"]; ok {
+		if v, ok := minTrustScoreVal.(float64); ok && v >= 0 && v <= 1 {
+			minTrustScore = v
+			logger.Debug("Using custom minTrustScore", "minTrustScore", minTrustScore)
+		} else {
+			logger.Warn("Invalid minTrustScore parameter, using default", "providedMinTrustScore", minTrustScoreVal)
+		}
+	}
+
+	// Extract limitToTLD if provided
+	limitToTLD := ""
+	if limitToTLDVal, ok := params.Arguments["limitToTLD"]; ok {
+		if v, ok := limitToTLDVal.(string); ok {
+			limitToTLD = v
+			logger.Debug("Using custom limitToTLD", "limitToTLD", limitToTLD)
+		} else {
+			logger.Warn("Invalid limitToTLD parameter, using default", "providedLimitToTLD", limitToTLDVal)
+		}
+	}
+
+	// Extract limitToRegisteredDomain if provided
+	limitToRegisteredDomain := false
+	if limitVal, ok := params.Arguments["limitToRegisteredDomain"]; ok {
+		if v, ok := limitVal.(bool); ok {
+			limitToRegisteredDomain = v
+			logger.Debug("Using custom limitToRegisteredDomain setting", "limitToRegisteredDomain", limitToRegisteredDomain)
+		} else {
+			logger.Warn("Invalid limitToRegisteredDomain parameter, using default", "providedLimitToRegisteredDomain", limitVal)
+		}
+	}
+
+	// Extract substring filters if provided
+	subdomainPrefix := stringArrayArgument(params.Arguments, "subdomainPrefix", logger)
+	subdomainSuffix := stringArrayArgument(params.Arguments, "subdomainSuffix", logger)
+	subdomainContains := stringArrayArgument(params.Arguments, "subdomainContains", logger)
+
+	// Extract first label length limits if provided
+	lengthLimits := [2]int{}
+	for i, name := range []string{"minSubdomainLength", "maxSubdomainLength"} {
+		if lengthVal, ok := params.Arguments[name]; ok {
+			if v, ok := lengthVal.(float64); ok && v >= 1 {
+				lengthLimits[i] = int(v)
+				logger.Debug("Using custom subdomain length limit", name, lengthLimits[i])
+			} else {
+				logger.Warn("Invalid subdomain length parameter, ignoring it", "parameter", name, "providedLength", lengthVal)
+			}
+		}
+	}
+	minSubdomainLength, maxSubdomainLength := lengthLimits[0], lengthLimits[1]
+
+	// Extract recursionExcludePatterns if provided; a malformed pattern would recurse where the caller said not to
+	if excludeVal, ok := params.Arguments["recursionExcludePatterns"]; ok {
+		patterns := stringArrayArgument(params.Arguments, "recursionExcludePatterns", logger)
+		if err := validation.ValidateGlobs(patterns); patterns == nil || err != nil {
+			logger.Warn("Invalid recursionExcludePatterns parameter", "providedRecursionExcludePatterns", excludeVal, "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+		config.RecursionExcludePatterns = patterns
+	}
+
+	// Extract customDNSSeed if provided
+	config.SeedSubdomains = stringArrayArgument(params.Arguments, "customDNSSeed", logger)
+
+	// Extract baselineBase64 if provided; a malformed baseline would make the diff meaningless
+	var baseline []string
+	hasBaseline := false
+	if baselineVal, ok := params.Arguments["baselineBase64"]; ok {
+		encoded, isString := baselineVal.(string)
+		if !isString {
+			logger.Warn("Invalid baselineBase64 parameter", "providedBaselineBase64", baselineVal)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+		parsed, err := parseBaseline(encoded)
+		if err != nil {
+			logger.Warn("Invalid baselineBase64 parameter", "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+		baseline = parsed
+		hasBaseline = true
+		logger.Debug("Using baseline", "baselineSubdomains", len(baseline))
+	}
+
+	// Extract includeProviderStatus if provided
+	includeProviderStatus := false
+	if includeVal, ok := params.Arguments["includeProviderStatus"]; ok {
+		if v, ok := includeVal.(bool); ok {
+			includeProviderStatus = v
+			logger.Debug("Using custom includeProviderStatus setting", "includeProviderStatus", includeProviderStatus)
+		} else {
+			logger.Warn("Invalid includeProviderStatus parameter, using default", "providedIncludeProviderStatus", includeVal)
+		}
+	}
+
+	// Extract sensitivePatternAlert if provided
+	sensitivePatternAlert := false
+	if alertVal, ok := params.Arguments["sensitivePatternAlert"]; ok {
+		if v, ok := alertVal.(bool); ok {
+			sensitivePatternAlert = v
+			logger.Debug("Using custom sensitivePatternAlert setting", "sensitivePatternAlert", sensitivePatternAlert)
+		} else {
+			logger.Warn("Invalid sensitivePatternAlert parameter, using default", "providedSensitivePatternAlert", alertVal)
+		}
+	}
+
+	// Extract resolveIPs if provided
+	resolveIPs := false
+	if resolveIPsVal, ok := params.Arguments["resolveIPs"]; ok {
+		if v, ok := resolveIPsVal.(bool); ok {
+			resolveIPs = v
+			logger.Debug("Using custom resolveIPs setting", "resolveIPs", resolveIPs)
+		} else {
+			logger.Warn("Invalid resolveIPs parameter, using default", "providedResolveIPs", resolveIPsVal)
+		}
+	}
+
+	// Extract IP range exclusions if provided
+	var ipFilter subfinder.IPFilter
+	for name, target := range map[string]*bool{
+		"excludePrivateIPs": &ipFilter.ExcludePrivate,
+		"excludeLoopback":   &ipFilter.ExcludeLoopback,
+		"excludeMulticast":  &ipFilter.ExcludeMulticast,
+	} {
+		if val, ok := params.Arguments[name]; ok {
+			if v, ok := val.(bool); ok {
+				*target = v
+				logger.Debug("Using custom IP exclusion", "parameter", name, "value", v)
+			} else {
+				logger.Warn("Invalid IP exclusion parameter, using default", "parameter", name, "providedValue", val)
+			}
+		}
+	}
+
+	// Resolving results would query the target's DNS, which CT-only runs must avoid
+	if resolveIPs && config.CertTransparencyOnly {
+		logger.Warn("resolveIPs is not allowed with certTransparencyOnly, ignoring it")
+		resolveIPs = false
+	}
+
+	// Extract the cloud provider filters; without resolved addresses they would drop everything
+	var cloudProviderFilters [2][]string
+	for i, name := range []string{"limitByCloudProvider", "excludeCloudProvider"} {
+		if _, ok := params.Arguments[name]; !ok {
+			continue
+		}
+		for _, provider := range stringArrayArgument(params.Arguments, name, logger) {
+			provider = strings.ToLower(strings.TrimSpace(provider))
+			// A misspelled provider would silently drop or keep everything
+			if !slices.Contains(cloudProviders, provider) {
+				logger.Warn("Unknown cloud provider in filter", "parameter", name, "provider", provider)
+				return Response{
+					JSONRPC: "2.0",
+					ID:      req.ID,
+					Error:   NewInvalidParamsError(fmt.Sprintf("%s: unknown cloud provider %q, expected one of %s", name, provider, strings.Join(cloudProviders, ", "))),
+				}
+			}
+			cloudProviderFilters[i] = append(cloudProviderFilters[i], provider)
+		}
+		if !resolveIPs {
+			logger.Warn("Cloud provider filter used without resolveIPs", "parameter", name)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   NewInvalidParamsError(name + " requires resolveIPs: true"),
+			}
+		}
+	}
+	limitByCloudProvider, excludeCloudProvider := cloudProviderFilters[0], cloudProviderFilters[1]
+
+	// IP exclusions only make sense on resolved results
+	if ipFilter.Enabled() && !resolveIPs {
+		logger.Warn("IP exclusion parameters require resolveIPs, ignoring them")
+		ipFilter = subfinder.IPFilter{}
+	}
+
+	// Extract probeTLS if provided
+	probeTLS := false
+	if probeTLSVal, ok := params.Arguments["probeTLS"]; ok {
+		if v, ok := probeTLSVal.(bool); ok {
+			probeTLS = v
+			logger.Debug("Using custom probeTLS setting", "probeTLS", probeTLS)
+		} else {
+			logger.Warn("Invalid probeTLS parameter, using default", "providedProbeTLS", probeTLSVal)
+		}
+	}
+
+	// Only subdomains that resolve can be connected to
+	if probeTLS && !resolveIPs {
+		logger.Warn("probeTLS requires resolveIPs, ignoring it")
+		probeTLS = false
+	}
+
+	// Extract probeTLSVersion if provided
+	probeTLSVersion := false
+	if probeVersionVal, ok := params.Arguments["probeTLSVersion"]; ok {
+		if v, ok := probeVersionVal.(bool); ok {
+			probeTLSVersion = v
+			logger.Debug("Using custom probeTLSVersion setting", "probeTLSVersion", probeTLSVersion)
+		} else {
+			logger.Warn("Invalid probeTLSVersion parameter, using default", "providedProbeTLSVersion", probeVersionVal)
+		}
+	}
+	if probeTLSVersion && !resolveIPs {
+		logger.Warn("probeTLSVersion requires resolveIPs, ignoring it")
+		probeTLSVersion = false
+	}
+
+	// Extract the TLS version filters; an unknown version or one without probing would drop everything
+	var tlsVersionFilters [2][]string
+	for i, name := range []string{"onlyTLSVersions", "excludeTLSVersions"} {
+		if _, ok := params.Arguments[name]; !ok {
+			continue
+		}
+		for _, version := range stringArrayArgument(params.Arguments, name, logger) {
+			version = strings.TrimSpace(version)
+			if !slices.Contains(probe.TLSVersions, version) {
+				logger.Warn("Invalid TLS version filter", "parameter", name, "providedVersion", version)
+				return Response{
+					JSONRPC: "2.0",
+					ID:      req.ID,
+					Error:   NewInvalidParamsError(fmt.Sprintf("%s accepts only %s", name, strings.Join(probe.TLSVersions, ", "))),
+				}
+			}
+			tlsVersionFilters[i] = append(tlsVersionFilters[i], version)
+		}
+		if !probeTLSVersion {
+			logger.Warn("TLS version filter used without probeTLSVersion", "parameter", name)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   NewInvalidParamsError(name + " requires probeTLSVersion: true and resolveIPs: true"),
+			}
+		}
+	}
+	onlyTLSVersions, excludeTLSVersions := tlsVersionFilters[0], tlsVersionFilters[1]
+
+	// Extract trustAnchorsBase64 if provided
+	var trustAnchors *x509.CertPool
+	if trustAnchorsVal, ok := params.Arguments["trustAnchorsBase64"]; ok {
+		encoded, _ := trustAnchorsVal.(string)
+		bundle, err := base64.StdEncoding.DecodeString(encoded)
+		switch {
+		case !currentSettings().AllowCustomTrustAnchors:
+			logger.Warn("trustAnchorsBase64 requires the server to allow custom trust anchors, ignoring it")
+		case !probeTLS:
+			logger.Warn("trustAnchorsBase64 requires probeTLS, ignoring it")
+		case encoded == "" || err != nil:
+			logger.Warn("Invalid trustAnchorsBase64 parameter, ignoring it", "error", err)
+		default:
+			if pool, err := subfinder.TrustAnchorPool(bundle); err != nil {
+				logger.Warn("Invalid trustAnchorsBase64 parameter, ignoring it", "error", err)
+			} else {
+				trustAnchors = pool
+				logger.Warn("Using custom trust anchors for TLS probing", "domain", domain)
+			}
+		}
+	}
+
+	// Extract scopePatterns if provided; a malformed scope would tag results wrongly
+	var scopePatterns []string
+	if scopeVal, ok := params.Arguments["scopePatterns"]; ok {
+		values, isArray := scopeVal.([]interface{})
+		for _, value := range values {
+			if pattern, ok := value.(string); ok && pattern != "" && pattern != "!" {
+				scopePatterns = append(scopePatterns, pattern)
+			} else {
+				isArray = false
+			}
+		}
+		err := subfinder.ValidateScopePatterns(scopePatterns)
+		if !isArray || err != nil {
+			logger.Warn("Invalid scopePatterns parameter", "providedScopePatterns", scopeVal, "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+		logger.Debug("Using scope patterns", "scopePatterns", scopePatterns)
+	}
+
+	// Extract outputFields if provided; an unknown field is more likely a typo than a request for nothing
+	var outputFields []string
+	if fieldsVal, ok := params.Arguments["outputFields"]; ok {
+		outputFields = stringArrayArgument(params.Arguments, "outputFields", logger)
+		valid := len(outputFields) > 0
+		for _, field := range outputFields {
+			valid = valid && format.IsField(field)
+		}
+		if !valid {
+			logger.Warn("Invalid outputFields parameter", "providedOutputFields", fieldsVal, "supportedFields", format.Fields)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+	}
+
+	// Extract outputLineDelimiter if provided
+	lineDelimiter := defaultLineDelimiter
+	if delimiterVal, ok := params.Arguments["outputLineDelimiter"]; ok {
+		if v, ok := delimiterVal.(string); ok && validLineDelimiter(v) {
+			lineDelimiter = v
+			logger.Debug("Using custom outputLineDelimiter", "outputLineDelimiter", lineDelimiter)
+		} else {
+			logger.Warn("Invalid outputLineDelimiter parameter, using default", "providedOutputLineDelimiter", delimiterVal)
+		}
+	}
+
+	// Extract networkTimeout if provided
+	networkTimeout := probe.DefaultNetworkTimeout
+	if networkTimeoutVal, ok := params.Arguments["networkTimeout"]; ok {
+		if v, ok := networkTimeoutVal.(float64); ok && v > 0 {
+			networkTimeout = time.Duration(v * float64(time.Second))
+			logger.Debug("Using custom networkTimeout", "networkTimeout", networkTimeout)
+		} else {
+			logger.Warn("Invalid networkTimeout parameter, using default", "providedNetworkTimeout", networkTimeoutVal)
+		}
+	}
+
+	// Extract excludeParked if provided
+	excludeParked := false
+	if excludeParkedVal, ok := params.Arguments["excludeParked"]; ok {
+		if v, ok := excludeParkedVal.(bool); ok {
+			excludeParked = v
+			logger.Debug("Using custom excludeParked setting", "excludeParked", excludeParked)
+		} else {
+			logger.Warn("Invalid excludeParked parameter, using default", "providedExcludeParked", excludeParkedVal)
+		}
+	}
+
+	// Only subdomains that resolve can be probed for parking pages
+	if excludeParked && !resolveIPs {
+		logger.Warn("excludeParked requires resolveIPs, ignoring it")
+		excludeParked = false
+	}
+
+	// Extract httpProbe and excludeIdle if provided
+	httpProbe := false
+	if httpProbeVal, ok := params.Arguments["httpProbe"]; ok {
+		if v, ok := httpProbeVal.(bool); ok {
+			httpProbe = v
+			logger.Debug("Using custom httpProbe setting", "httpProbe", httpProbe)
+		} else {
+			logger.Warn("Invalid httpProbe parameter, using default", "providedHTTPProbe", httpProbeVal)
+		}
+	}
+	excludeIdle := false
+	if excludeIdleVal, ok := params.Arguments["excludeIdle"]; ok {
+		if v, ok := excludeIdleVal.(bool); ok {
+			excludeIdle = v
+			logger.Debug("Using custom excludeIdle setting", "excludeIdle", excludeIdle)
+		} else {
+			logger.Warn("Invalid excludeIdle parameter, using default", "providedExcludeIdle", excludeIdleVal)
+		}
+	}
+
+	// Only resolved subdomains are probed, and only probed ones can be idle
+	if httpProbe && !resolveIPs {
+		logger.Warn("httpProbe requires resolveIPs, ignoring it")
+		httpProbe = false
+	}
+	if excludeIdle && !httpProbe {
+		logger.Warn("excludeIdle requires httpProbe, ignoring it")
+		excludeIdle = false
+	}
+
+	// Extract injectConsentCookies if provided
+	injectConsentCookies := false
+	if injectVal, ok := params.Arguments["injectConsentCookies"]; ok {
+		if v, ok := injectVal.(bool); ok {
+			injectConsentCookies = v
+			logger.Debug("Using custom injectConsentCookies setting", "injectConsentCookies", injectConsentCookies)
+		} else {
+			logger.Warn("Invalid injectConsentCookies parameter, using default", "providedInjectConsentCookies", injectVal)
+		}
+	}
+	if injectConsentCookies && !httpProbe {
+		logger.Warn("injectConsentCookies requires httpProbe, ignoring it")
+		injectConsentCookies = false
+	}
+
+	// Extract enrichWithShodan and shodanApiKey if provided
+	enrichWithShodan := false
+	if enrichVal, ok := params.Arguments["enrichWithShodan"]; ok {
+		if v, ok := enrichVal.(bool); ok {
+			enrichWithShodan = v
+			logger.Debug("Using custom enrichWithShodan setting", "enrichWithShodan", enrichWithShodan)
+		} else {
+			logger.Warn("Invalid enrichWithShodan parameter, using default", "providedEnrichWithShodan", enrichVal)
+		}
+	}
+	shodanAPIKey := os.Getenv(shodan.APIKeyEnv)
+	if keyVal, ok := params.Arguments["shodanApiKey"]; ok {
+		if v, ok := keyVal.(string); ok && v != "" {
+			shodanAPIKey = v
+			logger.Debug("Using custom shodanApiKey")
+		} else {
+			// Never log the provided value, it may be a real key
+			logger.Warn("Invalid shodanApiKey parameter, using default")
+		}
+	}
+
+	// Only resolved subdomains have addresses to look up
+	if enrichWithShodan && !resolveIPs {
+		logger.Warn("enrichWithShodan requires resolveIPs, ignoring it")
+		enrichWithShodan = false
+	}
+	if enrichWithShodan && shodanAPIKey == "" {
+		logger.Warn("enrichWithShodan requires shodanApiKey or " + shodan.APIKeyEnv + ", ignoring it")
+		enrichWithShodan = false
+	}
+
+	// Extract enrichWithVirusTotal and virusTotalApiKey if provided
+	enrichWithVirusTotal := false
+	if enrichVal, ok := params.Arguments["enrichWithVirusTotal"]; ok {
+		if v, ok := enrichVal.(bool); ok {
+			enrichWithVirusTotal = v
+			logger.Debug("Using custom enrichWithVirusTotal setting", "enrichWithVirusTotal", enrichWithVirusTotal)
+		} else {
+			logger.Warn("Invalid enrichWithVirusTotal parameter, using default", "providedEnrichWithVirusTotal", enrichVal)
+		}
+	}
+	virusTotalAPIKey := os.Getenv(virustotal.APIKeyEnv)
+	if keyVal, ok := params.Arguments["virusTotalApiKey"]; ok {
+		if v, ok := keyVal.(string); ok && v != "" {
+			virusTotalAPIKey = v
+			logger.Debug("Using custom virusTotalApiKey")
+		} else {
+			// Never log the provided value, it may be a real key
+			logger.Warn("Invalid virusTotalApiKey parameter, using default")
+		}
+	}
+	if enrichWithVirusTotal && virusTotalAPIKey == "" {
+		logger.Warn("enrichWithVirusTotal requires virusTotalApiKey or " + virustotal.APIKeyEnv + ", ignoring it")
+		enrichWithVirusTotal = false
+	}
+
+	// Extract uniqueByIP if provided
+	uniqueByIP := false
+	if uniqueByIPVal, ok := params.Arguments["uniqueByIP"]; ok {
+		if v, ok := uniqueByIPVal.(bool); ok {
+			uniqueByIP = v
+			logger.Debug("Using custom uniqueByIP setting", "uniqueByIP", uniqueByIP)
+		} else {
+			logger.Warn("Invalid uniqueByIP parameter, using default", "providedUniqueByIP", uniqueByIPVal)
+		}
+	}
+
+	// Unresolved subdomains have no addresses to group by
+	if uniqueByIP && !resolveIPs {
+		logger.Warn("uniqueByIP requires resolveIPs, ignoring it")
+		uniqueByIP = false
+	}
+
+	// Extract exportFormat if provided
+	exportFormat := format.Plain
+	if exportFormatVal, ok := params.Arguments["exportFormat"]; ok {
+		if v, ok := exportFormatVal.(string); ok && format.IsSupported(v) {
+			exportFormat = v
+			logger.Debug("Using custom exportFormat", "exportFormat", exportFormat)
+		} else {
+			logger.Warn("Invalid exportFormat parameter, using default", "providedExportFormat", exportFormatVal)
+		}
+	}
+
+	// Extract outputGroupBy if provided
+	outputGroupBy := format.GroupNone
+	if groupByVal, ok := params.Arguments["outputGroupBy"]; ok {
+		if v, ok := groupByVal.(string); ok && format.IsGroupKey(v) {
+			outputGroupBy = v
+			logger.Debug("Using custom outputGroupBy", "outputGroupBy", outputGroupBy)
+		} else {
+			logger.Warn("Invalid outputGroupBy parameter, using default", "providedOutputGroupBy", groupByVal)
+		}
+	}
+
+	// ASNs only come from Shodan records; without them every entry would land in unknown
+	if outputGroupBy == format.GroupASN && !enrichWithShodan {
+		logger.Warn("outputGroupBy asn requires enrichWithShodan")
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError("outputGroupBy asn requires enrichWithShodan with resolveIPs and a Shodan API key"),
+		}
+	}
+
+	// Cloud providers come from resolved addresses
+	if outputGroupBy == format.GroupCloudProvider && !resolveIPs {
+		logger.Warn("outputGroupBy requires resolveIPs for this key, ignoring it", "outputGroupBy", outputGroupBy)
+		outputGroupBy = format.GroupNone
+	}
+
+	// Extract generateReport if provided
+	generateReport := report.None
+	if generateReportVal, ok := params.Arguments["generateReport"]; ok {
+		if v, ok := generateReportVal.(string); ok && report.IsSupported(v) {
+			generateReport = v
+			logger.Debug("Using custom generateReport", "generateReport", generateReport)
+		} else {
+			logger.Warn("Invalid generateReport parameter, using default", "providedGenerateReport", generateReportVal)
+		}
+	}
+
+	// Extract outputTemplate if provided; a template that does not parse is a mistake worth reporting
+	var outputTemplate *template.Template
+	if templateVal, ok := params.Arguments["outputTemplate"]; ok {
+		text, isString := templateVal.(string)
+		tmpl, err := report.ParseCustom(text)
+		if !isString || err != nil {
+			detail := "outputTemplate must be a string"
+			if isString {
+				detail = err.Error()
+			}
+			logger.Warn("Invalid outputTemplate parameter", "error", detail)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   NewInvalidParamsError(detail),
+			}
+		}
+		outputTemplate = tmpl
+		logger.Debug("Using custom outputTemplate")
+	}
+
+	// Extract followCNAME if provided
+	followCNAME := false
+	if followCNAMEVal, ok := params.Arguments["followCNAME"]; ok {
+		if v, ok := followCNAMEVal.(bool); ok {
+			followCNAME = v
+			logger.Debug("Using custom followCNAME setting", "followCNAME", followCNAME)
+		} else {
+			logger.Warn("Invalid followCNAME parameter, using default", "providedFollowCNAME", followCNAMEVal)
+		}
+	}
+
+	// CNAME lookups query the target's DNS, which CT-only runs must avoid
+	if followCNAME && config.CertTransparencyOnly {
+		logger.Warn("followCNAME is not allowed with certTransparencyOnly, ignoring it")
+		followCNAME = false
+	}
+
+	// Extract autoExpandWildcard if provided
+	autoExpandWildcard := false
+	if autoExpandWildcardVal, ok := params.Arguments["autoExpandWildcard"]; ok {
+		if v, ok := autoExpandWildcardVal.(bool); ok {
+			autoExpandWildcard = v
+			logger.Debug("Using custom autoExpandWildcard setting", "autoExpandWildcard", autoExpandWildcard)
+		} else {
+			logger.Warn("Invalid autoExpandWildcard parameter, using default", "providedAutoExpandWildcard", autoExpandWildcardVal)
+		}
+	}
+
+	// Wildcard detection queries the target's DNS, which CT-only runs must avoid
+	if autoExpandWildcard && config.CertTransparencyOnly {
+		logger.Warn("autoExpandWildcard is not allowed with certTransparencyOnly, ignoring it")
+		autoExpandWildcard = false
+	}
+
+	// Extract autoDetectWildcardSources if provided
+	autoDetectWildcardSources := false
+	if detectVal, ok := params.Arguments["autoDetectWildcardSources"]; ok {
+		if v, ok := detectVal.(bool); ok {
+			autoDetectWildcardSources = v
+			logger.Debug("Using custom autoDetectWildcardSources setting", "autoDetectWildcardSources", autoDetectWildcardSources)
+		} else {
+			logger.Warn("Invalid autoDetectWildcardSources parameter, using default", "providedAutoDetectWildcardSources", detectVal)
+		}
+	}
+
+	// Extract permutations if provided
+	permutations := false
+	if permutationsVal, ok := params.Arguments["permutations"]; ok {
+		if v, ok := permutationsVal.(bool); ok {
+			permutations = v
+			logger.Debug("Using custom permutations setting", "permutations", permutations)
+		} else {
+			logger.Warn("Invalid permutations parameter, using default", "providedPermutations", permutationsVal)
+		}
+	}
+
+	// Resolving permutations is active DNS enumeration
+	if permutations && config.CertTransparencyOnly {
+		logger.Warn("permutations is not allowed with certTransparencyOnly, ignoring it")
+		permutations = false
+	}
+
+	// Wait for any running enumeration of the same domain instead of duplicating it
+	release, retryAfter, locked := enumerationLocks.acquire(ctx, domain, lockWaitTimeout(), time.Duration(config.Timeout)*time.Second)
+	if !locked {
+		logger.Warn("Domain enumeration already in progress", "domain", domain, "retryAfter", retryAfter)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   enumerationInProgressError(retryAfter),
+		}
+	}
+	defer release()
+
+	// subfinder's passive sources do no wildcard filtering, so with a wildcard
+	// record the matches are dropped after enumeration
+	var wildcardIPs []string
+	if autoExpandWildcard {
+		wildcardIPs = subfinder.DetectWildcard(ctx, domain)
+		if len(wildcardIPs) > 0 {
+			logger.Info("Wildcard DNS detected, filtering results after enumeration",
+				"domain", domain,
+				"wildcardIPs", wildcardIPs)
+		}
+	}
+
+	// Leave out the sources that claim to find a subdomain that cannot exist
+	if autoDetectWildcardSources {
+		calibrationStart := time.Now()
+		wildcardSources, err := subfinder.DetectWildcardSources(ctx, domain, config, logger)
+		// The calibration came out of the same budget, so the enumeration gets what is left
+		calibrationTook := int(math.Ceil(time.Since(calibrationStart).Seconds()))
+		requested := config.Timeout
+		config.Timeout = max(config.Timeout-calibrationTook, 1)
+		logger.Debug("Reducing enumeration timeout by the calibration time",
+			"calibrationSeconds", calibrationTook,
+			"requestedTimeout", requested,
+			"effectiveTimeout", config.Timeout)
+		switch {
+		case err != nil:
+			logger.Warn("Wildcard source calibration failed, using every source", "domain", domain, "error", err)
+		case len(wildcardSources) > 0:
+			logger.Info("Excluding sources that reported a nonexistent subdomain",
+				"domain", domain,
+				"excludedSources", wildcardSources)
+			if config.ExcludeSourcesFilter != "" {
+				wildcardSources = append([]string{config.ExcludeSourcesFilter}, wildcardSources...)
+			}
+			config.ExcludeSourcesFilter = strings.Join(wildcardSources, ",")
+		default:
+			logger.Info("Wildcard source calibration excluded no sources", "domain", domain)
+		}
+	}
+
 	// Execute the subdomain enumeration
-	logger.Info("Running subdomain enumeration", "domain", domain, "config", config)
-	subdomains, err := subfinder.RunEnumeration(ctx, domain, config, logger)
+	clientInfo := clientInfoFromContext(ctx)
+	logger.Info("Running subdomain enumeration",
+		"domain", domain,
+		"config", config,
+		"clientName", clientInfo.Name,
+		"clientVersion", clientInfo.Version)
+	// Streamed names go through the same per-name filters as the results; filters
+	// that need the whole enumeration, such as minSources, only apply to the result
+	if config.ResultWriter != nil {
+		config.StreamFilter = nameFilters{
+			domain:                  domain,
+			limitToTLD:              limitToTLD,
+			limitToRegisteredDomain: limitToRegisteredDomain,
+			prefixes:                subdomainPrefix,
+			suffixes:                subdomainSuffix,
+			contains:                subdomainContains,
+			minLength:               minSubdomainLength,
+			maxLength:               maxSubdomainLength,
+			match:                   filterRegex,
+			exclude:                 excludeRegex,
+		}.apply
+	}
+	var rawOutput *rawOutputBuffer
+	if retainRawOutput {
+		rawOutput = &rawOutputBuffer{}
+		config.RawOutputWriter = rawOutput
+	}
+	// Plugins see every name, streamed or returned, before any filtering
+	enumeration, err := enumerateDomain(enumCtx, domain, config, logger)
+
+	// Say when only the priority sources made it into the results
+	if err == nil && enumeration.Partial {
+		partialWarning := "Only results from prioritizeSources are included because the remaining sources failed or ran out of time"
+		if timeoutWarning != "" {
+			partialWarning = timeoutWarning + "; " + partialWarning
+		}
+		timeoutWarning = partialWarning
+	}
+
+	// Drop the names that only resolve to the detected wildcard addresses
+	wildcardFiltered := 0
+	if err == nil && len(wildcardIPs) > 0 {
+		enumeration, wildcardFiltered = subfinder.FilterWildcardMatches(ctx, enumeration, wildcardIPs, logger)
+		logger.Info("Filtered wildcard DNS matches",
+			"wildcardIPs", wildcardIPs,
+			"filtered", wildcardFiltered)
+	}
+
+	// Add the permutations of the discovered names that resolve
+	if err == nil && permutations {
+		// Generated names are lowercase, so the labels are cut from a lowercase domain
+		base := strings.ToLower(domain)
+		generated := permutation.Generate(enumeration.Subdomains, base)
+		labels := make([]string, len(generated))
+		for i, name := range generated {
+			labels[i] = strings.TrimSuffix(name, "."+base)
+		}
+		// Brute forcing the relative labels also drops wildcard matches
+		resolved := subfinder.BruteForceSubdomains(ctx, base, labels, subfinder.DefaultBruteConcurrency, logger)
+		// The permutations are new names, so plugins see them too
+		if resolved, err = plugin.RunPostEnumerate(ctx, domain, resolved); err == nil {
+			enumeration = subfinder.AddSubdomains(enumeration, resolved, permutation.Source)
+			logger.Info("Resolved subdomain permutations",
+				"generated", len(generated),
+				"resolved", len(resolved))
+		}
+	}
 
 	// Prepare result
 	var toolCallResult ToolCallResult
+	// reported is what the call returns, the only names raw output may repeat
+	var reported []string
 
 	// Handle execution errors
 	if err != nil {
@@ -220,37 +2102,659 @@ func HandleToolsCall(ctx context.Context, req *Request, providerConfigPath strin
 				},
 			},
 		}
+		if timeoutWarning != "" || domainAlias != "" {
+			toolCallResult.Meta = &ToolCallMeta{Warning: timeoutWarning, Alias: domainAlias}
+		}
 	} else {
-		// Format successful results
-		resultText := fmt.Sprintf("Found %d subdomains for %s:\n\n%s", 
-			len(subdomains), 
-			domain,
-			strings.Join(subdomains, "\n"),
-		)
-
-		// Add simple text content item for CLI interfaces
+		// The threshold checks the sources, so it counts what they found before the
+		// caller's filters narrow it down
+		discovered := len(confirmedSubdomains(enumeration.Subdomains, enumeration.Sources))
+
+		// Drop subdomains without enough corroborating sources
+		filtered := subfinder.FilterByMinSources(enumeration, minSources)
+		if len(filtered.Subdomains) != len(enumeration.Subdomains) {
+			logger.Info("Filtered subdomains by source count",
+				"minSources", minSources,
+				"before", len(enumeration.Subdomains),
+				"after", len(filtered.Subdomains))
+		}
+		if minTrustScore > 0 {
+			before := len(filtered.Subdomains)
+			filtered = subfinder.FilterByTrustScore(filtered, minTrustScore)
+			logger.Info("Filtered subdomains by trust score",
+				"minTrustScore", minTrustScore,
+				"before", before,
+				"after", len(filtered.Subdomains))
+		}
+
+		// Drop results outside the requested TLD or registered domain
+		scoped := subfinder.FilterByTLD(filtered, limitToTLD)
+		if limitToRegisteredDomain {
+			scoped = subfinder.FilterByRegisteredDomain(scoped, domain)
+		}
+		if len(scoped.Subdomains) != len(filtered.Subdomains) {
+			logger.Info("Filtered subdomains by domain scope",
+				"limitToTLD", limitToTLD,
+				"limitToRegisteredDomain", limitToRegisteredDomain,
+				"before", len(filtered.Subdomains),
+				"after", len(scoped.Subdomains))
+		}
+
+		// Keep only the names matching the requested substrings
+		before := len(scoped.Subdomains)
+		scoped = subfinder.FilterBySubstrings(scoped, subdomainPrefix, subdomainSuffix, subdomainContains)
+		if len(scoped.Subdomains) != before {
+			logger.Info("Filtered subdomains by substring",
+				"subdomainPrefix", subdomainPrefix,
+				"subdomainSuffix", subdomainSuffix,
+				"subdomainContains", subdomainContains,
+				"before", before,
+				"after", len(scoped.Subdomains))
+		}
+
+		// Drop names whose first label is implausibly short or long
+		var totalFilteredByLength *int
+		if minSubdomainLength > 0 || maxSubdomainLength > 0 {
+			before := len(scoped.Subdomains)
+			scoped = subfinder.FilterByLabelLength(scoped, minSubdomainLength, maxSubdomainLength)
+			filteredByLength := before - len(scoped.Subdomains)
+			totalFilteredByLength = &filteredByLength
+			logger.Info("Filtered subdomains by label length",
+				"minSubdomainLength", minSubdomainLength,
+				"maxSubdomainLength", maxSubdomainLength,
+				"before", before,
+				"after", len(scoped.Subdomains))
+		}
+
+		// Then apply the regex filters, counting what each one kept and dropped
+		var regexMatchCount, regexExcludeCount *int
+		if filterRegex != nil {
+			scoped = subfinder.FilterByRegex(scoped, filterRegex)
+			matched := len(scoped.Subdomains)
+			regexMatchCount = &matched
+		}
+		if excludeRegex != nil {
+			before := len(scoped.Subdomains)
+			scoped = subfinder.ExcludeByRegex(scoped, excludeRegex)
+			excluded := before - len(scoped.Subdomains)
+			regexExcludeCount = &excluded
+		}
+		if regexMatchCount != nil || regexExcludeCount != nil {
+			logger.Info("Filtered subdomains by regex",
+				"filterByRegex", filterRegex,
+				"excludeByRegex", excludeRegex,
+				"after", len(scoped.Subdomains))
+		}
+		subdomains := scoped.Subdomains
+
+		// Resolve addresses and apply IP range exclusions when requested
+		var entries []subfinder.SubdomainEntry
+		var idleSubdomains []string
+		totalBeforeDedup := 0
+		if resolveIPs {
+			entries = subfinder.ResolveSubdomains(ctx, subdomains, logger)
+			learnSourceWeights(entries, scoped.Sources)
+			entries = subfinder.FilterByIP(entries, ipFilter)
+			if len(entries) != len(subdomains) {
+				logger.Info("Filtered subdomains by resolved IP range",
+					"before", len(subdomains),
+					"after", len(entries))
+			}
+			if len(limitByCloudProvider) > 0 || len(excludeCloudProvider) > 0 {
+				before := len(entries)
+				entries = subfinder.FilterByCloudProvider(entries, limitByCloudProvider, excludeCloudProvider)
+				logger.Info("Filtered subdomains by cloud provider",
+					"limitByCloudProvider", limitByCloudProvider,
+					"excludeCloudProvider", excludeCloudProvider,
+					"before", before,
+					"after", len(entries))
+			}
+
+			// Record certificates and add their names that no source reported
+			if probeTLS {
+				subfinder.ProbeCertificates(ctx, entries, trustAnchors, networkTimeout, logger)
+				if expanded := subfinder.CertificateSubdomains(entries, domain, subdomains); len(expanded) > 0 {
+					logger.Info("Found additional subdomains in TLS certificates", "count", len(expanded))
+					extra := subfinder.FilterByIP(subfinder.ResolveSubdomains(ctx, expanded, logger), ipFilter)
+					extra = subfinder.FilterByCloudProvider(extra, limitByCloudProvider, excludeCloudProvider)
+					entries = append(entries, extra...)
+					sort.Slice(entries, func(i, j int) bool { return entries[i].Subdomain < entries[j].Subdomain })
+				}
+				tagAssetTypes(ctx, entries, logger)
+			}
+
+			if probeTLSVersion {
+				tagTLSVersions(ctx, entries, networkTimeout, logger)
+				if len(onlyTLSVersions) > 0 || len(excludeTLSVersions) > 0 {
+					before := len(entries)
+					entries = subfinder.FilterByTLSVersion(entries, onlyTLSVersions, excludeTLSVersions)
+					logger.Info("Filtered subdomains by TLS version",
+						"onlyTLSVersions", onlyTLSVersions,
+						"excludeTLSVersions", excludeTLSVersions,
+						"before", before,
+						"after", len(entries))
+				}
+			}
+
+			if excludeParked {
+				markParked(ctx, entries, config.UserAgent, networkTimeout, logger)
+			}
+
+			if httpProbe {
+				markIdle(ctx, entries, config.UserAgent, networkTimeout, injectConsentCookies, logger)
+			}
+
+			if enrichWithShodan {
+				enrichShodan(ctx, entries, shodanAPIKey, config.UserAgent, networkTimeout, logger)
+			}
+
+			if uniqueByIP {
+				totalBeforeDedup = len(entries)
+				entries = subfinder.CollapseByIP(entries)
+				logger.Info("Collapsed subdomains by resolved IP",
+					"before", totalBeforeDedup,
+					"after", len(entries))
+			}
+
+			// Parked and excluded idle entries stay in the JSON resource, marked, but leave the list
+			subdomains = make([]string, 0, len(entries))
+			for _, entry := range entries {
+				if entry.Status == subfinder.StatusIdle {
+					idleSubdomains = append(idleSubdomains, entry.Subdomain)
+					if excludeIdle {
+						continue
+					}
+				}
+				if entry.Parked {
+					continue
+				}
+				subdomains = append(subdomains, entry.Subdomain)
+			}
+		}
+
+		// Scope tags and field selection cover every result, resolved or not
+		if !resolveIPs && (len(scopePatterns) > 0 || len(outputFields) > 0 || outputGroupBy != format.GroupNone || outputTemplate != nil || sensitivePatternAlert || enrichWithVirusTotal) {
+			entries = make([]subfinder.SubdomainEntry, len(subdomains))
+			for i, subdomain := range subdomains {
+				entries[i].Subdomain = subdomain
+			}
+		}
+		if len(scopePatterns) > 0 {
+			subfinder.TagScope(entries, scopePatterns)
+		}
+		for i := range entries {
+			entries[i].TrustScore = subfinder.TrustScore(scoped.Sources[entries[i].Subdomain])
+		}
+		var sensitiveCount *int
+		if sensitivePatternAlert {
+			sensitiveCount = tagSensitive(entries, domain, subdomains)
+			logger.Info("Tagged sensitive-looking subdomains", "sensitiveCount", *sensitiveCount)
+		}
+		if enrichWithVirusTotal {
+			// Lookups get what is left of the request, not another full timeout
+			budget := time.Duration(config.Timeout) * time.Second
+			if deadline, ok := ctx.Deadline(); ok {
+				budget = min(budget, time.Until(deadline)-deadlineBuffer)
+			}
+			enrichVirusTotal(ctx, entries, subdomains, virusTotalAPIKey, config.UserAgent, networkTimeout, budget, logger)
+		}
+		averageTrustScore, highConfidenceCount := trustSummary(subdomains, scoped.Sources)
+
+		stats.Default.SubdomainsFound(len(subdomains))
+		reported = subdomains
 		toolCallResult = ToolCallResult{
 			IsError: false,
-			Content: []interface{}{
-				ContentItem{
+			Content: subdomainListContent(domain, subdomains, lineDelimiter),
+			Meta: &ToolCallMeta{
+				TotalSources:          enumeration.TotalSources,
+				TotalErrors:           enumeration.TotalErrors,
+				Warning:               timeoutWarning,
+				Alias:                 domainAlias,
+				WildcardIPs:           wildcardIPs,
+				WildcardFiltered:      wildcardFiltered,
+				TotalBeforeDedup:      totalBeforeDedup,
+				RegexMatchCount:       regexMatchCount,
+				RegexExcludeCount:     regexExcludeCount,
+				TotalFilteredByLength: totalFilteredByLength,
+				SensitiveCount:        sensitiveCount,
+				AverageTrustScore:     averageTrustScore,
+				HighConfidenceCount:   highConfidenceCount,
+				IdleSubdomains:        idleSubdomains,
+				IdleCount:             len(idleSubdomains),
+				NotifyJobID:           notifyJobID,
+				EffectiveMaxDepth:     config.MaxDepth,
+			},
+		}
+		if includeProviderStatus {
+			toolCallResult.Meta.ProviderStatus = providerStatus(enumeration.SourceStats)
+		}
+
+		// Replace the plain list resource with the requested export format
+		if exportFormat != format.Plain {
+			data, mimeType, err := format.Export(exportFormat, exportRecords(domain, subdomains, scoped.Sources, entries))
+			if err != nil {
+				logger.Error("Failed to export subdomains", "exportFormat", exportFormat, "error", err)
+			} else {
+				toolCallResult.Content[1] = ResourceItem{
+					Type:     "resource",
+					MimeType: mimeType,
+					Blob:     base64.StdEncoding.EncodeToString(data),
+				}
+			}
+		}
+
+		// Report what changed since the baseline as JSON text
+		if hasBaseline {
+			// Suggested fallback names were never reported by a source, so they are not changes
+			diff := diffBaseline(confirmedSubdomains(subdomains, scoped.Sources), baseline)
+			logger.Info("Compared results to baseline",
+				"added", len(diff.Added),
+				"removed", len(diff.Removed),
+				"unchanged", len(diff.Unchanged))
+			diffJSON, err := jsoniter.Marshal(diff)
+			if err != nil {
+				logger.Error("Failed to encode baseline diff", "error", err)
+			} else {
+				toolCallResult.Content = append(toolCallResult.Content, ContentItem{
 					Type: "text",
-					Text: fmt.Sprintf("Successfully enumerated %d subdomains for %s", len(subdomains), domain),
-				},
-				ResourceItem{
+					Text: string(diffJSON),
+				})
+			}
+		}
+
+		// Report the hosting infrastructure reached through CNAMEs as JSON text
+		if followCNAME {
+			derived := subfinder.FollowCNAMEs(ctx, domain, subdomains, config, logger)
+			logger.Info("Followed CNAME targets", "derivedDomains", len(derived))
+			derivedJSON, err := jsoniter.Marshal(map[string]interface{}{"derivedDomains": derived})
+			if err != nil {
+				logger.Error("Failed to encode CNAME-derived domains", "error", err)
+			} else {
+				toolCallResult.Content = append(toolCallResult.Content, ContentItem{
+					Type: "text",
+					Text: string(derivedJSON),
+				})
+			}
+		}
+
+		// Attach resolved addresses and scope tags as structured JSON
+		if resolveIPs || len(scopePatterns) > 0 || len(outputFields) > 0 || outputGroupBy != format.GroupNone || sensitivePatternAlert || enrichWithVirusTotal {
+			var entriesJSON []byte
+			var err error
+			if outputGroupBy != format.GroupNone {
+				entriesJSON, err = groupedEntriesJSON(entries, scoped.Sources, outputGroupBy, outputFields)
+			} else if len(outputFields) > 0 {
+				entriesJSON, err = selectedEntriesJSON(entries, scoped.Sources, outputFields)
+			} else {
+				entriesJSON, err = jsoniter.Marshal(entries)
+			}
+			if err != nil {
+				logger.Error("Failed to encode resolved subdomains", "error", err)
+			} else {
+				toolCallResult.Content = append(toolCallResult.Content, ResourceItem{
+					Type:     "resource",
+					MimeType: "application/json",
+					Blob:     base64.StdEncoding.EncodeToString(entriesJSON),
+				})
+			}
+		}
+
+		// Attach a summary for humans in the requested format
+		if generateReport != report.None {
+			data := reportData(domain, subdomains, scoped.Sources, entries, time.Now())
+			if resolveIPs {
+				data.TakeoverRisks = takeoverRisks(ctx, entries, logger)
+			}
+			rendered, mimeType, err := report.Render(generateReport, data)
+			if err != nil {
+				logger.Error("Failed to render report", "generateReport", generateReport, "error", err)
+			} else {
+				toolCallResult.Content = append(toolCallResult.Content, ResourceItem{
 					Type:     "resource",
-					MimeType: "text/plain",
-					Blob:     base64.StdEncoding.EncodeToString([]byte(resultText)),
+					MimeType: mimeType,
+					Blob:     base64.StdEncoding.EncodeToString(rendered),
+				})
+			}
+		}
+
+		// Render the results through the caller's own template, within the call's deadline
+		if outputTemplate != nil {
+			rendered, err := report.RenderCustom(ctx, outputTemplate, EnumerationOutput{
+				Domain:     domain,
+				Subdomains: entries,
+				Stats:      *toolCallResult.Meta,
+				Timestamp:  time.Now().UTC(),
+			})
+			if err != nil {
+				logger.Warn("Failed to render outputTemplate", "error", err)
+				toolCallResult = ToolCallResult{
+					IsError: true,
+					Content: []interface{}{
+						ContentItem{
+							Type: "text",
+							Text: fmt.Sprintf("Failed to render outputTemplate: %v", err),
+						},
+					},
+					Meta: toolCallResult.Meta,
+				}
+			} else {
+				toolCallResult.Content = append(toolCallResult.Content, ContentItem{
+					Type: "text",
+					Text: rendered,
+				})
+			}
+		}
+
+		// Too few results usually means missing API keys or the wrong domain, not a small attack surface
+		if discovered < minimumResultThreshold {
+			logger.Warn("Enumeration found fewer subdomains than minimumResultThreshold",
+				"found", discovered,
+				"minimumResultThreshold", minimumResultThreshold)
+			toolCallResult = ToolCallResult{
+				IsError: true,
+				Content: []interface{}{
+					ContentItem{
+						Type: "text",
+						Text: belowThresholdMessage(discovered, minimumResultThreshold),
+					},
 				},
-			},
+				Meta: toolCallResult.Meta,
+			}
 		}
 	}
 
-	// Return final response
+	// The raw output goes last, and also on failures, where it helps most. Lines
+	// for names that plugins or filters removed are left out.
+	if rawOutput != nil {
+		toolCallResult.Content = append(toolCallResult.Content, ContentItem{
+			Type: "text",
+			Text: rawOutput.Text(reported),
+		})
+	}
+
+	// Return final response shaped for the negotiated protocol version
 	return Response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Result:  toolCallResult,
+		Result:  formatToolCallResult(toolCallResult, protocolVersionFromContext(ctx)),
+	}
+}
+
+// nameFilters are the enumerateSubdomains filters that decide on a name alone,
+// so they can be applied to each subdomain as it is streamed
+type nameFilters struct {
+	domain                  string
+	limitToTLD              string
+	limitToRegisteredDomain bool
+	prefixes                []string
+	suffixes                []string
+	contains                []string
+	minLength               int
+	maxLength               int
+	match                   *regexp.Regexp
+	exclude                 *regexp.Regexp
+}
+
+// apply returns subdomain when every filter keeps it, and nothing otherwise
+func (f nameFilters) apply(subdomain string) []string {
+	single := &subfinder.EnumerationResult{
+		Subdomains: []string{subdomain},
+		Sources:    map[string][]string{subdomain: nil},
+	}
+	single = subfinder.FilterByTLD(single, f.limitToTLD)
+	if f.limitToRegisteredDomain {
+		single = subfinder.FilterByRegisteredDomain(single, f.domain)
+	}
+	single = subfinder.FilterBySubstrings(single, f.prefixes, f.suffixes, f.contains)
+	single = subfinder.FilterByLabelLength(single, f.minLength, f.maxLength)
+	single = subfinder.FilterByRegex(single, f.match)
+	single = subfinder.ExcludeByRegex(single, f.exclude)
+	return single.Subdomains
+}
+
+// confirmedSubdomains returns the subdomains a source reported, leaving out the
+// fallback suggestions listed when nothing was found
+func confirmedSubdomains(subdomains []string, sources map[string][]string) []string {
+	var confirmed []string
+	for _, subdomain := range subdomains {
+		if _, ok := sources[subdomain]; ok {
+			confirmed = append(confirmed, subdomain)
+		}
+	}
+	return confirmed
+}
+
+// belowThresholdMessage is the error text of a call whose sources found fewer
+// than minimumResultThreshold subdomains
+func belowThresholdMessage(found, threshold int) string {
+	return fmt.Sprintf("Enumeration found only %d subdomains before filtering, expected at least %d. Check API key configuration.", found, threshold)
+}
+
+// trustSummary returns the mean trust score of subdomains and how many of them
+// score as high confidence
+func trustSummary(subdomains []string, sources map[string][]string) (float64, int) {
+	if len(subdomains) == 0 {
+		return 0, 0
+	}
+	total, high := 0.0, 0
+	for _, subdomain := range subdomains {
+		score := subfinder.TrustScore(sources[subdomain])
+		total += score
+		if score >= subfinder.HighTrustScore {
+			high++
+		}
+	}
+	return total / float64(len(subdomains)), high
+}
+
+// exportRecords combines the listed subdomains with their sources and any resolved addresses
+func exportRecords(domain string, subdomains []string, sources map[string][]string, entries []subfinder.SubdomainEntry) []format.Record {
+	addresses := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		for _, addr := range entry.IPs {
+			addresses[entry.Subdomain] = append(addresses[entry.Subdomain], addr.IP)
+		}
+	}
+
+	records := make([]format.Record, 0, len(subdomains))
+	for _, subdomain := range subdomains {
+		records = append(records, format.Record{
+			Name:      subdomain,
+			Domain:    domain,
+			Addresses: addresses[subdomain],
+			Sources:   sources[subdomain],
+		})
+	}
+	return records
+}
+
+// selectedEntriesJSON encodes entries keeping only the requested output fields.
+// Besides the entries' own keys, ip4 and ip6 split the resolved addresses by
+// family and sources lists the passive sources that reported each subdomain.
+func selectedEntriesJSON(entries []subfinder.SubdomainEntry, sources map[string][]string, fields []string) ([]byte, error) {
+	rows, err := entryRows(entries, sources)
+	if err != nil {
+		return nil, err
+	}
+	// The standard-library-compatible config sorts map keys, keeping output stable
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(format.SelectFields(rows, fields))
+}
+
+// groupedEntriesJSON encodes entries as an object of groups keyed by groupBy,
+// keeping only the requested output fields when there are any
+func groupedEntriesJSON(entries []subfinder.SubdomainEntry, sources map[string][]string, groupBy string, fields []string) ([]byte, error) {
+	groups := format.GroupBy(entries, sources, groupBy)
+	if len(fields) == 0 {
+		return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(groups)
+	}
+
+	selected := make(map[string][]map[string]interface{}, len(groups))
+	for key, group := range groups {
+		rows, err := entryRows(group, sources)
+		if err != nil {
+			return nil, err
+		}
+		selected[key] = format.SelectFields(rows, fields)
+	}
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(selected)
+}
+
+// entryRows turns entries into the generic rows fields are selected from
+func entryRows(entries []subfinder.SubdomainEntry, sources map[string][]string) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		encoded, err := jsoniter.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		var row map[string]interface{}
+		if err := jsoniter.Unmarshal(encoded, &row); err != nil {
+			return nil, err
+		}
+
+		var ip4, ip6 []string
+		for _, addr := range entry.IPs {
+			if ip := net.ParseIP(addr.IP); ip != nil && ip.To4() == nil {
+				ip6 = append(ip6, addr.IP)
+			} else {
+				ip4 = append(ip4, addr.IP)
+			}
+		}
+		if len(ip4) > 0 {
+			row[format.FieldIP4] = ip4
+		}
+		if len(ip6) > 0 {
+			row[format.FieldIP6] = ip6
+		}
+		if entrySources, ok := sources[entry.Subdomain]; ok {
+			row[format.FieldSources] = entrySources
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// tagSensitive marks the entries whose names under domain match a sensitive
+// pattern and returns how many of the listed subdomains were marked
+func tagSensitive(entries []subfinder.SubdomainEntry, domain string, listed []string) *int {
+	count := 0
+	for i := range entries {
+		reason, ok := classify.Sensitive(entries[i].Subdomain, domain)
+		if !ok {
+			continue
+		}
+		entries[i].Sensitive = true
+		entries[i].SensitiveReason = reason
+		if slices.Contains(listed, entries[i].Subdomain) {
+			count++
+		}
+	}
+	return &count
+}
+
+// reportData collects what a generated report shows about the reported subdomains
+func reportData(domain string, subdomains []string, sources map[string][]string, entries []subfinder.SubdomainEntry, now time.Time) report.Data {
+	providers := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		providers[entry.Subdomain] = entry.CloudProvider
+	}
+
+	data := report.Data{
+		Domain:      domain,
+		GeneratedAt: now,
+		Subdomains:  make([]report.Subdomain, 0, len(subdomains)),
+	}
+	for _, subdomain := range subdomains {
+		data.Subdomains = append(data.Subdomains, report.Subdomain{
+			Name:          subdomain,
+			Sources:       sources[subdomain],
+			CloudProvider: providers[subdomain],
+		})
+	}
+	return data
+}
+
+// takeoverRisks returns the unresolved entries that still have a CNAME, the
+// dangling records through which a subdomain can be taken over
+func takeoverRisks(ctx context.Context, entries []subfinder.SubdomainEntry, logger *slog.Logger) []report.TakeoverRisk {
+	var unresolved []string
+	for _, entry := range entries {
+		if len(entry.IPs) == 0 {
+			unresolved = append(unresolved, entry.Subdomain)
+		}
 	}
+
+	cnames := subfinder.ResolveCNAMEs(ctx, unresolved, logger)
+	var risks []report.TakeoverRisk
+	for _, subdomain := range unresolved {
+		if target, ok := cnames[subdomain]; ok {
+			risks = append(risks, report.TakeoverRisk{Subdomain: subdomain, CNAME: target})
+		}
+	}
+	return risks
+}
+
+// subdomainListContent builds the content shared by tools that return a list of
+// subdomains: a short text summary for CLI interfaces and the full list as a resource
+func subdomainListContent(domain string, subdomains []string, delimiter string) []interface{} {
+	resultText := fmt.Sprintf("Found %d subdomains for %s:\n\n%s",
+		len(subdomains),
+		domain,
+		strings.Join(subdomains, delimiter),
+	)
+
+	return []interface{}{
+		ContentItem{
+			Type: "text",
+			Text: fmt.Sprintf("Successfully enumerated %d subdomains for %s", len(subdomains), domain),
+		},
+		ResourceItem{
+			Type:     "resource",
+			MimeType: "text/plain",
+			Blob:     base64.StdEncoding.EncodeToString([]byte(resultText)),
+		},
+	}
+}
+
+// defaultLineDelimiter separates subdomains in the plain-text list resource
+const defaultLineDelimiter = "\n"
+
+// maxLineDelimiterLength is the longest outputLineDelimiter accepted, in characters
+const maxLineDelimiterLength = 5
+
+// validLineDelimiter reports whether delimiter can separate subdomains in the list:
+// non-empty, at most maxLineDelimiterLength characters and free of null bytes
+func validLineDelimiter(delimiter string) bool {
+	return delimiter != "" && utf8.RuneCountInString(delimiter) <= maxLineDelimiterLength && !strings.ContainsRune(delimiter, 0)
+}
+
+// formatToolCallResult adapts a tool result to what the negotiated protocol version allows.
+// The 2024-11-05 revision only accepts text content, so resources are inlined as text.
+func formatToolCallResult(result ToolCallResult, protocolVersion string) ToolCallResult {
+	if protocolVersion != ProtocolVersion20241105 {
+		return result
+	}
+
+	content := make([]interface{}, 0, len(result.Content))
+	for _, item := range result.Content {
+		resource, ok := item.(ResourceItem)
+		if !ok {
+			content = append(content, item)
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(resource.Blob)
+		if err != nil {
+			continue
+		}
+		content = append(content, ContentItem{
+			Type: "text",
+			Text: string(decoded),
+		})
+	}
+
+	result.Content = content
+	return result
 }
 
 // ProcessSingleRequest handles a single JSON-RPC request
@@ -263,11 +2767,35 @@ func ProcessSingleRequest(ctx context.Context, req Request, providerConfigPath s
 	// Route to appropriate handler based on method
 	switch req.Method {
 	case "initialize":
-		return HandleInitialize(&req)
+		return HandleInitialize(ctx, &req, logger)
 	case "tools.list":
 		return HandleToolsList(&req)
+	case "tools.get":
+		return HandleToolsGet(&req, logger)
 	case "tools.call":
 		return HandleToolsCall(ctx, &req, providerConfigPath, logger)
+	case "schedule.create":
+		return HandleScheduleCreate(ctx, &req, providerConfigPath, logger)
+	case "schedule.list":
+		return HandleScheduleList(ctx, &req, logger)
+	case "schedule.delete":
+		return HandleScheduleDelete(ctx, &req, logger)
+	case "schedule.pause":
+		return HandleSchedulePause(ctx, &req, logger)
+	case "schedule.resume":
+		return HandleScheduleResume(ctx, &req, logger)
+	case "cache.invalidate":
+		return HandleCacheInvalidate(ctx, &req, logger)
+	case "resources.subscribe":
+		return HandleResourcesSubscribe(ctx, &req, logger)
+	case "jobs.get":
+		return HandleJobsGet(ctx, &req, logger)
+	case "notifications/initialized", "initialized":
+		// Completes the handshake; notifications never get a response
+		if session := SessionFromContext(ctx); session != nil && session.State() == SessionInitializing {
+			session.SetState(SessionReady)
+		}
+		return Response{}
 	default:
 		// Check if it's a notification (no ID)
 		if req.ID == nil {
@@ -2,12 +2,24 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/format"
+	"mcp-subfinder-server/internal/permutation"
+	"mcp-subfinder-server/internal/report"
+	"mcp-subfinder-server/internal/shodan"
+	"mcp-subfinder-server/internal/subfinder"
+	"mcp-subfinder-server/internal/weights"
 )
 
 func TestHandleInitialize(t *testing.T) {
@@ -31,6 +43,26 @@ func TestHandleInitialize(t *testing.T) {
 					Name:            "MCP Subfinder Server",
 					ProtocolVersion: "0.3",
 					Version:         "1.0.0",
+					Capabilities:    ServerCapabilities{Resources: ResourcesCapability{Subscribe: false}},
+				},
+			},
+		},
+		{
+			name: "Dated protocol version",
+			request: &Request{
+				JSONRPC: "2.0",
+				Method:  "initialize",
+				ID:      rawMessagePtr("1"),
+				Params:  jsoniter.RawMessage(`{"protocolVersion": "2024-11-05"}`),
+			},
+			expected: Response{
+				JSONRPC: "2.0",
+				ID:      rawMessagePtr("1"),
+				Result: InitializeResult{
+					Name:            "MCP Subfinder Server",
+					ProtocolVersion: "2024-11-05",
+					Version:         "1.0.0",
+					Capabilities:    ServerCapabilities{Resources: ResourcesCapability{Subscribe: false}},
 				},
 			},
 		},
@@ -47,15 +79,17 @@ func TestHandleInitialize(t *testing.T) {
 				ID:      rawMessagePtr("1"),
 				Error: &RPCError{
 					Code:    InvalidParamsCode,
-					Message: "Unsupported protocol version: 0.2. Server supports: 0.3",
+					Message: "Unsupported protocol version: 0.2. Server supports: 0.3, 2024-11-05",
 				},
 			},
 		},
 	}
 
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			response := HandleInitialize(tc.request)
+			response := HandleInitialize(context.Background(), tc.request, logger)
 
 			// Compare the responses, ignoring specific error message if comparing fails
 			if !reflect.DeepEqual(response, tc.expected) {
@@ -72,6 +106,73 @@ func TestHandleInitialize(t *testing.T) {
 	}
 }
 
+func TestHandleResourcesSubscribe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	req := Request{
+		JSONRPC: "2.0",
+		Method:  "resources.subscribe",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"uri": "subfinder://example.com"}`),
+	}
+
+	response := ProcessSingleRequest(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != SubscriptionsNotSupportedCode || response.Error.Data != "Subscriptions not yet supported" {
+		t.Errorf("Expected a subscriptions not supported error, got %+v", response.Error)
+	}
+}
+
+func TestHandleInitializeStoresClientInfo(t *testing.T) {
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "initialize",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"protocolVersion": "0.3", "clientInfo": {"name": "test-client", "version": "2.1.0"}}`),
+	}
+
+	session := NewSession()
+	ctx := WithSession(context.Background(), session)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	response := HandleInitialize(ctx, req, logger)
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	info := session.ClientInfo()
+	if info.Name != "test-client" || info.Version != "2.1.0" {
+		t.Errorf("Expected client info test-client/2.1.0, got %s/%s", info.Name, info.Version)
+	}
+}
+
+func TestFormatToolCallResult(t *testing.T) {
+	result := ToolCallResult{
+		Content: []interface{}{
+			ContentItem{Type: "text", Text: "summary"},
+			ResourceItem{Type: "resource", MimeType: "text/plain", Blob: base64.StdEncoding.EncodeToString([]byte("a.example.com"))},
+		},
+	}
+
+	// The default protocol keeps resource items untouched
+	legacy := formatToolCallResult(result, SupportedProtocolVersion)
+	if _, ok := legacy.Content[1].(ResourceItem); !ok {
+		t.Errorf("Expected resource item for protocol %s, got %T", SupportedProtocolVersion, legacy.Content[1])
+	}
+
+	// The dated protocol only allows text content
+	dated := formatToolCallResult(result, ProtocolVersion20241105)
+	if len(dated.Content) != 2 {
+		t.Fatalf("Expected 2 content items, got %d", len(dated.Content))
+	}
+	for i, item := range dated.Content {
+		if _, ok := item.(ContentItem); !ok {
+			t.Errorf("Expected text content at index %d, got %T", i, item)
+		}
+	}
+	if text := dated.Content[1].(ContentItem).Text; text != "a.example.com" {
+		t.Errorf("Expected decoded resource text, got %q", text)
+	}
+}
+
 func TestHandleToolsList(t *testing.T) {
 	// Create test request
 	req := &Request{
@@ -191,3 +292,699 @@ func rawMessagePtr(s string) *jsoniter.RawMessage {
 	m := jsoniter.RawMessage(s)
 	return &m
 }
+
+func TestIDRoundTrip(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	for _, id := range []string{`1`, `"abc"`, `null`} {
+		var req Request
+		if err := jsoniter.Unmarshal([]byte(`{"jsonrpc":"2.0","id":`+id+`,"method":"tools.list"}`), &req); err != nil {
+			t.Fatalf("Failed to parse request with ID %s: %v", id, err)
+		}
+
+		response := ProcessSingleRequest(context.Background(), req, "", logger)
+		encoded, err := jsoniter.Marshal(response)
+		if err != nil {
+			t.Fatalf("Failed to encode response for ID %s: %v", id, err)
+		}
+		if prefix := `{"jsonrpc":"2.0","id":` + id + `,`; !strings.HasPrefix(string(encoded), prefix) {
+			t.Errorf("Expected the response to echo ID %s, got %.60s", id, encoded)
+		}
+	}
+
+	// Without an id the request is a notification and no ID is made up for it
+	var notification Request
+	if err := jsoniter.Unmarshal([]byte(`{"jsonrpc":"2.0","method":"tools.list"}`), &notification); err != nil || notification.ID != nil {
+		t.Errorf("Expected no ID for a notification, got %v (%v)", notification.ID, err)
+	}
+}
+
+func TestFitTimeoutToDeadline(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// No deadline leaves the timeout alone
+	config := subfinder.SubfinderConfig{Timeout: 60}
+	enumCtx, cancelEnumeration, warning := fitTimeoutToDeadline(context.Background(), &config, logger)
+	defer cancelEnumeration()
+	if _, ok := enumCtx.Deadline(); ok || warning != "" || config.Timeout != 60 {
+		t.Errorf("Expected unchanged timeout without a deadline, got %d (%q)", config.Timeout, warning)
+	}
+
+	// A distant deadline leaves the timeout alone, but still keeps the buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	enumCtx, cancelEnumeration, warning = fitTimeoutToDeadline(ctx, &config, logger)
+	defer cancelEnumeration()
+	if warning != "" || config.Timeout != 60 {
+		t.Errorf("Expected unchanged timeout with a distant deadline, got %d (%q)", config.Timeout, warning)
+	}
+	deadline, _ := ctx.Deadline()
+	if stop, ok := enumCtx.Deadline(); !ok || deadline.Sub(stop) < deadlineBuffer {
+		t.Errorf("Expected the enumeration to stop %s before the deadline, got %s", deadlineBuffer, deadline.Sub(stop))
+	}
+
+	// A deadline only just past the timeout cannot also fit the buffer
+	ctx, cancel = context.WithTimeout(context.Background(), 62*time.Second)
+	defer cancel()
+	_, cancelEnumeration, warning = fitTimeoutToDeadline(ctx, &config, logger)
+	defer cancelEnumeration()
+	if config.Timeout > 57 || warning == "" {
+		t.Errorf("Expected the timeout to leave the buffer, got %d (%q)", config.Timeout, warning)
+	}
+
+	// A close deadline shortens the timeout and explains why
+	config.Timeout = 60
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, cancelEnumeration, warning = fitTimeoutToDeadline(ctx, &config, logger)
+	defer cancelEnumeration()
+	if config.Timeout < 1 || config.Timeout > 5 {
+		t.Errorf("Expected timeout reduced to at most 5s, leaving the buffer, got %d", config.Timeout)
+	}
+	if !strings.Contains(warning, "Effective timeout reduced to") || !strings.Contains(warning, "requested 60s") {
+		t.Errorf("Unexpected warning: %q", warning)
+	}
+}
+
+func TestEnumerationStopsBeforeDeadline(t *testing.T) {
+	defer func(enumerate func(context.Context, string, subfinder.SubfinderConfig, *slog.Logger) (*subfinder.EnumerationResult, error)) {
+		enumerateDomain = enumerate
+	}(enumerateDomain)
+	stopped := make(chan time.Time, 1)
+	enumerateDomain = func(ctx context.Context, domain string, config subfinder.SubfinderConfig, logger *slog.Logger) (*subfinder.EnumerationResult, error) {
+		// Stands in for sources that keep answering until they are cancelled
+		<-ctx.Done()
+		stopped <- time.Now()
+		return &subfinder.EnumerationResult{
+			Subdomains: []string{"www.example.com"},
+			Sources:    map[string][]string{"www.example.com": {"crtsh"}},
+		}, nil
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), deadlineBuffer+time.Second)
+	defer cancel()
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "timeout": 60}}`),
+	}
+	response := HandleToolsCall(ctx, req, "", logger)
+	if result, ok := response.Result.(ToolCallResult); !ok || result.IsError {
+		t.Fatalf("Expected the enumeration's results, got %+v", response)
+	}
+	deadline, _ := ctx.Deadline()
+	if left := deadline.Sub(<-stopped); left < deadlineBuffer-time.Second {
+		t.Errorf("Expected the enumeration to stop about %s before the deadline, stopped %s before it", deadlineBuffer, left)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected the call to return before the request deadline")
+	}
+}
+
+func TestProviderStatus(t *testing.T) {
+	stats := []subfinder.SourceStatistic{
+		{Source: "alienvault", Results: 4},
+		{Source: "securitytrails", Errors: 3},
+	}
+
+	expected := []ProviderStatus{
+		{Name: "alienvault", ResultsCount: 4, HadErrors: false},
+		{Name: "securitytrails", ResultsCount: 0, HadErrors: true},
+	}
+	if got := providerStatus(stats); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	if got := providerStatus(nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty status list, got %+v", got)
+	}
+}
+
+func TestParseEnumerationConfigVerbosity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	config := parseEnumerationConfig(map[string]interface{}{}, "", logger)
+	if !config.SilentMode || config.VerboseMode {
+		t.Errorf("Expected silent, non-verbose defaults, got silent=%v verbose=%v", config.SilentMode, config.VerboseMode)
+	}
+
+	config = parseEnumerationConfig(map[string]interface{}{"verbose": true}, "", logger)
+	if config.SilentMode || !config.VerboseMode {
+		t.Errorf("Expected verbose to disable silent mode, got silent=%v verbose=%v", config.SilentMode, config.VerboseMode)
+	}
+
+	config = parseEnumerationConfig(map[string]interface{}{"verbose": "yes"}, "", logger)
+	if !config.SilentMode || config.VerboseMode {
+		t.Errorf("Expected an invalid verbose value to keep the defaults, got silent=%v verbose=%v", config.SilentMode, config.VerboseMode)
+	}
+}
+
+func TestParseEnumerationConfigRetryStrategy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	config := parseEnumerationConfig(map[string]interface{}{}, "", logger)
+	if config.RetryConfig != subfinder.DefaultRetryConfig {
+		t.Errorf("Expected the default retry strategy, got %+v", config.RetryConfig)
+	}
+
+	config = parseEnumerationConfig(map[string]interface{}{"retryStrategy": map[string]interface{}{
+		"maxAttempts": 5.0, "baseDelaySeconds": 1.5, "backoffMultiplier": 2.0,
+	}}, "", logger)
+	expected := subfinder.RetryConfig{MaxAttempts: 5, BaseDelay: 1500 * time.Millisecond, BackoffMultiplier: 2}
+	if config.RetryConfig != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.RetryConfig)
+	}
+
+	// Out-of-range fields keep their defaults while valid ones apply
+	config = parseEnumerationConfig(map[string]interface{}{"retryStrategy": map[string]interface{}{
+		"maxAttempts": 10.0, "baseDelaySeconds": 5.0, "backoffMultiplier": 0.5,
+	}}, "", logger)
+	expected = subfinder.RetryConfig{MaxAttempts: 3, BaseDelay: 5 * time.Second, BackoffMultiplier: 1}
+	if config.RetryConfig != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.RetryConfig)
+	}
+}
+
+func TestParseEnumerationConfigMaxRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		args     map[string]interface{}
+		expected int
+	}{
+		{args: map[string]interface{}{"maxRetries": 0.0}, expected: 1},
+		{args: map[string]interface{}{"maxRetries": 1.0}, expected: 1},
+		{args: map[string]interface{}{"maxRetries": 5.0}, expected: 5},
+		{args: map[string]interface{}{"maxRetries": 6.0}, expected: 3},
+		{args: map[string]interface{}{"maxRetries": 2.5}, expected: 3},
+		// maxRetries takes precedence over retryStrategy.maxAttempts
+		{args: map[string]interface{}{"maxRetries": 0.0, "retryStrategy": map[string]interface{}{"maxAttempts": 4.0}}, expected: 1},
+	}
+
+	for _, tc := range tests {
+		config := parseEnumerationConfig(tc.args, "", logger)
+		if config.RetryConfig.MaxAttempts != tc.expected {
+			t.Errorf("Args %v: expected %d attempts, got %d", tc.args, tc.expected, config.RetryConfig.MaxAttempts)
+		}
+	}
+}
+
+func TestParseEnumerationConfigMaxDepthClamp(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	defer Configure(ServerSettings{})
+
+	tests := []struct {
+		limit    int
+		maxDepth float64
+		expected int
+	}{
+		{limit: 0, maxDepth: 2, expected: 2},
+		{limit: 0, maxDepth: 5, expected: DefaultMaxRecursiveDepth},
+		{limit: 5, maxDepth: 5, expected: 5},
+		{limit: 2, maxDepth: 4, expected: 2},
+		{limit: 9, maxDepth: 9, expected: MaxRecursiveDepthLimit},
+	}
+
+	for _, tc := range tests {
+		Configure(ServerSettings{MaxRecursiveDepth: tc.limit})
+		config := parseEnumerationConfig(map[string]interface{}{"maxDepth": tc.maxDepth}, "", logger)
+		if config.MaxDepth != tc.expected {
+			t.Errorf("Limit %d, maxDepth %v: expected depth %d, got %d", tc.limit, tc.maxDepth, tc.expected, config.MaxDepth)
+		}
+	}
+}
+
+func TestParseEnumerationConfigRateLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	config := parseEnumerationConfig(map[string]interface{}{
+		"rateLimitPerSource": map[string]interface{}{"Shodan": 1.0, "securitytrails": 2.0, "crtsh": 0.0, "virustotal": "fast"},
+	}, "", logger)
+	expected := map[string]int{"shodan": 1, "securitytrails": 2}
+	if !reflect.DeepEqual(config.RateLimits, expected) {
+		t.Errorf("Expected rate limits %v, got %v", expected, config.RateLimits)
+	}
+
+	if config := parseEnumerationConfig(map[string]interface{}{"rateLimitPerSource": 5.0}, "", logger); config.RateLimits != nil {
+		t.Errorf("Expected no rate limits for an invalid parameter, got %v", config.RateLimits)
+	}
+}
+
+func TestExportRecords(t *testing.T) {
+	entries := []subfinder.SubdomainEntry{
+		{Subdomain: "api.example.com", IPs: []subfinder.IPEntry{{IP: "93.184.216.34", TTL: 300}, {IP: "2606:2800:220:1::1"}}},
+		{Subdomain: "old.example.com"},
+	}
+	sources := map[string][]string{"api.example.com": {"crtsh"}}
+
+	records := exportRecords("example.com", []string{"api.example.com", "old.example.com"}, sources, entries)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if !reflect.DeepEqual(records[0].Addresses, []string{"93.184.216.34", "2606:2800:220:1::1"}) {
+		t.Errorf("Unexpected addresses %v", records[0].Addresses)
+	}
+	if !reflect.DeepEqual(records[0].Sources, []string{"crtsh"}) || records[0].Domain != "example.com" {
+		t.Errorf("Unexpected record %+v", records[0])
+	}
+	if records[1].Addresses != nil || records[1].Sources != nil {
+		t.Errorf("Expected no addresses or sources for an unresolved fallback, got %+v", records[1])
+	}
+}
+
+func TestSubdomainListDelimiter(t *testing.T) {
+	content := subdomainListContent("example.com", []string{"a.example.com", "b.example.com"}, ", ")
+	resource, ok := content[1].(ResourceItem)
+	if !ok {
+		t.Fatalf("Expected a resource item, got %+v", content[1])
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(resource.Blob)
+	if !strings.HasSuffix(string(decoded), "\n\na.example.com, b.example.com") {
+		t.Errorf("Expected comma-separated subdomains, got %q", decoded)
+	}
+
+	for delimiter, expected := range map[string]bool{",": true, " | ": true, "\t": true, "": false, "------": false, "a\x00b": false} {
+		if got := validLineDelimiter(delimiter); got != expected {
+			t.Errorf("validLineDelimiter(%q): expected %v, got %v", delimiter, expected, got)
+		}
+	}
+}
+
+func TestSelectedEntriesJSON(t *testing.T) {
+	entries := []subfinder.SubdomainEntry{
+		{Subdomain: "api.example.com", IPs: []subfinder.IPEntry{{IP: "93.184.216.34", TTL: 300}, {IP: "2606:2800:220:1::1"}}, CloudProvider: "aws"},
+		{Subdomain: "old.example.com"},
+	}
+	sources := map[string][]string{"api.example.com": {"crtsh", "dnsdumpster"}}
+
+	data, err := selectedEntriesJSON(entries, sources, []string{"subdomain", "ip4", "sources"})
+	if err != nil {
+		t.Fatalf("selectedEntriesJSON failed: %v", err)
+	}
+	expected := `[{"ip4":["93.184.216.34"],"sources":["crtsh","dnsdumpster"],"subdomain":"api.example.com"},{"subdomain":"old.example.com"}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestTrustSummary(t *testing.T) {
+	sources := map[string][]string{
+		"api.example.com": {"a", "b", "c", "d"},
+		"www.example.com": {"a", "b"},
+	}
+
+	average, high := trustSummary([]string{"api.example.com", "www.example.com", "guess.example.com"}, sources)
+	if average < 0.39 || average > 0.41 || high != 1 {
+		t.Errorf("Expected an average of 0.4 with one high-confidence result, got %v and %d", average, high)
+	}
+	if average, high := trustSummary(nil, sources); average != 0 || high != 0 {
+		t.Errorf("Expected zeros without subdomains, got %v and %d", average, high)
+	}
+}
+
+func TestGroupedEntriesJSON(t *testing.T) {
+	entries := []subfinder.SubdomainEntry{
+		{Subdomain: "api.example.com", CloudProvider: "aws"},
+		{Subdomain: "old.example.com"},
+	}
+	sources := map[string][]string{"api.example.com": {"crtsh", "dnsdumpster"}}
+
+	data, err := groupedEntriesJSON(entries, sources, format.GroupSource, []string{"subdomain"})
+	if err != nil {
+		t.Fatalf("groupedEntriesJSON failed: %v", err)
+	}
+	expected := `{"crtsh":[{"subdomain":"api.example.com"}],"dnsdumpster":[{"subdomain":"api.example.com"}],"unknown":[{"subdomain":"old.example.com"}]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	data, err = groupedEntriesJSON(entries, sources, format.GroupCloudProvider, nil)
+	if err != nil {
+		t.Fatalf("groupedEntriesJSON failed: %v", err)
+	}
+	expected = `{"aws":[{"subdomain":"api.example.com","cloudProvider":"aws"}],"unknown":[{"subdomain":"old.example.com"}]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestOutputFieldsRejectsUnknownField(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("9"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"outputFields": ["subdomain", "hostname"]}}`),
+	}
+
+	if response := HandleToolsCall(context.Background(), req, "", logger); response.Error != ErrInvalidParams {
+		t.Errorf("Expected invalid params for an unknown field, got %+v", response)
+	}
+}
+
+func TestGroupByASNRequiresShodan(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Setenv(shodan.APIKeyEnv, "")
+
+	// Without a Shodan key enrichWithShodan is dropped, so asn has nothing to group by
+	for _, args := range []string{`"resolveIPs": true`, `"resolveIPs": true, "enrichWithShodan": true`} {
+		req := &Request{
+			JSONRPC: "2.0",
+			Method:  "tools.call",
+			ID:      rawMessagePtr("9"),
+			Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "outputGroupBy": "asn", ` + args + `}}`),
+		}
+		if response := HandleToolsCall(context.Background(), req, "", logger); response.Error == nil || response.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %s, got %+v", args, response)
+		}
+	}
+}
+
+func TestInvalidRegexFilters(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	for _, args := range []string{`"filterByRegex": "dev-(api"`, `"excludeByRegex": "[a-"`, `"filterByRegex": 5`} {
+		req := &Request{
+			JSONRPC: "2.0",
+			Method:  "tools.call",
+			ID:      rawMessagePtr("9"),
+			Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", ` + args + `}}`),
+		}
+
+		response := HandleToolsCall(context.Background(), req, "", logger)
+		if response.Error == nil || response.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %s, got %+v", args, response)
+			continue
+		}
+		if message, _ := response.Error.Data.(string); message == "" {
+			t.Errorf("Expected the compile error as data for %s, got %+v", args, response.Error)
+		}
+	}
+}
+
+func TestCloudProviderFilterRequiresResolveIPs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"excludeCloudProvider": ["cloudflare"]}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Fatalf("Expected invalid params, got %+v", response)
+	}
+	if response.Error.Data != "excludeCloudProvider requires resolveIPs: true" {
+		t.Errorf("Unexpected error data %v", response.Error.Data)
+	}
+
+	// Unknown providers are rejected rather than matching nothing
+	req.Params = jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+		"resolveIPs": true, "limitByCloudProvider": ["aws", "amazon"]}}`)
+	response = HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Fatalf("Expected invalid params for an unknown provider, got %+v", response)
+	}
+	if data, _ := response.Error.Data.(string); !strings.Contains(data, `"amazon"`) {
+		t.Errorf("Expected the unknown provider in the error, got %v", response.Error.Data)
+	}
+}
+
+func TestInvalidOutputTemplate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"outputTemplate": "{{range .Subdomains}}{{.Subdomain}}"}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Fatalf("Expected invalid params, got %+v", response)
+	}
+	if data, _ := response.Error.Data.(string); !strings.Contains(data, "outputTemplate") {
+		t.Errorf("Expected the parse error in the error data, got %v", response.Error.Data)
+	}
+}
+
+func TestDomainAliasInMeta(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// A CT-only run with no CT sources fails before any source is queried
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("6"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"certTransparencyOnly": true, "sourcesFilter": "hackertarget", "domainAlias": " Acme Corp "}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	result, ok := response.Result.(ToolCallResult)
+	if !ok || !result.IsError {
+		t.Fatalf("Expected an error result, got %+v", response)
+	}
+	if result.Meta == nil || result.Meta.Alias != "Acme Corp" {
+		t.Errorf("Expected alias Acme Corp in _meta, got %+v", result.Meta)
+	}
+}
+
+func TestAnnotationsPassthrough(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("7"),
+		Params: jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com",
+			"certTransparencyOnly": true, "sourcesFilter": "hackertarget"},
+			"annotations": {"projectId": "abc123", "task": {"id": 456}}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	result, ok := response.Result.(ToolCallResult)
+	if !ok {
+		t.Fatalf("Expected a tool result, got %+v", response)
+	}
+	expected := map[string]interface{}{"projectId": "abc123", "task": map[string]interface{}{"id": float64(456)}}
+	if !reflect.DeepEqual(result.Annotations, expected) {
+		t.Errorf("Expected annotations %v, got %v", expected, result.Annotations)
+	}
+}
+
+func TestTrustAnchorsAdvertised(t *testing.T) {
+	defer Configure(ServerSettings{})
+
+	advertised := func() bool {
+		properties := availableTools()[0].InputSchema.(map[string]interface{})["properties"].(map[string]interface{})
+		_, ok := properties["trustAnchorsBase64"]
+		return ok
+	}
+
+	Configure(ServerSettings{})
+	if advertised() {
+		t.Error("Expected trustAnchorsBase64 to be hidden when custom trust anchors are disabled")
+	}
+
+	Configure(ServerSettings{AllowCustomTrustAnchors: true})
+	if !advertised() {
+		t.Error("Expected trustAnchorsBase64 to be listed when custom trust anchors are enabled")
+	}
+}
+
+func TestCommentArgument(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	params := ToolCallParams{Arguments: map[string]interface{}{"comment": "scheduled nightly scan for bug bounty"}}
+	if got := commentArgument(params, logger); got != "scheduled nightly scan for bug bounty" {
+		t.Errorf("Expected the comment unchanged, got %q", got)
+	}
+
+	params.Arguments["comment"] = strings.Repeat("é", maxCommentLength+20)
+	if got := commentArgument(params, logger); len([]rune(got)) != maxCommentLength {
+		t.Errorf("Expected the comment truncated to %d characters, got %d", maxCommentLength, len([]rune(got)))
+	}
+
+	params.Arguments["comment"] = 42.0
+	if got := commentArgument(params, logger); got != "" {
+		t.Errorf("Expected a non-string comment to be ignored, got %q", got)
+	}
+}
+
+func TestReportData(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []subfinder.SubdomainEntry{{Subdomain: "api.example.com", CloudProvider: "aws"}}
+	sources := map[string][]string{"api.example.com": {"crtsh"}}
+
+	data := reportData("example.com", []string{"api.example.com", "www.example.com"}, sources, entries, now)
+	expected := []report.Subdomain{
+		{Name: "api.example.com", Sources: []string{"crtsh"}, CloudProvider: "aws"},
+		{Name: "www.example.com"},
+	}
+	if data.Domain != "example.com" || !data.GeneratedAt.Equal(now) || !reflect.DeepEqual(data.Subdomains, expected) {
+		t.Errorf("Unexpected report data %+v", data)
+	}
+}
+
+func TestTagSensitive(t *testing.T) {
+	entries := []subfinder.SubdomainEntry{
+		{Subdomain: "admin.example.com"},
+		{Subdomain: "jenkins.example.com"},
+		{Subdomain: "www.example.com"},
+	}
+
+	// jenkins is parked, so it stays tagged in the resource but is not counted
+	count := tagSensitive(entries, "example.com", []string{"admin.example.com", "www.example.com"})
+	if count == nil || *count != 1 {
+		t.Errorf("Expected 1 listed sensitive subdomain, got %v", count)
+	}
+	if !entries[0].Sensitive || entries[0].SensitiveReason != "admin panel indicator" {
+		t.Errorf("Expected admin to be tagged as an admin panel, got %+v", entries[0])
+	}
+	if !entries[1].Sensitive || entries[2].Sensitive || entries[2].SensitiveReason != "" {
+		t.Errorf("Unexpected tags %+v", entries)
+	}
+}
+
+func TestNameFilters(t *testing.T) {
+	filters := nameFilters{
+		domain:                  "example.com",
+		limitToRegisteredDomain: true,
+		prefixes:                []string{"api"},
+		exclude:                 regexp.MustCompile(`internal`),
+	}
+	for subdomain, kept := range map[string]bool{
+		"api.example.com":          true,
+		"www.example.com":          false,
+		"api.internal.example.com": false,
+		"api.example.org":          false,
+	} {
+		if got := filters.apply(subdomain); (len(got) == 1) != kept {
+			t.Errorf("%s: expected kept=%v, got %v", subdomain, kept, got)
+		}
+	}
+	if got := (nameFilters{}).apply("www.example.com"); !reflect.DeepEqual(got, []string{"www.example.com"}) {
+		t.Errorf("Expected no filters to keep the name, got %v", got)
+	}
+}
+
+func TestConfirmedSubdomains(t *testing.T) {
+	sources := map[string][]string{"api.example.com": {"crtsh"}}
+	got := confirmedSubdomains([]string{"api.example.com", "www.example.com"}, sources)
+	if !reflect.DeepEqual(got, []string{"api.example.com"}) {
+		t.Errorf("Expected only the sourced subdomain, got %v", got)
+	}
+
+	expected := "Enumeration found only 1 subdomains before filtering, expected at least 5. Check API key configuration."
+	if msg := belowThresholdMessage(len(got), 5); msg != expected {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
+
+func TestMinimumResultThreshold(t *testing.T) {
+	defer func(enumerate func(context.Context, string, subfinder.SubfinderConfig, *slog.Logger) (*subfinder.EnumerationResult, error)) {
+		enumerateDomain = enumerate
+	}(enumerateDomain)
+	enumerateDomain = func(ctx context.Context, domain string, config subfinder.SubfinderConfig, logger *slog.Logger) (*subfinder.EnumerationResult, error) {
+		return &subfinder.EnumerationResult{
+			Subdomains: []string{"api.example.com", "www.example.com"},
+			Sources: map[string][]string{
+				"api.example.com": {"crtsh"},
+				"www.example.com": {"crtsh"},
+			},
+		}, nil
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	call := func(arguments string) ToolCallResult {
+		t.Helper()
+		req := &Request{
+			JSONRPC: "2.0",
+			Method:  "tools.call",
+			ID:      rawMessagePtr("1"),
+			Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", ` + arguments + `}}`),
+		}
+		response := HandleToolsCall(context.Background(), req, "", logger)
+		result, ok := response.Result.(ToolCallResult)
+		if !ok {
+			t.Fatalf("Expected a tool call result, got %+v", response)
+		}
+		return result
+	}
+
+	result := call(`"minimumResultThreshold": 3`)
+	if !result.IsError {
+		t.Fatalf("Expected an error below the threshold, got %+v", result)
+	}
+	if text := result.Content[0].(ContentItem).Text; text != belowThresholdMessage(2, 3) {
+		t.Errorf("Unexpected message %q", text)
+	}
+
+	// The caller's own filters do not count against the threshold
+	if result := call(`"minimumResultThreshold": 2, "filterByRegex": "^api\\."`); result.IsError {
+		t.Errorf("Expected filtered results not to fail the threshold, got %+v", result)
+	}
+}
+
+func TestUnknownPrioritySources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, arguments := range []string{
+		`"prioritizeSources": ["crtsh", "bogus"]`,
+		`"sourceWeights": {"crtsh": 2, "bogus": 5}`,
+	} {
+		req := &Request{
+			JSONRPC: "2.0",
+			Method:  "tools.call",
+			ID:      rawMessagePtr("1"),
+			Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", ` + arguments + `}}`),
+		}
+		response := HandleToolsCall(context.Background(), req, "", logger)
+		if response.Error == nil || response.Error.Code != InvalidParamsCode || !strings.Contains(fmt.Sprint(response.Error.Data), "bogus") || strings.Contains(fmt.Sprint(response.Error.Data), "crtsh") {
+			t.Errorf("Expected invalid params naming only the unknown source for %s, got %+v", arguments, response.Error)
+		}
+	}
+}
+
+func TestLearnedSourceWeights(t *testing.T) {
+	defer func(learner *weights.Learner) { weights.Default = learner }(weights.Default)
+	weights.Default = weights.New()
+
+	entries := make([]subfinder.SubdomainEntry, 0, 2*weights.MinReports)
+	sources := make(map[string][]string)
+	for i := 0; i < 2*weights.MinReports; i++ {
+		entry := subfinder.SubdomainEntry{Subdomain: fmt.Sprintf("host%d.example.com", i)}
+		sources[entry.Subdomain] = []string{"alienvault", subfinder.SeedSource}
+		if i%2 == 0 {
+			entry.IPs = []subfinder.IPEntry{{IP: "192.0.2.1"}}
+			sources[entry.Subdomain] = append(sources[entry.Subdomain], "crtsh", "retired", permutation.Source)
+		}
+		entries = append(entries, entry)
+	}
+	learnSourceWeights(entries, sources)
+
+	// Seeds and permutations never get a weight of their own
+	for _, learned := range weights.Default.Snapshot().Sources {
+		if learned.Source == subfinder.SeedSource || learned.Source == permutation.Source {
+			t.Errorf("Expected %s not to be learned", learned.Source)
+		}
+	}
+
+	// crtsh only reported resolving names, so it runs first unless the caller says
+	// otherwise; retired did too, but is not a subfinder source
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config := parseEnumerationConfig(map[string]interface{}{"sourceWeights": map[string]interface{}{}}, "", logger)
+	if !reflect.DeepEqual(config.PrioritySources, []string{"crtsh"}) {
+		t.Errorf("Expected the learned weights to prioritize crtsh, got %v", config.PrioritySources)
+	}
+	config = parseEnumerationConfig(map[string]interface{}{"sourceWeights": map[string]interface{}{"crtsh": 1.0}}, "", logger)
+	if len(config.PrioritySources) != 0 {
+		t.Errorf("Expected the caller's weight to override the learned one, got %v", config.PrioritySources)
+	}
+}
@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// defaultIdempotencyTTL is how long a result is replayed for a repeated idempotency key
+const defaultIdempotencyTTL = 5 * time.Minute
+
+// idempotencyEntry is a cached tool result, the domains it covers, the hash of
+// the arguments that produced it and when it stops being replayed
+type idempotencyEntry struct {
+	result    ToolCallResult
+	domains   []string
+	arguments string
+	expires   time.Time
+}
+
+// idempotencyCache remembers tool results by idempotency key until they expire
+type idempotencyCache struct {
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+}
+
+// newIdempotencyCache creates an empty idempotency cache
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{entries: make(map[string]idempotencyEntry)}
+}
+
+// idempotentResults is the process-wide cache shared by every tools.call
+var idempotentResults = newIdempotencyCache()
+
+// get returns the unexpired entry stored for key
+func (c *idempotencyCache) get(key string, now time.Time) (idempotencyEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return idempotencyEntry{}, false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return idempotencyEntry{}, false
+	}
+	return entry, true
+}
+
+// put stores result for domains under key for ttl, along with the hash of the
+// arguments that produced it, evicting any expired entries
+func (c *idempotencyCache) put(key string, domains []string, arguments string, result ToolCallResult, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = idempotencyEntry{result: result, domains: domains, arguments: arguments, expires: now.Add(ttl)}
+}
+
+// invalidate removes every entry whose call covered domain and returns how many were removed
+func (c *idempotencyCache) invalidate(domain string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	domain = strings.ToLower(domain)
+	evicted := 0
+	for k, entry := range c.entries {
+		for _, d := range entry.domains {
+			if d == domain {
+				delete(c.entries, k)
+				evicted++
+				break
+			}
+		}
+	}
+	return evicted
+}
+
+// InvalidateCache evicts every cached result for domain so the next call enumerates afresh
+func InvalidateCache(domain string) int {
+	return idempotentResults.invalidate(domain)
+}
+
+// callDomains returns the lowercased target domains of a tool call
+func callDomains(params ToolCallParams) []string {
+	var domains []string
+	for _, name := range []string{"domain", "domain1", "domain2"} {
+		if domain, ok := params.Arguments[name].(string); ok && domain != "" {
+			domains = append(domains, strings.ToLower(domain))
+		}
+	}
+	return domains
+}
+
+// idempotencyTTL returns the configured replay window
+func idempotencyTTL() time.Duration {
+	if ttl := currentSettings().IdempotencyTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultIdempotencyTTL
+}
+
+// cacheKeyPattern limits cacheKey to characters that are safe in file names and
+// cannot express a path, should the cache ever be stored on disk
+var cacheKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+
+// uncachedTools are never replayed, since their results hold secrets such as API keys
+var uncachedTools = map[string]bool{
+	"generateAmassConfig": true,
+}
+
+// idempotencyKeyArgument returns the cache key for a call's idempotencyKey, scoped to
+// session, the ID of the caller's session; to the tool so the same key never
+// replays another tool's result; and to cacheKey when one is given. The scope only
+// keeps unrelated calls from colliding: a client naming the same X-Session-Id
+// shares the session, so it is not an access boundary. It is empty when unset or
+// for an uncached tool.
+func idempotencyKeyArgument(session string, params ToolCallParams, logger *slog.Logger) string {
+	val, ok := params.Arguments["idempotencyKey"]
+	if !ok || uncachedTools[params.Name] {
+		return ""
+	}
+	key, ok := val.(string)
+	if !ok || key == "" {
+		logger.Warn("Invalid idempotencyKey parameter, ignoring it", "providedIdempotencyKey", val)
+		return ""
+	}
+	group, _ := params.Arguments["cacheKey"].(string)
+	return session + "\x00" + group + "\x00" + params.Name + "\x00" + key
+}
+
+// argumentsHash returns the SHA-256 of the call's canonical arguments, leaving out
+// those that do not change the result, so a key reused for a different call is caught
+func argumentsHash(params ToolCallParams) string {
+	arguments := make(map[string]interface{}, len(params.Arguments))
+	for name, value := range params.Arguments {
+		if name != "idempotencyKey" && name != "comment" {
+			arguments[name] = value
+		}
+	}
+	// encoding/json sorts map keys at every level, which makes the encoding canonical
+	canonical, err := json.Marshal(arguments)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionIDFromContext returns the ID of ctx's session, or an empty string
+func sessionIDFromContext(ctx context.Context) string {
+	if s := SessionFromContext(ctx); s != nil {
+		return s.ID()
+	}
+	return ""
+}
+
+// validCacheKeyArgument reports whether the call's cacheKey, if any, is acceptable.
+// An invalid one is rejected rather than ignored, since ignoring it would mix the
+// caller's results into the shared group it asked to be isolated from.
+func validCacheKeyArgument(params ToolCallParams, logger *slog.Logger) bool {
+	val, ok := params.Arguments["cacheKey"]
+	if !ok {
+		return true
+	}
+	group, ok := val.(string)
+	if !ok || !cacheKeyPattern.MatchString(group) || strings.Contains(group, "..") {
+		logger.Warn("Invalid cacheKey parameter", "providedCacheKey", val)
+		return false
+	}
+	logger.Info("Using custom cache key", "cacheKey", group)
+	return true
+}
+
+// HandleCacheInvalidate processes a cache.invalidate request, which evicts other
+// clients' results too and so requires the server's API token
+func HandleCacheInvalidate(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+	if !bearerAuthorizedFromContext(ctx) {
+		logger.Warn("cache.invalidate called without the API token")
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrUnauthorized,
+		}
+	}
+
+	var params CacheInvalidateParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse cache.invalidate params", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+	if params.Domain == "" {
+		logger.Warn("Missing required parameter", "parameter", "domain")
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	evicted := InvalidateCache(params.Domain)
+	logger.Info("Invalidated cached results", "domain", params.Domain, "evicted", evicted)
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  CacheInvalidateResult{Evicted: evicted},
+	}
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	cache := newIdempotencyCache()
+	now := time.Now()
+	result := ToolCallResult{Content: []interface{}{ContentItem{Type: "text", Text: "done"}}}
+
+	cache.put("key", []string{"example.com"}, "", result, now, 5*time.Minute)
+
+	if _, ok := cache.get("key", now.Add(4*time.Minute)); !ok {
+		t.Error("Expected result within the TTL")
+	}
+	if _, ok := cache.get("other", now); ok {
+		t.Error("Expected no result for an unknown key")
+	}
+	if _, ok := cache.get("key", now.Add(5*time.Minute)); ok {
+		t.Error("Expected result to expire after the TTL")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected expired entry to be evicted, %d remain", len(cache.entries))
+	}
+}
+
+func TestIdempotencyCacheInvalidate(t *testing.T) {
+	cache := newIdempotencyCache()
+	now := time.Now()
+	result := ToolCallResult{Content: []interface{}{ContentItem{Type: "text", Text: "done"}}}
+
+	cache.put("a", []string{"example.com"}, "", result, now, time.Minute)
+	cache.put("b", []string{"example.com", "example.org"}, "", result, now, time.Minute)
+	cache.put("c", []string{"example.org"}, "", result, now, time.Minute)
+
+	if evicted := cache.invalidate("Example.com"); evicted != 2 {
+		t.Errorf("Expected 2 evicted entries, got %d", evicted)
+	}
+	if _, ok := cache.get("c", now); !ok {
+		t.Error("Expected the entry for another domain to remain")
+	}
+	if evicted := cache.invalidate("example.com"); evicted != 0 {
+		t.Errorf("Expected nothing left to evict, got %d", evicted)
+	}
+
+	params := ToolCallParams{Arguments: map[string]interface{}{"domain1": "A.example.com", "domain2": "b.example.com", "timeout": 30.0}}
+	if domains := callDomains(params); len(domains) != 2 || domains[0] != "a.example.com" || domains[1] != "b.example.com" {
+		t.Errorf("Unexpected call domains %v", domains)
+	}
+}
+
+func TestHandleToolsCallIdempotencyKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	original := idempotentResults
+	idempotentResults = newIdempotencyCache()
+	defer func() { idempotentResults = original }()
+
+	cached := ToolCallResult{
+		Content: []interface{}{ContentItem{Type: "text", Text: "Successfully enumerated 1 subdomains for example.com"}},
+		Meta:    &ToolCallMeta{TotalSources: 40, TotalErrors: 2},
+	}
+	stored := ToolCallParams{Name: "enumerateSubdomains", Arguments: map[string]interface{}{"domain": "example.com", "idempotencyKey": "retry-1"}}
+	idempotentResults.put(idempotencyKeyArgument("", stored, logger), []string{"example.com"}, argumentsHash(stored), cached, time.Now(), time.Minute)
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("8"),
+		Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "idempotencyKey": "retry-1"}}`),
+	}
+
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	result, ok := response.Result.(ToolCallResult)
+	if !ok {
+		t.Fatalf("Expected a ToolCallResult, got %T", response.Result)
+	}
+	if result.Meta == nil || !result.Meta.Idempotent {
+		t.Fatalf("Expected replayed result to be marked idempotent, got %+v", result.Meta)
+	}
+	if result.Meta.TotalSources != 40 || result.Meta.TotalErrors != 2 {
+		t.Errorf("Expected replayed result to keep its source statistics, got %+v", result.Meta)
+	}
+	if cached.Meta.Idempotent {
+		t.Error("Expected the cached result itself to be left unmarked")
+	}
+	if len(result.Content) != 1 {
+		t.Errorf("Expected cached content, got %+v", result.Content)
+	}
+
+	// The same key with different arguments is rejected rather than replayed
+	req.Params = jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.org", "idempotencyKey": "retry-1"}}`)
+	response = HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params for a key reused with other arguments, got %+v", response)
+	}
+
+	// The same key on a different tool or in another session is not a replay
+	key := idempotencyKeyArgument("", stored, logger)
+	if idempotencyKeyArgument("", ToolCallParams{Name: "compareEnumerations", Arguments: stored.Arguments}, logger) == key {
+		t.Error("Expected idempotency keys to be scoped per tool")
+	}
+	if idempotencyKeyArgument(NewSession().ID(), stored, logger) == key {
+		t.Error("Expected idempotency keys to be scoped per session")
+	}
+
+	// Results holding API keys are never cached
+	if key := idempotencyKeyArgument("", ToolCallParams{Name: "generateAmassConfig", Arguments: stored.Arguments}, logger); key != "" {
+		t.Errorf("Expected no idempotency key for generateAmassConfig, got %q", key)
+	}
+}
+
+func TestArgumentsHash(t *testing.T) {
+	params := func(args map[string]interface{}) ToolCallParams {
+		return ToolCallParams{Name: "enumerateSubdomains", Arguments: args}
+	}
+	base := argumentsHash(params(map[string]interface{}{"domain": "example.com", "sources": map[string]interface{}{"b": 1.0, "a": 2.0}}))
+	if argumentsHash(params(map[string]interface{}{"sources": map[string]interface{}{"a": 2.0, "b": 1.0}, "domain": "example.com", "idempotencyKey": "k", "comment": "nightly"})) != base {
+		t.Error("Expected the hash to ignore key order, idempotencyKey and comment")
+	}
+	if argumentsHash(params(map[string]interface{}{"domain": "example.com", "sources": map[string]interface{}{"a": 2.0}})) == base {
+		t.Error("Expected different arguments to hash differently")
+	}
+}
+
+func TestCacheKeyGroupsIdempotencyKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	arguments := func(cacheKey interface{}) ToolCallParams {
+		args := map[string]interface{}{"domain": "example.com", "idempotencyKey": "nightly"}
+		if cacheKey != nil {
+			args["cacheKey"] = cacheKey
+		}
+		return ToolCallParams{Name: "enumerateSubdomains", Arguments: args}
+	}
+
+	shared := idempotencyKeyArgument("session", arguments(nil), logger)
+	projectA := idempotencyKeyArgument("session", arguments("project-a"), logger)
+	projectB := idempotencyKeyArgument("session", arguments("project-b"), logger)
+	if shared == projectA || projectA == projectB {
+		t.Errorf("Expected distinct keys per cache group, got %q, %q and %q", shared, projectA, projectB)
+	}
+	if projectA != idempotencyKeyArgument("session", arguments("project-a"), logger) {
+		t.Error("Expected the same cache group to share a key")
+	}
+	if projectA == idempotencyKeyArgument("other-session", arguments("project-a"), logger) {
+		t.Error("Expected a cache group not to be shared across sessions")
+	}
+
+	for _, cacheKey := range []interface{}{"project-a", "team_1.scans"} {
+		if !validCacheKeyArgument(arguments(cacheKey), logger) {
+			t.Errorf("Expected cacheKey %v to be accepted", cacheKey)
+		}
+	}
+	for _, cacheKey := range []interface{}{"", "../etc/passwd", "a/b", ".hidden", "a..b", 42.0, strings.Repeat("a", 129)} {
+		if validCacheKeyArgument(arguments(cacheKey), logger) {
+			t.Errorf("Expected cacheKey %v to be rejected", cacheKey)
+		}
+	}
+}
+
+func TestHandleCacheInvalidateRequiresToken(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "cache.invalidate",
+		ID:      rawMessagePtr("13"),
+		Params:  jsoniter.RawMessage(`{"domain": "example.com"}`),
+	}
+
+	if response := HandleCacheInvalidate(context.Background(), req, logger); response.Error != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized without the API token, got %+v", response)
+	}
+	response := HandleCacheInvalidate(WithBearerAuthorized(context.Background()), req, logger)
+	if _, ok := response.Result.(CacheInvalidateResult); !ok {
+		t.Errorf("Expected the cache to be invalidated with the API token, got %+v", response)
+	}
+}
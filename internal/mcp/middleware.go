@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/logging"
+	"mcp-subfinder-server/internal/stats"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// ToolHandler runs a tools.call request. config holds the shared enumeration
+// options parsed from the call's arguments; it is there for middleware to
+// inspect, since each tool parses its own options when it runs.
+type ToolHandler func(ctx context.Context, req *Request, config subfinder.SubfinderConfig) Response
+
+// ToolMiddleware wraps a tools.call request. It either calls next, possibly
+// with a derived context, or returns a response of its own to stop the call.
+type ToolMiddleware func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response
+
+// namedMiddleware is a registered ToolMiddleware
+type namedMiddleware struct {
+	name string
+	m    ToolMiddleware
+}
+
+var (
+	middlewareMu sync.RWMutex
+	middleware   []namedMiddleware
+)
+
+// RegisterToolMiddleware adds m to every tools.call, after the built-in session,
+// params and idempotency middleware and those already registered. Registering
+// a name again replaces the earlier middleware in its place.
+func RegisterToolMiddleware(name string, m ToolMiddleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	for i := range middleware {
+		if middleware[i].name == name {
+			middleware[i].m = m
+			return
+		}
+	}
+	middleware = append(middleware, namedMiddleware{name: name, m: m})
+}
+
+// UnregisterToolMiddleware removes the middleware registered under name, if any
+func UnregisterToolMiddleware(name string) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	for i := range middleware {
+		if middleware[i].name == name {
+			middleware = append(middleware[:i], middleware[i+1:]...)
+			return
+		}
+	}
+}
+
+// registeredMiddleware returns a snapshot of the registered middleware
+func registeredMiddleware() []ToolMiddleware {
+	middlewareMu.RLock()
+	defer middlewareMu.RUnlock()
+	chain := make([]ToolMiddleware, len(middleware))
+	for i, named := range middleware {
+		chain[i] = named.m
+	}
+	return chain
+}
+
+// toolCallParamsContextKey stores the parsed tools.call params in a context
+type toolCallParamsContextKey struct{}
+
+// ToolCallParamsFromContext returns the params of the tools.call being handled,
+// which are available to every middleware after the built-in params middleware
+func ToolCallParamsFromContext(ctx context.Context) (ToolCallParams, bool) {
+	params, ok := ctx.Value(toolCallParamsContextKey{}).(ToolCallParams)
+	return params, ok
+}
+
+// chainToolMiddleware returns handler wrapped by chain, the first middleware outermost
+func chainToolMiddleware(chain []ToolMiddleware, handler ToolHandler) ToolHandler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		m, next := chain[i], handler
+		handler = func(ctx context.Context, req *Request, config subfinder.SubfinderConfig) Response {
+			return m(ctx, req, config, next)
+		}
+	}
+	return handler
+}
+
+// sessionMiddleware only lets calls through once the client has completed initialize
+func sessionMiddleware(logger *slog.Logger) ToolMiddleware {
+	return func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		if resp, ok := requireInitialized(ctx, req, logger); !ok {
+			return resp
+		}
+		return next(ctx, req, config)
+	}
+}
+
+// paramsMiddleware parses and validates the call's params, then passes them on
+// in the context along with the shared enumeration options
+func paramsMiddleware(providerConfigPath string, logger *slog.Logger) ToolMiddleware {
+	return func(ctx context.Context, req *Request, _ subfinder.SubfinderConfig, next ToolHandler) Response {
+		var params ToolCallParams
+		if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+			logger.Error("Failed to parse tools.call params", "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrParse,
+			}
+		}
+
+		stats.Default.RequestReceived()
+
+		// Record the caller's audit comment alongside what is being called, masked
+		// like the tool's own logs when the caller asks for that
+		domain, _ := params.Arguments["domain"].(string)
+		callLogger := logger
+		if mask, _ := params.Arguments["maskResults"].(bool); mask && domain != "" {
+			callLogger = logging.NewMaskingLogger(logger, domain)
+		}
+		callLogger.Info("Tool call received",
+			"tool", params.Name,
+			"domain", domain,
+			"requestId", requestIDString(req.ID),
+			"comment", commentArgument(params, callLogger))
+
+		if !validCacheKeyArgument(params, callLogger) {
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   ErrInvalidParams,
+			}
+		}
+
+		if err := validateSourceArguments(params.Arguments); err != nil {
+			callLogger.Warn("Invalid source parameter", "error", err)
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   NewInvalidParamsError(err.Error()),
+			}
+		}
+
+		// The tool parses and logs its own options, so these are parsed quietly
+		config := parseEnumerationConfig(params.Arguments, providerConfigPath, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		return next(context.WithValue(ctx, toolCallParamsContextKey{}, params), req, config)
+	}
+}
+
+// idempotencyMiddleware replays the earlier result for a repeated idempotency
+// key within the cache group, and remembers successful results for replay
+func idempotencyMiddleware(logger *slog.Logger) ToolMiddleware {
+	return func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		params, _ := ToolCallParamsFromContext(ctx)
+		idempotencyKey := idempotencyKeyArgument(sessionIDFromContext(ctx), params, logger)
+		if idempotencyKey == "" {
+			return next(ctx, req, config)
+		}
+		arguments := argumentsHash(params)
+
+		if entry, ok := idempotentResults.get(idempotencyKey, time.Now()); ok {
+			// Replaying the earlier result would answer a different question
+			if entry.arguments != arguments {
+				logger.Warn("Idempotency key reused with different arguments", "tool", params.Name)
+				return Response{
+					JSONRPC: "2.0",
+					ID:      req.ID,
+					Error:   NewInvalidParamsError("idempotencyKey was already used with different arguments"),
+				}
+			}
+			cached := entry.result
+			logger.Info("Returning cached result for idempotency key", "tool", params.Name)
+			stats.Default.CacheHit()
+			meta := ToolCallMeta{}
+			if cached.Meta != nil {
+				meta = *cached.Meta
+			}
+			meta.Idempotent = true
+			cached.Meta = &meta
+			cached.Annotations = params.Annotations
+			return Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Result:  cached,
+			}
+		}
+
+		resp := next(ctx, req, config)
+
+		// Only successful results are remembered so failed calls can be retried
+		if resp.Error == nil {
+			if result, ok := resp.Result.(ToolCallResult); ok && !result.IsError {
+				idempotentResults.put(idempotencyKey, callDomains(params), arguments, result, time.Now(), idempotencyTTL())
+			}
+		}
+		return resp
+	}
+}
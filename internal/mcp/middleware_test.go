@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+func TestToolMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var order []string
+	var seenConfig subfinder.SubfinderConfig
+	var seenParams ToolCallParams
+	RegisterToolMiddleware("record", func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		order = append(order, "record")
+		seenConfig = config
+		seenParams, _ = ToolCallParamsFromContext(ctx)
+		return next(ctx, req, config)
+	})
+	RegisterToolMiddleware("deny", func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		order = append(order, "deny")
+		return Response{JSONRPC: "2.0", ID: req.ID, Error: ErrInvalidParams}
+	})
+	defer UnregisterToolMiddleware("record")
+	defer UnregisterToolMiddleware("deny")
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "sourcesFilter": "crtsh"}}`),
+	}
+	response := HandleToolsCall(context.Background(), req, "", logger)
+	if response.Error != ErrInvalidParams {
+		t.Fatalf("Expected the deny middleware to stop the call, got %+v", response)
+	}
+	if len(order) != 2 || order[0] != "record" || order[1] != "deny" {
+		t.Errorf("Expected middleware to run in registration order, got %v", order)
+	}
+	if seenParams.Name != "enumerateSubdomains" || seenConfig.SourcesFilter != "crtsh" {
+		t.Errorf("Expected the parsed params and config, got %+v and %+v", seenParams, seenConfig)
+	}
+
+	// Registering a name again replaces the middleware in place
+	order = nil
+	RegisterToolMiddleware("record", func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		order = append(order, "replaced")
+		return next(ctx, req, config)
+	})
+	HandleToolsCall(context.Background(), req, "", logger)
+	if len(order) != 2 || order[0] != "replaced" {
+		t.Errorf("Expected the replacement to keep its place, got %v", order)
+	}
+
+	// Malformed params never reach registered middleware
+	order = nil
+	req.Params = jsoniter.RawMessage(`{"name": 5}`)
+	if response := HandleToolsCall(context.Background(), req, "", logger); response.Error != ErrParse {
+		t.Errorf("Expected a parse error, got %+v", response)
+	}
+	if len(order) != 0 {
+		t.Errorf("Expected no registered middleware to run, got %v", order)
+	}
+}
+
+func TestParamsMiddlewareMasksResults(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	RegisterToolMiddleware("deny", func(ctx context.Context, req *Request, config subfinder.SubfinderConfig, next ToolHandler) Response {
+		return Response{JSONRPC: "2.0", ID: req.ID, Error: ErrInvalidParams}
+	})
+	defer UnregisterToolMiddleware("deny")
+
+	req := &Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"name": "enumerateSubdomains", "arguments": {"domain": "example.com", "maskResults": true, "comment": "recheck vpn.example.com"}}`),
+	}
+	HandleToolsCall(context.Background(), req, "", logger)
+	if !strings.Contains(logs.String(), "Tool call received") {
+		t.Fatalf("Expected the call to be logged, got %q", logs.String())
+	}
+	if strings.Contains(logs.String(), "vpn.example.com") {
+		t.Errorf("Expected subdomains in the call log to be masked, got %q", logs.String())
+	}
+}
@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+const (
+	// notifyTimeout bounds each notifyWebhookURL delivery
+	notifyTimeout = 3 * time.Second
+	// notifyQueueSize is how many notifications may wait for delivery before new
+	// ones are dropped, so a slow webhook never holds up the enumeration
+	notifyQueueSize = 100
+)
+
+// SubdomainNotification is POSTed to notifyWebhookURL for every subdomain found
+type SubdomainNotification struct {
+	Subdomain    string    `json:"subdomain"`
+	Sources      []string  `json:"sources"`
+	DiscoveredAt time.Time `json:"discoveredAt"`
+	Domain       string    `json:"domain"`
+	JobID        string    `json:"jobId"`
+}
+
+// subdomainNotifier passes a call's result stream on to the caller's stream, if
+// any, and queues a webhook notification for each subdomain event in it
+type subdomainNotifier struct {
+	next       io.Writer
+	webhookURL string
+	domain     string
+	jobID      string
+	logger     *slog.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan SubdomainNotification
+}
+
+// newSubdomainNotifier starts delivering notifications to webhookURL in the
+// background; call Close once the enumeration has finished
+func newSubdomainNotifier(next io.Writer, webhookURL, domain, jobID string, logger *slog.Logger) *subdomainNotifier {
+	n := &subdomainNotifier{
+		next:       next,
+		webhookURL: webhookURL,
+		domain:     domain,
+		jobID:      jobID,
+		logger:     logger,
+		queue:      make(chan SubdomainNotification, notifyQueueSize),
+	}
+	go n.deliver()
+	return n
+}
+
+// Write is called once per NDJSON event by the result stream
+func (n *subdomainNotifier) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		var event subfinder.StreamEvent
+		// Fallback suggestions have no sources and were never discovered
+		if jsoniter.Unmarshal(line, &event) != nil || event.Type != subfinder.StreamEventSubdomain || len(event.Sources) == 0 {
+			continue
+		}
+		notification := SubdomainNotification{
+			Subdomain:    event.Subdomain,
+			Sources:      event.Sources,
+			DiscoveredAt: time.Now().UTC(),
+			Domain:       n.domain,
+			JobID:        n.jobID,
+		}
+		n.enqueue(notification)
+	}
+
+	if n.next == nil {
+		return len(p), nil
+	}
+	written, err := n.next.Write(p)
+	if err == nil {
+		// The result stream only flushes this writer, so pass the flush on
+		if flusher, ok := n.next.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+	}
+	return written, err
+}
+
+// enqueue queues a notification without waiting, dropping it when the queue is full
+func (n *subdomainNotifier) enqueue(notification SubdomainNotification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		n.logger.Warn("Notification queue full, dropping subdomain notification")
+	}
+}
+
+// Close stops accepting notifications; those already queued are still delivered
+func (n *subdomainNotifier) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+}
+
+// deliver POSTs queued notifications one at a time, logging failures
+func (n *subdomainNotifier) deliver() {
+	for notification := range n.queue {
+		if err := n.post(notification); err != nil {
+			// Webhook URLs often embed secrets, so only the reason is logged
+			n.logger.Warn("Failed to deliver subdomain notification", "error", err)
+		}
+	}
+}
+
+// post makes one delivery attempt; any non-2xx status is a failure
+func (n *subdomainNotifier) post(notification SubdomainNotification) error {
+	body, err := jsoniter.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Job-ID", n.jobID)
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
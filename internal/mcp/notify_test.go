@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestSubdomainNotifier(t *testing.T) {
+	received := make(chan SubdomainNotification, 2)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification SubdomainNotification
+		if err := jsoniter.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		if r.Header.Get("X-Job-ID") != "async-1" {
+			t.Errorf("Expected X-Job-ID async-1, got %q", r.Header.Get("X-Job-ID"))
+		}
+		received <- notification
+	}))
+	defer server.Close()
+
+	client := callbackClient
+	callbackClient = server.Client()
+	defer func() { callbackClient = client }()
+
+	var stream bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notifier := newSubdomainNotifier(&stream, server.URL, "example.com", "async-1", logger)
+
+	events := `{"type":"subdomain","subdomain":"www.example.com","sources":["crtsh"]}` + "\n" +
+		`{"type":"subdomain","subdomain":"guess.example.com"}` + "\n" +
+		`{"type":"stats","domain":"example.com","subdomainsFound":1}` + "\n"
+	for _, line := range bytes.SplitAfter([]byte(events), []byte("\n")) {
+		if len(line) > 0 {
+			notifier.Write(line)
+		}
+	}
+	if stream.String() != events {
+		t.Errorf("Expected the events to be passed on, got %q", stream.String())
+	}
+
+	// Writes after the enumeration finished are passed on but not notified
+	notifier.Close()
+	notifier.Write([]byte(events))
+
+	select {
+	case notification := <-received:
+		if notification.Subdomain != "www.example.com" || notification.Domain != "example.com" || notification.JobID != "async-1" ||
+			len(notification.Sources) != 1 || notification.DiscoveredAt.IsZero() {
+			t.Errorf("Unexpected notification %+v", notification)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a notification")
+	}
+	select {
+	case notification := <-received:
+		t.Errorf("Expected only the discovered subdomain to be notified, got %+v", notification)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxRawOutput is the most of subfinder's raw output retainRawOutput returns
+const maxRawOutput = 100 << 10
+
+// rawOutputBuffer keeps the first maxRawOutput bytes written to it and counts
+// the rest, so a large enumeration cannot grow the result without bound
+type rawOutputBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	total int
+}
+
+// Write never fails, so subfinder keeps writing once the limit is reached
+func (b *rawOutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += len(p)
+	if room := maxRawOutput - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// Text returns the retained lines about one of names, noting how much was left
+// out when truncated. Each line is a host, optionally followed by ",[sources]".
+func (b *rawOutputBuffer) Text(names []string) string {
+	keep := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		keep[strings.ToLower(name)] = struct{}{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var text strings.Builder
+	for _, line := range strings.SplitAfter(b.buf.String(), "\n") {
+		host, _, _ := strings.Cut(strings.TrimSpace(line), ",")
+		if _, ok := keep[strings.ToLower(host)]; ok {
+			text.WriteString(line)
+		}
+	}
+	if b.total <= b.buf.Len() {
+		return text.String()
+	}
+	return text.String() + fmt.Sprintf("\n[raw output truncated to %d of %d bytes]", b.buf.Len(), b.total)
+}
@@ -0,0 +1,31 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawOutputBuffer(t *testing.T) {
+	var small rawOutputBuffer
+	small.Write([]byte("www.example.com\n"))
+	small.Write([]byte("api.example.com,[crtsh,alienvault]\n"))
+	small.Write([]byte("secret.example.com\n"))
+	if got := small.Text([]string{"www.example.com", "api.example.com"}); got != "www.example.com\napi.example.com,[crtsh,alienvault]\n" {
+		t.Errorf("Unexpected raw output %q", got)
+	}
+
+	var large rawOutputBuffer
+	line := []byte(strings.Repeat("a", 1011) + ".example.com\n")
+	for i := 0; i < 150; i++ {
+		if n, err := large.Write(line); n != len(line) || err != nil {
+			t.Fatalf("Expected writes past the limit to succeed, got %d, %v", n, err)
+		}
+	}
+	got := large.Text([]string{strings.Repeat("a", 1011) + ".example.com"})
+	if !strings.HasPrefix(got, strings.Repeat(string(line), 100)) {
+		t.Error("Expected the first 100KB to be kept")
+	}
+	if !strings.HasSuffix(got, "\n[raw output truncated to 102400 of 153600 bytes]") {
+		t.Errorf("Expected a truncation note, got %q", got[len(got)-80:])
+	}
+}
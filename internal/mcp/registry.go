@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"log/slog"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// FindTool returns the advertised tool called name, as listed by tools.list
+func FindTool(name string) (*Tool, bool) {
+	for _, tool := range availableTools() {
+		if tool.Name == name {
+			return &tool, true
+		}
+	}
+	return nil, false
+}
+
+// HandleToolsGet processes a tools.get request, returning a single tool definition
+func HandleToolsGet(req *Request, logger *slog.Logger) Response {
+	var params ToolsGetParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse tools.get params", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+
+	tool, ok := FindTool(params.Name)
+	if !ok {
+		logger.Warn("Tool not found", "requestedTool", params.Name)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  tool,
+	}
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestFindTool(t *testing.T) {
+	tool, ok := FindTool("enumerateSubdomains")
+	if !ok || tool.Name != "enumerateSubdomains" || tool.InputSchema == nil {
+		t.Fatalf("Expected enumerateSubdomains with a schema, got %+v", tool)
+	}
+	if _, ok := FindTool("missingTool"); ok {
+		t.Error("Expected no tool for an unknown name")
+	}
+}
+
+func TestHandleToolsGet(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	req := &Request{JSONRPC: "2.0", Method: "tools.get", ID: rawMessagePtr("1"), Params: jsoniter.RawMessage(`{"name": "compareEnumerations"}`)}
+	response := HandleToolsGet(req, logger)
+	if tool, ok := response.Result.(*Tool); !ok || tool.Name != "compareEnumerations" {
+		t.Errorf("Expected the compareEnumerations tool, got %+v", response)
+	}
+
+	req.Params = jsoniter.RawMessage(`{"name": "missingTool"}`)
+	response = HandleToolsGet(req, logger)
+	if response.Error == nil || response.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params for an unknown tool, got %+v", response.Error)
+	}
+}
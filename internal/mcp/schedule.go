@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/net/idna"
+	"mcp-subfinder-server/internal/plugin"
+	"mcp-subfinder-server/internal/scheduler"
+)
+
+var (
+	schedulerOnce sync.Once
+	jobScheduler  *scheduler.Scheduler
+)
+
+// getScheduler returns the process-wide scheduler, creating it on first use
+func getScheduler(logger *slog.Logger) *scheduler.Scheduler {
+	schedulerOnce.Do(func() {
+		jobScheduler = scheduler.New(scheduler.NewMemoryStore(), func(job scheduler.JobInfo, added []string) {
+			notifyNewSubdomains(job, added, logger)
+		}, logger)
+	})
+	return jobScheduler
+}
+
+// StopScheduler cancels every scheduled job; it is a no-op if none were ever created
+func StopScheduler() {
+	if jobScheduler != nil {
+		jobScheduler.Stop()
+	}
+}
+
+// notifyNewSubdomains emits a notifications/message event for subdomains a job discovered.
+// The HTTP transport has no server-initiated channel, so events go to the server log.
+func notifyNewSubdomains(job scheduler.JobInfo, added []string, logger *slog.Logger) {
+	notification := Notification{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params: MessageParams{
+			Level:  "info",
+			Logger: "scheduler",
+			Data: map[string]interface{}{
+				"jobId":         job.ID,
+				"domain":        job.Domain,
+				"newSubdomains": added,
+			},
+		},
+	}
+	logger.Info("Scheduled job found new subdomains",
+		"jobId", job.ID,
+		"domain", job.Domain,
+		"notification", notification)
+}
+
+// HandleScheduleCreate processes a schedule.create request
+func HandleScheduleCreate(ctx context.Context, req *Request, providerConfigPath string, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+
+	var params ScheduleCreateParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse schedule.create params", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+
+	domain := strings.TrimSpace(params.Domain)
+	interval, err := time.ParseDuration(params.Interval)
+	if domain == "" || err != nil || interval < scheduler.MinInterval {
+		logger.Warn("Invalid schedule.create params",
+			"domain", params.Domain,
+			"interval", params.Interval,
+			"minInterval", scheduler.MinInterval.String())
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+	// Jobs run against the ASCII form, which is what the scheduler validates
+	if err := validateDomainArgument(domain); err != nil {
+		logger.Warn("Invalid schedule.create domain", "providedDomain", domain, "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewDomainValidationError(domain, err.Error()),
+		}
+	}
+	domain, _ = idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+
+	// Each run parses the stored arguments the same way tools.call does
+	args := params.Params
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	if err := validateSourceArguments(args); err != nil {
+		logger.Warn("Invalid schedule.create source parameter", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError(err.Error()),
+		}
+	}
+	run := func(ctx context.Context) ([]string, error) {
+		config := parseEnumerationConfig(args, providerConfigPath, logger)
+		result, err := plugin.Enumerate(ctx, domain, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		// Suggested fallback names were never reported by a source, so they are not discoveries
+		confirmed := make([]string, 0, len(result.Subdomains))
+		for _, subdomain := range result.Subdomains {
+			if _, ok := result.Sources[subdomain]; ok {
+				confirmed = append(confirmed, subdomain)
+			}
+		}
+		return confirmed, nil
+	}
+
+	job, err := getScheduler(logger).Create(scheduler.JobSpec{
+		Domain:   domain,
+		Interval: interval,
+		Owner:    sessionIDFromContext(ctx),
+		Params:   params.Params,
+		Run:      run,
+	})
+	if errors.Is(err, scheduler.ErrTooManyJobs) {
+		logger.Warn("Too many scheduled jobs, rejecting schedule.create",
+			"maxJobsPerSession", scheduler.MaxJobsPerOwner,
+			"maxJobs", scheduler.MaxJobs)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   NewInvalidParamsError(fmt.Sprintf("too many scheduled jobs: at most %d per session and %d in total; delete one first", scheduler.MaxJobsPerOwner, scheduler.MaxJobs)),
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to create scheduled job", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInternal,
+		}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  job,
+	}
+}
+
+// HandleScheduleList processes a schedule.list request
+func HandleScheduleList(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ScheduleListResult{Jobs: getScheduler(logger).List(sessionIDFromContext(ctx))},
+	}
+}
+
+// HandleScheduleDelete processes a schedule.delete request
+func HandleScheduleDelete(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+
+	var params ScheduleJobParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse schedule.delete params", "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+
+	if err := getScheduler(logger).Delete(sessionIDFromContext(ctx), params.ID); err != nil {
+		logger.Warn("Failed to delete scheduled job", "jobId", params.ID, "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{"deleted": true, "id": params.ID},
+	}
+}
+
+// HandleSchedulePause processes a schedule.pause request
+func HandleSchedulePause(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	return handleSchedulePaused(ctx, req, true, logger)
+}
+
+// HandleScheduleResume processes a schedule.resume request
+func HandleScheduleResume(ctx context.Context, req *Request, logger *slog.Logger) Response {
+	return handleSchedulePaused(ctx, req, false, logger)
+}
+
+// handleSchedulePaused pauses or resumes the job named in the request and returns its new state
+func handleSchedulePaused(ctx context.Context, req *Request, paused bool, logger *slog.Logger) Response {
+	if resp, ok := requireInitialized(ctx, req, logger); !ok {
+		return resp
+	}
+
+	var params ScheduleJobParams
+	if err := jsoniter.Unmarshal(req.Params, &params); err != nil {
+		logger.Error("Failed to parse params", "method", req.Method, "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrParse,
+		}
+	}
+
+	update := getScheduler(logger).Resume
+	if paused {
+		update = getScheduler(logger).Pause
+	}
+	job, err := update(sessionIDFromContext(ctx), params.ID)
+	if err != nil {
+		logger.Warn("Failed to update scheduled job", "jobId", params.ID, "paused", paused, "error", err)
+		return Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   ErrInvalidParams,
+		}
+	}
+
+	return Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  job,
+	}
+}
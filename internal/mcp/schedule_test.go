@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/scheduler"
+)
+
+func TestScheduleMethods(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	call := func(method, params string) Response {
+		req := Request{JSONRPC: "2.0", ID: rawMessagePtr("1"), Method: method}
+		if params != "" {
+			req.Params = jsoniter.RawMessage(params)
+		}
+		return ProcessSingleRequest(ctx, req, "", logger)
+	}
+
+	// Intervals must parse and be at least a minute, and domains must be valid
+	for _, params := range []string{
+		`{"domain": "example.com", "interval": "soon"}`,
+		`{"domain": "example.com", "interval": "5s"}`,
+		`{"interval": "1h"}`,
+		`{"domain": "-example.com", "interval": "1h"}`,
+		`{"domain": "example.com", "interval": "1h", "params": {"prioritizeSources": ["bogus"]}}`,
+	} {
+		if resp := call("schedule.create", params); resp.Error == nil || resp.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %s, got %+v", params, resp.Error)
+		}
+	}
+
+	resp := call("schedule.create", `{"domain": "example.com", "interval": "1h", "params": {"timeout": 30}}`)
+	if resp.Error != nil {
+		t.Fatalf("Expected job to be created, got %+v", resp.Error)
+	}
+	job, ok := resp.Result.(scheduler.JobInfo)
+	if !ok || job.ID == "" || job.Domain != "example.com" || job.Interval != "1h0m0s" {
+		t.Fatalf("Unexpected job: %+v", resp.Result)
+	}
+
+	resp = call("schedule.list", "")
+	list, ok := resp.Result.(ScheduleListResult)
+	if !ok {
+		t.Fatalf("Expected a ScheduleListResult, got %T", resp.Result)
+	}
+	found := false
+	for _, listed := range list.Jobs {
+		if listed.ID == job.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected %s in %+v", job.ID, list.Jobs)
+	}
+
+	// Pausing and resuming report the job's new state
+	resp = call("schedule.pause", `{"id": "`+job.ID+`"}`)
+	if paused, ok := resp.Result.(scheduler.JobInfo); !ok || !paused.Paused || paused.ID != job.ID {
+		t.Errorf("Expected a paused job, got %+v (error %+v)", resp.Result, resp.Error)
+	}
+	resp = call("schedule.resume", `{"id": "`+job.ID+`"}`)
+	if resumed, ok := resp.Result.(scheduler.JobInfo); !ok || resumed.Paused {
+		t.Errorf("Expected a resumed job, got %+v (error %+v)", resp.Result, resp.Error)
+	}
+	if resp := call("schedule.pause", `{"id": "job-missing"}`); resp.Error == nil || resp.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params when pausing an unknown job, got %+v", resp.Error)
+	}
+
+	// Another session neither sees the job nor can delete it
+	other := NewSession()
+	other.SetState(SessionReady)
+	otherCtx := WithSession(ctx, other)
+	resp = ProcessSingleRequest(otherCtx, Request{JSONRPC: "2.0", ID: rawMessagePtr("1"), Method: "schedule.list"}, "", logger)
+	if list, ok := resp.Result.(ScheduleListResult); !ok || len(list.Jobs) != 0 {
+		t.Errorf("Expected no jobs for another session, got %+v", resp.Result)
+	}
+	resp = ProcessSingleRequest(otherCtx, Request{JSONRPC: "2.0", ID: rawMessagePtr("1"), Method: "schedule.delete", Params: jsoniter.RawMessage(`{"id": "` + job.ID + `"}`)}, "", logger)
+	if resp.Error == nil || resp.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params deleting another session's job, got %+v", resp.Error)
+	}
+	for _, method := range []string{"schedule.pause", "schedule.resume"} {
+		resp = ProcessSingleRequest(otherCtx, Request{JSONRPC: "2.0", ID: rawMessagePtr("1"), Method: method, Params: jsoniter.RawMessage(`{"id": "` + job.ID + `"}`)}, "", logger)
+		if resp.Error == nil || resp.Error.Code != InvalidParamsCode {
+			t.Errorf("Expected invalid params for %s of another session's job, got %+v", method, resp.Error)
+		}
+	}
+
+	if resp := call("schedule.delete", `{"id": "`+job.ID+`"}`); resp.Error != nil {
+		t.Errorf("Expected delete to succeed, got %+v", resp.Error)
+	}
+	if resp := call("schedule.delete", `{"id": "`+job.ID+`"}`); resp.Error == nil || resp.Error.Code != InvalidParamsCode {
+		t.Errorf("Expected invalid params for an unknown job, got %+v", resp.Error)
+	}
+
+	// Scheduling requires a completed handshake like tools.call
+	uninitialized := WithSession(ctx, NewSession())
+	req := Request{JSONRPC: "2.0", ID: rawMessagePtr("2"), Method: "schedule.list"}
+	if resp := ProcessSingleRequest(uninitialized, req, "", logger); resp.Error == nil || resp.Error.Code != ServerNotInitializedCode {
+		t.Errorf("Expected not initialized error, got %+v", resp.Error)
+	}
+}
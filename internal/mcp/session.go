@@ -0,0 +1,249 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionIDHeader lets stateless HTTP clients share one session across connections
+const SessionIDHeader = "X-Session-Id"
+
+const (
+	// sessionIdleTimeout is how long an unused session is kept in the registry
+	sessionIdleTimeout = time.Hour
+	// sessionSweepInterval is how often the registry looks for idle sessions
+	sessionSweepInterval = time.Minute
+	// MaxSessions bounds how many client-chosen sessions the registry holds
+	MaxSessions = 10000
+	// MaxSessionIDLength is the longest client-chosen session ID accepted
+	MaxSessionIDLength = 128
+)
+
+var (
+	// ErrInvalidSessionID is returned for a session ID that is empty, longer than
+	// MaxSessionIDLength or uses characters outside [A-Za-z0-9._-]
+	ErrInvalidSessionID = errors.New("invalid session ID")
+	// ErrTooManySessions is returned when the registry already holds MaxSessions
+	// sessions that are still in use
+	ErrTooManySessions = errors.New("too many sessions")
+)
+
+// SessionState tracks where a client is in the initialize/initialized handshake
+type SessionState int
+
+const (
+	// SessionUninitialized means initialize has not succeeded yet
+	SessionUninitialized SessionState = iota
+	// SessionInitializing means initialize succeeded but the initialized notification is pending
+	SessionInitializing
+	// SessionReady means the client completed the full handshake
+	SessionReady
+)
+
+// String returns a readable name for the state
+func (s SessionState) String() string {
+	switch s {
+	case SessionUninitialized:
+		return "uninitialized"
+	case SessionInitializing:
+		return "initializing"
+	case SessionReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// sessionContextKey is the context key under which the active Session is stored
+type sessionContextKey struct{}
+
+// Session holds client state captured during the initialize handshake.
+// HTTP servers attach one per connection so state survives across requests.
+type Session struct {
+	// id is random, but a session registered under a client-chosen X-Session-Id is
+	// shared by every client sending that header
+	id              string
+	mu              sync.RWMutex
+	clientInfo      ClientInfo
+	protocolVersion string
+	state           SessionState
+	lastSeen        time.Time
+}
+
+// NewSession creates an empty, uninitialized session
+func NewSession() *Session {
+	return &Session{id: uuid.NewString(), lastSeen: time.Now()}
+}
+
+// ID returns the session's server-assigned identifier
+func (s *Session) ID() string {
+	return s.id
+}
+
+// SetState moves the session to a new handshake state
+func (s *Session) SetState(state SessionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// State returns the current handshake state
+func (s *Session) State() SessionState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// SetClientInfo records the client identity announced in initialize
+func (s *Session) SetClientInfo(info ClientInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientInfo = info
+}
+
+// ClientInfo returns the client identity announced in initialize
+func (s *Session) ClientInfo() ClientInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+// SetProtocolVersion records the protocol version negotiated in initialize
+func (s *Session) SetProtocolVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+}
+
+// ProtocolVersion returns the negotiated protocol version, or an empty string before initialize
+func (s *Session) ProtocolVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion
+}
+
+// WithSession returns a copy of ctx carrying the given session
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, s)
+}
+
+// SessionFromContext returns the session stored in ctx, or nil if there is none
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return s
+}
+
+// bearerAuthorizedContextKey marks a request that carried the server's API token
+type bearerAuthorizedContextKey struct{}
+
+// WithBearerAuthorized returns a copy of ctx marking the request as carrying the
+// server's API token, which administrative methods such as cache.invalidate require
+func WithBearerAuthorized(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bearerAuthorizedContextKey{}, true)
+}
+
+// bearerAuthorizedFromContext reports whether ctx was marked by WithBearerAuthorized
+func bearerAuthorizedFromContext(ctx context.Context) bool {
+	authorized, _ := ctx.Value(bearerAuthorizedContextKey{}).(bool)
+	return authorized
+}
+
+// clientInfoFromContext returns the client identity stored in ctx's session, if any
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	if s := SessionFromContext(ctx); s != nil {
+		return s.ClientInfo()
+	}
+	return ClientInfo{}
+}
+
+// protocolVersionFromContext returns the negotiated protocol version, defaulting to SupportedProtocolVersion
+func protocolVersionFromContext(ctx context.Context) string {
+	if s := SessionFromContext(ctx); s != nil {
+		if version := s.ProtocolVersion(); version != "" {
+			return version
+		}
+	}
+	return SupportedProtocolVersion
+}
+
+// SessionRegistry maps client-chosen session IDs to sessions for stateless HTTP clients
+type SessionRegistry struct {
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+	max       int
+}
+
+// NewSessionRegistry creates an empty registry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*Session), lastSweep: time.Now(), max: MaxSessions}
+}
+
+// DefaultSessions is the registry used by the HTTP handlers
+var DefaultSessions = NewSessionRegistry()
+
+// GetOrCreate returns the session registered under id, creating it on first use.
+// Sessions idle for longer than sessionIdleTimeout are evicted by a sweep that
+// runs at most every sessionSweepInterval. It fails
+// with ErrInvalidSessionID for a malformed id, and with ErrTooManySessions when
+// no room is left for a new one.
+func (r *SessionRegistry) GetOrCreate(id string) (*Session, error) {
+	if !validSessionID(id) {
+		return nil, ErrInvalidSessionID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastSweep) >= sessionSweepInterval {
+		r.sweep(now)
+	}
+
+	session, ok := r.sessions[id]
+	if !ok {
+		if len(r.sessions) >= r.max {
+			return nil, ErrTooManySessions
+		}
+		session = NewSession()
+		r.sessions[id] = session
+	}
+
+	session.mu.Lock()
+	session.lastSeen = now
+	session.mu.Unlock()
+
+	return session, nil
+}
+
+// sweep evicts the sessions idle for longer than sessionIdleTimeout; r.mu must be held
+func (r *SessionRegistry) sweep(now time.Time) {
+	r.lastSweep = now
+	for key, session := range r.sessions {
+		session.mu.RLock()
+		idle := now.Sub(session.lastSeen)
+		session.mu.RUnlock()
+		if idle > sessionIdleTimeout {
+			delete(r.sessions, key)
+		}
+	}
+}
+
+// validSessionID reports whether id is a usable client-chosen session ID
+func validSessionID(id string) bool {
+	if id == "" || len(id) > MaxSessionIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	session := NewSession()
+	ctx := WithSession(context.Background(), session)
+
+	toolCall := Request{
+		JSONRPC: "2.0",
+		Method:  "tools.call",
+		ID:      rawMessagePtr("1"),
+		Params:  jsoniter.RawMessage(`{"name": "nonExistentTool", "arguments": {}}`),
+	}
+
+	// Calling a tool before initialize is rejected
+	response := ProcessSingleRequest(ctx, toolCall, "", logger)
+	if response.Error == nil || response.Error.Code != ServerNotInitializedCode {
+		t.Fatalf("Expected error code %d before initialize, got %+v", ServerNotInitializedCode, response.Error)
+	}
+
+	initialize := Request{
+		JSONRPC: "2.0",
+		Method:  "initialize",
+		ID:      rawMessagePtr("2"),
+		Params:  jsoniter.RawMessage(`{"protocolVersion": "0.3"}`),
+	}
+	if response := ProcessSingleRequest(ctx, initialize, "", logger); response.Error != nil {
+		t.Fatalf("Expected initialize to succeed, got %+v", response.Error)
+	}
+	if session.State() != SessionInitializing {
+		t.Errorf("Expected state %s after initialize, got %s", SessionInitializing, session.State())
+	}
+
+	// After initialize the call reaches tool lookup
+	response = ProcessSingleRequest(ctx, toolCall, "", logger)
+	if response.Error == nil || response.Error.Code != MethodNotFoundCode {
+		t.Errorf("Expected error code %d after initialize, got %+v", MethodNotFoundCode, response.Error)
+	}
+
+	initialized := Request{JSONRPC: "2.0", Method: "notifications/initialized"}
+	ProcessSingleRequest(ctx, initialized, "", logger)
+	if session.State() != SessionReady {
+		t.Errorf("Expected state %s after initialized notification, got %s", SessionReady, session.State())
+	}
+}
+
+func TestSessionRegistry(t *testing.T) {
+	registry := NewSessionRegistry()
+
+	first, err := registry.GetOrCreate("abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first.SetState(SessionReady)
+
+	if again, _ := registry.GetOrCreate("abc"); again != first {
+		t.Errorf("Expected the same session for a repeated ID")
+	}
+	if other, _ := registry.GetOrCreate("def"); other == first || other.State() != SessionUninitialized {
+		t.Errorf("Expected a fresh uninitialized session for a new ID")
+	}
+
+	// Malformed IDs are rejected before they reach the registry
+	for _, id := range []string{"", "has space", "semi;colon", "ünicode", strings.Repeat("a", MaxSessionIDLength+1)} {
+		if _, err := registry.GetOrCreate(id); !errors.Is(err, ErrInvalidSessionID) {
+			t.Errorf("Expected ErrInvalidSessionID for %q, got %v", id, err)
+		}
+	}
+	if _, err := registry.GetOrCreate("client-1.session_A"); err != nil {
+		t.Errorf("Expected a valid ID to be accepted, got %v", err)
+	}
+
+	// A full registry only accepts new sessions once idle ones are swept
+	registry.max = len(registry.sessions)
+	if _, err := registry.GetOrCreate("ghi"); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("Expected ErrTooManySessions, got %v", err)
+	}
+	if _, err := registry.GetOrCreate("abc"); err != nil {
+		t.Errorf("Expected an existing session to be returned when full, got %v", err)
+	}
+	first.mu.Lock()
+	first.lastSeen = time.Now().Add(-2 * sessionIdleTimeout)
+	first.mu.Unlock()
+	registry.lastSweep = time.Now().Add(-sessionSweepInterval)
+	if _, err := registry.GetOrCreate("ghi"); err != nil {
+		t.Errorf("Expected the idle session to make room, got %v", err)
+	}
+	if _, ok := registry.sessions["abc"]; ok {
+		t.Error("Expected the idle session to be evicted")
+	}
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+)
+
+// ServerSettings holds server-wide options chosen at startup, typically from command-line flags
+type ServerSettings struct {
+	// AllowBruteForce enables the wildcardSubdomainBrute tool, which actively queries the target's DNS
+	AllowBruteForce bool
+	// AllowCustomTrustAnchors lets probeTLS callers supply their own CA certificates
+	AllowCustomTrustAnchors bool
+	// IdempotencyTTL is how long results are replayed for a repeated idempotencyKey; zero uses the default
+	IdempotencyTTL time.Duration
+	// LockWaitTimeout is how long an enumeration waits for a running one on the same domain; zero uses the default
+	LockWaitTimeout time.Duration
+	// MaxBackgroundJobs caps the callbackURL and deferred enumerations running at
+	// once; zero uses DefaultMaxBackgroundJobs
+	MaxBackgroundJobs int
+	// WordlistDir is the only directory wildcardSubdomainBrute reads wordlistPath
+	// from; when empty, only wordlistURL is accepted
+	WordlistDir string
+	// MaxRecursiveDepth caps the maxDepth of every call; zero uses DefaultMaxRecursiveDepth
+	MaxRecursiveDepth int
+}
+
+const (
+	// DefaultMaxRecursiveDepth is the maxDepth cap when none is configured
+	DefaultMaxRecursiveDepth = 3
+	// MaxRecursiveDepthLimit is the highest cap that can be configured
+	MaxRecursiveDepthLimit = 5
+	// MaxEnumerationTimeout is the longest timeout, in seconds, any enumeration may ask for
+	MaxEnumerationTimeout = 600
+)
+
+var (
+	settingsMu sync.RWMutex
+	settings   ServerSettings
+)
+
+// Configure replaces the server-wide settings
+func Configure(s ServerSettings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = s
+}
+
+// maxRecursiveDepth returns the configured maxDepth cap, or the default
+func maxRecursiveDepth() int {
+	if depth := currentSettings().MaxRecursiveDepth; depth > 0 {
+		return min(depth, MaxRecursiveDepthLimit)
+	}
+	return DefaultMaxRecursiveDepth
+}
+
+// currentSettings returns the server-wide settings
+func currentSettings() ServerSettings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
+}
@@ -0,0 +1,23 @@
+package mcp
+
+import (
+	"context"
+	"io"
+)
+
+// StreamContentType is the Accept/Content-Type value that selects NDJSON streaming
+const StreamContentType = "application/x-ndjson"
+
+// streamWriterContextKey is the context key under which a streaming writer is stored
+type streamWriterContextKey struct{}
+
+// WithStreamWriter returns a copy of ctx asking tool calls to stream results to w
+func WithStreamWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, streamWriterContextKey{}, w)
+}
+
+// streamWriterFromContext returns the streaming writer stored in ctx, or nil
+func streamWriterFromContext(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(streamWriterContextKey{}).(io.Writer)
+	return w
+}
@@ -2,15 +2,34 @@
 package mcp
 
 import (
+	"time"
+
 	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/scheduler"
+	"mcp-subfinder-server/internal/subfinder"
 )
 
 // Protocol versions
 const (
-	// SupportedProtocolVersion is the MCP protocol version this server supports
+	// SupportedProtocolVersion is the default MCP protocol version this server speaks
 	SupportedProtocolVersion = "0.3"
+	// ProtocolVersion20241105 is the dated MCP revision that only allows text content in tool results
+	ProtocolVersion20241105 = "2024-11-05"
 )
 
+// SupportedProtocolVersions lists every MCP protocol version accepted during initialize
+var SupportedProtocolVersions = []string{SupportedProtocolVersion, ProtocolVersion20241105}
+
+// isSupportedProtocolVersion reports whether version is listed in SupportedProtocolVersions
+func isSupportedProtocolVersion(version string) bool {
+	for _, v := range SupportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // Common JSON-RPC 2.0 structures
 // =============================
 
@@ -22,6 +41,23 @@ type Request struct {
 	Params  jsoniter.RawMessage  `json:"params,omitempty"`
 }
 
+// UnmarshalJSON keeps an explicit "id": null rather than dropping it, since that
+// is still a request whose response must echo the null; only a request without
+// an id is a notification
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type request Request
+	var decoded request
+	if err := jsoniter.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.ID == nil && jsoniter.Get(data, "id").ValueType() == jsoniter.NilValue {
+		null := jsoniter.RawMessage("null")
+		decoded.ID = &null
+	}
+	*r = Request(decoded)
+	return nil
+}
+
 // Response represents a JSON-RPC 2.0 response
 type Response struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -51,6 +87,24 @@ const (
 	InternalErrorCode = -32603
 )
 
+// Server-defined JSON-RPC error codes
+const (
+	// ServerNotInitializedCode indicates a request arrived before the initialize handshake
+	ServerNotInitializedCode = -32002
+	// ServerOverloadedCode indicates the tools.call queue is full or a request waited too long in it
+	ServerOverloadedCode = -32027
+	// EnumerationInProgressCode indicates another enumeration of the same domain is still running
+	EnumerationInProgressCode = -32028
+	// SubscriptionsNotSupportedCode indicates a resources.subscribe request, which the server does not implement
+	SubscriptionsNotSupportedCode = -32004
+	// RateLimitedCode indicates the client sent too many requests and should retry later
+	RateLimitedCode = -32029
+	// EnumerationTimeoutCode indicates an enumeration ran out of time before producing a result
+	EnumerationTimeoutCode = -32030
+	// UnauthorizedCode indicates an administrative method was called without the server's API token
+	UnauthorizedCode = -32031
+)
+
 // Standard RPC error instances for reuse
 var (
 	// ErrParse is returned when invalid JSON was received by the server
@@ -63,21 +117,47 @@ var (
 	ErrInvalidParams = &RPCError{Code: InvalidParamsCode, Message: "Invalid params"}
 	// ErrInternal is returned when there was an internal JSON-RPC error
 	ErrInternal = &RPCError{Code: InternalErrorCode, Message: "Internal error"}
+	// ErrServerNotInitialized is returned when a tool is called before initialize
+	ErrServerNotInitialized = &RPCError{Code: ServerNotInitializedCode, Message: "Server not initialized"}
+	// ErrServerOverloaded is returned when a tools.call cannot be queued or leaves the queue too late
+	ErrServerOverloaded = &RPCError{Code: ServerOverloadedCode, Message: "Server overloaded"}
+	// ErrUnauthorized is returned when an administrative method lacks the server's API token
+	ErrUnauthorized = &RPCError{Code: UnauthorizedCode, Message: "Unauthorized"}
+	// ErrSubscriptionsNotSupported is returned for resources.subscribe
+	ErrSubscriptionsNotSupported = &RPCError{Code: SubscriptionsNotSupportedCode, Message: "Internal error", Data: "Subscriptions not yet supported"}
 )
 
 // MCP-specific structures
 // ======================
 
+// ClientInfo identifies the MCP client that sent the initialize request
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
 // InitializeParams represents parameters for initialize method
 type InitializeParams struct {
-	ProtocolVersion string `json:"protocolVersion"`
+	ProtocolVersion string     `json:"protocolVersion"`
+	ClientInfo      ClientInfo `json:"clientInfo"`
 }
 
 // InitializeResult represents the result of initialize method
 type InitializeResult struct {
-	Name            string `json:"name"`
-	Version         string `json:"version"`
-	ProtocolVersion string `json:"protocolVersion"`
+	Name            string             `json:"name"`
+	Version         string             `json:"version"`
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities announces which optional MCP features the server supports
+type ServerCapabilities struct {
+	Resources ResourcesCapability `json:"resources"`
+}
+
+// ResourcesCapability describes resource support; subscriptions are not implemented
+type ResourcesCapability struct {
+	Subscribe bool `json:"subscribe"`
 }
 
 // Tool represents a tool available via the MCP protocol
@@ -95,11 +175,18 @@ type ToolsListResult struct {
 	Tools []Tool `json:"tools"`
 }
 
+// ToolsGetParams represents parameters for the tools.get method
+type ToolsGetParams struct {
+	Name string `json:"name"`
+}
+
 // ToolCallParams represents parameters for tools.call method
 type ToolCallParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
 	Binary    []byte                 `json:"binary,omitempty"`
+	// Annotations is caller metadata echoed back in the result without interpretation
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
 }
 
 // ContentItem represents a text content item
@@ -120,4 +207,162 @@ type ResourceItem struct {
 type ToolCallResult struct {
 	Content []interface{} `json:"content"`
 	IsError bool          `json:"isError,omitempty"`
+	Meta    *ToolCallMeta `json:"_meta,omitempty"`
+	// Annotations echoes ToolCallParams.Annotations for correlation by the caller
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// AsyncToolCallResult acknowledges a tools.call given a callbackURL; the full
+// ToolCallResult is POSTed to the callback once the job finishes
+type AsyncToolCallResult struct {
+	Async bool   `json:"async"`
+	JobID string `json:"jobId"`
+}
+
+// JobsGetParams represents parameters for the jobs.get method
+type JobsGetParams struct {
+	JobID string `json:"jobId"`
+}
+
+// JobStatusResult reports a deferred job that has not finished yet; it is also the
+// immediate result of a deferred tools.call
+type JobStatusResult struct {
+	JobID  string `json:"jobId,omitempty"`
+	Status string `json:"status"`
+	// SubdomainsFoundSoFar is only reported while the job is running
+	SubdomainsFoundSoFar *int `json:"subdomainsFoundSoFar,omitempty"`
+}
+
+// JobSummary is one deferred job in the GET /mcp/jobs listing
+type JobSummary struct {
+	JobID       string     `json:"jobId"`
+	Domain      string     `json:"domain"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"startedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// SubdomainsFound counts the subdomains streamed by the job so far
+	SubdomainsFound int `json:"subdomainsFound"`
+}
+
+// EnumerationOutput is the value an outputTemplate is rendered against
+type EnumerationOutput struct {
+	Domain     string
+	Subdomains []subfinder.SubdomainEntry
+	Stats      ToolCallMeta
+	Timestamp  time.Time
+}
+
+// ToolCallMeta carries metadata about how a tool call result was produced
+type ToolCallMeta struct {
+	// Idempotent is set when the result was replayed for a repeated idempotency key
+	Idempotent bool `json:"idempotent,omitempty"`
+	// TotalSources and TotalErrors summarize the passive sources queried; omitted when zero
+	TotalSources int `json:"totalSources,omitempty"`
+	TotalErrors  int `json:"totalErrors,omitempty"`
+	// Warning explains anything that limited the call, such as a reduced timeout
+	Warning string `json:"warning,omitempty"`
+	// ProviderStatus lists how each passive source fared when includeProviderStatus is set
+	ProviderStatus []ProviderStatus `json:"providerStatus,omitempty"`
+	// Alias is the caller's human-friendly name for the enumerated domain, from domainAlias
+	Alias string `json:"alias,omitempty"`
+	// WildcardIPs and WildcardFiltered report the wildcard DNS addresses found by
+	// autoExpandWildcard and how many results resolving only to them were dropped
+	WildcardIPs      []string `json:"wildcardIPs,omitempty"`
+	WildcardFiltered int      `json:"wildcardFiltered,omitempty"`
+	// TotalBeforeDedup is the number of resolved results before uniqueByIP collapsed them
+	TotalBeforeDedup int `json:"totalBeforeDedup,omitempty"`
+	// RegexMatchCount and RegexExcludeCount are how many results filterByRegex kept
+	// and excludeByRegex dropped; each is only set when its filter was given
+	RegexMatchCount   *int `json:"regexMatchCount,omitempty"`
+	RegexExcludeCount *int `json:"regexExcludeCount,omitempty"`
+	// TotalFilteredByLength is how many results min/maxSubdomainLength dropped;
+	// it is only set when either limit was given
+	TotalFilteredByLength *int `json:"totalFilteredByLength,omitempty"`
+	// SensitiveCount is how many listed subdomains sensitivePatternAlert flagged;
+	// it is only set when the alert was requested
+	SensitiveCount *int `json:"sensitiveCount,omitempty"`
+	// AverageTrustScore is the mean trust score of the listed subdomains, and
+	// HighConfidenceCount how many of them scored at least subfinder.HighTrustScore
+	AverageTrustScore   float64 `json:"averageTrustScore,omitempty"`
+	HighConfidenceCount int     `json:"highConfidenceCount,omitempty"`
+	// IdleSubdomains lists the subdomains httpProbe got no answer from, and IdleCount
+	// how many there are; they are listed even when excludeIdle drops them
+	IdleSubdomains []string `json:"idleSubdomains,omitempty"`
+	IdleCount      int      `json:"idleCount,omitempty"`
+	// NotifyJobID is the jobId sent with every notifyWebhookURL notification
+	NotifyJobID string `json:"notifyJobId,omitempty"`
+	// EffectiveMaxDepth is the maxDepth the enumeration ran with, after the server's cap
+	EffectiveMaxDepth int `json:"effectiveMaxDepth,omitempty"`
+}
+
+// ProviderStatus reports whether a passive source returned data during one call
+type ProviderStatus struct {
+	Name         string `json:"name"`
+	ResultsCount int    `json:"resultsCount"`
+	HadErrors    bool   `json:"hadErrors"`
+}
+
+// ScheduleCreateParams represents parameters for the schedule.create method
+type ScheduleCreateParams struct {
+	Domain   string                 `json:"domain"`
+	Interval string                 `json:"interval"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+}
+
+// ScheduleJobParams identifies a scheduled job, as taken by schedule.delete, schedule.pause and schedule.resume
+type ScheduleJobParams struct {
+	ID string `json:"id"`
+}
+
+// CacheInvalidateParams represents parameters for the cache.invalidate method
+type CacheInvalidateParams struct {
+	Domain string `json:"domain"`
+}
+
+// CacheInvalidateResult reports how many cached results cache.invalidate removed
+type CacheInvalidateResult struct {
+	Evicted int `json:"evicted"`
+}
+
+// ScheduleListResult represents the result of the schedule.list method
+type ScheduleListResult struct {
+	Jobs []scheduler.JobInfo `json:"jobs"`
+}
+
+// Notification represents a JSON-RPC notification, which carries no ID
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// MessageParams represents the params of a notifications/message notification
+type MessageParams struct {
+	Level  string      `json:"level"`
+	Logger string      `json:"logger,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+// ValidationResult represents the outcome of validating a request without executing it
+type ValidationResult struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// BaselineDiff represents how an enumeration changed relative to a known baseline of subdomains
+type BaselineDiff struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// ComparisonResult represents the subdomain overlap between two enumerated domains.
+// Lists contain subdomain labels relative to their base domain (e.g. "api" for api.example.com).
+type ComparisonResult struct {
+	Domain1        string   `json:"domain1"`
+	Domain2        string   `json:"domain2"`
+	Domain1Only    []string `json:"domain1Only"`
+	Domain2Only    []string `json:"domain2Only"`
+	Common         []string `json:"common"`
+	OverlapPercent float64  `json:"overlapPercent"`
 }
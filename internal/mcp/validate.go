@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// knownMethods lists the JSON-RPC methods the server routes to a handler
+var knownMethods = map[string]bool{
+	"initialize": true,
+	"tools.list": true,
+	"tools.get":  true,
+	"tools.call": true,
+
+	"schedule.create": true,
+	"schedule.list":   true,
+	"schedule.delete": true,
+	"schedule.pause":  true,
+	"schedule.resume": true,
+
+	"cache.invalidate": true,
+
+	"resources.subscribe": true,
+
+	"jobs.get": true,
+}
+
+// ValidateRequest checks a raw JSON-RPC request body without executing it
+func ValidateRequest(body []byte) ValidationResult {
+	var req Request
+	if err := jsoniter.Unmarshal(body, &req); err != nil {
+		return ValidationResult{
+			Valid:  false,
+			Errors: []string{fmt.Sprintf("request is not a valid JSON-RPC object: %v", err)},
+		}
+	}
+
+	var errs []string
+
+	// Validate the JSON-RPC envelope
+	if req.JSONRPC != "2.0" {
+		errs = append(errs, fmt.Sprintf("jsonrpc must be \"2.0\", got %q", req.JSONRPC))
+	}
+	if req.ID != nil && !isValidID(*req.ID) {
+		errs = append(errs, "id must be a string, number or null")
+	}
+	if req.Method == "" {
+		errs = append(errs, "method is required")
+	} else if !knownMethods[req.Method] {
+		errs = append(errs, fmt.Sprintf("unknown method: %s", req.Method))
+	}
+
+	// Validate tool call parameters against the tool's input schema
+	if req.Method == "tools.call" {
+		errs = append(errs, validateToolCallParams(req.Params)...)
+	}
+
+	return ValidationResult{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}
+}
+
+// isValidID reports whether a raw id is a string, number or null as required by JSON-RPC 2.0
+func isValidID(id jsoniter.RawMessage) bool {
+	switch jsoniter.Get(id).ValueType() {
+	case jsoniter.StringValue, jsoniter.NumberValue, jsoniter.NilValue:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateToolCallParams checks tools.call params against the named tool's schema
+func validateToolCallParams(raw jsoniter.RawMessage) []string {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return []string{"params are required for tools.call"}
+	}
+
+	var params ToolCallParams
+	if err := jsoniter.Unmarshal(raw, &params); err != nil {
+		return []string{fmt.Sprintf("params are not a valid tools.call object: %v", err)}
+	}
+
+	if params.Name == "" {
+		return []string{"params.name is required"}
+	}
+
+	var tool *Tool
+	for _, t := range availableTools() {
+		if t.Name == params.Name {
+			tool = &t
+			break
+		}
+	}
+	if tool == nil {
+		return []string{fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+
+	return validateArguments(tool.InputSchema, params.Arguments)
+}
+
+// validateArguments checks arguments for required fields and property types
+func validateArguments(schema interface{}, args map[string]interface{}) []string {
+	schemaMap, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	properties, _ := schemaMap["properties"].(map[string]interface{})
+
+	var errs []string
+
+	if required, ok := schemaMap["required"].([]string); ok {
+		for _, name := range required {
+			if _, exists := args[name]; !exists {
+				errs = append(errs, fmt.Sprintf("missing required argument: %s", name))
+			}
+		}
+	}
+
+	// Check arguments in a stable order so error lists are deterministic
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		expectedType, _ := property["type"].(string)
+		if !matchesSchemaType(args[name], expectedType) {
+			errs = append(errs, fmt.Sprintf("argument %s must be of type %s", name, expectedType))
+		}
+	}
+
+	return errs
+}
+
+// matchesSchemaType reports whether a decoded JSON value matches a JSON Schema type name
+func matchesSchemaType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true
+	}
+}
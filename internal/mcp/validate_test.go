@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectValid   bool
+		expectedError string
+	}{
+		{
+			name:        "Valid tools.list",
+			body:        `{"jsonrpc":"2.0","id":1,"method":"tools.list"}`,
+			expectValid: true,
+		},
+		{
+			name:        "Valid tools.call",
+			body:        `{"jsonrpc":"2.0","id":"abc","method":"tools.call","params":{"name":"enumerateSubdomains","arguments":{"domain":"example.com","timeout":30}}}`,
+			expectValid: true,
+		},
+		{
+			name:          "Invalid JSON",
+			body:          `{invalid`,
+			expectValid:   false,
+			expectedError: "",
+		},
+		{
+			name:          "Wrong JSON-RPC version",
+			body:          `{"jsonrpc":"1.0","id":1,"method":"tools.list"}`,
+			expectValid:   false,
+			expectedError: `jsonrpc must be "2.0", got "1.0"`,
+		},
+		{
+			name:          "Object ID",
+			body:          `{"jsonrpc":"2.0","id":{"a":1},"method":"tools.list"}`,
+			expectValid:   false,
+			expectedError: "id must be a string, number or null",
+		},
+		{
+			name:          "Unknown method",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools.delete"}`,
+			expectValid:   false,
+			expectedError: "unknown method: tools.delete",
+		},
+		{
+			name:          "Unknown tool",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools.call","params":{"name":"nope","arguments":{}}}`,
+			expectValid:   false,
+			expectedError: "unknown tool: nope",
+		},
+		{
+			name:          "Missing domain",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools.call","params":{"name":"enumerateSubdomains","arguments":{}}}`,
+			expectValid:   false,
+			expectedError: "missing required argument: domain",
+		},
+		{
+			name:          "Wrong argument type",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools.call","params":{"name":"enumerateSubdomains","arguments":{"domain":"example.com","timeout":1.5}}}`,
+			expectValid:   false,
+			expectedError: "argument timeout must be of type integer",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ValidateRequest([]byte(tc.body))
+
+			if result.Valid != tc.expectValid {
+				t.Fatalf("Expected valid=%v, got %v (errors: %v)", tc.expectValid, result.Valid, result.Errors)
+			}
+
+			if tc.expectedError == "" {
+				return
+			}
+
+			found := false
+			for _, e := range result.Errors {
+				if e == tc.expectedError {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Expected error %q, got %v", tc.expectedError, result.Errors)
+			}
+		})
+	}
+}
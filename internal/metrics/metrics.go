@@ -0,0 +1,183 @@
+// Package metrics exposes server metrics in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// SizeBuckets are the histogram bucket boundaries used for payload sizes
+var SizeBuckets = []float64{1 << 10, 10 << 10, 100 << 10, 1 << 20}
+
+var (
+	// RequestBodyBytes observes the size of /mcp request bodies
+	RequestBodyBytes = NewHistogram("mcp_request_body_bytes", "Size of MCP request bodies in bytes.", SizeBuckets)
+	// ResponseBodyBytes observes the size of /mcp response bodies
+	ResponseBodyBytes = NewHistogram("mcp_response_body_bytes", "Size of MCP response bodies in bytes.", SizeBuckets)
+	// QueueDepth reports how many tools.call requests are waiting for a worker
+	QueueDepth = NewGauge("mcp_queue_depth", "Number of tools.call requests waiting in the queue.")
+)
+
+// collector is a metric that can write itself in the exposition format
+type collector interface {
+	writeTo(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register adds a metric to the set served by Handler
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the given upper bounds and registers it
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(name, help, buckets)
+	register(h)
+	return h
+}
+
+// newHistogram creates a histogram without registering it
+func newHistogram(name, help string, buckets []float64) *Histogram {
+	return &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+// Observe records a single value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// writeTo writes the histogram's HELP, TYPE, bucket, sum and count lines
+func (h *Histogram) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", h.name, formatFloat(upper), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+// Gauge is a single value that can go up and down
+type Gauge struct {
+	name string
+	help string
+
+	mu    sync.Mutex
+	value float64
+}
+
+// NewGauge creates a gauge and registers it
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+// Set replaces the gauge's value
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = v
+}
+
+// Value returns the gauge's current value
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// writeTo writes the gauge's HELP, TYPE and sample lines
+func (g *Gauge) writeTo(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+}
+
+// formatFloat renders a sample value the way Prometheus expects
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves all registered metrics in the Prometheus text format
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	buf := bufio.NewWriter(w)
+	registryMu.Lock()
+	for _, c := range registry {
+		c.writeTo(buf)
+	}
+	registryMu.Unlock()
+	buf.Flush()
+}
+
+// CountingResponseWriter wraps an http.ResponseWriter and counts the body bytes written
+type CountingResponseWriter struct {
+	http.ResponseWriter
+	Bytes int64
+}
+
+// Write forwards to the wrapped writer and adds to the byte count
+func (c *CountingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.Bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the wrapped writer so streamed responses keep working
+func (c *CountingResponseWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := newHistogram("test_bytes", "Test sizes.", SizeBuckets)
+	for _, v := range []float64{100, 2048, 2048, 500 << 10, 5 << 20} {
+		h.Observe(v)
+	}
+
+	var sb strings.Builder
+	h.writeTo(&sb)
+	out := sb.String()
+
+	expected := []string{
+		"# TYPE test_bytes histogram",
+		`test_bytes_bucket{le="1024"} 1`,
+		`test_bytes_bucket{le="10240"} 3`,
+		`test_bytes_bucket{le="102400"} 3`,
+		`test_bytes_bucket{le="1.048576e+06"} 4`,
+		`test_bytes_bucket{le="+Inf"} 5`,
+		"test_bytes_count 5",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	RequestBodyBytes.Observe(10)
+
+	rr := httptest.NewRecorder()
+	Handler(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	for _, name := range []string{"mcp_request_body_bytes_count", "mcp_response_body_bytes_count"} {
+		if !strings.Contains(rr.Body.String(), name) {
+			t.Errorf("Expected metrics output to contain %s", name)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	Handler(rr, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rr.Code)
+	}
+}
+
+func TestCountingResponseWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := &CountingResponseWriter{ResponseWriter: rr}
+	w.Write([]byte("hello "))
+	w.Write([]byte("world"))
+	w.Flush()
+
+	if w.Bytes != 11 {
+		t.Errorf("Expected 11 bytes counted, got %d", w.Bytes)
+	}
+	if !rr.Flushed {
+		t.Error("Expected Flush to reach the underlying writer")
+	}
+}
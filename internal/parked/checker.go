@@ -0,0 +1,127 @@
+package parked
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"mcp-subfinder-server/internal/probe"
+)
+
+const (
+	// DefaultProbeTimeout bounds each HTTP request made while checking a subdomain
+	DefaultProbeTimeout = 10 * time.Second
+	// checkWorkers is the number of subdomains probed concurrently
+	checkWorkers = 10
+	// maxBodyBytes caps how much of a page is read when looking for body fingerprints
+	maxBodyBytes = 64 << 10
+)
+
+// Checker probes subdomains over HTTP(S) for parking pages
+type Checker struct {
+	client  *http.Client
+	schemes []string
+	logger  *slog.Logger
+}
+
+// NewChecker creates a Checker whose requests carry userAgent and give up after timeout,
+// with each connection allowed networkTimeout to be established
+func NewChecker(userAgent string, timeout, networkTimeout time.Duration, logger *slog.Logger) *Checker {
+	client := probe.NewHTTPClient(timeout, networkTimeout, userAgent)
+	// Redirects are inspected rather than followed, since the Location is the fingerprint
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Checker{
+		client:  client,
+		schemes: []string{"https", "http"},
+		logger:  logger,
+	}
+}
+
+// Check probes every host concurrently and returns the parked ones mapped to their provider
+func (c *Checker) Check(ctx context.Context, hosts []string) map[string]string {
+	parked := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+
+	for w := 0; w < checkWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range queue {
+				if provider, ok := c.probe(ctx, host); ok {
+					mu.Lock()
+					parked[host] = provider
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, host := range hosts {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- host:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return parked
+}
+
+// probe checks one host, trying each scheme until one answers. A HEAD request
+// catches parking redirects; a GET of the page catches parking pages served in place.
+func (c *Checker) probe(ctx context.Context, host string) (string, bool) {
+	for _, scheme := range c.schemes {
+		target := scheme + "://" + host + "/"
+
+		location, err := c.request(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			c.logger.Debug("Parked check failed", "url", target, "error", err)
+			continue
+		}
+		if provider, ok := Match(location, ""); ok {
+			return provider, true
+		}
+
+		var body []byte
+		if _, err := c.request(ctx, http.MethodGet, target, &body); err != nil {
+			c.logger.Debug("Parked check failed", "url", target, "error", err)
+			return "", false
+		}
+		return Match("", string(body))
+	}
+	return "", false
+}
+
+// request sends one request and returns its Location header, reading up to
+// maxBodyBytes of the body into body when it is non-nil
+func (c *Checker) request(ctx context.Context, method, target string, body *[]byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if body != nil {
+		*body, err = io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			return "", err
+		}
+	}
+	return resp.Header.Get("Location"), nil
+}
@@ -0,0 +1,47 @@
+package parked
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckerCheck(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	newServer := func(handler http.HandlerFunc) string {
+		ts := httptest.NewServer(handler)
+		t.Cleanup(ts.Close)
+		return strings.TrimPrefix(ts.URL, "http://")
+	}
+
+	redirecting := newServer(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://www.sedo.com/search/details/?domain=example.com", http.StatusFound)
+	})
+	parkedPage := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>This domain is registered at Namecheap</html>"))
+	})
+	regular := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>Example Corp</html>"))
+	})
+
+	checker := NewChecker("mcp-subfinder/test", 5*time.Second, time.Second, logger)
+	checker.schemes = []string{"http"}
+
+	parked := checker.Check(context.Background(), []string{redirecting, parkedPage, regular, "127.0.0.1:1"})
+
+	if parked[redirecting] != "Sedo" {
+		t.Errorf("Expected redirecting host to be parked with Sedo, got %q", parked[redirecting])
+	}
+	if parked[parkedPage] != "Namecheap" {
+		t.Errorf("Expected parking page to be parked with Namecheap, got %q", parked[parkedPage])
+	}
+	if len(parked) != 2 {
+		t.Errorf("Expected only two parked hosts, got %v", parked)
+	}
+}
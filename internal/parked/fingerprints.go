@@ -0,0 +1,89 @@
+// Package parked detects subdomains that serve registrar or marketplace parking pages
+package parked
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Fingerprint identifies a parking provider by where it redirects and what its pages contain
+type Fingerprint struct {
+	Name string
+	// RedirectHosts match the host of a Location header, including any subdomain of it
+	RedirectHosts []string
+	// BodyPatterns are lowercase substrings found in the provider's parking pages
+	BodyPatterns []string
+}
+
+// Fingerprints are the known parking providers
+var Fingerprints = []Fingerprint{
+	{
+		Name:          "GoDaddy",
+		RedirectHosts: []string{"godaddy.com", "afternic.com"},
+		BodyPatterns:  []string{"parked free, courtesy of godaddy", "godaddy.com/domainsearch"},
+	},
+	{
+		Name:          "Namecheap",
+		RedirectHosts: []string{"namecheap.com"},
+		BodyPatterns:  []string{"this domain is registered at namecheap", "namecheap.com/domains/registration"},
+	},
+	{
+		Name:          "Sedo",
+		RedirectHosts: []string{"sedo.com", "sedoparking.com"},
+		BodyPatterns:  []string{"sedoparking.com", "sedo.com/search/details"},
+	},
+	{
+		Name:          "Bodis",
+		RedirectHosts: []string{"bodis.com"},
+		BodyPatterns:  []string{"bodis.com"},
+	},
+	{
+		Name:          "ParkingCrew",
+		RedirectHosts: []string{"parkingcrew.net"},
+		BodyPatterns:  []string{"parkingcrew.net"},
+	},
+	{
+		Name:          "Dan.com",
+		RedirectHosts: []string{"dan.com"},
+		BodyPatterns:  []string{"dan.com/buy-domain"},
+	},
+	{
+		Name:          "HugeDomains",
+		RedirectHosts: []string{"hugedomains.com"},
+		BodyPatterns:  []string{"hugedomains.com"},
+	},
+	{
+		Name:          "Above.com",
+		RedirectHosts: []string{"above.com"},
+		BodyPatterns:  []string{"above.com/marketplace"},
+	},
+}
+
+// Match returns the provider whose fingerprint matches a response's Location header or body
+func Match(location, body string) (string, bool) {
+	if location != "" {
+		if u, err := url.Parse(location); err == nil {
+			host := strings.ToLower(u.Hostname())
+			for _, fp := range Fingerprints {
+				for _, redirectHost := range fp.RedirectHosts {
+					if host == redirectHost || strings.HasSuffix(host, "."+redirectHost) {
+						return fp.Name, true
+					}
+				}
+			}
+		}
+	}
+
+	if body != "" {
+		lowered := strings.ToLower(body)
+		for _, fp := range Fingerprints {
+			for _, pattern := range fp.BodyPatterns {
+				if strings.Contains(lowered, pattern) {
+					return fp.Name, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,31 @@
+package parked
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		body     string
+		provider string
+		parked   bool
+	}{
+		{"Sedo redirect", "https://sedo.com/search/details/?domain=example.com", "", "Sedo", true},
+		{"Redirect to provider subdomain", "https://www.afternic.com/forsale/example.com", "", "GoDaddy", true},
+		{"Lookalike redirect host", "https://notgodaddy.com/", "", "", false},
+		{"Relative redirect", "/login", "", "", false},
+		{"GoDaddy body", "", "<p>This Web page is parked FREE, courtesy of GoDaddy.com</p>", "GoDaddy", true},
+		{"Namecheap body", "", "This domain is registered at Namecheap", "Namecheap", true},
+		{"Regular page", "", "<html><title>Example Corp</title></html>", "", false},
+		{"Nothing to match", "", "", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, parked := Match(tc.location, tc.body)
+			if parked != tc.parked || provider != tc.provider {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tc.provider, tc.parked, provider, parked)
+			}
+		})
+	}
+}
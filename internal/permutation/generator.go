@@ -0,0 +1,94 @@
+// Package permutation derives likely subdomain names from ones already discovered
+package permutation
+
+import (
+	"strings"
+)
+
+// MaxPermutations caps how many names Generate returns
+const MaxPermutations = 1000
+
+// Source is the source attributed to subdomains found by resolving permutations
+const Source = "permutation"
+
+// words are the environment and role labels commonly combined with existing names
+var words = []string{
+	"dev", "staging", "stage", "test", "qa", "uat", "prod",
+	"api", "admin", "internal", "beta", "old", "new",
+}
+
+// Generate combines the first label of every subdomain of domain with common
+// words, as in dev-{label} and {label}-prod, and swaps any common word already
+// in a label for the others, so dev-api yields staging-api. Names that are
+// already in subdomains are skipped and at most MaxPermutations are returned,
+// in a stable order.
+func Generate(subdomains []string, domain string) []string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	suffix := "." + domain
+
+	seen := make(map[string]struct{}, len(subdomains))
+	for _, subdomain := range subdomains {
+		seen[strings.ToLower(subdomain)] = struct{}{}
+	}
+
+	var generated []string
+	add := func(label, rest string) bool {
+		name := label + rest
+		if _, ok := seen[name]; ok {
+			return true
+		}
+		seen[name] = struct{}{}
+		generated = append(generated, name)
+		return len(generated) < MaxPermutations
+	}
+
+	for _, subdomain := range subdomains {
+		subdomain = strings.ToLower(subdomain)
+		if !strings.HasSuffix(subdomain, suffix) {
+			continue
+		}
+		label, rest, _ := strings.Cut(strings.TrimSuffix(subdomain, suffix), ".")
+		if rest != "" {
+			rest = "." + rest
+		}
+		rest += suffix
+
+		for _, word := range words {
+			if word == label {
+				continue
+			}
+			if !add(word+"-"+label, rest) || !add(label+"-"+word, rest) {
+				return generated
+			}
+		}
+
+		tokens := strings.Split(label, "-")
+		for i, token := range tokens {
+			if !isWord(token) {
+				continue
+			}
+			for _, word := range words {
+				if word == token {
+					continue
+				}
+				swapped := append([]string(nil), tokens...)
+				swapped[i] = word
+				if !add(strings.Join(swapped, "-"), rest) {
+					return generated
+				}
+			}
+		}
+	}
+
+	return generated
+}
+
+// isWord reports whether token is one of the common words
+func isWord(token string) bool {
+	for _, word := range words {
+		if word == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package permutation
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	got := Generate([]string{"api.example.com", "dev-web.shop.example.com", "other.org", "example.com"}, "example.com")
+
+	contains := func(name string) bool {
+		for _, g := range got {
+			if g == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, name := range []string{
+		"dev-api.example.com",
+		"api-prod.example.com",
+		"staging-dev-web.shop.example.com",
+		"dev-web-prod.shop.example.com",
+		"staging-web.shop.example.com",
+		"dev.example.com",
+	} {
+		if !contains(name) {
+			t.Errorf("Expected %s among the permutations", name)
+		}
+	}
+	for _, name := range []string{"api.example.com", "api-api.example.com", "dev-other.org"} {
+		if contains(name) {
+			t.Errorf("Expected no permutation %s", name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range got {
+		if seen[name] {
+			t.Errorf("Duplicate permutation %s", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestGenerateCap(t *testing.T) {
+	subdomains := make([]string, 100)
+	for i := range subdomains {
+		subdomains[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	if got := Generate(subdomains, "example.com"); len(got) != MaxPermutations {
+		t.Errorf("Expected %d permutations, got %d", MaxPermutations, len(got))
+	}
+}
@@ -0,0 +1,171 @@
+// Package plugin lets operators hook custom pre- and post-processing into enumerations
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	goplugin "plugin"
+	"sync"
+
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// Symbol is the exported variable a plugin .so file must define; its value, or
+// what it points to, must implement Plugin
+const Symbol = "Plugin"
+
+// Plugin is called around every enumeration run through Enumerate
+type Plugin interface {
+	// OnPreEnumerate runs before the enumeration starts; an error aborts the call
+	OnPreEnumerate(ctx context.Context, domain string, config subfinder.SubfinderConfig) error
+	// OnPostEnumerate may rewrite the subdomains found, e.g. to redact or add names;
+	// an error fails the call
+	OnPostEnumerate(ctx context.Context, domain string, results []string) ([]string, error)
+}
+
+var (
+	mu      sync.RWMutex
+	plugins []Plugin
+)
+
+// RegisterPlugin adds p to the plugins run on every enumeration, after those already registered
+func RegisterPlugin(p Plugin) {
+	mu.Lock()
+	defer mu.Unlock()
+	plugins = append(plugins, p)
+}
+
+// registered returns a snapshot of the registered plugins
+func registered() []Plugin {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Plugin(nil), plugins...)
+}
+
+// RunPreEnumerate calls OnPreEnumerate on every plugin in order, stopping at the first error
+func RunPreEnumerate(ctx context.Context, domain string, config subfinder.SubfinderConfig) error {
+	for _, p := range registered() {
+		if err := p.OnPreEnumerate(ctx, domain, config); err != nil {
+			return fmt.Errorf("plugin %T: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// RunPostEnumerate passes results through OnPostEnumerate of every plugin in order,
+// each seeing the previous plugin's output
+func RunPostEnumerate(ctx context.Context, domain string, results []string) ([]string, error) {
+	for _, p := range registered() {
+		var err error
+		if results, err = p.OnPostEnumerate(ctx, domain, results); err != nil {
+			return nil, fmt.Errorf("plugin %T: %w", p, err)
+		}
+	}
+	return results, nil
+}
+
+// enumerate runs the enumerations wrapped by Enumerate; tests replace it
+var enumerate = subfinder.Enumerate
+
+// Enumerate runs subfinder.Enumerate between the plugin hooks. OnPreEnumerate
+// runs first, and OnPostEnumerate sees each streamed subdomain before it is
+// written and then the whole result before it is returned, so no name leaves the
+// server before plugins have had the chance to redact it.
+func Enumerate(ctx context.Context, domain string, config subfinder.SubfinderConfig, logger *slog.Logger) (*subfinder.EnumerationResult, error) {
+	if err := RunPreEnumerate(ctx, domain, config); err != nil {
+		return nil, err
+	}
+	if len(registered()) > 0 {
+		config.StreamFilter = streamFilter(ctx, domain, config.StreamFilter, logger)
+	}
+	result, err := enumerate(ctx, domain, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	if result.Subdomains, err = RunPostEnumerate(ctx, domain, result.Subdomains); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RunEnumeration is Enumerate returning only the subdomains, like subfinder.RunEnumeration
+func RunEnumeration(ctx context.Context, domain string, config subfinder.SubfinderConfig, logger *slog.Logger) ([]string, error) {
+	result, err := Enumerate(ctx, domain, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return result.Subdomains, nil
+}
+
+// streamFilter passes each streamed subdomain through OnPostEnumerate, then
+// through next when set. A plugin error keeps the name out of the stream.
+func streamFilter(ctx context.Context, domain string, next func(string) []string, logger *slog.Logger) func(string) []string {
+	return func(subdomain string) []string {
+		names, err := RunPostEnumerate(ctx, domain, []string{subdomain})
+		if err != nil {
+			logger.Warn("Plugin failed on a streamed subdomain, leaving it out", "error", err)
+			return nil
+		}
+		if next == nil {
+			return names
+		}
+		var filtered []string
+		for _, name := range names {
+			filtered = append(filtered, next(name)...)
+		}
+		return filtered
+	}
+}
+
+// LoadDir opens every .so file in dir and registers the Plugin it exports
+func LoadDir(dir string, logger *slog.Logger) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		opened, err := goplugin.Open(path)
+		if err != nil {
+			return fmt.Errorf("open plugin %s: %w", path, err)
+		}
+		sym, err := opened.Lookup(Symbol)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+
+		// Lookup returns a pointer when the symbol is a variable
+		var p Plugin
+		switch v := sym.(type) {
+		case Plugin:
+			p = v
+		case *Plugin:
+			p = *v
+		default:
+			return fmt.Errorf("plugin %s: %s does not implement Plugin", path, Symbol)
+		}
+
+		RegisterPlugin(p)
+		logger.Info("Loaded plugin", "path", path, "type", fmt.Sprintf("%T", p))
+	}
+	return nil
+}
+
+// LoggingPlugin is an example plugin that logs every enumeration and its result count
+type LoggingPlugin struct {
+	Logger *slog.Logger
+}
+
+// OnPreEnumerate implements Plugin
+func (l LoggingPlugin) OnPreEnumerate(_ context.Context, domain string, config subfinder.SubfinderConfig) error {
+	l.Logger.Info("Enumeration starting", "plugin", "logging", "domain", domain, "timeout", config.Timeout)
+	return nil
+}
+
+// OnPostEnumerate implements Plugin
+func (l LoggingPlugin) OnPostEnumerate(_ context.Context, domain string, results []string) ([]string, error) {
+	l.Logger.Info("Enumeration finished", "plugin", "logging", "domain", domain, "subdomains", len(results))
+	return results, nil
+}
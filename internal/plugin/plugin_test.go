@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+// redactPlugin drops one subdomain and can fail either hook
+type redactPlugin struct {
+	redact  string
+	preErr  error
+	postErr error
+}
+
+func (r redactPlugin) OnPreEnumerate(context.Context, string, subfinder.SubfinderConfig) error {
+	return r.preErr
+}
+
+func (r redactPlugin) OnPostEnumerate(_ context.Context, _ string, results []string) ([]string, error) {
+	if r.postErr != nil {
+		return nil, r.postErr
+	}
+	var kept []string
+	for _, result := range results {
+		if result != r.redact {
+			kept = append(kept, result)
+		}
+	}
+	return kept, nil
+}
+
+func TestRunHooks(t *testing.T) {
+	defer func() { plugins = nil }()
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	RegisterPlugin(LoggingPlugin{Logger: logger})
+	RegisterPlugin(redactPlugin{redact: "admin.example.com"})
+	RegisterPlugin(redactPlugin{redact: "dev.example.com"})
+
+	if err := RunPreEnumerate(ctx, "example.com", subfinder.SubfinderConfig{}); err != nil {
+		t.Fatalf("RunPreEnumerate failed: %v", err)
+	}
+	got, err := RunPostEnumerate(ctx, "example.com", []string{"admin.example.com", "dev.example.com", "www.example.com"})
+	if err != nil {
+		t.Fatalf("RunPostEnumerate failed: %v", err)
+	}
+	if expected := []string{"www.example.com"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	RegisterPlugin(redactPlugin{preErr: errors.New("domain not allowed"), postErr: errors.New("backend down")})
+	if err := RunPreEnumerate(ctx, "example.com", subfinder.SubfinderConfig{}); err == nil || !strings.Contains(err.Error(), "domain not allowed") {
+		t.Errorf("Expected the pre-enumerate error, got %v", err)
+	}
+	if _, err := RunPostEnumerate(ctx, "example.com", nil); err == nil || !strings.Contains(err.Error(), "backend down") {
+		t.Errorf("Expected the post-enumerate error, got %v", err)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	defer func() { plugins = nil }()
+	defer func(original func(context.Context, string, subfinder.SubfinderConfig, *slog.Logger) (*subfinder.EnumerationResult, error)) {
+		enumerate = original
+	}(enumerate)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var streamed []string
+	enumerate = func(_ context.Context, _ string, config subfinder.SubfinderConfig, _ *slog.Logger) (*subfinder.EnumerationResult, error) {
+		// Stand in for the result stream, which writes what the filter returns
+		for _, subdomain := range []string{"admin.example.com", "www.example.com"} {
+			streamed = append(streamed, config.StreamFilter(subdomain)...)
+		}
+		return &subfinder.EnumerationResult{Subdomains: []string{"admin.example.com", "www.example.com"}}, nil
+	}
+	RegisterPlugin(redactPlugin{redact: "admin.example.com"})
+
+	subdomains, err := RunEnumeration(context.Background(), "example.com", subfinder.SubfinderConfig{}, logger)
+	if err != nil {
+		t.Fatalf("RunEnumeration failed: %v", err)
+	}
+	if expected := []string{"www.example.com"}; !reflect.DeepEqual(subdomains, expected) || !reflect.DeepEqual(streamed, expected) {
+		t.Errorf("Expected only %v returned and streamed, got %v and %v", expected, subdomains, streamed)
+	}
+
+	RegisterPlugin(redactPlugin{preErr: errors.New("domain not allowed")})
+	if _, err := Enumerate(context.Background(), "example.com", subfinder.SubfinderConfig{}, logger); err == nil {
+		t.Error("Expected the pre-enumerate error to stop the enumeration")
+	}
+}
+
+func TestLoadDirEmpty(t *testing.T) {
+	defer func() { plugins = nil }()
+	if err := LoadDir(t.TempDir(), slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatalf("LoadDir failed on an empty directory: %v", err)
+	}
+	if len(registered()) != 0 {
+		t.Errorf("Expected no plugins, got %d", len(registered()))
+	}
+}
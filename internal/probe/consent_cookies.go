@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"net/http"
+	"sort"
+)
+
+// ConsentCookies are the cookies common consent management platforms set once a
+// visitor accepts, so a probe sees the page behind the consent wall
+var ConsentCookies = map[string]string{
+	"cookieyes-consent":                "yes",
+	"CookieConsent":                    "yes",
+	"cookieconsent_status":             "allow",
+	"OptanonAlertBoxClosed":            "2024-01-01T00:00:00.000Z",
+	"OptanonConsent":                   "isGpcEnabled=0&groups=C0001%3A1%2CC0002%3A1%2CC0003%3A1%2CC0004%3A1",
+	"euconsent-v2":                     "CPzAAAAPzAAAAAGABCENDgCgAP_AAH_AAAqIAAAAAAAA",
+	"didomi_token":                     "accepted",
+	"cmplz_consent_status":             "allow",
+	"borlabs-cookie":                   "all",
+	"moove_gdpr_popup":                 "%7B%22strict%22%3A%221%22%2C%22thirdparty%22%3A%221%22%2C%22advanced%22%3A%221%22%7D",
+	"gdpr-cookie-consent":              "accepted",
+	"cookie_consent_accepted":          "true",
+	"consentUUID":                      "00000000-0000-0000-0000-000000000000",
+	"usercentrics_consent":             "true",
+	"cookielawinfo-checkbox-necessary": "yes",
+}
+
+// consentTransport adds ConsentCookies to every request before delegating to base
+type consentTransport struct {
+	base http.RoundTripper
+}
+
+// WithConsentCookies returns a copy of client whose requests also carry
+// ConsentCookies. Cookies a request already sets are left as they are.
+func WithConsentCookies(client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	withCookies := *client
+	withCookies.Transport = &consentTransport{base: base}
+	return &withCookies
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *consentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	names := make([]string, 0, len(ConsentCookies))
+	for name := range ConsentCookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// RoundTrippers must not modify the caller's request
+	clone := req.Clone(req.Context())
+	for _, name := range names {
+		if _, err := clone.Cookie(name); err == http.ErrNoCookie {
+			clone.AddCookie(&http.Cookie{Name: name, Value: ConsentCookies[name]})
+		}
+	}
+	return t.base.RoundTrip(clone)
+}
@@ -0,0 +1,45 @@
+package probe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithConsentCookies(t *testing.T) {
+	var received []*http.Cookie
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Cookies()
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(2*time.Second, time.Second, "probe-test")
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "CookieConsent", Value: "no"})
+	resp, err := WithConsentCookies(client).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	got := make(map[string]string, len(received))
+	for _, cookie := range received {
+		got[cookie.Name] = cookie.Value
+	}
+	if len(got) != len(ConsentCookies) {
+		t.Errorf("Expected %d cookies, got %d", len(ConsentCookies), len(got))
+	}
+	if got["cookieconsent_status"] != "allow" {
+		t.Errorf("Expected the consent cookies to be sent, got %v", got)
+	}
+	if got["CookieConsent"] != "no" {
+		t.Errorf("Expected the request's own cookie to be kept, got %q", got["CookieConsent"])
+	}
+	if len(req.Cookies()) != 1 {
+		t.Error("Expected the caller's request to be left unchanged")
+	}
+}
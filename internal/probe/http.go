@@ -0,0 +1,38 @@
+// Package probe provides the network clients used to probe discovered subdomains
+package probe
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"mcp-subfinder-server/internal/useragent"
+)
+
+// DefaultNetworkTimeout bounds the TCP connect phase of each probe connection
+const DefaultNetworkTimeout = 5 * time.Second
+
+// NewHTTPClient returns a client whose requests carry userAgent and give up after
+// timeout, and whose connections must be established within networkTimeout so a
+// slow host cannot hold a probe worker for the whole request timeout.
+func NewHTTPClient(timeout, networkTimeout time.Duration, userAgent string) *http.Client {
+	return newHTTPClient(timeout, networkTimeout, userAgent, nil)
+}
+
+// newHTTPClient is NewHTTPClient with the transport's TLS configuration, nil for the default
+func newHTTPClient(timeout, networkTimeout time.Duration, userAgent string, tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   networkTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: useragent.NewTransport(transport, userAgent),
+	}
+}
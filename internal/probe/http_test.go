@@ -0,0 +1,31 @@
+package probe
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(5*time.Second, time.Second, "probe-test")
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "probe-test" {
+		t.Errorf("Expected User-Agent probe-test, got %q", body)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Expected request timeout 5s, got %v", client.Timeout)
+	}
+}
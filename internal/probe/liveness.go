@@ -0,0 +1,85 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLivenessTimeout bounds each request made while checking whether a host answers
+	DefaultLivenessTimeout = 10 * time.Second
+	// livenessWorkers is the number of hosts checked concurrently
+	livenessWorkers = 10
+)
+
+// NewLivenessClient returns a client for Responsive like NewHTTPClient, except
+// that it accepts any certificate. A host with a self-signed or expired
+// certificate still completed a handshake and answered, so it counts as live;
+// nothing read through this client is trusted.
+func NewLivenessClient(timeout, networkTimeout time.Duration, userAgent string) *http.Client {
+	return newHTTPClient(timeout, networkTimeout, userAgent, &tls.Config{InsecureSkipVerify: true})
+}
+
+// Responsive checks every host over HTTPS and then HTTP and returns the set of
+// hosts that answered either with any response at all, whatever its status.
+// Redirects are not followed, since a redirect is already an answer.
+func Responsive(ctx context.Context, client *http.Client, hosts []string) map[string]struct{} {
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	responsive := make(map[string]struct{})
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+
+	for w := 0; w < livenessWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range queue {
+				if answers(ctx, &noRedirects, host) {
+					mu.Lock()
+					responsive[host] = struct{}{}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, host := range hosts {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- host:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return responsive
+}
+
+// answers reports whether host responds to a HEAD request over either scheme
+func answers(ctx context.Context, client *http.Client, host string) bool {
+	for _, scheme := range []string{"https", "http"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, scheme+"://"+host+"/", nil)
+		if err != nil {
+			return false
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponsive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://elsewhere.example/", http.StatusFound)
+	}))
+	defer server.Close()
+	live := strings.TrimPrefix(server.URL, "http://")
+
+	// A listener closed straight away leaves a port nothing answers on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle := listener.Addr().String()
+	listener.Close()
+
+	client := NewHTTPClient(2*time.Second, time.Second, "probe-test")
+	got := Responsive(context.Background(), client, []string{live, idle})
+	if _, ok := got[live]; !ok {
+		t.Errorf("Expected %s to be responsive", live)
+	}
+	if _, ok := got[idle]; ok {
+		t.Errorf("Expected %s not to be responsive", idle)
+	}
+	if client.CheckRedirect != nil {
+		t.Error("Expected the caller's client to be left unchanged")
+	}
+}
+
+func TestResponsiveUntrustedCertificate(t *testing.T) {
+	// httptest's certificate is self-signed, like many internal hosts'
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	got := Responsive(context.Background(), NewLivenessClient(2*time.Second, time.Second, "probe-test"), []string{host})
+	if _, ok := got[host]; !ok {
+		t.Errorf("Expected %s to be responsive despite its certificate", host)
+	}
+}
@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// TLS protocol versions as accepted by probeTLSVersion's filters, oldest first
+const (
+	TLS10 = "1.0"
+	TLS11 = "1.1"
+	TLS12 = "1.2"
+	TLS13 = "1.3"
+)
+
+// TLSVersions lists every version SupportedTLSVersions tries, oldest first
+var TLSVersions = []string{TLS10, TLS11, TLS12, TLS13}
+
+// tlsVersionIDs maps each version to its crypto/tls identifier
+var tlsVersionIDs = map[string]uint16{
+	TLS10: tls.VersionTLS10,
+	TLS11: tls.VersionTLS11,
+	TLS12: tls.VersionTLS12,
+	TLS13: tls.VersionTLS13,
+}
+
+// legacyCipherSuites lets the TLS 1.0 and 1.1 probes offer every suite crypto/tls
+// implements. Servers stuck on those versions often only speak suites Go leaves out
+// by default, such as RSA key exchange or 3DES, and would wrongly look unsupported.
+var legacyCipherSuites = func() []uint16 {
+	var ids []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}()
+
+// tlsVersionWorkers is the number of hosts probed concurrently
+const tlsVersionWorkers = 10
+
+// SupportedTLSVersions connects to port 443 of every host once per version in
+// TLSVersions, pinning the handshake to that version, and returns the versions
+// each host completed a handshake with. Hosts that support none are left out.
+func SupportedTLSVersions(ctx context.Context, hosts []string, networkTimeout time.Duration) map[string][]string {
+	supported := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+
+	for w := 0; w < tlsVersionWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range queue {
+				versions := HostTLSVersions(ctx, net.JoinHostPort(host, "443"), host, networkTimeout)
+				if len(versions) > 0 {
+					mu.Lock()
+					supported[host] = versions
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, host := range hosts {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- host:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return supported
+}
+
+// HostTLSVersions returns the versions in TLSVersions that addr completes a
+// handshake with, using serverName for SNI. Certificates are not verified, since
+// which versions are offered does not depend on the chain.
+func HostTLSVersions(ctx context.Context, addr, serverName string, networkTimeout time.Duration) []string {
+	var versions []string
+	for _, version := range TLSVersions {
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: networkTimeout},
+			Config: &tls.Config{
+				ServerName:         serverName,
+				InsecureSkipVerify: true,
+				MinVersion:         tlsVersionIDs[version],
+				MaxVersion:         tlsVersionIDs[version],
+			},
+		}
+		if version == TLS10 || version == TLS11 {
+			dialer.Config.CipherSuites = legacyCipherSuites
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		versions = append(versions, version)
+	}
+	return versions
+}
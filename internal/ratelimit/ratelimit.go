@@ -0,0 +1,91 @@
+// Package ratelimit spaces out requests made with the same API key, however many
+// tool calls share it
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxPacers bounds how many API keys are tracked; idle keys are dropped past it
+const maxPacers = 1000
+
+// Pacer lets one request run at a time, each starting at least an interval
+// after the previous one finished
+type Pacer struct {
+	turn chan struct{}
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewPacer returns a Pacer whose first request may start at once
+func NewPacer() *Pacer {
+	return &Pacer{turn: make(chan struct{}, 1)}
+}
+
+// Acquire blocks until no other request holds the pacer and interval has passed
+// since the last one finished, or returns the context's error if it ends first.
+// The returned function must be called once the request has finished.
+func (p *Pacer) Acquire(ctx context.Context, interval time.Duration) (func(), error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case p.turn <- struct{}{}:
+	}
+
+	p.mu.Lock()
+	wait := time.Until(p.next)
+	p.mu.Unlock()
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			<-p.turn
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return func() {
+		p.mu.Lock()
+		p.next = time.Now().Add(interval)
+		p.mu.Unlock()
+		<-p.turn
+	}, nil
+}
+
+// idle reports whether nothing holds the pacer and its interval has passed
+func (p *Pacer) idle(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.turn) == 0 && !p.next.After(now)
+}
+
+var (
+	pacersMu sync.Mutex
+	pacers   = make(map[string]*Pacer)
+)
+
+// ForKey returns the Pacer shared by every client of service using apiKey
+func ForKey(service, apiKey string) *Pacer {
+	key := service + "\x00" + apiKey
+
+	pacersMu.Lock()
+	defer pacersMu.Unlock()
+	if pacer, ok := pacers[key]; ok {
+		return pacer
+	}
+	if len(pacers) >= maxPacers {
+		now := time.Now()
+		for k, pacer := range pacers {
+			if pacer.idle(now) {
+				delete(pacers, k)
+			}
+		}
+	}
+	pacer := NewPacer()
+	pacers[key] = pacer
+	return pacer
+}
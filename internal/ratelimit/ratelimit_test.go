@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestForKey(t *testing.T) {
+	if ForKey("virustotal", "a") != ForKey("virustotal", "a") {
+		t.Error("Expected the same key to share a pacer")
+	}
+	if ForKey("virustotal", "a") == ForKey("shodan", "a") || ForKey("virustotal", "a") == ForKey("virustotal", "b") {
+		t.Error("Expected different services and keys to have their own pacers")
+	}
+}
+
+func TestPacerAcquire(t *testing.T) {
+	pacer := NewPacer()
+	interval := 50 * time.Millisecond
+	var finished time.Time
+	for i := 0; i < 3; i++ {
+		release, err := pacer.Acquire(context.Background(), interval)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if i > 0 {
+			if gap := time.Since(finished); gap < interval {
+				t.Errorf("Expected requests at least %s apart, got %s", interval, gap)
+			}
+		}
+		finished = time.Now()
+		release()
+	}
+
+	// A request waiting on a held pacer gives up when its context ends
+	release, err := pacer.Acquire(context.Background(), interval)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pacer.Acquire(ctx, interval); err == nil {
+		t.Error("Expected the wait to end with the context")
+	}
+	release()
+	if _, err := pacer.Acquire(context.Background(), 0); err != nil {
+		t.Errorf("Expected the pacer to be free after release, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// MaxCustomOutput is the most a caller-supplied template may render, in bytes
+	MaxCustomOutput = 10 << 20
+	// MaxCustomSteps caps the range iterations and template calls of one render,
+	// so a template that loops without writing still stops
+	MaxCustomSteps = 1_000_000
+)
+
+var (
+	// ErrCustomOutputTooLarge is returned when a template renders more than MaxCustomOutput
+	ErrCustomOutputTooLarge = errors.New("rendered template exceeds 10MB")
+	// ErrCustomTooManySteps is returned when a render takes more than MaxCustomSteps steps
+	ErrCustomTooManySteps = errors.New("template exceeds 1000000 range iterations and template calls")
+)
+
+// stepFunc is called at the start of every range iteration and template call.
+// The name cannot clash with a builtin, and calling it from a template is harmless.
+const stepFunc = "_renderStep"
+
+// ParseCustom parses a caller-supplied text/template. Only the template
+// language's builtins are available to it; no functions are added that could
+// reach the filesystem, environment or processes.
+func ParseCustom(text string) (*template.Template, error) {
+	funcs := template.FuncMap{stepFunc: func() (string, error) { return "", nil }}
+	tmpl, err := template.New("outputTemplate").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every loop body and template starts with a step, which is where a render is stopped
+	stepTree, err := parse.Parse("step", "{{"+stepFunc+"}}", "", "", funcs)
+	if err != nil {
+		return nil, err
+	}
+	step := stepTree["step"].Root.Nodes[0]
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			instrument(t.Tree.Root, step)
+			t.Tree.Root.Nodes = append([]parse.Node{step.Copy()}, t.Tree.Root.Nodes...)
+		}
+	}
+	return tmpl, nil
+}
+
+// instrument adds step to the start of every range body below list
+func instrument(list *parse.ListNode, step parse.Node) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		var branch *parse.BranchNode
+		switch n := node.(type) {
+		case *parse.RangeNode:
+			branch = &n.BranchNode
+			n.List.Nodes = append([]parse.Node{step.Copy()}, n.List.Nodes...)
+		case *parse.IfNode:
+			branch = &n.BranchNode
+		case *parse.WithNode:
+			branch = &n.BranchNode
+		default:
+			continue
+		}
+		instrument(branch.List, step)
+		instrument(branch.ElseList, step)
+	}
+}
+
+// RenderCustom executes tmpl against data, stopping once MaxCustomOutput is
+// reached, after MaxCustomSteps steps or when ctx is done
+func RenderCustom(ctx context.Context, tmpl *template.Template, data any) (string, error) {
+	// Each render counts its own steps, so concurrent calls cannot share the template
+	run, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	steps := 0
+	run.Funcs(template.FuncMap{stepFunc: func() (string, error) {
+		if steps++; steps > MaxCustomSteps {
+			return "", ErrCustomTooManySteps
+		}
+		return "", ctx.Err()
+	}})
+
+	w := &limitedBuffer{limit: MaxCustomOutput}
+	if err := run.Execute(w, data); err != nil {
+		switch {
+		case w.exceeded:
+			return "", ErrCustomOutputTooLarge
+		case steps > MaxCustomSteps:
+			return "", ErrCustomTooManySteps
+		case ctx.Err() != nil:
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+// limitedBuffer is a bytes.Buffer that refuses writes past limit
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+// Write fails instead of growing the buffer past its limit
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		b.exceeded = true
+		return 0, ErrCustomOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderCustom(t *testing.T) {
+	tmpl, err := ParseCustom(`{{.Domain}}:{{range .Subdomains}} {{.Name}}{{end}} ({{.Total}})`)
+	if err != nil {
+		t.Fatalf("ParseCustom failed: %v", err)
+	}
+	rendered, err := RenderCustom(context.Background(), tmpl, testData())
+	if err != nil {
+		t.Fatalf("RenderCustom failed: %v", err)
+	}
+	if expected := "example.com: api.example.com cdn.example.com <script>.example.com (3)"; rendered != expected {
+		t.Errorf("Expected %q, got %q", expected, rendered)
+	}
+}
+
+func TestParseCustomRejectsInvalidTemplates(t *testing.T) {
+	for _, text := range []string{`{{.Domain`, `{{env "HOME"}}`, `{{exec "id"}}`} {
+		if _, err := ParseCustom(text); err == nil {
+			t.Errorf("Expected %q to fail to parse", text)
+		}
+	}
+}
+
+func TestRenderCustomLimitsOutput(t *testing.T) {
+	// Each chunk renders all four 1MB blocks, 12MB in total
+	tmpl, err := ParseCustom(`{{define "mb"}}{{range .}}{{.}}{{end}}{{end}}{{range .Chunks}}{{template "mb" $.Blocks}}{{end}}`)
+	if err != nil {
+		t.Fatalf("ParseCustom failed: %v", err)
+	}
+	block := string(make([]byte, 1<<20))
+	data := struct {
+		Chunks []int
+		Blocks []string
+	}{Chunks: make([]int, 3), Blocks: []string{block, block, block, block}}
+
+	if _, err := RenderCustom(context.Background(), tmpl, data); !errors.Is(err, ErrCustomOutputTooLarge) {
+		t.Errorf("Expected ErrCustomOutputTooLarge, got %v", err)
+	}
+}
+
+func TestRenderCustomStopsLoopsWithoutOutput(t *testing.T) {
+	for _, text := range []string{
+		`{{range 1000000000000}}{{end}}`,
+		`{{range .Subdomains}}{{range $.Subdomains}}{{range 1000000}}{{end}}{{end}}{{end}}`,
+		doublingTemplates(40),
+	} {
+		tmpl, err := ParseCustom(text)
+		if err != nil {
+			t.Fatalf("ParseCustom(%q) failed: %v", text, err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := RenderCustom(context.Background(), tmpl, testData())
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrCustomTooManySteps) {
+				t.Errorf("Expected ErrCustomTooManySteps for %q, got %v", text, err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Expected %q to stop", text)
+		}
+	}
+}
+
+// doublingTemplates defines n templates that each call the next twice, 2^n calls
+// in all without a single range
+func doublingTemplates(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `{{define "t%d"}}{{template "t%d"}}{{template "t%d"}}{{end}}`, i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `{{define "t%d"}}{{end}}{{template "t0"}}`, n)
+	return b.String()
+}
+
+func TestRenderCustomStopsAtDeadline(t *testing.T) {
+	tmpl, err := ParseCustom(`{{range 1000000000000}}{{end}}`)
+	if err != nil {
+		t.Fatalf("ParseCustom failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderCustom(ctx, tmpl, testData()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the render to stop with the context, got %v", err)
+	}
+}
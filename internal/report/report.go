@@ -0,0 +1,113 @@
+// Package report renders human-readable summaries of enumeration results
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// Report formats accepted by Render
+const (
+	None     = "none"
+	Markdown = "markdown"
+	HTML     = "html"
+)
+
+// Formats lists the accepted report formats in the order they are documented
+var Formats = []string{None, Markdown, HTML}
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+	markdownTemplate = template.Must(template.ParseFS(templateFS, "templates/report.md.tmpl"))
+	htmlTemplate     = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/report.html.tmpl"))
+)
+
+// Subdomain is one reported name with the sources that found it
+type Subdomain struct {
+	Name          string
+	Sources       []string
+	CloudProvider string
+}
+
+// ProviderCount is how many subdomains a cloud provider hosts
+type ProviderCount struct {
+	Provider string
+	Count    int
+}
+
+// TakeoverRisk is a subdomain whose CNAME points at a name that no longer resolves
+type TakeoverRisk struct {
+	Subdomain string
+	CNAME     string
+}
+
+// Data is everything a report shows
+type Data struct {
+	Domain        string
+	GeneratedAt   time.Time
+	Subdomains    []Subdomain
+	TakeoverRisks []TakeoverRisk
+}
+
+// Total is the number of subdomains reported
+func (d Data) Total() int {
+	return len(d.Subdomains)
+}
+
+// CloudProviders counts subdomains per cloud provider, most common first
+func (d Data) CloudProviders() []ProviderCount {
+	counts := make(map[string]int)
+	for _, subdomain := range d.Subdomains {
+		if subdomain.CloudProvider != "" {
+			counts[subdomain.CloudProvider]++
+		}
+	}
+
+	providers := make([]ProviderCount, 0, len(counts))
+	for provider, count := range counts {
+		providers = append(providers, ProviderCount{Provider: provider, Count: count})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].Count != providers[j].Count {
+			return providers[i].Count > providers[j].Count
+		}
+		return providers[i].Provider < providers[j].Provider
+	})
+	return providers
+}
+
+// IsSupported reports whether name is a known report format
+func IsSupported(name string) bool {
+	for _, f := range Formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render produces the report in the named format and returns it with its MIME type
+func Render(name string, data Data) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch name {
+	case Markdown:
+		if err := markdownTemplate.Execute(&buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/markdown", nil
+	case HTML:
+		if err := htmlTemplate.Execute(&buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/html", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported report format %q", name)
+	}
+}
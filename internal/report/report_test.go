@@ -0,0 +1,76 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testData() Data {
+	return Data{
+		Domain:      "example.com",
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Subdomains: []Subdomain{
+			{Name: "api.example.com", Sources: []string{"crtsh", "hackertarget"}, CloudProvider: "aws"},
+			{Name: "cdn.example.com", Sources: []string{"crtsh"}, CloudProvider: "cloudflare"},
+			{Name: "<script>.example.com", CloudProvider: "aws"},
+		},
+		TakeoverRisks: []TakeoverRisk{{Subdomain: "old.example.com", CNAME: "old-app.herokuapp.com"}},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	data, mimeType, err := Render(Markdown, testData())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if mimeType != "text/markdown" {
+		t.Errorf("Expected text/markdown, got %s", mimeType)
+	}
+
+	report := string(data)
+	for _, want := range []string{
+		"# Subdomain Report: example.com",
+		"Generated 2024-05-01 12:00:00 UTC. Found **3** subdomains.",
+		"| aws | 2 |\n| cloudflare | 1 |",
+		"| old.example.com | old-app.herokuapp.com |",
+		"| api.example.com | crtsh, hackertarget | aws |",
+		"| <script>.example.com | - | aws |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	data, mimeType, err := Render(HTML, testData())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if mimeType != "text/html" {
+		t.Errorf("Expected text/html, got %s", mimeType)
+	}
+
+	report := string(data)
+	if !strings.Contains(report, "<td>api.example.com</td><td>crtsh, hackertarget</td><td>aws</td>") {
+		t.Errorf("Expected a row for api.example.com:\n%s", report)
+	}
+	if strings.Contains(report, "<script>.example.com") || !strings.Contains(report, "&lt;script&gt;.example.com") {
+		t.Error("Expected subdomain names to be HTML-escaped")
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	data, _, err := Render(Markdown, Data{Domain: "example.com"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if report := string(data); !strings.Contains(report, "No subdomains found.") || strings.Contains(report, "Cloud Providers") {
+		t.Errorf("Unexpected empty report:\n%s", report)
+	}
+
+	if _, _, err := Render(None, Data{}); err == nil {
+		t.Error("Expected an error when rendering format none")
+	}
+}
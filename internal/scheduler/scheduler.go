@@ -0,0 +1,308 @@
+// Package scheduler runs periodic re-enumeration jobs
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"mcp-subfinder-server/internal/validation"
+)
+
+// Limits on the jobs a Scheduler accepts
+const (
+	// MinInterval is the shortest interval a job may run at
+	MinInterval = time.Minute
+	// MaxJobsPerOwner is how many jobs one owner may have registered at once
+	MaxJobsPerOwner = 10
+	// MaxJobs is how many jobs may be registered at once across all owners
+	MaxJobs = 100
+)
+
+var (
+	// ErrJobNotFound is returned when a job ID is not registered
+	ErrJobNotFound = errors.New("job not found")
+	// ErrTooManyJobs is returned when the owner or the scheduler has no room for another job
+	ErrTooManyJobs = errors.New("too many scheduled jobs")
+)
+
+// RunFunc enumerates a job's domain and returns the subdomains found
+type RunFunc func(ctx context.Context) ([]string, error)
+
+// NotifyFunc is called with the subdomains a run found that no earlier run of the job had
+type NotifyFunc func(job JobInfo, newSubdomains []string)
+
+// JobSpec describes a job to register
+type JobSpec struct {
+	Domain   string
+	Interval time.Duration
+	// Owner identifies who created the job, such as a session ID, for the per-owner job limit
+	Owner string
+	// Params are the enumeration arguments, kept for display
+	Params map[string]interface{}
+	Run    RunFunc
+}
+
+// JobInfo is a snapshot of a registered job
+type JobInfo struct {
+	ID              string                 `json:"id"`
+	Domain          string                 `json:"domain"`
+	Interval        string                 `json:"interval"`
+	Params          map[string]interface{} `json:"params,omitempty"`
+	CreatedAt       time.Time              `json:"createdAt"`
+	LastRunAt       *time.Time             `json:"lastRunAt,omitempty"`
+	LastError       string                 `json:"lastError,omitempty"`
+	RunCount        int                    `json:"runCount"`
+	KnownSubdomains int                    `json:"knownSubdomains"`
+	// Paused jobs keep their configuration and history but skip their ticks
+	Paused bool `json:"paused"`
+}
+
+// job is a registered job and its running state
+type job struct {
+	info  JobInfo
+	owner string
+	run   RunFunc
+	known map[string]struct{}
+	// baselined is set once a successful run has established the known subdomains
+	baselined bool
+	cancel    context.CancelFunc
+}
+
+// Scheduler owns the job registry and one ticker goroutine per job
+type Scheduler struct {
+	mu     sync.RWMutex
+	jobs   map[string]*job
+	nextID int
+	ctx    context.Context
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	store  ResultStore
+	notify NotifyFunc
+	logger *slog.Logger
+	// minInterval and maxJobsPerOwner default to MinInterval and MaxJobsPerOwner
+	minInterval     time.Duration
+	maxJobsPerOwner int
+}
+
+// New creates a scheduler that saves every run to store and reports new subdomains to notify
+func New(store ResultStore, notify NotifyFunc, logger *slog.Logger) *Scheduler {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Scheduler{
+		jobs:   make(map[string]*job),
+		ctx:    ctx,
+		stop:   stop,
+		store:  store,
+		notify: notify,
+		logger: logger,
+
+		minInterval:     MinInterval,
+		maxJobsPerOwner: MaxJobsPerOwner,
+	}
+}
+
+// Create registers a job and starts its ticker. The first run happens after one interval.
+// It returns ErrTooManyJobs when the owner already has MaxJobsPerOwner jobs or the
+// scheduler has MaxJobs.
+func (s *Scheduler) Create(spec JobSpec) (JobInfo, error) {
+	if err := validation.ValidateDomain(spec.Domain); err != nil {
+		return JobInfo{}, fmt.Errorf("invalid domain: %w", err)
+	}
+	if spec.Interval < s.minInterval || spec.Interval <= 0 {
+		return JobInfo{}, fmt.Errorf("interval must be at least %s", s.minInterval)
+	}
+	if spec.Run == nil {
+		return JobInfo{}, errors.New("run function is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return JobInfo{}, errors.New("scheduler is stopped")
+	}
+	if len(s.jobs) >= MaxJobs {
+		return JobInfo{}, ErrTooManyJobs
+	}
+	owned := 0
+	for _, j := range s.jobs {
+		if j.owner == spec.Owner {
+			owned++
+		}
+	}
+	if owned >= s.maxJobsPerOwner {
+		return JobInfo{}, ErrTooManyJobs
+	}
+
+	s.nextID++
+	ctx, cancel := context.WithCancel(s.ctx)
+	j := &job{
+		info: JobInfo{
+			ID:        fmt.Sprintf("job-%d", s.nextID),
+			Domain:    spec.Domain,
+			Interval:  spec.Interval.String(),
+			Params:    spec.Params,
+			CreatedAt: time.Now(),
+		},
+		owner:  spec.Owner,
+		run:    spec.Run,
+		known:  make(map[string]struct{}),
+		cancel: cancel,
+	}
+	s.jobs[j.info.ID] = j
+
+	s.wg.Add(1)
+	go s.loop(ctx, j, spec.Interval)
+
+	s.logger.Info("Scheduled job created", "jobId", j.info.ID, "domain", spec.Domain, "interval", j.info.Interval)
+	return j.info, nil
+}
+
+// List returns the jobs owner created, oldest first
+func (s *Scheduler) List(owner string) []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		if j.owner == owner {
+			infos = append(infos, j.info)
+		}
+	}
+	sort.Slice(infos, func(i, k int) bool {
+		return infos[i].CreatedAt.Before(infos[k].CreatedAt) ||
+			(infos[i].CreatedAt.Equal(infos[k].CreatedAt) && infos[i].ID < infos[k].ID)
+	})
+	return infos
+}
+
+// Delete stops and removes one of owner's jobs. Another owner's job is reported
+// as not found.
+func (s *Scheduler) Delete(owner, id string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	ok = ok && j.owner == owner
+	if ok {
+		delete(s.jobs, id)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrJobNotFound
+	}
+	j.cancel()
+	s.logger.Info("Scheduled job deleted", "jobId", id)
+	return nil
+}
+
+// Pause stops one of owner's jobs from running on its ticks without removing it
+func (s *Scheduler) Pause(owner, id string) (JobInfo, error) {
+	return s.setPaused(owner, id, true)
+}
+
+// Resume lets one of owner's paused jobs run again from its next tick
+func (s *Scheduler) Resume(owner, id string) (JobInfo, error) {
+	return s.setPaused(owner, id, false)
+}
+
+// setPaused updates the paused flag of one of owner's jobs and returns its new
+// state. Another owner's job is reported as not found.
+func (s *Scheduler) setPaused(owner, id string, paused bool) (JobInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok || j.owner != owner {
+		return JobInfo{}, ErrJobNotFound
+	}
+	j.info.Paused = paused
+	s.logger.Info("Scheduled job updated", "jobId", id, "paused", paused)
+	return j.info, nil
+}
+
+// Stop cancels every job and waits for runs in progress to finish
+func (s *Scheduler) Stop() {
+	s.stop()
+	s.wg.Wait()
+}
+
+// loop runs a job on every tick until its context is cancelled
+func (s *Scheduler) loop(ctx context.Context, j *job, interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			paused := j.info.Paused
+			s.mu.RUnlock()
+			if paused {
+				continue
+			}
+			s.runJob(ctx, j)
+		}
+	}
+}
+
+// runJob runs one enumeration, saves it and reports subdomains not seen by earlier runs
+func (s *Scheduler) runJob(ctx context.Context, j *job) {
+	subdomains, err := j.run(ctx)
+	ranAt := time.Now()
+
+	s.mu.Lock()
+	j.info.LastRunAt = &ranAt
+	j.info.RunCount++
+	if err != nil {
+		j.info.LastError = err.Error()
+		s.mu.Unlock()
+		s.logger.Warn("Scheduled enumeration failed", "jobId", j.info.ID, "domain", j.info.Domain, "error", err)
+		return
+	}
+	j.info.LastError = ""
+
+	// The first successful run only establishes what is already known
+	firstRun := !j.baselined
+	j.baselined = true
+	var added []string
+	for _, subdomain := range subdomains {
+		if _, ok := j.known[subdomain]; ok {
+			continue
+		}
+		j.known[subdomain] = struct{}{}
+		if !firstRun {
+			added = append(added, subdomain)
+		}
+	}
+	j.info.KnownSubdomains = len(j.known)
+	info := j.info
+	s.mu.Unlock()
+
+	if err := s.store.SaveRun(RunRecord{
+		JobID:      info.ID,
+		Domain:     info.Domain,
+		RanAt:      ranAt,
+		Subdomains: subdomains,
+	}); err != nil {
+		s.logger.Error("Failed to save scheduled run", "jobId", info.ID, "error", err)
+	}
+
+	s.logger.Info("Scheduled enumeration complete",
+		"jobId", info.ID,
+		"domain", info.Domain,
+		"subdomainsFound", len(subdomains),
+		"newSubdomains", len(added))
+
+	if len(added) > 0 && s.notify != nil {
+		sort.Strings(added)
+		s.notify(info, added)
+	}
+}
@@ -0,0 +1,252 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLogger discards everything below error level
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// sequenceRun returns each result in turn, repeating the last one
+func sequenceRun(results ...[]string) RunFunc {
+	var mu sync.Mutex
+	calls := 0
+	return func(ctx context.Context) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result := results[min(calls, len(results)-1)]
+		calls++
+		return result, nil
+	}
+}
+
+// waitFor polls until cond holds or the deadline passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerNotifiesNewSubdomains(t *testing.T) {
+	store := NewMemoryStore()
+	var mu sync.Mutex
+	var notified [][]string
+	s := New(store, func(job JobInfo, added []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		notified = append(notified, added)
+	}, testLogger())
+	defer s.Stop()
+	// Sub-minute intervals keep the test fast
+	s.minInterval = time.Millisecond
+
+	job, err := s.Create(JobSpec{
+		Domain:   "example.com",
+		Interval: 10 * time.Millisecond,
+		Run: sequenceRun(
+			[]string{"a.example.com", "b.example.com"},
+			[]string{"a.example.com", "b.example.com", "c.example.com"},
+		),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	waitFor(t, func() bool { return len(store.Runs(job.ID)) >= 3 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The first run is the baseline; only c.example.com is ever new
+	expected := [][]string{{"c.example.com"}}
+	if !reflect.DeepEqual(notified, expected) {
+		t.Errorf("Expected notifications %v, got %v", expected, notified)
+	}
+
+	jobs := s.List("")
+	if len(jobs) != 1 || jobs[0].KnownSubdomains != 3 || jobs[0].RunCount < 3 || jobs[0].LastRunAt == nil {
+		t.Errorf("Unexpected job state: %+v", jobs)
+	}
+}
+
+func TestSchedulerRecordsErrors(t *testing.T) {
+	s := New(NewMemoryStore(), nil, testLogger())
+	defer s.Stop()
+	// Sub-minute intervals keep the test fast
+	s.minInterval = time.Millisecond
+
+	job, err := s.Create(JobSpec{
+		Domain:   "example.com",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) ([]string, error) {
+			return nil, errors.New("sources unavailable")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		jobs := s.List("")
+		return len(jobs) == 1 && jobs[0].LastError != ""
+	})
+	if jobs := s.List(""); jobs[0].ID != job.ID || jobs[0].LastError != "sources unavailable" {
+		t.Errorf("Unexpected job state: %+v", jobs[0])
+	}
+}
+
+func TestSchedulerCreateAndDelete(t *testing.T) {
+	s := New(NewMemoryStore(), nil, testLogger())
+	defer s.Stop()
+
+	run := sequenceRun([]string{"a.example.com"})
+	tests := []struct {
+		name string
+		spec JobSpec
+	}{
+		{"Missing domain", JobSpec{Interval: time.Hour, Run: run}},
+		{"Zero interval", JobSpec{Domain: "example.com", Run: run}},
+		{"Invalid domain", JobSpec{Domain: "example..com", Interval: time.Hour, Run: run}},
+		{"Interval under a minute", JobSpec{Domain: "example.com", Interval: 10 * time.Second, Run: run}},
+		{"Missing run function", JobSpec{Domain: "example.com", Interval: time.Hour}},
+	}
+	for _, tc := range tests {
+		if _, err := s.Create(tc.spec); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+
+	first, _ := s.Create(JobSpec{Domain: "example.com", Interval: time.Hour, Run: run})
+	second, _ := s.Create(JobSpec{Domain: "example.org", Interval: time.Hour, Run: run})
+	if first.ID == second.ID {
+		t.Fatalf("Expected unique job IDs, got %s twice", first.ID)
+	}
+	if jobs := s.List(""); len(jobs) != 2 || jobs[0].ID != first.ID {
+		t.Errorf("Expected two jobs in creation order, got %+v", jobs)
+	}
+
+	if err := s.Delete("", first.ID); err != nil {
+		t.Errorf("Expected delete to succeed, got %v", err)
+	}
+	if err := s.Delete("", first.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if jobs := s.List(""); len(jobs) != 1 || jobs[0].ID != second.ID {
+		t.Errorf("Expected only the second job, got %+v", jobs)
+	}
+
+	// Each owner has their own job limit
+	for i := 0; i < MaxJobsPerOwner; i++ {
+		if _, err := s.Create(JobSpec{Domain: "example.net", Interval: time.Hour, Owner: "session-a", Run: run}); err != nil {
+			t.Fatalf("Expected job %d of session-a to be created, got %v", i+1, err)
+		}
+	}
+	if _, err := s.Create(JobSpec{Domain: "example.net", Interval: time.Hour, Owner: "session-a", Run: run}); !errors.Is(err, ErrTooManyJobs) {
+		t.Errorf("Expected ErrTooManyJobs past the per-owner limit, got %v", err)
+	}
+	other, err := s.Create(JobSpec{Domain: "example.net", Interval: time.Hour, Owner: "session-b", Run: run})
+	if err != nil {
+		t.Errorf("Expected another owner to create a job, got %v", err)
+	}
+
+	// Owners only see and delete their own jobs
+	if jobs := s.List("session-b"); len(jobs) != 1 || jobs[0].ID != other.ID {
+		t.Errorf("Expected only session-b's job, got %+v", jobs)
+	}
+	if err := s.Delete("session-a", other.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound deleting another owner's job, got %v", err)
+	}
+	if err := s.Delete("session-b", other.ID); err != nil {
+		t.Errorf("Expected the owner to delete their job, got %v", err)
+	}
+
+	s.Stop()
+	if _, err := s.Create(JobSpec{Domain: "example.net", Interval: time.Hour, Run: run}); err == nil {
+		t.Error("Expected create to fail after Stop")
+	}
+}
+
+func TestSchedulerPauseResume(t *testing.T) {
+	store := NewMemoryStore()
+	var mu sync.Mutex
+	var notified [][]string
+	s := New(store, func(job JobInfo, added []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		notified = append(notified, added)
+	}, testLogger())
+	defer s.Stop()
+	// Sub-minute intervals keep the test fast
+	s.minInterval = time.Millisecond
+
+	job, err := s.Create(JobSpec{
+		Domain:   "example.com",
+		Interval: 10 * time.Millisecond,
+		Run: sequenceRun(
+			[]string{"a.example.com"},
+			[]string{"a.example.com"},
+			[]string{"a.example.com", "b.example.com"},
+		),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	waitFor(t, func() bool { return len(store.Runs(job.ID)) >= 2 })
+
+	paused, err := s.Pause("", job.ID)
+	if err != nil || !paused.Paused {
+		t.Fatalf("Expected pause to succeed, got %+v, %v", paused, err)
+	}
+
+	// A run already in progress may still finish; after that nothing fires
+	time.Sleep(20 * time.Millisecond)
+	before := s.List("")[0]
+	time.Sleep(50 * time.Millisecond)
+	during := s.List("")[0]
+	if !during.Paused || during.RunCount != before.RunCount {
+		t.Errorf("Expected a paused job not to run, went from %d to %d runs", before.RunCount, during.RunCount)
+	}
+	if during.KnownSubdomains != 1 || during.LastRunAt == nil || during.Domain != "example.com" {
+		t.Errorf("Expected a paused job to keep its state, got %+v", during)
+	}
+
+	resumed, err := s.Resume("", job.ID)
+	if err != nil || resumed.Paused {
+		t.Fatalf("Expected resume to succeed, got %+v, %v", resumed, err)
+	}
+	waitFor(t, func() bool { return s.List("")[0].RunCount > during.RunCount+1 })
+
+	// The known set survived the pause, so only b.example.com is new
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := [][]string{{"b.example.com"}}; !reflect.DeepEqual(notified, expected) {
+		t.Errorf("Expected notifications %v, got %v", expected, notified)
+	}
+
+	if _, err := s.Pause("", "job-missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if _, err := s.Resume("", "job-missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	// Only the job's owner can pause or resume it
+	if _, err := s.Pause("session-b", job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound pausing another owner's job, got %v", err)
+	}
+	if _, err := s.Resume("session-b", job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound resuming another owner's job, got %v", err)
+	}
+}
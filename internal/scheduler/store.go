@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRunsPerJob caps how many runs MemoryStore keeps for each job
+const maxRunsPerJob = 100
+
+// RunRecord is the outcome of one scheduled enumeration
+type RunRecord struct {
+	JobID      string    `json:"jobId"`
+	Domain     string    `json:"domain"`
+	RanAt      time.Time `json:"ranAt"`
+	Subdomains []string  `json:"subdomains"`
+}
+
+// ResultStore persists the results of scheduled runs. The server ships only
+// MemoryStore, since it has no database to write them to.
+type ResultStore interface {
+	SaveRun(record RunRecord) error
+}
+
+// MemoryStore keeps the most recent runs of each job in memory
+type MemoryStore struct {
+	mu   sync.Mutex
+	runs map[string][]RunRecord
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{runs: make(map[string][]RunRecord)}
+}
+
+// SaveRun records a run, dropping the job's oldest run once maxRunsPerJob is reached
+func (m *MemoryStore) SaveRun(record RunRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := append(m.runs[record.JobID], record)
+	if len(runs) > maxRunsPerJob {
+		runs = runs[len(runs)-maxRunsPerJob:]
+	}
+	m.runs[record.JobID] = runs
+	return nil
+}
+
+// Runs returns the stored runs of a job, oldest first
+func (m *MemoryStore) Runs(jobID string) []RunRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]RunRecord(nil), m.runs[jobID]...)
+}
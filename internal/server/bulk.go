@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/mcp"
+	"mcp-subfinder-server/internal/plugin"
+	"mcp-subfinder-server/internal/subfinder"
+	"mcp-subfinder-server/internal/validation"
+)
+
+// Limits of POST /mcp/bulk-enumerate
+const (
+	// MaxBulkDomains is the most domains one request may list
+	MaxBulkDomains = 50
+	// DefaultBulkTimeout is each enumeration's timeout in seconds without ?timeout
+	DefaultBulkTimeout = 30
+	// bulkWorkers is how many of a request's domains are enumerated at once
+	bulkWorkers = DefaultWorkerCount
+	// maxBulkBodyBytes bounds the domain list that is read
+	maxBulkBodyBytes = 64 << 10
+	// maxConcurrentBulk is how many bulk requests may run at once; each holds up
+	// to bulkWorkers enumerations for as long as its timeout allows
+	maxConcurrentBulk = 1
+)
+
+// BulkFailedHeader lists the domains whose enumeration failed, comma-separated;
+// they are still in the response body, with no subdomains
+const BulkFailedHeader = "X-Bulk-Failed"
+
+// enumerateFunc runs one enumeration and returns the subdomains found
+type enumerateFunc func(ctx context.Context, domain string, config subfinder.SubfinderConfig, logger *slog.Logger) ([]string, error)
+
+// NewBulkEnumerateHandler serves POST /mcp/bulk-enumerate: a text/plain body with
+// one domain per line, each enumerated with the server's default options, and a
+// JSON object mapping every domain to its subdomains in response
+func NewBulkEnumerateHandler(providerConfigPath string, logger *slog.Logger) http.HandlerFunc {
+	return newBulkEnumerateHandler(mcp.DefaultEnumerationConfig(providerConfigPath), plugin.RunEnumeration, logger)
+}
+
+// newBulkEnumerateHandler serves bulk enumerations run with enumerate
+func newBulkEnumerateHandler(config subfinder.SubfinderConfig, enumerate enumerateFunc, logger *slog.Logger) http.HandlerFunc {
+	running := make(chan struct{}, maxConcurrentBulk)
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "text/plain" {
+			http.Error(w, "Content type must be text/plain", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		timeout := DefaultBulkTimeout
+		if timeoutVal := r.URL.Query().Get("timeout"); timeoutVal != "" {
+			v, err := strconv.Atoi(timeoutVal)
+			if err != nil || v < 1 {
+				http.Error(w, "timeout must be a positive number of seconds", http.StatusBadRequest)
+				return
+			}
+			timeout = min(v, mcp.MaxEnumerationTimeout)
+		}
+
+		domains, message := bulkDomains(w, r)
+		if message != "" {
+			http.Error(w, message, http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case running <- struct{}{}:
+			defer func() { <-running }()
+		default:
+			logger.Warn("Bulk enumeration already running, rejecting request")
+			w.Header().Set("Retry-After", strconv.Itoa(timeout))
+			http.Error(w, "A bulk enumeration is already running, retry later", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Enumerations run in waves of bulkWorkers, so the response may take longer
+		// than the server's write timeout allows for ordinary requests
+		waves := (len(domains) + bulkWorkers - 1) / bulkWorkers
+		deadline := time.Now().Add(time.Duration(waves*timeout)*time.Second + 10*time.Second)
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			logger.Debug("Could not extend the bulk enumeration write deadline", "error", err)
+		}
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+
+		config.Timeout = timeout
+		logger.Info("Running bulk enumeration", "domains", len(domains), "timeout", timeout)
+
+		results := make(map[string][]string, len(domains))
+		var failed []string
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		work := make(chan string)
+		for i := 0; i < min(bulkWorkers, len(domains)); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for domain := range work {
+					// Each domain gets its own timeout, so a slow one cannot use up the others' share
+					domainCtx, cancelDomain := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+					subdomains, err := enumerate(domainCtx, domain, config, logger)
+					cancelDomain()
+					mu.Lock()
+					if err != nil {
+						logger.Warn("Bulk enumeration failed for domain", "domain", domain, "error", err)
+						failed = append(failed, domain)
+					}
+					if subdomains == nil {
+						subdomains = []string{}
+					}
+					results[domain] = subdomains
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, domain := range domains {
+			work <- domain
+		}
+		close(work)
+		wg.Wait()
+
+		responseJSON, _ := jsoniter.Marshal(results)
+		if len(failed) > 0 {
+			w.Header().Set(BulkFailedHeader, strings.Join(failed, ","))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(responseJSON)
+	}
+}
+
+// bulkDomains reads the request's domain list, skipping blank lines and repeats.
+// It returns a message for the caller instead when the list is unusable.
+func bulkDomains(w http.ResponseWriter, r *http.Request) ([]string, string) {
+	var domains []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxBulkBodyBytes))
+	for line := 1; scanner.Scan(); line++ {
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scanner.Text()), "."))
+		if domain == "" {
+			continue
+		}
+		if err := validation.ValidateDomain(domain); err != nil {
+			return nil, "line " + strconv.Itoa(line) + ": " + err.Error()
+		}
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		seen[domain] = struct{}{}
+		domains = append(domains, domain)
+		if len(domains) > MaxBulkDomains {
+			return nil, "at most " + strconv.Itoa(MaxBulkDomains) + " domains can be enumerated at once"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "failed to read the domain list: " + err.Error()
+	}
+	if len(domains) == 0 {
+		return nil, "no domains given, send one per line"
+	}
+	return domains, ""
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-subfinder-server/internal/mcp"
+	"mcp-subfinder-server/internal/subfinder"
+)
+
+func TestBulkEnumerateHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var mu sync.Mutex
+	var timeouts []int
+	var unbounded []string
+	enumerate := func(ctx context.Context, domain string, config subfinder.SubfinderConfig, _ *slog.Logger) ([]string, error) {
+		mu.Lock()
+		timeouts = append(timeouts, config.Timeout)
+		// Each domain's context ends with its own timeout, not the whole request's
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Duration(config.Timeout)*time.Second {
+			unbounded = append(unbounded, domain)
+		}
+		mu.Unlock()
+		if domain == "broken.com" {
+			return nil, errors.New("enumeration failed")
+		}
+		return []string{"www." + domain}, nil
+	}
+	handler := newBulkEnumerateHandler(subfinder.SubfinderConfig{Timeout: 60}, enumerate, logger)
+
+	post := func(target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := post("/mcp/bulk-enumerate?timeout=5", "text/plain; charset=utf-8", "example.com\n\nEXAMPLE.org.\nexample.com\nbroken.com\n")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var results map[string][]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	expected := map[string][]string{
+		"example.com": {"www.example.com"},
+		"example.org": {"www.example.org"},
+		"broken.com":  {},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+	if failed := rr.Header().Get(BulkFailedHeader); failed != "broken.com" {
+		t.Errorf("Expected broken.com in %s, got %q", BulkFailedHeader, failed)
+	}
+	if !reflect.DeepEqual(timeouts, []int{5, 5, 5}) {
+		t.Errorf("Expected each enumeration to use the 5s timeout, got %v", timeouts)
+	}
+	if len(unbounded) > 0 {
+		t.Errorf("Expected every domain to be bounded by the timeout, got %v", unbounded)
+	}
+
+	timeouts = nil
+	if rr := post("/mcp/bulk-enumerate?timeout=100000", "text/plain", "example.com\n"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if !reflect.DeepEqual(timeouts, []int{mcp.MaxEnumerationTimeout}) {
+		t.Errorf("Expected the timeout to be clamped to %d, got %v", mcp.MaxEnumerationTimeout, timeouts)
+	}
+
+	tooMany := "a.com\n"
+	for i := 0; i < MaxBulkDomains; i++ {
+		tooMany += "d" + strings.Repeat("x", i) + ".com\n"
+	}
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"JSON body", "/mcp/bulk-enumerate", "application/json", `["example.com"]`, http.StatusUnsupportedMediaType},
+		{"Invalid domain", "/mcp/bulk-enumerate", "text/plain", "example.com\nnot a domain\n", http.StatusBadRequest},
+		{"Empty list", "/mcp/bulk-enumerate", "text/plain", "\n\n", http.StatusBadRequest},
+		{"Too many domains", "/mcp/bulk-enumerate", "text/plain", tooMany, http.StatusBadRequest},
+		{"Invalid timeout", "/mcp/bulk-enumerate?timeout=0", "text/plain", "example.com", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		if rr := post(tc.target, tc.contentType, tc.body); rr.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/mcp/bulk-enumerate", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", rr.Code)
+	}
+}
+
+func TestBulkEnumerateHandlerConcurrency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	enumerate := func(_ context.Context, domain string, _ subfinder.SubfinderConfig, _ *slog.Logger) ([]string, error) {
+		close(started)
+		<-release
+		return []string{"www." + domain}, nil
+	}
+	handler := newBulkEnumerateHandler(subfinder.SubfinderConfig{}, enumerate, logger)
+
+	post := func(domain string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/bulk-enumerate", strings.NewReader(domain+"\n"))
+		req.Header.Set("Content-Type", "text/plain")
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	done := make(chan int, 1)
+	go func() { done <- post("example.com").Code }()
+	<-started
+
+	rr := post("example.org")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while a bulk request runs, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected the running request to finish with 200, got %d", code)
+	}
+}
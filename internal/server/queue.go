@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mcp-subfinder-server/internal/mcp"
+	"mcp-subfinder-server/internal/metrics"
+)
+
+// Defaults for the tools.call queue
+const (
+	DefaultQueueDepth  = 50
+	DefaultWorkerCount = 4
+)
+
+// States of a queued call; the worker and the waiting caller race to claim it
+const (
+	callQueued int32 = iota
+	callRunning
+	callAbandoned
+)
+
+// toolsCallHandler executes a tools.call request
+type toolsCallHandler func(ctx context.Context, req *mcp.Request, providerConfigPath string, logger *slog.Logger) mcp.Response
+
+// queuedCall is a tools.call waiting for or being run by a worker
+type queuedCall struct {
+	ctx     context.Context
+	req     *mcp.Request
+	state   atomic.Int32
+	started chan struct{}
+	done    chan mcp.Response
+}
+
+// Queue is a bounded tools.call queue drained by a fixed pool of workers, so
+// burst traffic is rejected quickly instead of piling up goroutines
+type Queue struct {
+	calls              chan *queuedCall
+	timeout            time.Duration
+	providerConfigPath string
+	logger             *slog.Logger
+	handle             toolsCallHandler
+	wg                 sync.WaitGroup
+	// mu guards closed, so Submit never sends on calls after Stop closes it
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewQueue starts workers goroutines draining a queue of the given depth.
+// A request that waits longer than timeout for a worker is rejected.
+func NewQueue(depth, workers int, timeout time.Duration, providerConfigPath string, logger *slog.Logger) *Queue {
+	return newQueue(depth, workers, timeout, providerConfigPath, logger, mcp.HandleToolsCall)
+}
+
+// newQueue creates a queue that runs calls with handle
+func newQueue(depth, workers int, timeout time.Duration, providerConfigPath string, logger *slog.Logger, handle toolsCallHandler) *Queue {
+	if depth < 1 {
+		depth = DefaultQueueDepth
+	}
+	if workers < 1 {
+		workers = DefaultWorkerCount
+	}
+
+	q := &Queue{
+		calls:              make(chan *queuedCall, depth),
+		timeout:            timeout,
+		providerConfigPath: providerConfigPath,
+		logger:             logger,
+		handle:             handle,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues a tools.call and waits for its response. It returns
+// ErrServerOverloaded at once when the queue is full, and also when no worker
+// picks the call up within the queue timeout.
+func (q *Queue) Submit(ctx context.Context, req *mcp.Request) mcp.Response {
+	call := &queuedCall{
+		ctx:     ctx,
+		req:     req,
+		started: make(chan struct{}),
+		done:    make(chan mcp.Response, 1),
+	}
+
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		q.logger.Warn("tools.call queue stopped, rejecting request")
+		return overloadedResponse(req)
+	}
+	select {
+	case q.calls <- call:
+		metrics.QueueDepth.Set(float64(len(q.calls)))
+		q.mu.RUnlock()
+	default:
+		q.mu.RUnlock()
+		q.logger.Warn("tools.call queue full, rejecting request", "queueDepth", cap(q.calls))
+		return overloadedResponse(req)
+	}
+
+	var timeout <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-call.started:
+	case <-timeout:
+		if call.state.CompareAndSwap(callQueued, callAbandoned) {
+			q.logger.Warn("tools.call timed out in queue", "timeout", q.timeout)
+			return overloadedResponse(req)
+		}
+	case <-ctx.Done():
+		if call.state.CompareAndSwap(callQueued, callAbandoned) {
+			return overloadedResponse(req)
+		}
+	}
+
+	return <-call.done
+}
+
+// Stop closes the queue and waits for the workers to finish the calls already
+// queued. Calls submitted afterwards are rejected with ErrServerOverloaded.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.calls)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// work runs queued calls until the queue is closed
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for call := range q.calls {
+		metrics.QueueDepth.Set(float64(len(q.calls)))
+
+		// The caller already gave up on this call
+		if !call.state.CompareAndSwap(callQueued, callRunning) {
+			continue
+		}
+		close(call.started)
+		call.done <- q.handle(call.ctx, call.req, q.providerConfigPath, q.logger)
+	}
+}
+
+// overloadedResponse builds the JSON-RPC error returned for rejected calls
+func overloadedResponse(req *mcp.Request) mcp.Response {
+	return mcp.Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error:   mcp.ErrServerOverloaded,
+	}
+}
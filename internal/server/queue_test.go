@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"mcp-subfinder-server/internal/mcp"
+)
+
+// blockingHandler returns a tools.call handler that signals started and then
+// waits for release before responding
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) toolsCallHandler {
+	return func(ctx context.Context, req *mcp.Request, _ string, _ *slog.Logger) mcp.Response {
+		if started != nil {
+			started <- struct{}{}
+		}
+		<-release
+		return mcp.Response{JSONRPC: "2.0", ID: req.ID, Result: "done"}
+	}
+}
+
+func queueRequest(id string) *mcp.Request {
+	raw := jsoniter.RawMessage(id)
+	return &mcp.Request{JSONRPC: "2.0", ID: &raw, Method: "tools.call"}
+}
+
+func TestQueueRunsCalls(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	q := newQueue(2, 1, time.Second, "", slog.New(slog.NewTextHandler(io.Discard, nil)), blockingHandler(nil, release))
+	defer q.Stop()
+
+	resp := q.Submit(context.Background(), queueRequest("1"))
+	if resp.Error != nil || resp.Result != "done" {
+		t.Fatalf("Expected a handled response, got %+v", resp)
+	}
+}
+
+func TestQueueRejectsWhenFull(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	q := newQueue(1, 1, 5*time.Second, "", slog.New(slog.NewTextHandler(io.Discard, nil)), blockingHandler(started, release))
+	defer q.Stop()
+
+	// The first call occupies the only worker, the second fills the queue
+	results := make(chan mcp.Response, 2)
+	go func() { results <- q.Submit(context.Background(), queueRequest("1")) }()
+	<-started
+	go func() { results <- q.Submit(context.Background(), queueRequest("2")) }()
+	deadline := time.Now().Add(time.Second)
+	for len(q.calls) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	resp := q.Submit(context.Background(), queueRequest("3"))
+	if resp.Error == nil || resp.Error.Code != mcp.ServerOverloadedCode {
+		t.Errorf("Expected server overloaded error, got %+v", resp)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if resp := <-results; resp.Error != nil {
+			t.Errorf("Expected queued call to succeed, got %+v", resp.Error)
+		}
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	q := newQueue(2, 1, 20*time.Millisecond, "", slog.New(slog.NewTextHandler(io.Discard, nil)), blockingHandler(started, release))
+	defer q.Stop()
+
+	first := make(chan mcp.Response, 1)
+	go func() { first <- q.Submit(context.Background(), queueRequest("1")) }()
+	<-started
+
+	// No worker frees up before the queue timeout
+	resp := q.Submit(context.Background(), queueRequest("2"))
+	if resp.Error == nil || resp.Error.Code != mcp.ServerOverloadedCode {
+		t.Fatalf("Expected server overloaded error after queue timeout, got %+v", resp)
+	}
+
+	close(release)
+	if resp := <-first; resp.Error != nil {
+		t.Errorf("Expected running call to succeed, got %+v", resp.Error)
+	}
+}
+
+func TestQueueSubmitAfterStop(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	q := newQueue(2, 1, time.Second, "", slog.New(slog.NewTextHandler(io.Discard, nil)), blockingHandler(nil, release))
+	q.Stop()
+	q.Stop()
+
+	// A call arriving during shutdown is rejected instead of panicking on the closed queue
+	resp := q.Submit(context.Background(), queueRequest("1"))
+	if resp.Error == nil || resp.Error.Code != mcp.ServerOverloadedCode {
+		t.Fatalf("Expected server overloaded error after Stop, got %+v", resp)
+	}
+}
@@ -3,15 +3,25 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"mcp-subfinder-server/internal/mcp"
+	"mcp-subfinder-server/internal/metrics"
+	"mcp-subfinder-server/internal/stats"
+	"mcp-subfinder-server/internal/weights"
 )
 
 // Server represents an HTTP server for handling MCP requests
@@ -39,7 +49,7 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 			JSONRPC: "2.0",
 			Error:   mcp.ErrParse,
 		}
-		responseJSON, _ := json.Marshal(errorResponse)
+		responseJSON, _ := jsoniter.Marshal(errorResponse)
 		w.WriteHeader(http.StatusOK) // Always return 200 OK for JSON-RPC
 		w.Write(responseJSON)
 		return
@@ -53,7 +63,7 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 			JSONRPC: "2.0",
 			Error:   mcp.ErrParse,
 		}
-		responseJSON, _ := json.Marshal(errorResponse)
+		responseJSON, _ := jsoniter.Marshal(errorResponse)
 		w.WriteHeader(http.StatusOK) // Always return 200 OK for JSON-RPC
 		w.Write(responseJSON)
 		return
@@ -62,19 +72,56 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 	// Create a default Logger for this request
 	logger := slog.Default()
 
+	// Prefer the client-chosen session, then the connection's, then a per-request one
+	reqCtx := r.Context()
+	if sessionID := r.Header.Get(mcp.SessionIDHeader); sessionID != "" {
+		session, err := mcp.DefaultSessions.GetOrCreate(sessionID)
+		if errors.Is(err, mcp.ErrTooManySessions) {
+			logger.Warn("Session registry is full, rejecting request")
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many sessions, retry later", http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, "Invalid "+mcp.SessionIDHeader+" header", http.StatusBadRequest)
+			return
+		}
+		reqCtx = mcp.WithSession(reqCtx, session)
+		w.Header().Set(mcp.SessionIDHeader, sessionID)
+	} else if mcp.SessionFromContext(reqCtx) == nil {
+		reqCtx = mcp.WithSession(reqCtx, mcp.NewSession())
+	}
+
 	// Process the request and get a response
 	var response mcp.Response
 
 	switch req.Method {
 	case "initialize":
-		response = mcp.HandleInitialize(&req)
+		response = mcp.HandleInitialize(reqCtx, &req, logger)
 	case "tools.list":
 		response = mcp.HandleToolsList(&req)
+	case "tools.get":
+		response = mcp.HandleToolsGet(&req, logger)
 	case "tools.call":
 		// Create a context with timeout for the operation
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(reqCtx, 5*time.Minute)
 		defer cancel()
 		response = mcp.HandleToolsCall(ctx, &req, "", logger)
+	case "schedule.create":
+		response = mcp.HandleScheduleCreate(reqCtx, &req, "", logger)
+	case "schedule.list":
+		response = mcp.HandleScheduleList(reqCtx, &req, logger)
+	case "schedule.delete":
+		response = mcp.HandleScheduleDelete(reqCtx, &req, logger)
+	case "schedule.pause":
+		response = mcp.HandleSchedulePause(reqCtx, &req, logger)
+	case "schedule.resume":
+		response = mcp.HandleScheduleResume(reqCtx, &req, logger)
+	case "cache.invalidate":
+		response = mcp.HandleCacheInvalidate(reqCtx, &req, logger)
+	case "resources.subscribe":
+		response = mcp.HandleResourcesSubscribe(reqCtx, &req, logger)
+	case "jobs.get":
+		response = mcp.HandleJobsGet(reqCtx, &req, logger)
 	default:
 		// Method not found
 		response = mcp.Response{
@@ -87,21 +134,229 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 	// Always set the JSONRPC version in the response
 	response.JSONRPC = "2.0"
 
-	// Write the response
-	responseJSON, err := json.Marshal(response)
+	// Write the response; encoding/json would base64 the jsoniter.RawMessage ID
+	responseJSON, err := jsoniter.Marshal(response)
 	if err != nil {
 		// If we can't marshal the response, return a server error
 		errorResponse := mcp.Response{
 			JSONRPC: "2.0",
 			Error:   mcp.ErrInternal,
 		}
-		responseJSON, _ = json.Marshal(errorResponse)
+		responseJSON, _ = jsoniter.Marshal(errorResponse)
 	}
 
 	w.WriteHeader(http.StatusOK) // Always return 200 OK for JSON-RPC
 	w.Write(responseJSON)
 }
 
+// ValidateHandler checks a JSON-RPC request body without executing it
+func ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var result mcp.ValidationResult
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		result = mcp.ValidationResult{
+			Valid:  false,
+			Errors: []string{"failed to read request body"},
+		}
+	} else {
+		result = mcp.ValidateRequest(body)
+	}
+
+	responseJSON, _ := json.Marshal(result)
+	w.WriteHeader(http.StatusOK) // Validation outcome is reported in the body
+	w.Write(responseJSON)
+}
+
+// StatsHandler reports aggregate server statistics since the last restart
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	responseJSON, _ := json.Marshal(stats.Default.Snapshot(time.Now()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// SourceWeightsHandler serves GET /mcp/sources/weights, the per-source weights
+// learned from which reported subdomains resolved
+func SourceWeightsHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	responseJSON, _ := json.Marshal(weights.Default.Snapshot())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// RequireBearerToken only lets requests through to next when they carry
+// "Authorization: Bearer <token>". It fails closed: with an empty token every
+// request is rejected.
+func RequireBearerToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !HasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// HasBearerToken reports whether r carries "Authorization: Bearer <token>"; it is
+// always false for an empty token
+func HasBearerToken(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// JobsHandler serves GET /mcp/jobs, the deferred jobs seen in the last 24 hours,
+// optionally filtered with ?status=pending|running|completed|failed
+func JobsHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(mcp.JobListStatuses, status) {
+		http.Error(w, "Unknown status, expected one of "+strings.Join(mcp.JobListStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, _ := json.Marshal(mcp.ListJobs(status))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// JobResultHandler serves GET /mcp/jobs/{id}/result, the ToolCallResult of a
+// finished deferred job
+func JobResultHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, status, ok := mcp.JobResult(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if result == nil {
+		http.Error(w, "Job is still "+status, http.StatusConflict)
+		return
+	}
+
+	responseJSON, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// CacheHandler serves DELETE /mcp/cache/{domain}, evicting every cached result for the domain
+func CacheHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE requests
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain := r.PathValue("domain")
+	if domain == "" {
+		http.Error(w, "Domain is required", http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, _ := json.Marshal(mcp.CacheInvalidateResult{Evicted: mcp.InvalidateCache(domain)})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// toolsCacheMaxAge is how long clients may reuse the tools list without revalidating
+const toolsCacheMaxAge = time.Hour
+
+// NewToolsHandler serves the tools list over GET. The list only changes with the
+// server settings, so it is serialized once and tagged with the SHA-256 of its
+// body; clients that send a matching If-None-Match get 304 Not Modified.
+func NewToolsHandler() http.HandlerFunc {
+	body, _ := json.Marshal(mcp.ToolsList())
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	cacheControl := fmt.Sprintf("max-age=%d", int(toolsCacheMaxAge.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET requests
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", cacheControl)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// ToolSchemaHandler serves GET /mcp/tools/{name}/schema with the named tool's input schema
+func ToolSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tool, ok := mcp.FindTool(r.PathValue("name"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"tool not found"}`))
+		return
+	}
+
+	responseJSON, _ := json.Marshal(tool.InputSchema)
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // HealthHandler responds to health check requests
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -117,8 +372,8 @@ func New(providerConfigPath string, logger *slog.Logger) *Server {
 	}
 }
 
-// Start starts the HTTP server on the given port
-func (s *Server) Start(port int) error {
+// Start starts the HTTP server listening on address, such as 127.0.0.1:8080
+func (s *Server) Start(address string) error {
 	// Set up the HTTP handlers
 	mux := http.NewServeMux()
 	
@@ -127,11 +382,35 @@ func (s *Server) Start(port int) error {
 		MCPHandler(w, r)
 	})
 	
+	// Register the request validation handler
+	mux.HandleFunc("/mcp/validate", ValidateHandler)
+
+	// Register the aggregate statistics handler
+	mux.HandleFunc("/mcp/stats", StatsHandler)
+
+	// Register the cacheable tools list handler
+	mux.HandleFunc("/mcp/tools", NewToolsHandler())
+
+	// Register the single tool schema handler
+	mux.HandleFunc("/mcp/tools/{name}/schema", ToolSchemaHandler)
+
+	// Register the result cache invalidation handler
+	mux.HandleFunc("/mcp/cache/{domain}", CacheHandler)
+
+	// Register the Prometheus metrics handler
+	mux.HandleFunc("/metrics", metrics.Handler)
+
 	// Register the health check handler
 	mux.HandleFunc("/health", HealthHandler)
 	
-	// Start the server
-	addr := fmt.Sprintf(":%d", port)
-	s.Logger.Info("Starting MCP Subfinder Server", "address", addr)
-	return http.ListenAndServe(addr, mux)
+	// Start the server with one MCP session per connection
+	srv := &http.Server{
+		Addr:    address,
+		Handler: mux,
+		ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+			return mcp.WithSession(ctx, mcp.NewSession())
+		},
+	}
+	s.Logger.Info("Starting MCP Subfinder Server", "address", address)
+	return srv.ListenAndServe()
 }
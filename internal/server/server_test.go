@@ -2,11 +2,21 @@ package server
 
 import (
 	"bytes"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"mcp-subfinder-server/internal/mcp"
 )
 
 func TestMCPHandler(t *testing.T) {
@@ -37,27 +47,13 @@ func TestMCPHandler(t *testing.T) {
 					t.Errorf("Expected no error, got %v", errVal)
 				}
 				
-				// Check ID - it might be base64 encoded in the response
+				// Check ID - the exact number sent, not a base64 string
 				idVal, exists := response["id"]
 				if !exists {
 					t.Errorf("Expected ID to exist, but it's missing")
 					return
 				}
-				
-				// ID might be a base64 encoded string
-				if idString, ok := idVal.(string); ok {
-					// Try to decode it as base64
-					decoded, err := base64.StdEncoding.DecodeString(idString)
-					if err == nil {
-						// If it's a valid base64 string, check if it decodes to "1"
-						if string(decoded) != "1" {
-							t.Errorf("Expected decoded ID to be 1, got %s", string(decoded))
-						}
-					} else {
-						t.Errorf("Expected ID to be base64 encoded 1, got %s", idString)
-					}
-				} else if idVal != float64(1) {
-					// If it's not a string, check if it's directly the number 1
+				if idVal != float64(1) {
 					t.Errorf("Expected ID 1, got %v of type %T", idVal, idVal)
 				}
 				
@@ -173,6 +169,77 @@ func TestMCPHandler(t *testing.T) {
 	}
 }
 
+func TestMCPHandlerSessionID(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"0.3"}}`
+	for sessionID, expected := range map[string]int{
+		"client-1":               http.StatusOK,
+		"not valid":              http.StatusBadRequest,
+		strings.Repeat("a", 129): http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(mcp.SessionIDHeader, sessionID)
+		rr := httptest.NewRecorder()
+		MCPHandler(rr, req)
+		if rr.Code != expected {
+			t.Errorf("Expected status %d for session ID %q, got %d", expected, sessionID, rr.Code)
+		}
+	}
+}
+
+func TestValidateHandler(t *testing.T) {
+	handler := http.HandlerFunc(ValidateHandler)
+
+	tests := []struct {
+		name        string
+		rawBody     string
+		expectValid bool
+	}{
+		{
+			name:        "Valid request",
+			rawBody:     `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"0.3"}}`,
+			expectValid: true,
+		},
+		{
+			name:        "Invalid request",
+			rawBody:     `{"jsonrpc":"2.0","id":1,"method":"tools.call","params":{"name":"enumerateSubdomains","arguments":{}}}`,
+			expectValid: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/mcp/validate", bytes.NewBufferString(tc.rawBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			// Validation always responds with 200 OK
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if valid, _ := response["valid"].(bool); valid != tc.expectValid {
+				t.Errorf("Expected valid=%v, got %v", tc.expectValid, response["valid"])
+			}
+
+			if !tc.expectValid {
+				if errs, ok := response["errors"].([]interface{}); !ok || len(errs) == 0 {
+					t.Errorf("Expected errors for invalid request, got %v", response["errors"])
+				}
+			}
+		})
+	}
+}
+
 func TestHealthHandler(t *testing.T) {
 	// Create a new instance of our handler
 	handler := http.HandlerFunc(HealthHandler)
@@ -200,3 +267,240 @@ func TestHealthHandler(t *testing.T) {
 		t.Errorf("Handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
 	}
 }
+
+func TestStartBindAddress(t *testing.T) {
+	// Reserve a free loopback port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	srv := New("", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go srv.Start(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+
+	// Loopback clients can connect once the server is up
+	url := fmt.Sprintf("http://127.0.0.1:%d/health", port)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Failed to reach the server over loopback: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The same port on any other interface is not listening
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatalf("Failed to list interface addresses: %v", err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ipNet.IP.String(), strconv.Itoa(port)), time.Second)
+		if err == nil {
+			conn.Close()
+			t.Errorf("Expected connections to %s to be refused", ipNet.IP)
+		}
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	StatsHandler(rr, httptest.NewRequest(http.MethodGet, "/mcp/stats", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	for _, field := range []string{"uptime", "totalRequests", "totalEnumerations", "totalSubdomainsFound",
+		"averageEnumerationDurationMs", "cacheHitRate", "activeEnumerations", "errorRate"} {
+		if _, ok := response[field]; !ok {
+			t.Errorf("Expected field %s in stats response", field)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	StatsHandler(rr, httptest.NewRequest(http.MethodPost, "/mcp/stats", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rr.Code)
+	}
+}
+
+func TestSourceWeightsHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	SourceWeightsHandler(rr, httptest.NewRequest(http.MethodGet, "/mcp/sources/weights", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if _, ok := response["sources"].([]interface{}); !ok {
+		t.Errorf("Expected a sources array, got %v", response)
+	}
+
+	rr = httptest.NewRecorder()
+	SourceWeightsHandler(rr, httptest.NewRequest(http.MethodPost, "/mcp/sources/weights", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rr.Code)
+	}
+}
+
+func TestToolsHandler(t *testing.T) {
+	handler := NewToolsHandler()
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/mcp/tools", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if cacheControl := rr.Header().Get("Cache-Control"); cacheControl != "max-age=3600" {
+		t.Errorf("Expected Cache-Control max-age=3600, got %q", cacheControl)
+	}
+
+	sum := sha256.Sum256(rr.Body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	if got := rr.Header().Get("ETag"); got != etag {
+		t.Errorf("Expected ETag %s, got %s", etag, got)
+	}
+
+	var result mcp.ToolsListResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || len(result.Tools) == 0 {
+		t.Errorf("Expected a tools list, got %s (%v)", rr.Body.String(), err)
+	}
+
+	// Revalidation with the current tag is answered without a body
+	for _, ifNoneMatch := range []string{etag, `"stale", W/` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/mcp/tools", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rr = httptest.NewRecorder()
+		handler(rr, req)
+		if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d", ifNoneMatch, rr.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/mcp/tools", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a stale ETag, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/mcp/tools", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rr.Code)
+	}
+}
+
+func TestCacheHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp/cache/{domain}", CacheHandler)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/mcp/cache/example.com", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if body := rr.Body.String(); body != `{"evicted":0}` {
+		t.Errorf("Expected {\"evicted\":0}, got %s", body)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/mcp/cache/example.com", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", rr.Code)
+	}
+}
+
+func TestJobsHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp/jobs", RequireBearerToken("secret", JobsHandler))
+	mux.HandleFunc("/mcp/jobs/{id}/result", RequireBearerToken("secret", JobResultHandler))
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if rr := get("/mcp/jobs", token); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with token %q, got %d", token, rr.Code)
+		}
+	}
+
+	rr := get("/mcp/jobs?status=completed", "secret")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var summaries []mcp.JobSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summaries); err != nil {
+		t.Errorf("Expected a JSON list of jobs, got %s", rr.Body.String())
+	}
+
+	if rr := get("/mcp/jobs?status=done", "secret"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown status, got %d", rr.Code)
+	}
+	if rr := get("/mcp/jobs/async-unknown/result", "secret"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown job, got %d", rr.Code)
+	}
+
+	// Without a configured token the endpoints are closed, not open
+	closed := http.NewServeMux()
+	closed.HandleFunc("/mcp/jobs", RequireBearerToken("", JobsHandler))
+	for _, token := range []string{"", "anything"} {
+		req := httptest.NewRequest(http.MethodGet, "/mcp/jobs", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		closed.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without a configured token, got %d", rr.Code)
+		}
+	}
+}
+
+func TestToolSchemaHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp/tools/{name}/schema", ToolSchemaHandler)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/mcp/tools/enumerateSubdomains/schema", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/schema+json" {
+		t.Errorf("Expected application/schema+json, got %q", contentType)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &schema); err != nil || schema["type"] != "object" || schema["properties"] == nil {
+		t.Errorf("Expected an object schema with properties, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/mcp/tools/missingTool/schema", nil))
+	if rr.Code != http.StatusNotFound || rr.Body.String() != `{"error":"tool not found"}` {
+		t.Errorf("Expected a 404 tool not found, got %d %s", rr.Code, rr.Body.String())
+	}
+}
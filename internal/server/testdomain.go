@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"mcp-subfinder-server/internal/sources"
+	"mcp-subfinder-server/internal/subfinder"
+	"mcp-subfinder-server/internal/validation"
+)
+
+const (
+	// TestDomainTimeout is how many seconds the smoke test enumeration may take
+	TestDomainTimeout = 5
+	// testDomainTarget is what the smoke test enumerates; free sources know plenty of its subdomains
+	testDomainTarget = "github.com"
+	// maxTestDomainBodyBytes bounds the request body that is read
+	maxTestDomainBodyBytes = 4 << 10
+)
+
+// TestDomainResult is the response of POST /mcp/test-domain
+type TestDomainResult struct {
+	DomainValid               bool     `json:"domainValid"`
+	ProviderConfigValid       bool     `json:"providerConfigValid"`
+	SubfinderInit             bool     `json:"subfinderInit"`
+	TestEnumerationSubdomains int      `json:"testEnumerationSubdomains"`
+	Issues                    []string `json:"issues"`
+}
+
+// smokeChecks are the steps of the smoke test, replaceable for tests
+type smokeChecks struct {
+	providerConfig func() error
+	initRunner     func() error
+	// enumerate returns how many subdomains a source reported for testDomainTarget
+	enumerate func(ctx context.Context) (int, error)
+}
+
+// NewTestDomainHandler serves POST /mcp/test-domain, a quick check of the server's
+// setup: it validates {"domain": ...}, parses the provider config, creates a
+// subfinder runner and enumerates github.com for a few seconds with the sources
+// that need no API key. Problems are listed in issues rather than failing the
+// request, with the underlying errors only in the server log.
+func NewTestDomainHandler(providerConfigPath string, logger *slog.Logger) http.HandlerFunc {
+	return newTestDomainHandler(smokeChecks{
+		providerConfig: func() error { return sources.ValidateProviderConfig(providerConfigPath) },
+		initRunner:     func() error { return subfinder.InitRunner(providerConfigPath) },
+		enumerate: func(ctx context.Context) (int, error) {
+			config := subfinder.SubfinderConfig{
+				ProviderConfigPath: providerConfigPath,
+				Timeout:            TestDomainTimeout,
+				SourcesFilter:      strings.Join(sources.KeylessSources(), ","),
+				SilentMode:         true,
+				RetryConfig:        subfinder.RetryConfig{MaxAttempts: 1},
+			}
+			result, err := subfinder.Enumerate(ctx, testDomainTarget, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				return 0, err
+			}
+			// Fallback suggestions are listed when nothing was found, but have no sources
+			return len(result.Sources), nil
+		},
+	}, logger)
+}
+
+// newTestDomainHandler serves smoke tests run with checks
+func newTestDomainHandler(checks smokeChecks, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body struct {
+			Domain string `json:"domain"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTestDomainBodyBytes)).Decode(&body); err != nil {
+			http.Error(w, "Request body must be a JSON object like {\"domain\": \"example.com\"}", http.StatusBadRequest)
+			return
+		}
+
+		result := TestDomainResult{Issues: []string{}}
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(body.Domain), "."))
+		if err := validation.ValidateDomain(domain); err != nil {
+			result.Issues = append(result.Issues, "domain: "+err.Error())
+		} else {
+			result.DomainValid = true
+		}
+
+		// The errors can name server paths, so callers get a summary and the log the detail
+		if err := checks.providerConfig(); err != nil {
+			logger.Warn("Smoke test provider config check failed", "error", err)
+			result.Issues = append(result.Issues, "provider config: could not be read or is invalid; see the server log")
+		} else {
+			result.ProviderConfigValid = true
+		}
+
+		// Without a runner there is nothing to enumerate with
+		if err := checks.initRunner(); err != nil {
+			logger.Warn("Smoke test subfinder runner creation failed", "error", err)
+			result.Issues = append(result.Issues, "subfinder: runner could not be created; see the server log")
+		} else {
+			result.SubfinderInit = true
+
+			ctx, cancel := context.WithTimeout(r.Context(), TestDomainTimeout*time.Second)
+			found, err := checks.enumerate(ctx)
+			cancel()
+			result.TestEnumerationSubdomains = found
+			switch {
+			case err != nil:
+				logger.Warn("Smoke test enumeration failed", "error", err)
+				result.Issues = append(result.Issues, "test enumeration of "+testDomainTarget+" failed; see the server log")
+			case found == 0:
+				result.Issues = append(result.Issues, "test enumeration of "+testDomainTarget+" found no subdomains; check outbound network access")
+			}
+		}
+
+		logger.Info("Ran server smoke test",
+			"domainValid", result.DomainValid,
+			"providerConfigValid", result.ProviderConfigValid,
+			"subfinderInit", result.SubfinderInit,
+			"testEnumerationSubdomains", result.TestEnumerationSubdomains,
+			"issues", len(result.Issues))
+
+		responseJSON, _ := json.Marshal(result)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK) // Problems are reported in the body
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTestDomainHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerated := false
+	healthy := smokeChecks{
+		providerConfig: func() error { return nil },
+		initRunner:     func() error { return nil },
+		enumerate: func(ctx context.Context) (int, error) {
+			enumerated = true
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("Expected the test enumeration to have a deadline")
+			}
+			return 42, nil
+		},
+	}
+
+	post := func(checks smokeChecks, body string) (int, TestDomainResult) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/test-domain", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		newTestDomainHandler(checks, logger)(rr, req)
+		var result TestDomainResult
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+		}
+		return rr.Code, result
+	}
+
+	code, result := post(healthy, `{"domain": "example.com"}`)
+	expected := TestDomainResult{DomainValid: true, ProviderConfigValid: true, SubfinderInit: true, TestEnumerationSubdomains: 42, Issues: []string{}}
+	if code != http.StatusOK || !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %d %+v", expected, code, result)
+	}
+
+	// Failed checks are reported as issues, and no runner means no enumeration
+	enumerated = false
+	broken := healthy
+	broken.providerConfig = func() error { return errors.New("invalid provider config: yaml: line 1") }
+	broken.initRunner = func() error { return errors.New("no resolvers") }
+	code, result = post(broken, `{"domain": "not a domain"}`)
+	if code != http.StatusOK || result.DomainValid || result.ProviderConfigValid || result.SubfinderInit || len(result.Issues) != 3 {
+		t.Errorf("Expected three issues, got %d %+v", code, result)
+	}
+	if enumerated {
+		t.Error("Expected no test enumeration without a runner")
+	}
+	for _, issue := range result.Issues {
+		if strings.Contains(issue, "yaml") || strings.Contains(issue, "resolvers") {
+			t.Errorf("Expected a generic issue without the underlying error, got %q", issue)
+		}
+	}
+
+	if code, _ := post(healthy, `example.com`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body that is not JSON, got %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	newTestDomainHandler(healthy, logger)(rr, httptest.NewRequest(http.MethodGet, "/mcp/test-domain", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", rr.Code)
+	}
+}
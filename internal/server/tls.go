@@ -0,0 +1,35 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// EnableHTTP2 configures srv to negotiate HTTP/2 with TLS clients while still
+// serving HTTP/1.1, so MCP clients get multiplexed connections by default
+func EnableHTTP2(srv *http.Server) {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	srv.Protocols = protocols
+
+	// A non-nil TLSNextProto map, even an empty one, turns HTTP/2 off
+	srv.TLSNextProto = nil
+
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+}
+
+// AdvertiseHTTP2 adds an Alt-Svc header to HTTP/1.1 responses telling clients
+// that HTTP/2 is available on port
+func AdvertiseHTTP2(port int, next http.Handler) http.Handler {
+	altSvc := fmt.Sprintf(`h2=":%d"`, port)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 1 {
+			w.Header().Set("Alt-Svc", altSvc)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// selfSignedCertificate creates a throwaway certificate for 127.0.0.1
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := &http.Server{
+		Handler: AdvertiseHTTP2(8443, http.HandlerFunc(HealthHandler)),
+		// An empty map like this would normally disable HTTP/2
+		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
+	}
+	EnableHTTP2(srv)
+	srv.TLSConfig.Certificates = []tls.Certificate{selfSignedCertificate(t)}
+	go srv.ServeTLS(listener, "", "")
+	defer srv.Close()
+
+	url := "https://" + listener.Addr().String() + "/health"
+
+	// HTTP/2 clients are accepted and get no upgrade advertisement
+	h2Client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}}
+	resp, err := h2Client.Get(url)
+	if err != nil {
+		t.Fatalf("HTTP/2 request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != "" {
+		t.Errorf("Expected no Alt-Svc header over HTTP/2, got %q", altSvc)
+	}
+
+	// HTTP/1.1 clients are told HTTP/2 is available
+	h1Only := new(http.Protocols)
+	h1Only.SetHTTP1(true)
+	h1Client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, Protocols: h1Only}}
+	resp, err = h1Client.Get(url)
+	if err != nil {
+		t.Fatalf("HTTP/1.1 request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 {
+		t.Errorf("Expected HTTP/1.1, got %s", resp.Proto)
+	}
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != `h2=":8443"` {
+		t.Errorf("Expected Alt-Svc h2=\":8443\", got %q", altSvc)
+	}
+}
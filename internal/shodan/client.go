@@ -0,0 +1,145 @@
+// Package shodan looks up open ports and ownership of discovered hosts in the Shodan API
+package shodan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"mcp-subfinder-server/internal/probe"
+	"mcp-subfinder-server/internal/ratelimit"
+)
+
+const (
+	// APIKeyEnv is the environment variable read when no API key is passed explicitly
+	APIKeyEnv = "SHODAN_API_KEY"
+	// DefaultBaseURL is the Shodan REST API endpoint
+	DefaultBaseURL = "https://api.shodan.io"
+	// DefaultInterval is the minimum time between requests, per Shodan's 1 request/second limit
+	DefaultInterval = time.Second
+	// requestTimeout bounds each host lookup
+	requestTimeout = 10 * time.Second
+)
+
+// ErrUnauthorized is returned when Shodan rejects the API key
+var ErrUnauthorized = errors.New("shodan rejected the API key")
+
+// Host is the part of a Shodan host record attached to subdomain results
+type Host struct {
+	IP    string   `json:"ip"`
+	Ports []int    `json:"ports,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Org   string   `json:"org,omitempty"`
+	ISP   string   `json:"isp,omitempty"`
+	// ASN is the autonomous system announcing the address, such as AS15169
+	ASN string `json:"asn,omitempty"`
+}
+
+// Client queries the Shodan host API, spacing requests at least interval apart.
+// Clients with the same API key share that spacing.
+type Client struct {
+	apiKey   string
+	baseURL  string
+	interval time.Duration
+	pacer    *ratelimit.Pacer
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// NewClient creates a Client authenticating with apiKey whose requests carry userAgent,
+// with each connection allowed networkTimeout to be established
+func NewClient(apiKey, userAgent string, networkTimeout time.Duration, logger *slog.Logger) *Client {
+	return &Client{
+		apiKey:   apiKey,
+		baseURL:  DefaultBaseURL,
+		interval: DefaultInterval,
+		pacer:    ratelimit.ForKey("shodan", apiKey),
+		client:   probe.NewHTTPClient(requestTimeout, networkTimeout, userAgent),
+		logger:   logger,
+	}
+}
+
+// Hosts looks up every address one at a time, waiting the client's interval between
+// requests made with its API key, and returns the records found keyed by address.
+// Addresses Shodan has no record of are left out. Lookups stop early if the context
+// ends or the key is rejected.
+func (c *Client) Hosts(ctx context.Context, ips []string) map[string]*Host {
+	hosts := make(map[string]*Host, len(ips))
+	for _, ip := range ips {
+		release, err := c.pacer.Acquire(ctx, c.interval)
+		if err != nil {
+			return hosts
+		}
+
+		host, err := c.Host(ctx, ip)
+		release()
+		if errors.Is(err, ErrUnauthorized) {
+			c.logger.Warn("Stopping Shodan enrichment", "error", err)
+			return hosts
+		}
+		if err != nil {
+			c.logger.Debug("Shodan lookup failed", "ip", ip, "error", err)
+			continue
+		}
+		if host != nil {
+			hosts[ip] = host
+		}
+	}
+	return hosts
+}
+
+// Host fetches the Shodan record for one address. It returns nil without an
+// error when Shodan has no information about the address.
+func (c *Client) Host(ctx context.Context, ip string) (*Host, error) {
+	target := c.baseURL + "/shodan/host/" + url.PathEscape(ip) + "?key=" + url.QueryEscape(c.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The request URL carries the API key, so report the underlying error only
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	default:
+		return nil, fmt.Errorf("shodan returned status %d", resp.StatusCode)
+	}
+
+	var record struct {
+		Ports []int    `json:"ports"`
+		Tags  []string `json:"tags"`
+		Org   string   `json:"org"`
+		ISP   string   `json:"isp"`
+		ASN   string   `json:"asn"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode shodan response: %w", err)
+	}
+
+	return &Host{
+		IP:    ip,
+		Ports: record.Ports,
+		Tags:  record.Tags,
+		Org:   record.Org,
+		ISP:   record.ISP,
+		ASN:   record.ASN,
+	}, nil
+}
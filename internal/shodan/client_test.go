@@ -0,0 +1,70 @@
+package shodan
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientHosts(t *testing.T) {
+	var mu sync.Mutex
+	var requested []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, time.Now())
+		mu.Unlock()
+
+		if r.URL.Query().Get("key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/shodan/host/192.0.2.1":
+			w.Write([]byte(`{"ip_str": "192.0.2.1", "ports": [443, 80], "tags": ["cdn"], "org": "Example Org", "isp": "Example ISP", "asn": "AS64496"}`))
+		case "/shodan/host/192.0.2.2":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "No information available for that IP."}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := NewClient("test-key", "test-agent", time.Second, logger)
+	client.baseURL = server.URL
+	client.interval = 50 * time.Millisecond
+
+	hosts := client.Hosts(context.Background(), []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"})
+	expected := map[string]*Host{
+		"192.0.2.1": {IP: "192.0.2.1", Ports: []int{443, 80}, Tags: []string{"cdn"}, Org: "Example Org", ISP: "Example ISP", ASN: "AS64496"},
+	}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, hosts)
+	}
+
+	if len(requested) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requested))
+	}
+	for i := 1; i < len(requested); i++ {
+		if gap := requested[i].Sub(requested[i-1]); gap < client.interval {
+			t.Errorf("Expected requests at least %s apart, got %s", client.interval, gap)
+		}
+	}
+
+	// A rejected key stops the remaining lookups
+	requested = nil
+	client.apiKey = "wrong-key"
+	if hosts := client.Hosts(context.Background(), []string{"192.0.2.1", "192.0.2.2"}); len(hosts) != 0 {
+		t.Errorf("Expected no hosts with a rejected key, got %+v", hosts)
+	}
+	if len(requested) != 1 {
+		t.Errorf("Expected lookups to stop after the first rejection, got %d requests", len(requested))
+	}
+}
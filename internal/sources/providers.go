@@ -0,0 +1,137 @@
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"gopkg.in/yaml.v3"
+)
+
+// keyFormat describes the credentials a source takes and where to get them
+type keyFormat struct {
+	// Placeholder shows the shape of one key, e.g. API_ID:API_SECRET
+	Placeholder string
+	// SignupURL is where the credentials are issued
+	SignupURL string
+}
+
+// defaultPlaceholder is used for sources taking a single API key
+const defaultPlaceholder = "YOUR_API_KEY"
+
+// keyFormats covers the subfinder sources that need credentials. Sources missing
+// here still get a template entry, with a generic placeholder and no URL.
+var keyFormats = map[string]keyFormat{
+	"bevigil":        {SignupURL: "https://bevigil.com/osint-api"},
+	"binaryedge":     {SignupURL: "https://app.binaryedge.io/account/api"},
+	"bufferover":     {SignupURL: "https://tls.bufferover.run"},
+	"builtwith":      {SignupURL: "https://api.builtwith.com"},
+	"c99":            {SignupURL: "https://api.c99.nl"},
+	"censys":         {Placeholder: "API_ID:API_SECRET", SignupURL: "https://search.censys.io/account/api"},
+	"certspotter":    {SignupURL: "https://sslmate.com/certspotter/api"},
+	"chaos":          {SignupURL: "https://cloud.projectdiscovery.io"},
+	"chinaz":         {SignupURL: "https://api.chinaz.com"},
+	"digitalyama":    {SignupURL: "https://digitalyama.com"},
+	"dnsdb":          {SignupURL: "https://www.domaintools.com/products/farsight-dnsdb"},
+	"dnsdumpster":    {SignupURL: "https://dnsdumpster.com"},
+	"dnsrepo":        {Placeholder: "API_TOKEN:API_KEY", SignupURL: "https://dnsarchive.net"},
+	"facebook":       {Placeholder: "APP_ID:APP_SECRET", SignupURL: "https://developers.facebook.com/apps"},
+	"fofa":           {Placeholder: "EMAIL:API_KEY", SignupURL: "https://fofa.info/userInfo"},
+	"fullhunt":       {SignupURL: "https://fullhunt.io/user/settings"},
+	"github":         {Placeholder: "YOUR_PERSONAL_ACCESS_TOKEN", SignupURL: "https://github.com/settings/tokens"},
+	"hunter":         {SignupURL: "https://hunter.qianxin.com"},
+	"intelx":         {Placeholder: "API_HOST:API_KEY", SignupURL: "https://intelx.io/account?tab=developer"},
+	"leakix":         {SignupURL: "https://leakix.net/settings/api"},
+	"netlas":         {SignupURL: "https://app.netlas.io/profile"},
+	"quake":          {SignupURL: "https://quake.360.net"},
+	"redhuntlabs":    {Placeholder: "ENDPOINT_URL:API_KEY", SignupURL: "https://devportal.redhuntlabs.com"},
+	"robtex":         {SignupURL: "https://www.robtex.com/api"},
+	"securitytrails": {SignupURL: "https://securitytrails.com/app/account/credentials"},
+	"shodan":         {SignupURL: "https://account.shodan.io"},
+	"threatbook":     {SignupURL: "https://x.threatbook.com"},
+	"virustotal":     {SignupURL: "https://www.virustotal.com/gui/my-apikey"},
+	"whoisxmlapi":    {SignupURL: "https://user.whoisxmlapi.com/products"},
+	"zoomeyeapi":     {Placeholder: "API_HOST:API_KEY", SignupURL: "https://www.zoomeye.ai/profile"},
+}
+
+// ProviderConfigTemplate renders a commented provider config with an entry for
+// every subfinder source that needs credentials. Each entry is left empty with
+// a commented-out placeholder key, so the template loads as is and a source is
+// enabled by uncommenting its key and filling it in.
+func ProviderConfigTemplate() []byte {
+	var keyed, keyless []string
+	for _, source := range passive.AllSources {
+		name := strings.ToLower(source.Name())
+		if source.NeedsKey() {
+			keyed = append(keyed, name)
+		} else {
+			keyless = append(keyless, name)
+		}
+	}
+	sort.Strings(keyed)
+	sort.Strings(keyless)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Subfinder provider config\n")
+	buf.WriteString("#\n")
+	buf.WriteString("# List one or more keys under each source you have credentials for; subfinder\n")
+	buf.WriteString("# picks one at random for each request. Sources left empty are skipped.\n")
+	buf.WriteString("#\n")
+	fmt.Fprintf(&buf, "# These sources need no key: %s\n", strings.Join(keyless, ", "))
+
+	for _, name := range keyed {
+		format := keyFormats[name]
+		placeholder := format.Placeholder
+		if placeholder == "" {
+			placeholder = defaultPlaceholder
+		}
+
+		buf.WriteString("\n")
+		if placeholder == defaultPlaceholder {
+			fmt.Fprintf(&buf, "# %s: API key", name)
+		} else {
+			fmt.Fprintf(&buf, "# %s: key as %s", name, placeholder)
+		}
+		if format.SignupURL != "" {
+			fmt.Fprintf(&buf, ", from %s", format.SignupURL)
+		}
+		buf.WriteString("\n")
+		fmt.Fprintf(&buf, "%s:\n", name)
+		fmt.Fprintf(&buf, "#  - %s\n", placeholder)
+	}
+	return buf.Bytes()
+}
+
+// KeylessSources returns the names of the subfinder sources that need no API key,
+// in alphabetical order
+func KeylessSources() []string {
+	var names []string
+	for _, source := range passive.AllSources {
+		if !source.NeedsKey() {
+			names = append(names, strings.ToLower(source.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateProviderConfig checks that the provider config at path lists keys
+// under source names the way subfinder expects. An empty file is valid and
+// configures no keys.
+func ValidateProviderConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read provider config: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var providers map[string][]string
+	if err := yaml.Unmarshal(data, &providers); err != nil {
+		return fmt.Errorf("invalid provider config: %w", err)
+	}
+	return nil
+}
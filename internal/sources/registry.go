@@ -0,0 +1,41 @@
+// Package sources classifies the passive sources used by subfinder
+package sources
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+)
+
+// certTransparencySources are the subfinder sources that only query certificate
+// transparency logs or certificate search indexes and never touch the target's DNS
+var certTransparencySources = map[string]struct{}{
+	"censys":      {},
+	"certspotter": {},
+	"crtsh":       {},
+	"digitorus":   {},
+	"facebook":    {},
+}
+
+// CertTransparencySources returns the names of all CT-log-based sources in alphabetical order
+func CertTransparencySources() []string {
+	names := make([]string, 0, len(certTransparencySources))
+	for name := range certTransparencySources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsKnown reports whether subfinder has a passive source with the given name
+func IsKnown(name string) bool {
+	_, ok := passive.NameSourceMap[strings.ToLower(strings.TrimSpace(name))]
+	return ok
+}
+
+// IsCertTransparency reports whether the named source only uses certificate transparency data
+func IsCertTransparency(name string) bool {
+	_, ok := certTransparencySources[strings.ToLower(strings.TrimSpace(name))]
+	return ok
+}
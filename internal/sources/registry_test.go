@@ -0,0 +1,110 @@
+package sources
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/runner"
+)
+
+func TestCertTransparencySourcesExist(t *testing.T) {
+	for _, name := range CertTransparencySources() {
+		if _, ok := passive.NameSourceMap[name]; !ok {
+			t.Errorf("CT source %s is not a subfinder source", name)
+		}
+	}
+}
+
+func TestIsCertTransparency(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"crtsh", true},
+		{" CertSpotter ", true},
+		{"hackertarget", false},
+		{"dnsdumpster", false},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		if got := IsCertTransparency(tc.name); got != tc.expected {
+			t.Errorf("IsCertTransparency(%q) = %v, expected %v", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestProviderConfigTemplate(t *testing.T) {
+	template := string(ProviderConfigTemplate())
+
+	for _, source := range passive.AllSources {
+		name := strings.ToLower(source.Name())
+		if source.NeedsKey() && !strings.Contains(template, "\n"+name+":\n") {
+			t.Errorf("Expected an entry for %s", name)
+		}
+	}
+	for _, expected := range []string{"#  - API_ID:API_SECRET\n", "https://account.shodan.io", "need no key: alienvault, anubis"} {
+		if !strings.Contains(template, expected) {
+			t.Errorf("Expected %q in the template", expected)
+		}
+	}
+
+	// The template must load in subfinder as written
+	path := filepath.Join(t.TempDir(), "provider-config.yaml")
+	if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.UnmarshalFrom(path); err != nil {
+		t.Errorf("Expected subfinder to load the template, got %v", err)
+	}
+}
+
+func TestKeyFormatsAreKeyedSources(t *testing.T) {
+	for name := range keyFormats {
+		source, ok := passive.NameSourceMap[name]
+		if !ok || !source.NeedsKey() {
+			t.Errorf("%s is not a subfinder source that needs a key", name)
+		}
+	}
+}
+
+func TestKeylessSources(t *testing.T) {
+	keyless := KeylessSources()
+	if len(keyless) == 0 {
+		t.Fatal("Expected some sources that need no key")
+	}
+	for _, name := range keyless {
+		if passive.NameSourceMap[name].NeedsKey() {
+			t.Errorf("%s needs a key", name)
+		}
+	}
+}
+
+func TestValidateProviderConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		valid   bool
+	}{
+		{"", true},
+		{string(ProviderConfigTemplate()), true},
+		{"shodan:\n  - KEY\n", true},
+		{"shodan: 5\n", false},
+		{"shodan: [KEY\n", false},
+	}
+	for i, tc := range tests {
+		path := filepath.Join(dir, "provider-config.yaml")
+		if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateProviderConfig(path); (err == nil) != tc.valid {
+			t.Errorf("Case %d: expected valid=%v, got %v", i, tc.valid, err)
+		}
+	}
+	if err := ValidateProviderConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing provider config")
+	}
+}
@@ -0,0 +1,94 @@
+// Package stats keeps lightweight aggregate counters about the server since it started
+package stats
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Collector counts tool calls and enumerations; all methods are safe for concurrent use
+type Collector struct {
+	started time.Time
+
+	requests     atomic.Int64
+	cacheHits    atomic.Int64
+	enumerations atomic.Int64
+	active       atomic.Int64
+	errors       atomic.Int64
+	subdomains   atomic.Int64
+	durationMs   atomic.Int64
+}
+
+// Snapshot is a point-in-time view of a Collector
+type Snapshot struct {
+	Uptime                       string  `json:"uptime"`
+	TotalRequests                int64   `json:"totalRequests"`
+	TotalEnumerations            int64   `json:"totalEnumerations"`
+	TotalSubdomainsFound         int64   `json:"totalSubdomainsFound"`
+	AverageEnumerationDurationMs float64 `json:"averageEnumerationDurationMs"`
+	CacheHitRate                 float64 `json:"cacheHitRate"`
+	ActiveEnumerations           int64   `json:"activeEnumerations"`
+	ErrorRate                    float64 `json:"errorRate"`
+}
+
+// New creates a collector whose uptime starts now
+func New() *Collector {
+	return &Collector{started: time.Now()}
+}
+
+// Default is the process-wide collector served by GET /mcp/stats
+var Default = New()
+
+// RequestReceived counts a tools.call request
+func (c *Collector) RequestReceived() {
+	c.requests.Add(1)
+}
+
+// CacheHit counts a tools.call answered from the idempotency cache
+func (c *Collector) CacheHit() {
+	c.cacheHits.Add(1)
+}
+
+// EnumerationStarted counts an enumeration that is now running
+func (c *Collector) EnumerationStarted() {
+	c.enumerations.Add(1)
+	c.active.Add(1)
+}
+
+// EnumerationFinished records how long a running enumeration took and whether it failed
+func (c *Collector) EnumerationFinished(duration time.Duration, failed bool) {
+	c.active.Add(-1)
+	c.durationMs.Add(duration.Milliseconds())
+	if failed {
+		c.errors.Add(1)
+	}
+}
+
+// SubdomainsFound adds to the total number of subdomains returned
+func (c *Collector) SubdomainsFound(n int) {
+	c.subdomains.Add(int64(n))
+}
+
+// Snapshot returns the current counters and the rates derived from them
+func (c *Collector) Snapshot(now time.Time) Snapshot {
+	requests := c.requests.Load()
+	enumerations := c.enumerations.Load()
+	active := c.active.Load()
+
+	snapshot := Snapshot{
+		Uptime:               now.Sub(c.started).Round(time.Second).String(),
+		TotalRequests:        requests,
+		TotalEnumerations:    enumerations,
+		TotalSubdomainsFound: c.subdomains.Load(),
+		ActiveEnumerations:   active,
+	}
+	if requests > 0 {
+		snapshot.CacheHitRate = float64(c.cacheHits.Load()) / float64(requests)
+	}
+	// Averages and error rates only cover enumerations that have finished
+	if finished := enumerations - active; finished > 0 {
+		snapshot.AverageEnumerationDurationMs = float64(c.durationMs.Load()) / float64(finished)
+		snapshot.ErrorRate = float64(c.errors.Load()) / float64(finished)
+	}
+	return snapshot
+}
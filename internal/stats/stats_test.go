@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectorSnapshot(t *testing.T) {
+	c := New()
+
+	empty := c.Snapshot(c.started)
+	if empty.TotalRequests != 0 || empty.CacheHitRate != 0 || empty.ErrorRate != 0 || empty.Uptime != "0s" {
+		t.Errorf("Unexpected empty snapshot %+v", empty)
+	}
+
+	for i := 0; i < 4; i++ {
+		c.RequestReceived()
+	}
+	c.CacheHit()
+	c.EnumerationStarted()
+	c.EnumerationFinished(100*time.Millisecond, false)
+	c.SubdomainsFound(12)
+	c.EnumerationStarted()
+	c.EnumerationFinished(300*time.Millisecond, true)
+	c.EnumerationStarted()
+
+	got := c.Snapshot(c.started.Add(90 * time.Second))
+	expected := Snapshot{
+		Uptime:                       "1m30s",
+		TotalRequests:                4,
+		TotalEnumerations:            3,
+		TotalSubdomainsFound:         12,
+		AverageEnumerationDurationMs: 200,
+		CacheHitRate:                 0.25,
+		ActiveEnumerations:           1,
+		ErrorRate:                    0.5,
+	}
+	if got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
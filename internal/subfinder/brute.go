@@ -0,0 +1,265 @@
+package subfinder
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBruteConcurrency is the default number of concurrent DNS lookups for brute forcing
+const DefaultBruteConcurrency = 100
+
+// hostLookup resolves a host name to its addresses
+type hostLookup func(ctx context.Context, host string) ([]string, error)
+
+// ErrWordlistOutsideDir is returned for a wordlist path that leaves the wordlist directory
+var ErrWordlistOutsideDir = errors.New("wordlist path must be relative and inside the wordlist directory")
+
+// WordlistInDir returns the path of the wordlist name inside dir. Absolute paths,
+// paths with .. elements and symlinks leading outside dir are rejected, so callers
+// can only pick among the wordlists the operator put there.
+func WordlistInDir(dir, name string) (string, error) {
+	if dir == "" || name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrWordlistOutsideDir
+	}
+	for _, element := range strings.FieldsFunc(filepath.ToSlash(name), func(r rune) bool { return r == '/' }) {
+		if element == ".." {
+			return "", ErrWordlistOutsideDir
+		}
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ErrWordlistOutsideDir
+	}
+	path := filepath.Join(root, filepath.Clean(name))
+	if !insideDir(root, path) {
+		return "", ErrWordlistOutsideDir
+	}
+
+	// A symlink in the directory may still point anywhere
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to open wordlist directory: %w", err)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	if !insideDir(realRoot, realPath) {
+		return "", ErrWordlistOutsideDir
+	}
+	return realPath, nil
+}
+
+// insideDir reports whether path is below dir, both being cleaned absolute paths
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// LoadWordlist reads a wordlist with one label per line. Blank lines and lines
+// starting with # are skipped, and duplicate labels are returned once.
+func LoadWordlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	defer file.Close()
+
+	seen := make(map[string]struct{})
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wordlist: %w", err)
+	}
+
+	return words, nil
+}
+
+// BruteForceSubdomains resolves {word}.{domain} for every word with a pool of
+// concurrency DNS workers and returns the subdomains that resolve, sorted.
+// If the domain has a wildcard record, names resolving only to the wildcard's
+// addresses are dropped.
+func BruteForceSubdomains(ctx context.Context, domain string, words []string, concurrency int, logger *slog.Logger) []string {
+	return bruteForce(ctx, net.DefaultResolver.LookupHost, domain, words, concurrency, logger)
+}
+
+// bruteForce implements BruteForceSubdomains with a pluggable resolver
+func bruteForce(ctx context.Context, lookup hostLookup, domain string, words []string, concurrency int, logger *slog.Logger) []string {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	wildcardAddrs := detectWildcard(ctx, lookup, domain)
+	if len(wildcardAddrs) > 0 {
+		logger.Info("Wildcard DNS detected, filtering matching results",
+			"domain", domain,
+			"wildcardIPs", len(wildcardAddrs))
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var found []string
+	queue := make(chan string)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for word := range queue {
+				subdomain := word + "." + domain
+				addrs, err := lookup(ctx, subdomain)
+				if err != nil || len(addrs) == 0 {
+					continue
+				}
+				if onlyWildcard(addrs, wildcardAddrs) {
+					continue
+				}
+				mu.Lock()
+				found = append(found, subdomain)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, word := range words {
+		select {
+		case <-ctx.Done():
+			logger.Warn("Context cancelled, stopping brute force enumeration")
+			break feed
+		case queue <- word:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Strings(found)
+	return found
+}
+
+// detectWildcard resolves a random label under domain and returns the addresses
+// of its wildcard record, or nil if the domain has none
+func detectWildcard(ctx context.Context, lookup hostLookup, domain string) map[string]struct{} {
+	label := make([]byte, 8)
+	if _, err := rand.Read(label); err != nil {
+		return nil
+	}
+
+	addrs, err := lookup(ctx, hex.EncodeToString(label)+"."+domain)
+	if err != nil || len(addrs) == 0 {
+		return nil
+	}
+
+	wildcard := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		wildcard[addr] = struct{}{}
+	}
+	return wildcard
+}
+
+// onlyWildcard reports whether every address is one of the wildcard addresses
+func onlyWildcard(addrs []string, wildcard map[string]struct{}) bool {
+	if len(wildcard) == 0 {
+		return false
+	}
+	for _, addr := range addrs {
+		if _, ok := wildcard[addr]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectWildcard returns the sorted addresses of domain's wildcard DNS record,
+// or nil if the domain has none
+func DetectWildcard(ctx context.Context, domain string) []string {
+	wildcard := detectWildcard(ctx, net.DefaultResolver.LookupHost, domain)
+	if len(wildcard) == 0 {
+		return nil
+	}
+
+	addrs := make([]string, 0, len(wildcard))
+	for addr := range wildcard {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// FilterWildcardMatches resolves every subdomain in result and drops those whose
+// addresses are all among wildcardIPs, returning the filtered result and the
+// number of subdomains dropped. Subdomains that fail to resolve are kept.
+func FilterWildcardMatches(ctx context.Context, result *EnumerationResult, wildcardIPs []string, logger *slog.Logger) (*EnumerationResult, int) {
+	return filterWildcardMatches(ctx, net.DefaultResolver.LookupHost, result, wildcardIPs, logger)
+}
+
+// filterWildcardMatches implements FilterWildcardMatches with a pluggable resolver
+func filterWildcardMatches(ctx context.Context, lookup hostLookup, result *EnumerationResult, wildcardIPs []string, logger *slog.Logger) (*EnumerationResult, int) {
+	if result == nil || len(wildcardIPs) == 0 {
+		return result, 0
+	}
+
+	wildcard := make(map[string]struct{}, len(wildcardIPs))
+	for _, addr := range wildcardIPs {
+		wildcard[addr] = struct{}{}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	matches := make(map[string]struct{})
+	queue := make(chan string)
+
+	for w := 0; w < resolveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for subdomain := range queue {
+				addrs, err := lookup(ctx, subdomain)
+				if err != nil || len(addrs) == 0 {
+					logger.Debug("Failed to resolve subdomain for wildcard filtering", "subdomain", subdomain, "error", err)
+					continue
+				}
+				if onlyWildcard(addrs, wildcard) {
+					mu.Lock()
+					matches[subdomain] = struct{}{}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, subdomain := range result.Subdomains {
+		queue <- subdomain
+	}
+	close(queue)
+	wg.Wait()
+
+	filtered := filterResult(result, func(subdomain string, _ []string) bool {
+		_, ok := matches[subdomain]
+		return !ok
+	})
+	return filtered, len(matches)
+}
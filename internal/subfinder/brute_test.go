@@ -0,0 +1,144 @@
+package subfinder
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testWordlist is a small wordlist with comments, blank lines and duplicates
+const testWordlist = `# common labels
+www
+mail
+
+API
+www
+  dev  
+`
+
+func TestLoadWordlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordlist.txt")
+	if err := os.WriteFile(path, []byte(testWordlist), 0644); err != nil {
+		t.Fatalf("Failed to write wordlist: %v", err)
+	}
+
+	words, err := LoadWordlist(path)
+	if err != nil {
+		t.Fatalf("Expected wordlist to load, got %v", err)
+	}
+
+	expected := []string{"www", "mail", "api", "dev"}
+	if strings.Join(words, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+
+	if _, err := LoadWordlist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing wordlist")
+	}
+}
+
+func TestWordlistInDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dns"), 0755); err != nil {
+		t.Fatalf("Failed to create wordlist directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dns", "top.txt"), []byte(testWordlist), 0644); err != nil {
+		t.Fatalf("Failed to write wordlist: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	path, err := WordlistInDir(dir, "dns/top.txt")
+	if err != nil {
+		t.Fatalf("Expected a wordlist inside the directory to be accepted, got %v", err)
+	}
+	if words, err := LoadWordlist(path); err != nil || len(words) != 4 {
+		t.Errorf("Expected the wordlist to load, got %v, %v", words, err)
+	}
+
+	for _, name := range []string{"", outside, "../secret.txt", "dns/../../secret.txt", "dns/../top.txt", ".", "link.txt"} {
+		if _, err := WordlistInDir(dir, name); !errors.Is(err, ErrWordlistOutsideDir) {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+	if _, err := WordlistInDir("", "top.txt"); !errors.Is(err, ErrWordlistOutsideDir) {
+		t.Errorf("Expected paths to be rejected without a wordlist directory, got %v", err)
+	}
+}
+
+// fakeLookup resolves hosts from a fixed table, falling back to wildcard for unknown names
+func fakeLookup(records map[string][]string, wildcard []string) hostLookup {
+	return func(_ context.Context, host string) ([]string, error) {
+		if addrs, ok := records[host]; ok {
+			return addrs, nil
+		}
+		if wildcard != nil {
+			return wildcard, nil
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+func TestBruteForce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	words := []string{"www", "mail", "api", "missing"}
+	records := map[string][]string{
+		"www.example.com":  {"192.0.2.1"},
+		"mail.example.com": {"192.0.2.2"},
+		"api.example.com":  {"198.51.100.7"},
+	}
+
+	t.Run("No wildcard", func(t *testing.T) {
+		got := bruteForce(context.Background(), fakeLookup(records, nil), "example.com", words, 2, logger)
+		expected := "api.example.com,mail.example.com,www.example.com"
+		if strings.Join(got, ",") != expected {
+			t.Errorf("Expected %s, got %v", expected, got)
+		}
+	})
+
+	t.Run("Wildcard addresses are filtered", func(t *testing.T) {
+		wildcard := []string{"192.0.2.1", "192.0.2.2"}
+		got := bruteForce(context.Background(), fakeLookup(records, wildcard), "example.com", words, 2, logger)
+		if strings.Join(got, ",") != "api.example.com" {
+			t.Errorf("Expected only api.example.com, got %v", got)
+		}
+	})
+}
+
+func TestFilterWildcardMatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	records := map[string][]string{
+		"www.example.com":  {"192.0.2.1"},
+		"api.example.com":  {"198.51.100.7"},
+		"both.example.com": {"192.0.2.1", "198.51.100.8"},
+	}
+	result := &EnumerationResult{
+		Subdomains: []string{"api.example.com", "both.example.com", "random.example.com", "www.example.com"},
+		Sources:    map[string][]string{"api.example.com": {"crtsh"}, "random.example.com": {"crtsh"}},
+	}
+
+	// Unknown names resolve to the wildcard address like the real zone would
+	filtered, count := filterWildcardMatches(context.Background(), fakeLookup(records, []string{"192.0.2.1"}), result, []string{"192.0.2.1"}, logger)
+	if count != 2 {
+		t.Errorf("Expected 2 wildcard matches, got %d", count)
+	}
+	if strings.Join(filtered.Subdomains, ",") != "api.example.com,both.example.com" {
+		t.Errorf("Expected api and both to remain, got %v", filtered.Subdomains)
+	}
+	if _, ok := filtered.Sources["random.example.com"]; ok {
+		t.Error("Expected sources of dropped subdomains to be removed")
+	}
+
+	if same, count := filterWildcardMatches(context.Background(), fakeLookup(records, nil), result, nil, logger); same != result || count != 0 {
+		t.Error("Expected the result to be unchanged without wildcard IPs")
+	}
+}
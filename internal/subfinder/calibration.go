@@ -0,0 +1,64 @@
+package subfinder
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalibrationTimeout is how many seconds the wildcard source calibration pass may take
+const CalibrationTimeout = 20
+
+// DetectWildcardSources enumerates a random UUID subdomain of domain, which cannot
+// exist, and returns the sources that report anything under it. Those sources
+// answer every query on the domain and only inflate its results.
+func DetectWildcardSources(ctx context.Context, domain string, config SubfinderConfig, logger *slog.Logger) ([]string, error) {
+	return detectWildcardSources(ctx, domain, config, Enumerate, logger)
+}
+
+// detectWildcardSources is DetectWildcardSources with the enumeration replaceable for tests
+func detectWildcardSources(ctx context.Context, domain string, config SubfinderConfig, enumerate func(context.Context, string, SubfinderConfig, *slog.Logger) (*EnumerationResult, error), logger *slog.Logger) ([]string, error) {
+	probe := uuid.NewString() + "." + strings.TrimSuffix(domain, ".")
+
+	// A single quick pass over the same sources, keeping everything they report
+	calibration := config
+	calibration.Timeout = min(CalibrationTimeout, config.Timeout)
+	calibration.Recursive = false
+	calibration.MaxPerSource = 0
+	calibration.SeedSubdomains = nil
+	calibration.PrioritySources = nil
+	calibration.ResultWriter = nil
+	calibration.RawOutputWriter = nil
+	calibration.RetryConfig.MaxAttempts = 1
+
+	calibrationCtx, cancel := context.WithTimeout(ctx, time.Duration(calibration.Timeout)*time.Second)
+	defer cancel()
+	result, err := enumerate(calibrationCtx, probe, calibration, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fallback suggestions have no sources, so only real reports count
+	found := make(map[string]struct{})
+	for subdomain, sources := range result.Sources {
+		if !strings.HasSuffix(strings.ToLower(subdomain), "."+probe) {
+			continue
+		}
+		for _, source := range sources {
+			if source != SeedSource {
+				found[source] = struct{}{}
+			}
+		}
+	}
+
+	wildcardSources := make([]string, 0, len(found))
+	for source := range found {
+		wildcardSources = append(wildcardSources, source)
+	}
+	sort.Strings(wildcardSources)
+	return wildcardSources, nil
+}
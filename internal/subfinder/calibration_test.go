@@ -0,0 +1,45 @@
+package subfinder
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectWildcardSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var probed string
+	var calibration SubfinderConfig
+	enumerate := func(_ context.Context, domain string, config SubfinderConfig, _ *slog.Logger) (*EnumerationResult, error) {
+		probed, calibration = domain, config
+		return &EnumerationResult{
+			Subdomains: []string{"www." + domain, "api." + domain, "www.example.com", "mail." + domain},
+			Sources: map[string][]string{
+				"www." + domain:   {"rapiddns", "hackertarget"},
+				"api." + domain:   {"rapiddns"},
+				"www.example.com": {"crtsh"},
+			},
+		}, nil
+	}
+
+	config := SubfinderConfig{Timeout: 120, Recursive: true, SeedSubdomains: []string{"www.example.com"}}
+	sources, err := detectWildcardSources(context.Background(), "example.com", config, enumerate, logger)
+	if err != nil {
+		t.Fatalf("detectWildcardSources failed: %v", err)
+	}
+
+	label, _, _ := strings.Cut(probed, ".")
+	if !strings.HasSuffix(probed, ".example.com") || len(label) != 36 {
+		t.Errorf("Expected a UUID subdomain of example.com, got %q", probed)
+	}
+	if calibration.Timeout != CalibrationTimeout || calibration.Recursive || calibration.SeedSubdomains != nil {
+		t.Errorf("Unexpected calibration config %+v", calibration)
+	}
+	if expected := []string{"hackertarget", "rapiddns"}; !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v, got %v", expected, sources)
+	}
+}
@@ -0,0 +1,180 @@
+package subfinder
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertInfo holds the fields of the leaf certificate served by a subdomain on port 443
+type CertInfo struct {
+	CommonName              string    `json:"commonName"`
+	SubjectAlternativeNames []string  `json:"subjectAlternativeNames,omitempty"`
+	NotBefore               time.Time `json:"notBefore"`
+	NotAfter                time.Time `json:"notAfter"`
+	Issuer                  string    `json:"issuer"`
+	SerialNumber            string    `json:"serialNumber"`
+	// Trusted reports whether the chain verifies against the probe's trust anchors;
+	// it is only set when custom trust anchors were supplied
+	Trusted *bool `json:"trusted,omitempty"`
+	// VerificationError explains why an untrusted chain failed verification
+	VerificationError string `json:"verificationError,omitempty"`
+}
+
+// certificateFetcher returns the certificate served by host
+type certificateFetcher func(ctx context.Context, host string) (*CertInfo, error)
+
+// ProbeCertificates connects to port 443 of every resolved entry and records its
+// certificate. The handshake never fails verification on purpose: self-signed and
+// mismatched certificates are exactly the ones worth reporting. When roots is
+// non-nil each chain is also verified against it and the outcome recorded. Each
+// connection, handshake included, must complete within networkTimeout.
+func ProbeCertificates(ctx context.Context, entries []SubdomainEntry, roots *x509.CertPool, networkTimeout time.Duration, logger *slog.Logger) {
+	probeCertificates(ctx, entries, func(ctx context.Context, host string) (*CertInfo, error) {
+		return fetchCertificate(ctx, net.JoinHostPort(host, "443"), host, roots, networkTimeout)
+	}, logger)
+}
+
+// TrustAnchorPool returns the system certificate pool extended with the CA
+// certificates in pemBundle
+func TrustAnchorPool(pemBundle []byte) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemBundle) {
+		return nil, errors.New("no PEM certificates found in trust anchor bundle")
+	}
+	return pool, nil
+}
+
+// probeCertificates implements ProbeCertificates with a pluggable fetcher
+func probeCertificates(ctx context.Context, entries []SubdomainEntry, fetch certificateFetcher, logger *slog.Logger) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < resolveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				info, err := fetch(ctx, entries[i].Subdomain)
+				if err != nil {
+					logger.Debug("Failed to probe TLS certificate", "subdomain", entries[i].Subdomain, "error", err)
+					continue
+				}
+				entries[i].Cert = info
+			}
+		}()
+	}
+
+	for i := range entries {
+		// Unresolved subdomains have nothing to connect to
+		if len(entries[i].IPs) > 0 {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// fetchCertificate performs a TLS handshake with addr using serverName for SNI and,
+// when roots is non-nil, verifies the served chain against it
+func fetchCertificate(ctx context.Context, addr, serverName string, roots *x509.CertPool, networkTimeout time.Duration) (*CertInfo, error) {
+	config := &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            roots,
+		InsecureSkipVerify: true,
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: networkTimeout},
+		Config:    config,
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no peer certificate")
+	}
+
+	info := certInfo(certs[0])
+	if config.RootCAs != nil {
+		err := verifyChain(certs, serverName, config.RootCAs)
+		trusted := err == nil
+		info.Trusted = &trusted
+		if err != nil {
+			info.VerificationError = err.Error()
+		}
+	}
+	return info, nil
+}
+
+// verifyChain checks the served certificates against roots the way a verifying
+// handshake with the same RootCAs would
+func verifyChain(certs []*x509.Certificate, serverName string, roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	return err
+}
+
+// certInfo extracts the reported fields from a certificate
+func certInfo(cert *x509.Certificate) *CertInfo {
+	return &CertInfo{
+		CommonName:              cert.Subject.CommonName,
+		SubjectAlternativeNames: cert.DNSNames,
+		NotBefore:               cert.NotBefore,
+		NotAfter:                cert.NotAfter,
+		Issuer:                  cert.Issuer.String(),
+		SerialNumber:            cert.SerialNumber.String(),
+	}
+}
+
+// CertificateSubdomains returns the names from probed certificates that belong to
+// domain but are not in known, in sorted order. Wildcard names contribute their base.
+func CertificateSubdomains(entries []SubdomainEntry, domain string, known []string) []string {
+	domain = strings.ToLower(domain)
+	seen := make(map[string]struct{}, len(known))
+	for _, subdomain := range known {
+		seen[strings.ToLower(subdomain)] = struct{}{}
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Cert == nil {
+			continue
+		}
+		for _, name := range append([]string{entry.Cert.CommonName}, entry.Cert.SubjectAlternativeNames...) {
+			name = strings.ToLower(strings.TrimPrefix(name, "*."))
+			if !strings.HasSuffix(name, "."+domain) {
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,107 @@
+package subfinder
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	info, err := fetchCertificate(context.Background(), server.Listener.Addr().String(), "example.com", nil, time.Second)
+	if err != nil {
+		t.Fatalf("fetchCertificate failed: %v", err)
+	}
+
+	if info.Trusted != nil {
+		t.Errorf("Expected no verification result without trust anchors, got %v", *info.Trusted)
+	}
+	if !reflect.DeepEqual(info.SubjectAlternativeNames, server.Certificate().DNSNames) {
+		t.Errorf("Expected SANs %v, got %v", server.Certificate().DNSNames, info.SubjectAlternativeNames)
+	}
+	if info.SerialNumber != server.Certificate().SerialNumber.String() {
+		t.Errorf("Expected serial %s, got %s", server.Certificate().SerialNumber, info.SerialNumber)
+	}
+}
+
+func TestFetchCertificateTrustAnchors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	roots, err := TrustAnchorPool(bundle)
+	if err != nil {
+		t.Fatalf("TrustAnchorPool failed: %v", err)
+	}
+
+	info, err := fetchCertificate(context.Background(), server.Listener.Addr().String(), "example.com", roots, time.Second)
+	if err != nil {
+		t.Fatalf("fetchCertificate failed: %v", err)
+	}
+	if info.Trusted == nil || !*info.Trusted {
+		t.Errorf("Expected the chain to be trusted, got error %q", info.VerificationError)
+	}
+
+	// The test certificate does not cover this name
+	info, err = fetchCertificate(context.Background(), server.Listener.Addr().String(), "other.org", roots, time.Second)
+	if err != nil {
+		t.Fatalf("fetchCertificate failed: %v", err)
+	}
+	if info.Trusted == nil || *info.Trusted || info.VerificationError == "" {
+		t.Errorf("Expected a verification failure, got %+v", info)
+	}
+
+	if _, err := TrustAnchorPool([]byte("not a certificate")); err == nil {
+		t.Error("Expected an error for a bundle without certificates")
+	}
+}
+
+func TestProbeCertificates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cert := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		Issuer:       pkix.Name{CommonName: "Test CA"},
+		DNSNames:     []string{"www.example.com", "*.internal.example.com", "admin.example.com", "other.org"},
+		SerialNumber: big.NewInt(42),
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	fetch := func(_ context.Context, host string) (*CertInfo, error) {
+		if host == "www.example.com" {
+			return certInfo(cert), nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	entries := []SubdomainEntry{
+		{Subdomain: "www.example.com", IPs: ipEntries("93.184.216.34")},
+		{Subdomain: "mail.example.com", IPs: ipEntries("93.184.216.35")},
+		{Subdomain: "unresolved.example.com"},
+	}
+	probeCertificates(context.Background(), entries, fetch, logger)
+
+	if entries[0].Cert == nil || entries[0].Cert.CommonName != "www.example.com" || entries[0].Cert.SerialNumber != "42" || entries[0].Cert.Issuer != "CN=Test CA" {
+		t.Errorf("Unexpected certificate info %+v", entries[0].Cert)
+	}
+	if entries[1].Cert != nil || entries[2].Cert != nil {
+		t.Error("Expected no certificate for failed or unresolved probes")
+	}
+
+	got := CertificateSubdomains(entries, "example.com", []string{"www.example.com", "mail.example.com"})
+	expected := []string{"admin.example.com", "internal.example.com"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
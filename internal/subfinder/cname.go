@@ -0,0 +1,185 @@
+package subfinder
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Limits on how far CNAME targets are followed
+const (
+	// MaxCNAMEHops is how many rounds of CNAME-derived enumeration are run
+	MaxCNAMEHops = 3
+	// MaxDerivedDomains caps the total number of CNAME-derived domains enumerated
+	MaxDerivedDomains = 5
+)
+
+// DerivedDomain is an apex domain reached through the CNAME of a discovered subdomain
+type DerivedDomain struct {
+	Domain      string   `json:"domain"`
+	Via         string   `json:"via"`
+	CNAMETarget string   `json:"cnameTarget"`
+	Hop         int      `json:"hop"`
+	Subdomains  []string `json:"subdomains"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// cnameLookup returns the canonical name of host, or host itself when it has no CNAME
+type cnameLookup func(ctx context.Context, host string) (string, error)
+
+// derivedEnumerator runs a passive enumeration and returns the subdomains found
+type derivedEnumerator func(ctx context.Context, domain string) ([]string, error)
+
+// FollowCNAMEs resolves the CNAMEs of subdomains, enumerates the apex domains of
+// their targets and repeats on the new results, up to MaxCNAMEHops rounds and
+// MaxDerivedDomains domains in total. Each derived domain is enumerated with config.
+func FollowCNAMEs(ctx context.Context, domain string, subdomains []string, config SubfinderConfig, logger *slog.Logger) []DerivedDomain {
+	// Derived results are reported separately, never streamed as the target's
+	config.ResultWriter = nil
+	config.RawOutputWriter = nil
+
+	enumerate := func(ctx context.Context, derived string) ([]string, error) {
+		result, err := Enumerate(ctx, derived, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		// Fallback suggestions were never seen by a source, so they are not infrastructure
+		found := make([]string, 0, len(result.Sources))
+		for _, subdomain := range result.Subdomains {
+			if _, ok := result.Sources[subdomain]; ok {
+				found = append(found, subdomain)
+			}
+		}
+		return found, nil
+	}
+
+	return followCNAMEs(ctx, domain, subdomains, net.DefaultResolver.LookupCNAME, enumerate, logger)
+}
+
+// followCNAMEs implements FollowCNAMEs with pluggable lookup and enumeration
+func followCNAMEs(ctx context.Context, domain string, subdomains []string, lookup cnameLookup, enumerate derivedEnumerator, logger *slog.Logger) []DerivedDomain {
+	seen := map[string]struct{}{strings.ToLower(domain): {}}
+	if apex, ok := apexDomain(strings.ToLower(domain)); ok {
+		seen[apex] = struct{}{}
+	}
+
+	derived := []DerivedDomain{}
+	frontier := subdomains
+	for hop := 1; hop <= MaxCNAMEHops && len(frontier) > 0 && len(derived) < MaxDerivedDomains; hop++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		targets := resolveCNAMEs(ctx, lookup, frontier, logger)
+		hosts := make([]string, 0, len(targets))
+		for host := range targets {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+
+		var next []string
+		for _, host := range hosts {
+			if len(derived) >= MaxDerivedDomains {
+				logger.Info("Reached derived domain limit, not following more CNAMEs", "limit", MaxDerivedDomains)
+				break
+			}
+
+			apex, ok := apexDomain(targets[host])
+			if !ok {
+				logger.Debug("Could not determine apex domain of CNAME target", "target", targets[host])
+				continue
+			}
+			if _, ok := seen[apex]; ok {
+				continue
+			}
+			seen[apex] = struct{}{}
+
+			logger.Info("Enumerating CNAME-derived domain",
+				"domain", apex,
+				"via", host,
+				"cnameTarget", targets[host],
+				"hop", hop)
+			entry := DerivedDomain{
+				Domain:      apex,
+				Via:         host,
+				CNAMETarget: targets[host],
+				Hop:         hop,
+				Subdomains:  []string{},
+			}
+			found, err := enumerate(ctx, apex)
+			if err != nil {
+				logger.Warn("CNAME-derived enumeration failed", "domain", apex, "error", err)
+				entry.Error = err.Error()
+			} else {
+				entry.Subdomains = found
+				next = append(next, found...)
+			}
+			derived = append(derived, entry)
+		}
+		frontier = next
+	}
+
+	return derived
+}
+
+// apexDomain returns the registered domain of host under its ICANN public suffix.
+// Private suffixes such as cloudfront.net are ignored on purpose: the provider's
+// own domain is what identifies the hosting infrastructure.
+func apexDomain(host string) (string, bool) {
+	labels := strings.Split(host, ".")
+	for i := 1; i < len(labels); i++ {
+		candidate := strings.Join(labels[i:], ".")
+		if suffix, icann := publicsuffix.PublicSuffix(candidate); icann && suffix == candidate {
+			return strings.Join(labels[i-1:], "."), true
+		}
+	}
+	return "", false
+}
+
+// ResolveCNAMEs returns the normalized CNAME target of each host that has one
+func ResolveCNAMEs(ctx context.Context, hosts []string, logger *slog.Logger) map[string]string {
+	return resolveCNAMEs(ctx, net.DefaultResolver.LookupCNAME, hosts, logger)
+}
+
+// resolveCNAMEs looks up hosts concurrently and returns the normalized CNAME
+// target of each host that has one pointing somewhere else
+func resolveCNAMEs(ctx context.Context, lookup cnameLookup, hosts []string, logger *slog.Logger) map[string]string {
+	targets := make(map[string]string)
+	var mu sync.Mutex
+	work := make(chan string)
+
+	var wg sync.WaitGroup
+	for w := 0; w < resolveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range work {
+				cname, err := lookup(ctx, host)
+				if err != nil {
+					logger.Debug("Failed to look up CNAME", "subdomain", host, "error", err)
+					continue
+				}
+				target := strings.ToLower(strings.TrimSuffix(cname, "."))
+				if target == "" || target == strings.ToLower(host) {
+					continue
+				}
+				mu.Lock()
+				targets[host] = target
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, host := range hosts {
+		work <- host
+	}
+	close(work)
+	wg.Wait()
+
+	return targets
+}
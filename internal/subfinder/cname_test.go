@@ -0,0 +1,117 @@
+package subfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// staticCNAMEs returns a lookup backed by a fixed CNAME table
+func staticCNAMEs(table map[string]string) cnameLookup {
+	return func(_ context.Context, host string) (string, error) {
+		if target, ok := table[host]; ok {
+			return target, nil
+		}
+		return host + ".", nil
+	}
+}
+
+func TestFollowCNAMEs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lookup := staticCNAMEs(map[string]string{
+		"cdn.example.com":            "d111.cloudfront.net.",
+		"www.example.com":            "example.com.",
+		"shop.example.com":           "shops.myshopify.com.",
+		"edge.cloudfront.net":        "edge.akamaiedge.net.",
+		"assets.example.com":         "d222.CloudFront.net.",
+		"status.shops.myshopify.com": "unknown",
+	})
+	enumerated := map[string][]string{
+		"cloudfront.net": {"edge.cloudfront.net"},
+		"myshopify.com":  {},
+	}
+	var calls []string
+	enumerate := func(_ context.Context, domain string) ([]string, error) {
+		calls = append(calls, domain)
+		if domain == "akamaiedge.net" {
+			return nil, errors.New("rate limited")
+		}
+		return enumerated[domain], nil
+	}
+
+	got := followCNAMEs(context.Background(), "example.com",
+		[]string{"assets.example.com", "cdn.example.com", "shop.example.com", "www.example.com"},
+		lookup, enumerate, logger)
+
+	expected := []DerivedDomain{
+		{Domain: "cloudfront.net", Via: "assets.example.com", CNAMETarget: "d222.cloudfront.net", Hop: 1, Subdomains: []string{"edge.cloudfront.net"}},
+		{Domain: "myshopify.com", Via: "shop.example.com", CNAMETarget: "shops.myshopify.com", Hop: 1, Subdomains: []string{}},
+		{Domain: "akamaiedge.net", Via: "edge.cloudfront.net", CNAMETarget: "edge.akamaiedge.net", Hop: 2, Subdomains: []string{}, Error: "rate limited"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+	if want := []string{"cloudfront.net", "myshopify.com", "akamaiedge.net"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected enumerations %v, got %v", want, calls)
+	}
+}
+
+func TestFollowCNAMEsLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Every host points at a fresh domain, so only the caps stop the walk
+	lookup := func(_ context.Context, host string) (string, error) {
+		return "x." + strings.ReplaceAll(host, ".", "-") + ".com.", nil
+	}
+	enumerate := func(_ context.Context, domain string) ([]string, error) {
+		return []string{"a." + domain, "b." + domain}, nil
+	}
+
+	subdomains := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		subdomains = append(subdomains, fmt.Sprintf("host%d.example.com", i))
+	}
+	got := followCNAMEs(context.Background(), "example.com", subdomains, lookup, enumerate, logger)
+	if len(got) != MaxDerivedDomains {
+		t.Errorf("Expected %d derived domains, got %d", MaxDerivedDomains, len(got))
+	}
+
+	// A single chain stops after MaxCNAMEHops rounds
+	enumerateOne := func(_ context.Context, domain string) ([]string, error) {
+		return []string{"a." + domain}, nil
+	}
+	got = followCNAMEs(context.Background(), "example.com", []string{"www.example.com"}, lookup, enumerateOne, logger)
+	if len(got) != MaxCNAMEHops {
+		t.Fatalf("Expected %d derived domains, got %d", MaxCNAMEHops, len(got))
+	}
+	if got[len(got)-1].Hop != MaxCNAMEHops {
+		t.Errorf("Expected last hop %d, got %d", MaxCNAMEHops, got[len(got)-1].Hop)
+	}
+}
+
+func TestApexDomain(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+		ok       bool
+	}{
+		{"d111.cloudfront.net", "cloudfront.net", true},
+		{"shops.myshopify.com", "myshopify.com", true},
+		{"www.example.co.uk", "example.co.uk", true},
+		{"example.com", "example.com", true},
+		{"com", "", false},
+		{"unknown", "", false},
+	}
+
+	for _, tc := range tests {
+		got, ok := apexDomain(tc.host)
+		if got != tc.expected || ok != tc.ok {
+			t.Errorf("apexDomain(%q) = %q, %v; expected %q, %v", tc.host, got, ok, tc.expected, tc.ok)
+		}
+	}
+}
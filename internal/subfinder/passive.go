@@ -0,0 +1,110 @@
+package subfinder
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/runner"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
+// passiveReplacer cleans the names sources report the way subfinder's runner does
+var passiveReplacer = strings.NewReplacer(
+	"/", "",
+	"•.", "",
+	"•", "",
+	"*.", "",
+	"http://", "",
+	"https://", "",
+)
+
+// passiveEnumerator runs subfinder's passive sources like the runner's
+// EnumerateSingleDomainWithCtx, but hands each subdomain to onResult as soon as
+// a source first reports it instead of once every source has finished. The
+// runner must have been created with the same options first, since that loads
+// the provider config's API keys into the sources. Like the runner, it returns
+// every name the sources report; the runner's wildcard removal only ever applied
+// to the output it writes.
+type passiveEnumerator struct {
+	options   *runner.Options
+	agent     *passive.Agent
+	rateLimit *subscraping.CustomRateLimit
+	onResult  func(subdomain, source string)
+	logger    *slog.Logger
+}
+
+// newPassiveEnumerator returns an enumerator for the sources options selects
+func newPassiveEnumerator(options *runner.Options, onResult func(subdomain, source string), logger *slog.Logger) *passiveEnumerator {
+	rateLimit := &subscraping.CustomRateLimit{
+		Custom: mapsutil.SyncLockMap[string, uint]{
+			Map: make(map[string]uint),
+		},
+	}
+	for source, sourceRateLimit := range options.RateLimits.AsMap() {
+		if sourceRateLimit.MaxCount > 0 && sourceRateLimit.MaxCount <= math.MaxUint {
+			_ = rateLimit.Custom.Set(source, sourceRateLimit.MaxCount)
+		}
+	}
+
+	return &passiveEnumerator{
+		options:   options,
+		agent:     passive.New(options.Sources, options.ExcludeSources, options.All, options.OnlyRecursive),
+		rateLimit: rateLimit,
+		onResult:  onResult,
+		logger:    logger,
+	}
+}
+
+// EnumerateSingleDomainWithCtx implements domainEnumerator. Each writer gets one
+// "host,[sources]" line per subdomain once enumeration has finished.
+func (e *passiveEnumerator) EnumerateSingleDomainWithCtx(ctx context.Context, domain string, writers []io.Writer) (map[string]map[string]struct{}, error) {
+	results := e.agent.EnumerateSubdomainsWithCtx(ctx, domain, e.options.Proxy, e.options.RateLimit, e.options.Timeout,
+		time.Duration(e.options.MaxEnumerationTime)*time.Minute, passive.WithCustomRateLimit(e.rateLimit))
+	found := collectPassiveResults(domain, results, e.onResult, e.logger)
+
+	outputWriter := runner.NewOutputWriter(false)
+	for _, writer := range writers {
+		if err := outputWriter.WriteSourceHost(domain, found, writer); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// GetStatistics returns how each source fared in the enumerations run so far
+func (e *passiveEnumerator) GetStatistics() map[string]subscraping.Statistics {
+	return e.agent.GetStatistics()
+}
+
+// collectPassiveResults reads a passive agent's results for domain into a map of
+// subdomain to sources, calling onResult, when set, for every new subdomain
+func collectPassiveResults(domain string, results <-chan subscraping.Result, onResult func(subdomain, source string), logger *slog.Logger) map[string]map[string]struct{} {
+	found := make(map[string]map[string]struct{})
+	for result := range results {
+		switch result.Type {
+		case subscraping.Error:
+			logger.Debug("Source returned an error", "source", result.Source, "error", result.Error)
+		case subscraping.Subdomain:
+			subdomain := passiveReplacer.Replace(result.Value)
+			if !strings.HasSuffix(subdomain, "."+domain) {
+				continue
+			}
+			sources, seen := found[subdomain]
+			if !seen {
+				sources = make(map[string]struct{})
+				found[subdomain] = sources
+			}
+			sources[result.Source] = struct{}{}
+			if !seen && onResult != nil {
+				onResult(subdomain, result.Source)
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,57 @@
+package subfinder
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+func TestCollectPassiveResults(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	results := make(chan subscraping.Result)
+	reported := make(chan string, 10)
+	done := make(chan map[string]map[string]struct{})
+	go func() {
+		done <- collectPassiveResults("example.com", results, func(subdomain, source string) {
+			reported <- subdomain + "@" + source
+		}, logger)
+	}()
+
+	results <- subscraping.Result{Type: subscraping.Subdomain, Source: "crtsh", Value: "*.www.example.com"}
+	// The first name is reported while the sources are still running
+	select {
+	case got := <-reported:
+		if got != "www.example.com@crtsh" {
+			t.Errorf("Expected www.example.com from crtsh, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected www.example.com to be reported as it arrived")
+	}
+	results <- subscraping.Result{Type: subscraping.Error, Source: "github", Error: errors.New("rate limited")}
+	results <- subscraping.Result{Type: subscraping.Subdomain, Source: "alienvault", Value: "www.example.com"}
+	results <- subscraping.Result{Type: subscraping.Subdomain, Source: "alienvault", Value: "https://api.example.com/"}
+	results <- subscraping.Result{Type: subscraping.Subdomain, Source: "crtsh", Value: "www.example.org"}
+	close(results)
+	found := <-done
+	close(reported)
+
+	expected := map[string]map[string]struct{}{
+		"www.example.com": {"crtsh": {}, "alienvault": {}},
+		"api.example.com": {"alienvault": {}},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+	var rest []string
+	for got := range reported {
+		rest = append(rest, got)
+	}
+	if want := []string{"api.example.com@alienvault"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("Expected each subdomain reported once, got %v", rest)
+	}
+}
@@ -0,0 +1,304 @@
+package subfinder
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-subfinder-server/internal/validation"
+
+	"golang.org/x/net/idna"
+)
+
+// Encodings internationalized subdomains can be normalized to
+const (
+	IDNPunycode = "punycode"
+	IDNUnicode  = "unicode"
+	IDNNone     = "none"
+)
+
+// IDNEncodings lists the accepted normalizeUnicode values
+var IDNEncodings = []string{IDNPunycode, IDNUnicode, IDNNone}
+
+// NormalizeIDN rewrites every subdomain in resultMap to encoding, so the Punycode
+// and Unicode forms of a name merge into one entry with the sources of both.
+// Names that are not valid IDNs are kept as they are, and IDNNone or an empty
+// encoding leaves resultMap unchanged.
+func NormalizeIDN(resultMap map[string]map[string]struct{}, encoding string) map[string]map[string]struct{} {
+	var convert func(string) (string, error)
+	switch encoding {
+	case IDNPunycode:
+		convert = idna.Punycode.ToASCII
+	case IDNUnicode:
+		convert = idna.Punycode.ToUnicode
+	default:
+		return resultMap
+	}
+
+	normalized := make(map[string]map[string]struct{}, len(resultMap))
+	for subdomain, sources := range resultMap {
+		name, err := convert(strings.ToLower(subdomain))
+		if err != nil {
+			name = subdomain
+		}
+		if normalized[name] == nil {
+			normalized[name] = make(map[string]struct{}, len(sources))
+		}
+		for source := range sources {
+			normalized[name][source] = struct{}{}
+		}
+	}
+	return normalized
+}
+
+// CapPerSource limits how many subdomains each source may contribute on its own.
+// Subdomains reported by more than one source are corroborated and always kept;
+// subdomains reported by a single source are kept in alphabetical order until
+// that source reaches maxPerSource. A maxPerSource of zero or less disables the cap.
+func CapPerSource(resultMap map[string]map[string]struct{}, maxPerSource int) map[string]map[string]struct{} {
+	if maxPerSource <= 0 {
+		return resultMap
+	}
+
+	capped := make(map[string]map[string]struct{}, len(resultMap))
+	exclusive := make(map[string][]string)
+
+	for subdomain, sources := range resultMap {
+		if len(sources) != 1 {
+			capped[subdomain] = sources
+			continue
+		}
+		for source := range sources {
+			exclusive[source] = append(exclusive[source], subdomain)
+		}
+	}
+
+	for _, subdomains := range exclusive {
+		sort.Strings(subdomains)
+		if len(subdomains) > maxPerSource {
+			subdomains = subdomains[:maxPerSource]
+		}
+		for _, subdomain := range subdomains {
+			capped[subdomain] = resultMap[subdomain]
+		}
+	}
+
+	return capped
+}
+
+// FilterByMinSources keeps only the subdomains reported by at least minSources
+// distinct sources. A minSources of one or less leaves the result unchanged.
+func FilterByMinSources(result *EnumerationResult, minSources int) *EnumerationResult {
+	if result == nil || minSources <= 1 {
+		return result
+	}
+
+	return filterResult(result, func(_ string, sources []string) bool {
+		return len(sources) >= minSources
+	})
+}
+
+// trustedSourceCount is how many distinct sources give a subdomain full trust
+const trustedSourceCount = 5
+
+// HighTrustScore is the lowest trust score counted as high confidence
+const HighTrustScore = 0.8
+
+// TrustScore rates how well corroborated a subdomain is from 0 to 1 by the
+// number of distinct sources that reported it: 0.2 per source, capped at 1
+func TrustScore(sources []string) float64 {
+	distinct := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		distinct[source] = struct{}{}
+	}
+	return min(1.0, float64(len(distinct))/trustedSourceCount)
+}
+
+// FilterByTrustScore keeps only the subdomains whose trust score is at least
+// minScore. A minScore of zero or less leaves the result unchanged.
+func FilterByTrustScore(result *EnumerationResult, minScore float64) *EnumerationResult {
+	if result == nil || minScore <= 0 {
+		return result
+	}
+
+	return filterResult(result, func(_ string, sources []string) bool {
+		return TrustScore(sources) >= minScore
+	})
+}
+
+// FilterByTLD keeps only the subdomains ending in ".<tld>". A leading dot in tld
+// is ignored and an empty tld leaves the result unchanged.
+func FilterByTLD(result *EnumerationResult, tld string) *EnumerationResult {
+	tld = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+	if result == nil || tld == "" {
+		return result
+	}
+
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		return strings.HasSuffix(strings.ToLower(subdomain), "."+tld)
+	})
+}
+
+// FilterByRegisteredDomain keeps only the subdomains that are domain itself or end
+// in ".<domain>", dropping results that sources attributed to an unrelated domain.
+func FilterByRegisteredDomain(result *EnumerationResult, domain string) *EnumerationResult {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if result == nil || domain == "" {
+		return result
+	}
+
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		subdomain = strings.ToLower(subdomain)
+		return subdomain == domain || strings.HasSuffix(subdomain, "."+domain)
+	})
+}
+
+// FilterBySubstrings keeps the subdomains that start with one of prefixes, end
+// with one of suffixes and contain one of contains, ignoring case. An empty list
+// places no constraint, so with all three empty result is returned unchanged.
+func FilterBySubstrings(result *EnumerationResult, prefixes, suffixes, contains []string) *EnumerationResult {
+	if len(prefixes) == 0 && len(suffixes) == 0 && len(contains) == 0 {
+		return result
+	}
+
+	matchesAny := func(subdomain string, patterns []string, match func(s, substr string) bool) bool {
+		if len(patterns) == 0 {
+			return true
+		}
+		for _, pattern := range patterns {
+			if match(subdomain, strings.ToLower(pattern)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		subdomain = strings.ToLower(subdomain)
+		return matchesAny(subdomain, prefixes, strings.HasPrefix) &&
+			matchesAny(subdomain, suffixes, strings.HasSuffix) &&
+			matchesAny(subdomain, contains, strings.Contains)
+	})
+}
+
+// FilterByLabelLength keeps only the subdomains whose first label, such as "cdn"
+// in cdn.example.com, is between minLength and maxLength characters long. A
+// bound of zero or less places no limit on that side.
+func FilterByLabelLength(result *EnumerationResult, minLength, maxLength int) *EnumerationResult {
+	if result == nil || (minLength <= 0 && maxLength <= 0) {
+		return result
+	}
+
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		label, _, _ := strings.Cut(subdomain, ".")
+		return (minLength <= 0 || len(label) >= minLength) && (maxLength <= 0 || len(label) <= maxLength)
+	})
+}
+
+// FilterByRegex keeps only the subdomains matching pattern. A nil pattern leaves
+// the result unchanged.
+func FilterByRegex(result *EnumerationResult, pattern *regexp.Regexp) *EnumerationResult {
+	if result == nil || pattern == nil {
+		return result
+	}
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		return pattern.MatchString(subdomain)
+	})
+}
+
+// ExcludeByRegex drops the subdomains matching pattern. A nil pattern leaves the
+// result unchanged.
+func ExcludeByRegex(result *EnumerationResult, pattern *regexp.Regexp) *EnumerationResult {
+	if result == nil || pattern == nil {
+		return result
+	}
+	return filterResult(result, func(subdomain string, _ []string) bool {
+		return !pattern.MatchString(subdomain)
+	})
+}
+
+// ValidateScopePatterns checks that every pattern, minus any leading "!", is a
+// valid glob as taken by validation.MatchAnyGlob
+func ValidateScopePatterns(patterns []string) error {
+	includes, excludes := splitScopePatterns(patterns)
+	if err := validation.ValidateGlobs(includes); err != nil {
+		return err
+	}
+	return validation.ValidateGlobs(excludes)
+}
+
+// InScope reports whether subdomain matches the scope patterns: it must match
+// at least one include pattern, if any are given, and no "!" exclude pattern.
+// Patterns are matched by validation.MatchAnyGlob, so "*.example.com" matches any depth.
+func InScope(subdomain string, patterns []string) bool {
+	includes, excludes := splitScopePatterns(patterns)
+	if validation.MatchAnyGlob(subdomain, excludes) {
+		return false
+	}
+	return len(includes) == 0 || validation.MatchAnyGlob(subdomain, includes)
+}
+
+// splitScopePatterns separates the include patterns from the "!" exclude
+// patterns, dropping the "!"
+func splitScopePatterns(patterns []string) (includes, excludes []string) {
+	for _, pattern := range patterns {
+		if exclude, ok := strings.CutPrefix(pattern, "!"); ok {
+			excludes = append(excludes, exclude)
+		} else {
+			includes = append(includes, pattern)
+		}
+	}
+	return includes, excludes
+}
+
+// TagScope sets InScope on every entry from patterns
+func TagScope(entries []SubdomainEntry, patterns []string) {
+	for i := range entries {
+		inScope := InScope(entries[i].Subdomain, patterns)
+		entries[i].InScope = &inScope
+	}
+}
+
+// AddSubdomains returns a copy of result with names added, attributed to source,
+// keeping the subdomains sorted. Names already in result are left as they are.
+func AddSubdomains(result *EnumerationResult, names []string, source string) *EnumerationResult {
+	known := make(map[string]struct{}, len(result.Subdomains))
+	added := filterResult(result, func(subdomain string, _ []string) bool {
+		known[subdomain] = struct{}{}
+		return true
+	})
+	for _, name := range names {
+		if _, ok := known[name]; ok {
+			continue
+		}
+		known[name] = struct{}{}
+		added.Subdomains = append(added.Subdomains, name)
+		added.Sources[name] = []string{source}
+	}
+	sort.Strings(added.Subdomains)
+	return added
+}
+
+// filterResult copies result keeping only the subdomains for which keep returns
+// true. Source statistics are carried over unchanged.
+func filterResult(result *EnumerationResult, keep func(subdomain string, sources []string) bool) *EnumerationResult {
+	filtered := &EnumerationResult{
+		Subdomains:   make([]string, 0, len(result.Subdomains)),
+		Sources:      make(map[string][]string, len(result.Sources)),
+		SourceStats:  result.SourceStats,
+		TotalSources: result.TotalSources,
+		TotalErrors:  result.TotalErrors,
+	}
+	for _, subdomain := range result.Subdomains {
+		sources, hasSources := result.Sources[subdomain]
+		if !keep(subdomain, sources) {
+			continue
+		}
+		filtered.Subdomains = append(filtered.Subdomains, subdomain)
+		if hasSources {
+			filtered.Sources[subdomain] = sources
+		}
+	}
+
+	return filtered
+}
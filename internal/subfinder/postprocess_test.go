@@ -0,0 +1,428 @@
+package subfinder
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// sourceSet builds a source set for a result map entry
+func sourceSet(sources ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		set[source] = struct{}{}
+	}
+	return set
+}
+
+// sortedKeys returns the subdomains of a result map in alphabetical order
+func sortedKeys(resultMap map[string]map[string]struct{}) []string {
+	keys := make([]string, 0, len(resultMap))
+	for key := range resultMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestCapPerSource(t *testing.T) {
+	resultMap := map[string]map[string]struct{}{
+		"a.example.com":      sourceSet("noisy"),
+		"b.example.com":      sourceSet("noisy"),
+		"c.example.com":      sourceSet("noisy"),
+		"d.example.com":      sourceSet("noisy", "crtsh"),
+		"e.example.com":      sourceSet("crtsh"),
+		"shared.example.com": sourceSet("noisy", "crtsh", "alienvault"),
+	}
+
+	tests := []struct {
+		name         string
+		maxPerSource int
+		expected     []string
+	}{
+		{
+			name:         "Unlimited",
+			maxPerSource: 0,
+			expected:     []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "shared.example.com"},
+		},
+		{
+			name:         "Cap of one",
+			maxPerSource: 1,
+			expected:     []string{"a.example.com", "d.example.com", "e.example.com", "shared.example.com"},
+		},
+		{
+			name:         "Cap of two",
+			maxPerSource: 2,
+			expected:     []string{"a.example.com", "b.example.com", "d.example.com", "e.example.com", "shared.example.com"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sortedKeys(CapPerSource(resultMap, tc.maxPerSource))
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("Expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByMinSources(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"one.example.com", "three.example.com", "two.example.com", "www.example.com"},
+		Sources: map[string][]string{
+			"one.example.com":   {"crtsh"},
+			"two.example.com":   {"alienvault", "crtsh"},
+			"three.example.com": {"alienvault", "crtsh", "hackertarget"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		minSources int
+		expected   []string
+	}{
+		{
+			name:       "Default keeps everything",
+			minSources: 1,
+			expected:   []string{"one.example.com", "three.example.com", "two.example.com", "www.example.com"},
+		},
+		{
+			name:       "Two sources",
+			minSources: 2,
+			expected:   []string{"three.example.com", "two.example.com"},
+		},
+		{
+			name:       "Three sources",
+			minSources: 3,
+			expected:   []string{"three.example.com"},
+		},
+		{
+			name:       "More sources than any subdomain has",
+			minSources: 4,
+			expected:   []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FilterByMinSources(result, tc.minSources)
+			if len(got.Subdomains) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got.Subdomains)
+			}
+			for i := range tc.expected {
+				if got.Subdomains[i] != tc.expected[i] {
+					t.Errorf("Expected %v, got %v", tc.expected, got.Subdomains)
+					break
+				}
+			}
+			for _, subdomain := range got.Subdomains {
+				if tc.minSources > 1 && len(got.Sources[subdomain]) < tc.minSources {
+					t.Errorf("Subdomain %s kept with sources %v", subdomain, got.Sources[subdomain])
+				}
+			}
+		})
+	}
+}
+
+func TestTrustScore(t *testing.T) {
+	tests := []struct {
+		sources  []string
+		expected float64
+	}{
+		{nil, 0},
+		{[]string{"crtsh"}, 0.2},
+		{[]string{"crtsh", "crtsh", "alienvault"}, 0.4},
+		{[]string{"a", "b", "c", "d", "e"}, 1},
+		{[]string{"a", "b", "c", "d", "e", "f", "g"}, 1},
+	}
+	for _, tc := range tests {
+		if got := TrustScore(tc.sources); got != tc.expected {
+			t.Errorf("TrustScore(%v) = %v, expected %v", tc.sources, got, tc.expected)
+		}
+	}
+}
+
+func TestFilterByTrustScore(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"one.example.com", "three.example.com", "www.example.com"},
+		Sources: map[string][]string{
+			"one.example.com":   {"crtsh"},
+			"three.example.com": {"alienvault", "crtsh", "hackertarget"},
+		},
+	}
+
+	if got := FilterByTrustScore(result, 0); len(got.Subdomains) != 3 {
+		t.Errorf("Expected a zero minimum to keep everything, got %v", got.Subdomains)
+	}
+	got := FilterByTrustScore(result, 0.6)
+	if len(got.Subdomains) != 1 || got.Subdomains[0] != "three.example.com" {
+		t.Errorf("Expected only three.example.com, got %v", got.Subdomains)
+	}
+}
+
+func TestFilterByTLD(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"api.example.com", "mail.example.net", "www.example.com", "www.example.org"},
+		Sources: map[string][]string{
+			"api.example.com":  {"crtsh"},
+			"mail.example.net": {"alienvault"},
+			"www.example.org":  {"crtsh"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		tld      string
+		expected []string
+	}{
+		{"Empty keeps everything", "", []string{"api.example.com", "mail.example.net", "www.example.com", "www.example.org"}},
+		{"Plain TLD", "com", []string{"api.example.com", "www.example.com"}},
+		{"Leading dot and case", ".NET", []string{"mail.example.net"}},
+		{"No match", "io", []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FilterByTLD(result, tc.tld)
+			if !reflect.DeepEqual(got.Subdomains, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got.Subdomains)
+			}
+			for _, subdomain := range got.Subdomains {
+				if _, ok := result.Sources[subdomain]; ok && got.Sources[subdomain] == nil {
+					t.Errorf("Sources for %s were dropped", subdomain)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByRegisteredDomain(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"WWW.Example.com", "example.com", "example.com.evil.net", "notexample.com", "www.example.net"},
+	}
+
+	got := FilterByRegisteredDomain(result, "example.com")
+	expected := []string{"WWW.Example.com", "example.com"}
+	if !reflect.DeepEqual(got.Subdomains, expected) {
+		t.Errorf("Expected %v, got %v", expected, got.Subdomains)
+	}
+
+	if unchanged := FilterByRegisteredDomain(result, ""); unchanged != result {
+		t.Error("Expected an empty domain to leave the result unchanged")
+	}
+}
+
+func TestAddSubdomains(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains:   []string{"mail.example.com", "www.example.com"},
+		Sources:      map[string][]string{"www.example.com": {"crtsh"}},
+		TotalSources: 3,
+	}
+
+	got := AddSubdomains(result, []string{"dev-www.example.com", "mail.example.com", "zz.example.com"}, "permutation")
+	expected := &EnumerationResult{
+		Subdomains: []string{"dev-www.example.com", "mail.example.com", "www.example.com", "zz.example.com"},
+		Sources: map[string][]string{
+			"www.example.com":     {"crtsh"},
+			"dev-www.example.com": {"permutation"},
+			"zz.example.com":      {"permutation"},
+		},
+		TotalSources: 3,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+	if len(result.Subdomains) != 2 {
+		t.Error("Expected the original result to be left unchanged")
+	}
+}
+
+func TestFilterByRegex(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"api-staging.example.com", "dev-api.example.com", "dev.example.com", "DEV-web.example.com", "devxexample.com", "www.example.com"},
+		Sources:    map[string][]string{"dev-api.example.com": {"crtsh"}},
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		matching []string
+	}{
+		{"prefix with dash", `^dev-`, []string{"dev-api.example.com"}},
+		{"case insensitive", `(?i)^dev-`, []string{"dev-api.example.com", "DEV-web.example.com"}},
+		{"suffix anchored on label", `-staging\.`, []string{"api-staging.example.com"}},
+		{"unescaped dot matches any character", `^dev.example`, []string{"dev.example.com", "devxexample.com"}},
+		{"alternation", `^(www|dev)\.`, []string{"dev.example.com", "www.example.com"}},
+		{"empty pattern matches everything", ``, result.Subdomains},
+		{"no match", `^mail\.`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := regexp.MustCompile(tt.pattern)
+			kept := FilterByRegex(result, pattern)
+			if !reflect.DeepEqual(kept.Subdomains, tt.matching) {
+				t.Errorf("FilterByRegex: expected %v, got %v", tt.matching, kept.Subdomains)
+			}
+			excluded := ExcludeByRegex(result, pattern)
+			if len(excluded.Subdomains)+len(kept.Subdomains) != len(result.Subdomains) {
+				t.Errorf("ExcludeByRegex: expected the complement of %v, got %v", kept.Subdomains, excluded.Subdomains)
+			}
+		})
+	}
+
+	if FilterByRegex(result, nil) != result || ExcludeByRegex(result, nil) != result {
+		t.Error("Expected a nil pattern to leave the result unchanged")
+	}
+}
+
+func TestInScope(t *testing.T) {
+	patterns := []string{"*.example.com", "!admin.example.com", "!*.internal.example.com"}
+	tests := map[string]bool{
+		"www.example.com":         true,
+		"API.Example.com":         true,
+		"a.b.example.com":         true,
+		"admin.example.com":       false,
+		"db.internal.example.com": false,
+		"example.com":             false,
+		"www.example.org":         false,
+	}
+	for subdomain, expected := range tests {
+		if got := InScope(subdomain, patterns); got != expected {
+			t.Errorf("InScope(%q): expected %v, got %v", subdomain, expected, got)
+		}
+	}
+
+	// Only excludes keeps everything else in scope
+	if !InScope("www.example.com", []string{"!admin.example.com"}) || InScope("admin.example.com", []string{"!admin.example.com"}) {
+		t.Error("Expected exclude-only patterns to scope out just the excluded names")
+	}
+
+	entries := []SubdomainEntry{{Subdomain: "www.example.com"}, {Subdomain: "admin.example.com"}}
+	TagScope(entries, patterns)
+	if entries[0].InScope == nil || !*entries[0].InScope || entries[1].InScope == nil || *entries[1].InScope {
+		t.Errorf("Unexpected scope tags %+v", entries)
+	}
+
+	if err := ValidateScopePatterns([]string{"!*.example.com", "[a-"}); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
+
+func TestFilterBySubstrings(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"api-staging.example.com", "dev-api.example.com", "dev-web.internal.example.com", "www.example.com"},
+		Sources:    map[string][]string{"dev-api.example.com": {"crtsh"}},
+	}
+
+	tests := []struct {
+		name     string
+		prefixes []string
+		suffixes []string
+		contains []string
+		expected []string
+	}{
+		{"No filters keeps everything", nil, nil, nil, result.Subdomains},
+		{"Any prefix", []string{"dev-", "WWW."}, nil, nil, []string{"dev-api.example.com", "dev-web.internal.example.com", "www.example.com"}},
+		{"Suffix", nil, []string{".internal.example.com"}, nil, []string{"dev-web.internal.example.com"}},
+		{"Contains", nil, nil, []string{"-staging", "-web"}, []string{"api-staging.example.com", "dev-web.internal.example.com"}},
+		{"All lists must match", []string{"dev-"}, nil, []string{"api"}, []string{"dev-api.example.com"}},
+		{"No match", nil, nil, []string{"mail"}, []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FilterBySubstrings(result, tc.prefixes, tc.suffixes, tc.contains)
+			if !reflect.DeepEqual(got.Subdomains, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got.Subdomains)
+			}
+		})
+	}
+}
+
+func TestFilterByLabelLength(t *testing.T) {
+	result := &EnumerationResult{
+		Subdomains: []string{"a.example.com", "cdn.example.com", "mail.dev.example.com", "very-long-label.example.com"},
+		Sources:    map[string][]string{"cdn.example.com": {"crtsh"}},
+	}
+
+	tests := []struct {
+		name      string
+		minLength int
+		maxLength int
+		expected  []string
+	}{
+		{"No limits keeps everything", 0, 0, result.Subdomains},
+		{"Minimum is inclusive", 3, 0, []string{"cdn.example.com", "mail.dev.example.com", "very-long-label.example.com"}},
+		{"Just above the minimum", 4, 0, []string{"mail.dev.example.com", "very-long-label.example.com"}},
+		{"Maximum is inclusive", 0, 4, []string{"a.example.com", "cdn.example.com", "mail.dev.example.com"}},
+		{"Just below the maximum", 0, 3, []string{"a.example.com", "cdn.example.com"}},
+		{"Only the first label counts", 4, 4, []string{"mail.dev.example.com"}},
+		{"Empty range", 5, 4, []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FilterByLabelLength(result, tc.minLength, tc.maxLength)
+			if !reflect.DeepEqual(got.Subdomains, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got.Subdomains)
+			}
+		})
+	}
+}
+
+func TestNormalizeIDN(t *testing.T) {
+	resultMap := func() map[string]map[string]struct{} {
+		return map[string]map[string]struct{}{
+			"xn--wgv71a119e.example.com": {"crtsh": {}},
+			"日本語.example.com":            {"alienvault": {}},
+			"Bücher.example.com":         {"hackertarget": {}},
+			"xn--mnchen-3ya.example.com": {"crtsh": {}},
+			"www.example.com":            {"crtsh": {}},
+		}
+	}
+
+	tests := []struct {
+		encoding string
+		expected map[string][]string
+	}{
+		{IDNPunycode, map[string][]string{
+			"xn--wgv71a119e.example.com": {"alienvault", "crtsh"},
+			"xn--bcher-kva.example.com":  {"hackertarget"},
+			"xn--mnchen-3ya.example.com": {"crtsh"},
+			"www.example.com":            {"crtsh"},
+		}},
+		{IDNUnicode, map[string][]string{
+			"日本語.example.com":     {"alienvault", "crtsh"},
+			"bücher.example.com":  {"hackertarget"},
+			"münchen.example.com": {"crtsh"},
+			"www.example.com":     {"crtsh"},
+		}},
+		{IDNNone, map[string][]string{
+			"xn--wgv71a119e.example.com": {"crtsh"},
+			"日本語.example.com":            {"alienvault"},
+			"Bücher.example.com":         {"hackertarget"},
+			"xn--mnchen-3ya.example.com": {"crtsh"},
+			"www.example.com":            {"crtsh"},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.encoding, func(t *testing.T) {
+			got := make(map[string][]string)
+			for subdomain, sources := range NormalizeIDN(resultMap(), tc.encoding) {
+				got[subdomain] = sortedSourceNames(sources)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
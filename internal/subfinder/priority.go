@@ -0,0 +1,262 @@
+package subfinder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-subfinder-server/internal/sources"
+)
+
+// PriorityBudgetShare is the share of the timeout the priority sources get
+// before the rest run, so the preferred sources have most of the time
+const PriorityBudgetShare = 2.0 / 3
+
+// minRemainingTimeout is the fewest seconds worth running the remaining sources with
+const minRemainingTimeout = 5
+
+// enumeratePrioritized runs config.PrioritySources on their own with
+// PriorityBudgetShare of config.Timeout, then the remaining sources with what is
+// left of it, and merges the two. Priority results are streamed as soon as they
+// are found. When the remaining sources fail or there is no time left for them,
+// the priority results are returned with Partial set. Recursion only follows the
+// second phase.
+func enumeratePrioritized(ctx context.Context, domain string, config SubfinderConfig, enumerate func(context.Context, string, SubfinderConfig, *slog.Logger) (*EnumerationResult, error), logger *slog.Logger) (*EnumerationResult, error) {
+	start := time.Now()
+	budget := time.Duration(config.Timeout) * time.Second
+	stream := newResultStream(config.ResultWriter, config.StreamFilter)
+	seen := &streamedSubdomains{names: make(map[string]struct{})}
+
+	// Unknown or excluded names would leave phase one without sources
+	priority := make([]string, 0, len(config.PrioritySources))
+	for _, source := range config.PrioritySources {
+		if !sources.IsKnown(source) || slices.Contains(splitSources(config.ExcludeSourcesFilter), source) {
+			continue
+		}
+		if config.SourcesFilter == "" || slices.Contains(splitSources(config.SourcesFilter), source) {
+			priority = append(priority, source)
+		}
+	}
+
+	// Phase one: only the priority sources, once, without recursion
+	first := config
+	first.PrioritySources = nil
+	first.SourcesFilter = strings.Join(priority, ",")
+	first.Timeout = max(int(float64(config.Timeout)*PriorityBudgetShare), 1)
+	first.Recursive = false
+	first.RetryConfig.MaxAttempts = 1
+	first.ResultWriter = phaseWriter(config.ResultWriter, seen, true)
+
+	var prioritized *EnumerationResult
+	if len(priority) > 0 {
+		firstCtx, cancel := context.WithTimeout(ctx, time.Duration(first.Timeout)*time.Second)
+		result, err := enumerate(firstCtx, domain, first, logger)
+		cancel()
+		if err != nil {
+			logger.Warn("Priority sources failed, continuing with the remaining sources", "error", err)
+		} else {
+			prioritized = withoutSuggestions(result)
+			logger.Info("Priority sources complete",
+				"sources", first.SourcesFilter,
+				"subdomainsFound", len(prioritized.Subdomains))
+		}
+	} else {
+		logger.Warn("None of the priority sources are enabled, running a single phase")
+	}
+
+	// Phase two: every other source, within what is left of the budget
+	rest := config
+	rest.PrioritySources = nil
+	if config.SourcesFilter != "" {
+		var remaining []string
+		for _, source := range splitSources(config.SourcesFilter) {
+			if !slices.Contains(priority, source) {
+				remaining = append(remaining, source)
+			}
+		}
+		rest.SourcesFilter = strings.Join(remaining, ",")
+	} else if len(priority) > 0 {
+		rest.ExcludeSourcesFilter = strings.Join(append(splitSources(config.ExcludeSourcesFilter), priority...), ",")
+	}
+	rest.Timeout = int((budget - time.Since(start)).Seconds())
+	rest.ResultWriter = phaseWriter(config.ResultWriter, seen, false)
+
+	var result *EnumerationResult
+	switch {
+	case config.SourcesFilter != "" && rest.SourcesFilter == "":
+		// Every requested source had priority
+		result = prioritized
+	case rest.Timeout < minRemainingTimeout:
+		logger.Warn("No time left for the remaining sources, returning priority results", "remainingSeconds", rest.Timeout)
+		result = markPartial(prioritized)
+	default:
+		restCtx, cancel := context.WithDeadline(ctx, start.Add(budget))
+		remaining, err := enumerate(restCtx, domain, rest, logger)
+		cancel()
+		switch {
+		case err != nil && (prioritized == nil || len(prioritized.Subdomains) == 0):
+			return nil, err
+		case err != nil:
+			logger.Warn("Remaining sources failed, returning priority results", "error", err)
+			result = markPartial(prioritized)
+		default:
+			result = mergeResults(prioritized, remaining)
+		}
+	}
+	if result == nil {
+		result = &EnumerationResult{Sources: map[string][]string{}}
+	}
+
+	stream.emit(StreamEvent{
+		Type:            StreamEventStats,
+		Domain:          domain,
+		SubdomainsFound: len(result.Subdomains),
+		DurationMs:      time.Since(start).Milliseconds(),
+	})
+	return result, nil
+}
+
+// NeutralSourceWeight is the weight of a source not named in sourceWeights
+const NeutralSourceWeight = 1.0
+
+// PrioritySourcesByWeight returns the subfinder sources weighted above NeutralSourceWeight,
+// heaviest first, to be run as priority sources
+func PrioritySourcesByWeight(weights map[string]float64) []string {
+	var preferred []string
+	for source, weight := range weights {
+		if weight > NeutralSourceWeight && sources.IsKnown(source) {
+			preferred = append(preferred, source)
+		}
+	}
+	slices.SortFunc(preferred, func(a, b string) int {
+		if weights[a] != weights[b] {
+			if weights[a] > weights[b] {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return preferred
+}
+
+// splitSources splits a comma-separated source list, dropping blanks
+func splitSources(list string) []string {
+	var sources []string
+	for _, source := range strings.Split(list, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// withoutSuggestions drops the fallback suggestions, which have no sources, so a
+// priority phase that found nothing contributes nothing
+func withoutSuggestions(result *EnumerationResult) *EnumerationResult {
+	return filterResult(result, func(_ string, sources []string) bool {
+		return len(sources) > 0
+	})
+}
+
+// markPartial flags result as missing the remaining sources' results
+func markPartial(result *EnumerationResult) *EnumerationResult {
+	if result == nil {
+		return nil
+	}
+	result.Partial = true
+	return result
+}
+
+// mergeResults combines the results of the two phases. The remaining phase's
+// fallback suggestions are dropped when the priority phase found anything.
+func mergeResults(prioritized, remaining *EnumerationResult) *EnumerationResult {
+	if prioritized == nil || len(prioritized.Subdomains) == 0 {
+		return remaining
+	}
+	remaining = withoutSuggestions(remaining)
+
+	merged := filterResult(prioritized, func(string, []string) bool { return true })
+	merged.SourceStats = append(append([]SourceStatistic(nil), prioritized.SourceStats...), remaining.SourceStats...)
+	merged.TotalSources += remaining.TotalSources
+	merged.TotalErrors += remaining.TotalErrors
+	for _, subdomain := range remaining.Subdomains {
+		sources, ok := merged.Sources[subdomain]
+		if !ok {
+			merged.Subdomains = append(merged.Subdomains, subdomain)
+		}
+		merged.Sources[subdomain] = sortedSourceNames(sourceSetOf(append(sources, remaining.Sources[subdomain]...)))
+	}
+	slices.Sort(merged.Subdomains)
+	return merged
+}
+
+// sourceSetOf turns source names into the set taken by sortedSourceNames
+func sourceSetOf(sources []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		set[source] = struct{}{}
+	}
+	return set
+}
+
+// streamedSubdomains remembers the subdomains already streamed by either phase
+type streamedSubdomains struct {
+	mu    sync.Mutex
+	names map[string]struct{}
+	// suggested is set once fallback suggestions are being streamed
+	suggested bool
+}
+
+// phaseStream forwards one phase's events to the caller's stream. Stats events
+// are dropped since a single one is written once both phases are done, as are
+// subdomains the other phase already streamed. Fallback suggestions are only
+// forwarded from the remaining phase, and only when nothing else was streamed.
+type phaseStream struct {
+	w        io.Writer
+	seen     *streamedSubdomains
+	priority bool
+}
+
+// phaseWriter returns the writer for one phase, or nil when not streaming
+func phaseWriter(w io.Writer, seen *streamedSubdomains, priority bool) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &phaseStream{w: w, seen: seen, priority: priority}
+}
+
+// Write is called once per NDJSON event by the phase's result stream
+func (p *phaseStream) Write(line []byte) (int, error) {
+	var event StreamEvent
+	if err := json.Unmarshal(line, &event); err != nil || event.Type != StreamEventSubdomain {
+		return len(line), nil
+	}
+	p.seen.mu.Lock()
+	_, streamed := p.seen.names[event.Subdomain]
+	suggestion := len(event.Sources) == 0
+	if suggestion && (p.priority || (len(p.seen.names) > 0 && !p.seen.suggested)) {
+		p.seen.mu.Unlock()
+		return len(line), nil
+	}
+	p.seen.names[event.Subdomain] = struct{}{}
+	p.seen.suggested = p.seen.suggested || suggestion
+	p.seen.mu.Unlock()
+	if streamed {
+		return len(line), nil
+	}
+
+	n, err := p.w.Write(line)
+	if err == nil {
+		// The phase's stream only flushes this writer, so pass the flush on
+		if flusher, ok := p.w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+	}
+	return n, err
+}
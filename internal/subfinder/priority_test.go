@@ -0,0 +1,132 @@
+package subfinder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnumeratePrioritized(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var phases []SubfinderConfig
+	enumerate := func(_ context.Context, domain string, config SubfinderConfig, _ *slog.Logger) (*EnumerationResult, error) {
+		phases = append(phases, config)
+		stream := newResultStream(config.ResultWriter, config.StreamFilter)
+		if len(phases) == 1 {
+			stream.emitSubdomain("www.example.com", []string{"crtsh"})
+			stream.emit(StreamEvent{Type: StreamEventStats, SubdomainsFound: 1})
+			return &EnumerationResult{
+				Subdomains:   []string{"www.example.com"},
+				Sources:      map[string][]string{"www.example.com": {"crtsh"}},
+				TotalSources: 1,
+			}, nil
+		}
+		stream.emitSubdomain("api.example.com", []string{"alienvault"})
+		stream.emitSubdomain("www.example.com", []string{"alienvault"})
+		return &EnumerationResult{
+			Subdomains: []string{"api.example.com", "www.example.com"},
+			Sources: map[string][]string{
+				"api.example.com": {"alienvault"},
+				"www.example.com": {"alienvault"},
+			},
+			TotalSources: 1,
+			TotalErrors:  1,
+		}, nil
+	}
+
+	var out bytes.Buffer
+	config := SubfinderConfig{
+		Timeout:              60,
+		Recursive:            true,
+		ExcludeSourcesFilter: "github",
+		PrioritySources:      []string{"crtsh"},
+		ResultWriter:         &out,
+	}
+	result, err := enumeratePrioritized(context.Background(), "example.com", config, enumerate, logger)
+	if err != nil {
+		t.Fatalf("enumeratePrioritized failed: %v", err)
+	}
+
+	if len(phases) != 2 {
+		t.Fatalf("Expected two phases, got %d", len(phases))
+	}
+	if phases[0].SourcesFilter != "crtsh" || phases[0].Recursive || phases[0].Timeout != 40 || phases[0].RetryConfig.MaxAttempts != 1 {
+		t.Errorf("Unexpected priority phase config %+v", phases[0])
+	}
+	if phases[1].ExcludeSourcesFilter != "github,crtsh" || !phases[1].Recursive || phases[1].PrioritySources != nil {
+		t.Errorf("Unexpected remaining phase config %+v", phases[1])
+	}
+
+	expected := &EnumerationResult{
+		Subdomains: []string{"api.example.com", "www.example.com"},
+		Sources: map[string][]string{
+			"api.example.com": {"alienvault"},
+			"www.example.com": {"alienvault", "crtsh"},
+		},
+		TotalSources: 2,
+		TotalErrors:  1,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// Each subdomain is streamed once, followed by a single stats event
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "www.example.com") || !strings.Contains(lines[1], "api.example.com") || !strings.Contains(lines[2], `"subdomainsFound":2`) {
+		t.Errorf("Unexpected stream %q", out.String())
+	}
+}
+
+func TestEnumeratePrioritizedPartial(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerate := func(_ context.Context, _ string, config SubfinderConfig, _ *slog.Logger) (*EnumerationResult, error) {
+		if config.SourcesFilter == "crtsh" {
+			return &EnumerationResult{
+				Subdomains: []string{"www.example.com"},
+				Sources:    map[string][]string{"www.example.com": {"crtsh"}},
+			}, nil
+		}
+		return nil, errors.New("context deadline exceeded")
+	}
+
+	config := SubfinderConfig{Timeout: 60, SourcesFilter: "crtsh, alienvault", PrioritySources: []string{"crtsh", "shodan"}}
+	result, err := enumeratePrioritized(context.Background(), "example.com", config, enumerate, logger)
+	if err != nil {
+		t.Fatalf("enumeratePrioritized failed: %v", err)
+	}
+	if !result.Partial || !reflect.DeepEqual(result.Subdomains, []string{"www.example.com"}) {
+		t.Errorf("Expected partial priority results, got %+v", result)
+	}
+}
+
+func TestEnumeratePrioritizedUnknownSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var phases []SubfinderConfig
+	enumerate := func(_ context.Context, _ string, config SubfinderConfig, _ *slog.Logger) (*EnumerationResult, error) {
+		phases = append(phases, config)
+		return &EnumerationResult{}, nil
+	}
+
+	// Names subfinder lacks, or excludes, never make up phase one on their own
+	config := SubfinderConfig{Timeout: 60, ExcludeSourcesFilter: "crtsh", PrioritySources: []string{"bogus", "crtsh"}}
+	if _, err := enumeratePrioritized(context.Background(), "example.com", config, enumerate, logger); err != nil {
+		t.Fatalf("enumeratePrioritized failed: %v", err)
+	}
+	if len(phases) != 1 || phases[0].SourcesFilter != "" || phases[0].ExcludeSourcesFilter != "crtsh" {
+		t.Errorf("Expected a single phase over the remaining sources, got %+v", phases)
+	}
+}
+
+func TestPrioritySourcesByWeight(t *testing.T) {
+	got := PrioritySourcesByWeight(map[string]float64{"crtsh": 2, "alienvault": 3, "hackertarget": 1, "anubis": 0, "digitorus": 2, "bogus": 5})
+	expected := []string{"alienvault", "crtsh", "digitorus"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
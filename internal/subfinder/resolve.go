@@ -0,0 +1,356 @@
+package subfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"mcp-subfinder-server/internal/cloud"
+	"mcp-subfinder-server/internal/shodan"
+	"mcp-subfinder-server/internal/virustotal"
+)
+
+// resolveWorkers is the number of concurrent DNS lookups performed when resolving results
+const resolveWorkers = 20
+
+// dnsQueryTimeout bounds each A/AAAA query sent to a nameserver
+const dnsQueryTimeout = 5 * time.Second
+
+// resolvConfPath is where the system nameservers are read from
+const resolvConfPath = "/etc/resolv.conf"
+
+// SubdomainEntry describes a discovered subdomain and the addresses it resolves to
+type SubdomainEntry struct {
+	Subdomain string    `json:"subdomain"`
+	IPs       []IPEntry `json:"ips,omitempty"`
+	// Parked is set when the subdomain serves a registrar or marketplace parking page
+	Parked bool `json:"parked,omitempty"`
+	// Cert is the certificate served on port 443, when probed
+	Cert *CertInfo `json:"cert,omitempty"`
+	// CloudProvider names the provider whose published ranges contain the first matching address
+	CloudProvider string `json:"cloudProvider,omitempty"`
+	// AssetType is a best-effort guess at what the subdomain hosts, such as api or mail
+	AssetType string `json:"assetType,omitempty"`
+	// InScope is set when scope patterns were given and reports whether the subdomain matched them
+	InScope *bool `json:"inScope,omitempty"`
+	// Shodan holds the Shodan records of the entry's addresses, when enriched
+	Shodan []shodan.Host `json:"shodan,omitempty"`
+	// Aliases lists the other subdomains resolving to the same addresses, when collapsed by IP
+	Aliases []string `json:"aliases,omitempty"`
+	// TrustScore is how well corroborated the subdomain is, from TrustScore; omitted for
+	// names no source reported
+	TrustScore float64 `json:"trustScore,omitempty"`
+	// Status is StatusLive or StatusIdle once the subdomain has been probed over HTTP(S)
+	Status string `json:"status,omitempty"`
+	// Sensitive is set by sensitivePatternAlert when the name suggests an internal
+	// service, and SensitiveReason says what it looks like
+	Sensitive       bool   `json:"sensitive,omitempty"`
+	SensitiveReason string `json:"sensitiveReason,omitempty"`
+	// TLSVersions lists the TLS versions port 443 completed a handshake with, when
+	// probed with probeTLSVersion
+	TLSVersions []string `json:"tlsVersions,omitempty"`
+	// VirusTotal is the subdomain's VirusTotal reputation, when enriched
+	VirusTotal *virustotal.Report `json:"virusTotal,omitempty"`
+}
+
+// Statuses recorded by an HTTP probe
+const (
+	// StatusLive marks a subdomain that answered over HTTP or HTTPS
+	StatusLive = "live"
+	// StatusIdle marks a subdomain that answered over neither
+	StatusIdle = "idle"
+)
+
+// IPEntry is a resolved address and the TTL in seconds of the record it came from.
+// TTL is omitted when the system resolver had to be used, since it does not expose TTLs.
+type IPEntry struct {
+	IP  string `json:"ip"`
+	TTL uint32 `json:"ttl,omitempty"`
+}
+
+// addressLookup resolves a host to its A/AAAA addresses
+type addressLookup func(ctx context.Context, host string) ([]IPEntry, error)
+
+// IPFilter selects which address classes cause a subdomain to be dropped.
+// A subdomain is only dropped when every one of its resolved addresses is excluded.
+type IPFilter struct {
+	ExcludePrivate   bool
+	ExcludeLoopback  bool
+	ExcludeMulticast bool
+}
+
+// Enabled reports whether any exclusion is configured
+func (f IPFilter) Enabled() bool {
+	return f.ExcludePrivate || f.ExcludeLoopback || f.ExcludeMulticast
+}
+
+// excludes reports whether a single address falls in an excluded range
+func (f IPFilter) excludes(ip net.IP) bool {
+	if f.ExcludePrivate && (ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+		return true
+	}
+	if f.ExcludeLoopback && ip.IsLoopback() {
+		return true
+	}
+	if f.ExcludeMulticast && ip.IsMulticast() {
+		return true
+	}
+	return false
+}
+
+// ResolveSubdomains looks up the A/AAAA records of each subdomain concurrently,
+// querying the system nameservers directly so record TTLs are available.
+// Subdomains that fail to resolve are returned without addresses.
+func ResolveSubdomains(ctx context.Context, subdomains []string, logger *slog.Logger) []SubdomainEntry {
+	lookup := systemResolverLookup
+	if config, err := dns.ClientConfigFromFile(resolvConfPath); err == nil && len(config.Servers) > 0 {
+		nameservers := make([]string, 0, len(config.Servers))
+		for _, server := range config.Servers {
+			nameservers = append(nameservers, net.JoinHostPort(server, config.Port))
+		}
+		lookup = nameserverLookup(nameservers)
+	} else {
+		logger.Warn("Could not read nameservers, resolving without TTLs", "path", resolvConfPath, "error", err)
+	}
+
+	return resolveSubdomains(ctx, lookup, subdomains, logger)
+}
+
+// resolveSubdomains implements ResolveSubdomains with a pluggable lookup
+func resolveSubdomains(ctx context.Context, lookup addressLookup, subdomains []string, logger *slog.Logger) []SubdomainEntry {
+	entries := make([]SubdomainEntry, len(subdomains))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < resolveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				entries[i].Subdomain = subdomains[i]
+				addrs, err := lookup(ctx, subdomains[i])
+				if err != nil {
+					logger.Debug("Failed to resolve subdomain", "subdomain", subdomains[i], "error", err)
+					continue
+				}
+				entries[i].IPs = addrs
+				entries[i].CloudProvider = cloudProvider(addrs)
+			}
+		}()
+	}
+
+	for i := range subdomains {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return entries
+}
+
+// cloudProvider returns the provider hosting the first address in a known cloud range
+func cloudProvider(addrs []IPEntry) string {
+	for _, addr := range addrs {
+		if provider := cloud.Classify(addr.IP); provider != "" {
+			return provider
+		}
+	}
+	return ""
+}
+
+// systemResolverLookup resolves through the Go resolver, which does not report TTLs
+func systemResolverLookup(ctx context.Context, host string) ([]IPEntry, error) {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]IPEntry, 0, len(addrs))
+	for _, addr := range addrs {
+		entries = append(entries, IPEntry{IP: addr})
+	}
+	return entries, nil
+}
+
+// nameserverLookup returns a lookup that sends A and AAAA queries to the given
+// nameservers in order, using the first one that answers each query
+func nameserverLookup(nameservers []string) addressLookup {
+	client := &dns.Client{Timeout: dnsQueryTimeout}
+	tcpClient := &dns.Client{Net: "tcp", Timeout: dnsQueryTimeout}
+
+	return func(ctx context.Context, host string) ([]IPEntry, error) {
+		var entries []IPEntry
+		var lastErr error
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			msg := new(dns.Msg)
+			msg.SetQuestion(dns.Fqdn(host), qtype)
+
+			var reply *dns.Msg
+			for _, nameserver := range nameservers {
+				var err error
+				reply, _, err = client.ExchangeContext(ctx, msg, nameserver)
+				if err == nil && reply.Truncated {
+					reply, _, err = tcpClient.ExchangeContext(ctx, msg, nameserver)
+				}
+				if err == nil {
+					break
+				}
+				lastErr = err
+				reply = nil
+			}
+			if reply == nil {
+				continue
+			}
+
+			// The answer may include the CNAME chain; only address records are kept
+			for _, rr := range reply.Answer {
+				switch record := rr.(type) {
+				case *dns.A:
+					entries = append(entries, IPEntry{IP: record.A.String(), TTL: record.Hdr.Ttl})
+				case *dns.AAAA:
+					entries = append(entries, IPEntry{IP: record.AAAA.String(), TTL: record.Hdr.Ttl})
+				}
+			}
+		}
+
+		if len(entries) == 0 {
+			if lastErr != nil {
+				return nil, fmt.Errorf("failed to query nameservers: %w", lastErr)
+			}
+			return nil, errors.New("no A or AAAA records")
+		}
+		return entries, nil
+	}
+}
+
+// FilterByIP drops entries whose resolved addresses all fall in ranges excluded by filter.
+// Entries without any resolved address are kept since there is nothing to judge them by.
+func FilterByIP(entries []SubdomainEntry, filter IPFilter) []SubdomainEntry {
+	if !filter.Enabled() {
+		return entries
+	}
+
+	filtered := make([]SubdomainEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.IPs) == 0 {
+			filtered = append(filtered, entry)
+			continue
+		}
+
+		allExcluded := true
+		for _, addr := range entry.IPs {
+			ip := net.ParseIP(addr.IP)
+			if ip == nil || !filter.excludes(ip) {
+				allExcluded = false
+				break
+			}
+		}
+
+		if !allExcluded {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// FilterByCloudProvider keeps the entries hosted on one of the include providers,
+// when any are given, then drops those hosted on an exclude provider. Entries
+// with no known provider only survive when include is empty.
+func FilterByCloudProvider(entries []SubdomainEntry, include, exclude []string) []SubdomainEntry {
+	if len(include) == 0 && len(exclude) == 0 {
+		return entries
+	}
+
+	filtered := make([]SubdomainEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(include) > 0 && !slices.Contains(include, entry.CloudProvider) {
+			continue
+		}
+		if entry.CloudProvider != "" && slices.Contains(exclude, entry.CloudProvider) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// FilterByTLSVersion keeps the entries supporting at least one of the only versions,
+// when given, and drops those supporting any of the exclude versions. Entries
+// that completed no handshake support no version.
+func FilterByTLSVersion(entries []SubdomainEntry, only, exclude []string) []SubdomainEntry {
+	if len(only) == 0 && len(exclude) == 0 {
+		return entries
+	}
+
+	filtered := make([]SubdomainEntry, 0, len(entries))
+	for _, entry := range entries {
+		supports := func(version string) bool { return slices.Contains(entry.TLSVersions, version) }
+		if len(only) > 0 && !slices.ContainsFunc(only, supports) {
+			continue
+		}
+		if slices.ContainsFunc(exclude, supports) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// CollapseByIP keeps only the alphabetically first entry of every group of entries
+// sharing a resolved address and lists the others in its Aliases. Sharing is
+// transitive, so names spread over a CDN's address pool end up in one group even
+// when no two resolve to exactly the same set. Entries without any resolved
+// address are kept as they are.
+func CollapseByIP(entries []SubdomainEntry) []SubdomainEntry {
+	sorted := make([]SubdomainEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Subdomain < sorted[j].Subdomain })
+
+	// Union-find over the entries, rooted at the alphabetically first of each group
+	parent := make([]int, len(sorted))
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	firstWithIP := make(map[string]int)
+	for i, entry := range sorted {
+		parent[i] = i
+		for _, addr := range entry.IPs {
+			j, ok := firstWithIP[addr.IP]
+			if !ok {
+				firstWithIP[addr.IP] = i
+				continue
+			}
+			if a, b := root(i), root(j); a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	collapsed := make([]SubdomainEntry, 0, len(sorted))
+	position := make(map[int]int)
+	for i, entry := range sorted {
+		if len(entry.IPs) == 0 {
+			collapsed = append(collapsed, entry)
+			continue
+		}
+		if r := root(i); r != i {
+			collapsed[position[r]].Aliases = append(collapsed[position[r]].Aliases, entry.Subdomain)
+			continue
+		}
+		position[i] = len(collapsed)
+		collapsed = append(collapsed, entry)
+	}
+
+	return collapsed
+}
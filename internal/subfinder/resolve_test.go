@@ -0,0 +1,226 @@
+package subfinder
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// ipEntries builds resolved address entries without TTLs
+func ipEntries(addrs ...string) []IPEntry {
+	entries := make([]IPEntry, 0, len(addrs))
+	for _, addr := range addrs {
+		entries = append(entries, IPEntry{IP: addr})
+	}
+	return entries
+}
+
+func TestFilterByIP(t *testing.T) {
+	entries := []SubdomainEntry{
+		{Subdomain: "public.example.com", IPs: ipEntries("93.184.216.34")},
+		{Subdomain: "private.example.com", IPs: ipEntries("10.0.0.5", "192.168.1.10")},
+		{Subdomain: "mixed.example.com", IPs: ipEntries("172.16.0.1", "93.184.216.35")},
+		{Subdomain: "linklocal.example.com", IPs: ipEntries("169.254.10.10")},
+		{Subdomain: "loopback.example.com", IPs: ipEntries("127.0.0.1", "::1")},
+		{Subdomain: "multicast.example.com", IPs: ipEntries("239.255.255.250")},
+		{Subdomain: "unresolved.example.com"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   IPFilter
+		expected []string
+	}{
+		{
+			name:   "No filter",
+			filter: IPFilter{},
+			expected: []string{
+				"public.example.com", "private.example.com", "mixed.example.com",
+				"linklocal.example.com", "loopback.example.com", "multicast.example.com",
+				"unresolved.example.com",
+			},
+		},
+		{
+			name:   "Exclude private",
+			filter: IPFilter{ExcludePrivate: true},
+			expected: []string{
+				"public.example.com", "mixed.example.com", "loopback.example.com",
+				"multicast.example.com", "unresolved.example.com",
+			},
+		},
+		{
+			name:   "Exclude loopback and multicast",
+			filter: IPFilter{ExcludeLoopback: true, ExcludeMulticast: true},
+			expected: []string{
+				"public.example.com", "private.example.com", "mixed.example.com",
+				"linklocal.example.com", "unresolved.example.com",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range FilterByIP(entries, tc.filter) {
+				got = append(got, entry.Subdomain)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// startTestNameserver serves fixed A/AAAA records over UDP on a random local port
+func startTestNameserver(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc("www.example.com.", func(w dns.ResponseWriter, r *dns.Msg) {
+		reply := new(dns.Msg)
+		reply.SetReply(r)
+		switch r.Question[0].Qtype {
+		case dns.TypeA:
+			reply.Answer = append(reply.Answer,
+				&dns.CNAME{Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300}, Target: "edge.example.net."},
+				&dns.A{Hdr: dns.RR_Header{Name: "edge.example.net.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP("192.0.2.10")})
+		case dns.TypeAAAA:
+			reply.Answer = append(reply.Answer,
+				&dns.AAAA{Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600}, AAAA: net.ParseIP("2001:db8::10")})
+		}
+		w.WriteMsg(reply)
+	})
+	mux.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+		reply := new(dns.Msg)
+		reply.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(reply)
+	})
+
+	server := &dns.Server{PacketConn: conn, Handler: mux}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+func TestCollapseByIP(t *testing.T) {
+	entries := []SubdomainEntry{
+		{Subdomain: "www.example.com", IPs: ipEntries("104.18.21.226")},
+		{Subdomain: "cdn.example.com", IPs: ipEntries("104.18.21.226")},
+		{Subdomain: "dual.example.com", IPs: ipEntries("2001:db8::1", "192.0.2.1")},
+		{Subdomain: "api.example.com", IPs: ipEntries("192.0.2.1", "2001:db8::1")},
+		{Subdomain: "mail.example.com", IPs: ipEntries("192.0.2.1")},
+		{Subdomain: "unresolved.example.com"},
+		{Subdomain: "old.example.com"},
+		// Spread over a pool: each shares an address with the next, none with both others
+		{Subdomain: "pool-c.example.com", IPs: ipEntries("198.51.100.3", "198.51.100.4")},
+		{Subdomain: "pool-a.example.com", IPs: ipEntries("198.51.100.1", "198.51.100.2")},
+		{Subdomain: "pool-b.example.com", IPs: ipEntries("198.51.100.2", "198.51.100.3")},
+	}
+
+	expected := []SubdomainEntry{
+		{Subdomain: "api.example.com", IPs: ipEntries("192.0.2.1", "2001:db8::1"), Aliases: []string{"dual.example.com", "mail.example.com"}},
+		{Subdomain: "cdn.example.com", IPs: ipEntries("104.18.21.226"), Aliases: []string{"www.example.com"}},
+		{Subdomain: "old.example.com"},
+		{Subdomain: "pool-a.example.com", IPs: ipEntries("198.51.100.1", "198.51.100.2"), Aliases: []string{"pool-b.example.com", "pool-c.example.com"}},
+		{Subdomain: "unresolved.example.com"},
+	}
+	if got := CollapseByIP(entries); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestResolveSubdomainsWithTTL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	lookup := nameserverLookup([]string{startTestNameserver(t)})
+
+	entries := resolveSubdomains(context.Background(), lookup, []string{"www.example.com", "missing.example.com"}, logger)
+
+	expected := []SubdomainEntry{
+		{Subdomain: "www.example.com", IPs: []IPEntry{{IP: "192.0.2.10", TTL: 30}, {IP: "2001:db8::10", TTL: 3600}}},
+		{Subdomain: "missing.example.com"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, entries)
+	}
+}
+
+func TestFilterByCloudProvider(t *testing.T) {
+	entries := []SubdomainEntry{
+		{Subdomain: "a.example.com", CloudProvider: "aws"},
+		{Subdomain: "g.example.com", CloudProvider: "gcp"},
+		{Subdomain: "c.example.com", CloudProvider: "cloudflare"},
+		{Subdomain: "onprem.example.com"},
+	}
+	names := func(entries []SubdomainEntry) []string {
+		out := []string{}
+		for _, entry := range entries {
+			out = append(out, entry.Subdomain)
+		}
+		return out
+	}
+
+	tests := []struct {
+		include, exclude []string
+		expected         []string
+	}{
+		{nil, nil, []string{"a.example.com", "g.example.com", "c.example.com", "onprem.example.com"}},
+		{[]string{"aws", "gcp"}, nil, []string{"a.example.com", "g.example.com"}},
+		{nil, []string{"cloudflare"}, []string{"a.example.com", "g.example.com", "onprem.example.com"}},
+		{[]string{"aws", "gcp"}, []string{"gcp"}, []string{"a.example.com"}},
+	}
+	for _, tt := range tests {
+		if got := names(FilterByCloudProvider(entries, tt.include, tt.exclude)); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("FilterByCloudProvider(%v, %v) = %v, expected %v", tt.include, tt.exclude, got, tt.expected)
+		}
+	}
+}
+
+func TestFilterByTLSVersion(t *testing.T) {
+	entries := []SubdomainEntry{
+		{Subdomain: "legacy.example.com", TLSVersions: []string{"1.0", "1.1", "1.2"}},
+		{Subdomain: "modern.example.com", TLSVersions: []string{"1.2", "1.3"}},
+		{Subdomain: "plain.example.com"},
+	}
+	names := func(entries []SubdomainEntry) []string {
+		out := []string{}
+		for _, entry := range entries {
+			out = append(out, entry.Subdomain)
+		}
+		return out
+	}
+
+	tests := []struct {
+		only, exclude []string
+		expected      []string
+	}{
+		{nil, nil, []string{"legacy.example.com", "modern.example.com", "plain.example.com"}},
+		{[]string{"1.0", "1.1"}, nil, []string{"legacy.example.com"}},
+		{nil, []string{"1.0"}, []string{"modern.example.com", "plain.example.com"}},
+		{[]string{"1.2"}, []string{"1.3"}, []string{"legacy.example.com"}},
+	}
+	for _, tt := range tests {
+		if got := names(FilterByTLSVersion(entries, tt.only, tt.exclude)); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("FilterByTLSVersion(%v, %v) = %v, expected %v", tt.only, tt.exclude, got, tt.expected)
+		}
+	}
+}
+
+func TestCloudProvider(t *testing.T) {
+	if got := cloudProvider([]IPEntry{{IP: "192.0.2.10"}, {IP: "104.16.1.1"}}); got != "cloudflare" {
+		t.Errorf("Expected cloudflare from the first classified address, got %q", got)
+	}
+	if got := cloudProvider([]IPEntry{{IP: "192.0.2.10"}}); got != "" {
+		t.Errorf("Expected no provider for a documentation address, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package subfinder
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Stream event types
+const (
+	// StreamEventSubdomain is emitted once per newly discovered subdomain
+	StreamEventSubdomain = "subdomain"
+	// StreamEventStats is emitted last, once enumeration has finished
+	StreamEventStats = "stats"
+)
+
+// StreamEvent is a single newline-delimited JSON record written to a result stream
+type StreamEvent struct {
+	Type            string   `json:"type"`
+	Subdomain       string   `json:"subdomain,omitempty"`
+	Sources         []string `json:"sources,omitempty"`
+	Domain          string   `json:"domain,omitempty"`
+	SubdomainsFound int      `json:"subdomainsFound,omitempty"`
+	DurationMs      int64    `json:"durationMs,omitempty"`
+}
+
+// resultStream writes StreamEvents to a writer as NDJSON, flushing after each line.
+// A nil *resultStream discards every event so callers need not check for streaming.
+type resultStream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	writer  io.Writer
+	filter  func(subdomain string) []string
+	err     error
+}
+
+// newResultStream returns a stream writing to w, or nil when w is nil. A non-nil
+// filter decides which names each subdomain event is written as.
+func newResultStream(w io.Writer, filter func(subdomain string) []string) *resultStream {
+	if w == nil {
+		return nil
+	}
+	return &resultStream{
+		encoder: json.NewEncoder(w),
+		writer:  w,
+		filter:  filter,
+	}
+}
+
+// emit writes a single event; after the first write error the stream goes quiet
+func (s *resultStream) emit(event StreamEvent) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if s.err = s.encoder.Encode(event); s.err != nil {
+		return
+	}
+
+	// Push the line to the client immediately when the writer supports it
+	if flusher, ok := s.writer.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
+
+// emitSubdomain writes a subdomain event for each name the filter maps subdomain to
+func (s *resultStream) emitSubdomain(subdomain string, sources []string) {
+	if s == nil {
+		return
+	}
+	names := []string{subdomain}
+	if s.filter != nil {
+		names = s.filter(subdomain)
+	}
+	for _, name := range names {
+		s.emit(StreamEvent{
+			Type:      StreamEventSubdomain,
+			Subdomain: name,
+			Sources:   sources,
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package subfinder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flushRecorder records writes and counts flushes
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+}
+
+func TestResultStreamEmit(t *testing.T) {
+	recorder := &flushRecorder{}
+	stream := newResultStream(recorder, nil)
+
+	stream.emitSubdomain("a.example.com", []string{"crtsh"})
+	stream.emit(StreamEvent{Type: StreamEventStats, Domain: "example.com", SubdomainsFound: 1})
+
+	lines := strings.Split(strings.TrimSpace(recorder.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 NDJSON lines, got %d: %q", len(lines), recorder.String())
+	}
+
+	var first StreamEvent
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Failed to decode first line: %v", err)
+	}
+	if first.Type != StreamEventSubdomain || first.Subdomain != "a.example.com" {
+		t.Errorf("Unexpected first event: %+v", first)
+	}
+
+	var last StreamEvent
+	if err := json.Unmarshal([]byte(lines[1]), &last); err != nil {
+		t.Fatalf("Failed to decode last line: %v", err)
+	}
+	if last.Type != StreamEventStats || last.SubdomainsFound != 1 {
+		t.Errorf("Unexpected stats event: %+v", last)
+	}
+
+	if recorder.flushes != 2 {
+		t.Errorf("Expected a flush per event, got %d", recorder.flushes)
+	}
+}
+
+func TestResultStreamFilter(t *testing.T) {
+	recorder := &flushRecorder{}
+	stream := newResultStream(recorder, func(subdomain string) []string {
+		if subdomain == "secret.example.com" {
+			return nil
+		}
+		return []string{strings.ToUpper(subdomain)}
+	})
+
+	stream.emitSubdomain("secret.example.com", []string{"crtsh"})
+	stream.emitSubdomain("www.example.com", []string{"crtsh"})
+
+	var event StreamEvent
+	if err := json.Unmarshal(recorder.Bytes(), &event); err != nil {
+		t.Fatalf("Expected a single event, got %q: %v", recorder.String(), err)
+	}
+	if event.Subdomain != "WWW.EXAMPLE.COM" {
+		t.Errorf("Expected the filtered name, got %+v", event)
+	}
+}
+
+func TestResultStreamNil(t *testing.T) {
+	// A nil stream must silently discard events
+	var stream *resultStream
+	stream.emitSubdomain("a.example.com", nil)
+
+	if newResultStream(nil, nil) != nil {
+		t.Errorf("Expected nil stream for nil writer")
+	}
+}
+
+func TestEnumerateRecursivelyStreams(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerator := &mockEnumerator{
+		results: map[string][]string{
+			"a.example.com": {"x.a.example.com", "y.a.example.com"},
+		},
+	}
+
+	recorder := &flushRecorder{}
+	known := map[string]map[string]struct{}{"a.example.com": {}, "y.a.example.com": {}}
+	enumerateRecursively(context.Background(), enumerator, []string{"a.example.com"}, known, 1,
+		time.Second, newResultStream(recorder, nil), logger)
+
+	// Only the subdomain that was not already known is streamed
+	lines := strings.Split(strings.TrimSpace(recorder.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "x.a.example.com") {
+		t.Errorf("Expected a single streamed x.a.example.com event, got %q", recorder.String())
+	}
+}
@@ -0,0 +1,187 @@
+package subfinder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"mcp-subfinder-server/internal/netguard"
+	"mcp-subfinder-server/internal/useragent"
+)
+
+const (
+	// MaxWordlistSize is the largest wordlist DownloadWordlist accepts, in bytes
+	MaxWordlistSize = 100 << 20
+	// wordlistDownloadTimeout bounds each wordlist download, body included
+	wordlistDownloadTimeout = 30 * time.Second
+	// MaxCachedWordlists is how many downloaded wordlists are kept at once
+	MaxCachedWordlists = 20
+	// MaxWordlistCacheSize bounds the total size of the cached wordlists, in bytes
+	MaxWordlistCacheSize = 500 << 20
+)
+
+var (
+	// ErrWordlistTooLarge is returned for a download larger than MaxWordlistSize
+	ErrWordlistTooLarge = errors.New("wordlist is larger than 100MB")
+	// ErrWordlistCacheUnsafe is returned when the cache directory is a symlink
+	// or belongs to another user
+	ErrWordlistCacheUnsafe = errors.New("wordlist cache directory is not owned by the server")
+)
+
+// WordlistCacheDir is where downloaded wordlists are kept, one file per URL.
+// It defaults to a directory under the user's cache directory, so other local
+// users cannot predict or pre-create it.
+var WordlistCacheDir = defaultWordlistCacheDir()
+
+// defaultWordlistCacheDir prefers the user cache directory and falls back to a
+// per-user directory under the temporary directory
+func defaultWordlistCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "mcp-subfinder-server", "wordlists")
+	}
+	return filepath.Join(os.TempDir(), "mcp-subfinder-wordlists-"+strconv.Itoa(os.Getuid()))
+}
+
+// wordlistClient downloads remote wordlists, only from public addresses
+var wordlistClient = &http.Client{
+	Timeout:   wordlistDownloadTimeout,
+	Transport: useragent.NewTransport(netguard.NewTransport(), useragent.Default),
+}
+
+// DownloadWordlist returns the path of the wordlist at rawURL in WordlistCacheDir,
+// fetching it first unless an earlier call already did. The server must answer
+// with Content-Type text/plain, and a body over MaxWordlistSize is rejected.
+// The least recently used wordlists are evicted to keep the cache within
+// MaxCachedWordlists and MaxWordlistCacheSize.
+func DownloadWordlist(ctx context.Context, rawURL string) (string, error) {
+	return downloadWordlist(ctx, wordlistClient, rawURL, WordlistCacheDir)
+}
+
+// downloadWordlist implements DownloadWordlist with a pluggable client and cache directory
+func downloadWordlist(ctx context.Context, client *http.Client, rawURL, cacheDir string) (string, error) {
+	if err := ensurePrivateDir(cacheDir); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	path := filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".txt")
+	if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+		// Touch the file so eviction keeps the wordlists that are in use
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return path, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download wordlist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wordlist download returned status %d", resp.StatusCode)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mediaType != "text/plain" {
+		return "", fmt.Errorf("wordlist must be served as text/plain, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	// Write to a temporary file first so a failed download never looks cached
+	tmp, err := os.CreateTemp(cacheDir, "download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create wordlist cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(resp.Body, MaxWordlistSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download wordlist: %w", err)
+	}
+	if written > MaxWordlistSize {
+		return "", ErrWordlistTooLarge
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to cache wordlist: %w", err)
+	}
+	evictWordlists(cacheDir, path)
+	return path, nil
+}
+
+// ensurePrivateDir creates dir with mode 0700 if needed and checks that it is a
+// real directory owned by the server's user, restricting its mode to 0700
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create wordlist cache: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create wordlist cache: %w", err)
+	}
+	if !info.IsDir() || !ownedByCurrentUser(info) {
+		return ErrWordlistCacheUnsafe
+	}
+	if info.Mode().Perm() != 0o700 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to restrict wordlist cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// evictWordlists removes the least recently used wordlists until the cache is
+// within its limits, always keeping keep
+func evictWordlists(cacheDir, keep string) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cached
+	var total int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".txt" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cached{filepath.Join(cacheDir, entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	count := len(files)
+	for _, file := range files {
+		if count <= MaxCachedWordlists && total <= MaxWordlistCacheSize {
+			break
+		}
+		if file.path == keep {
+			continue
+		}
+		if os.Remove(file.path) == nil {
+			count--
+			total -= file.size
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package subfinder
+
+import "os"
+
+// ownedByCurrentUser cannot check ownership outside unix, where the cache
+// relies on the user cache directory's own permissions
+func ownedByCurrentUser(os.FileInfo) bool {
+	return true
+}
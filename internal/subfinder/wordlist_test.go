@@ -0,0 +1,154 @@
+package subfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadWordlist(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/words.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(testWordlist))
+		case "/words.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	path, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", cacheDir)
+	if err != nil {
+		t.Fatalf("Expected the wordlist to download, got %v", err)
+	}
+	words, err := LoadWordlist(path)
+	if err != nil || strings.Join(words, ",") != "www,mail,api,dev" {
+		t.Errorf("Unexpected words %v (%v)", words, err)
+	}
+
+	// The second call is served from the cache
+	if again, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", cacheDir); err != nil || again != path || requests != 1 {
+		t.Errorf("Expected a cached path, got %q (%v) after %d requests", again, err, requests)
+	}
+
+	for _, name := range []string{"/words.html", "/missing.txt"} {
+		if _, err := downloadWordlist(context.Background(), server.Client(), server.URL+name, cacheDir); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 1 {
+		t.Errorf("Expected only the good wordlist to be cached, got %d files", len(entries))
+	}
+}
+
+func TestDownloadWordlistTooLarge(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		chunk := []byte(strings.Repeat("a\n", 1<<19))
+		for written := 0; written <= MaxWordlistSize; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	_, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/huge.txt", t.TempDir())
+	if !errors.Is(err, ErrWordlistTooLarge) {
+		t.Errorf("Expected ErrWordlistTooLarge, got %v", err)
+	}
+}
+
+func TestDownloadWordlistCacheDir(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(testWordlist))
+	}))
+	defer server.Close()
+
+	// A directory the server owns is restricted to 0700
+	shared := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(shared, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", shared); err != nil {
+		t.Fatalf("Expected the wordlist to download, got %v", err)
+	}
+	if info, err := os.Stat(shared); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("Expected the cache to be restricted to mode 0700, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	// One pre-created by another user is refused, when the test may chown
+	planted := filepath.Join(t.TempDir(), "planted")
+	if err := os.Mkdir(planted, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(planted, os.Getuid()+1, -1); err == nil {
+		if _, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", planted); !errors.Is(err, ErrWordlistCacheUnsafe) {
+			t.Errorf("Expected another user's cache to be refused, got %v", err)
+		}
+	}
+
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", link); !errors.Is(err, ErrWordlistCacheUnsafe) {
+		t.Errorf("Expected a symlinked cache to be refused, got %v", err)
+	}
+
+	created := filepath.Join(t.TempDir(), "wordlists")
+	if _, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", created); err != nil {
+		t.Fatalf("Expected the wordlist to download, got %v", err)
+	}
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("Expected the cache to be created with mode 0700, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestDownloadWordlistEviction(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(testWordlist))
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	// Fill the cache with older wordlists, the first one the least recently used
+	old := time.Now().Add(-time.Hour)
+	for i := 0; i < MaxCachedWordlists; i++ {
+		path := filepath.Join(cacheDir, fmt.Sprintf("old-%02d.txt", i))
+		if err := os.WriteFile(path, []byte("www\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old.Add(time.Duration(i)*time.Minute), old.Add(time.Duration(i)*time.Minute))
+	}
+
+	path, err := downloadWordlist(context.Background(), server.Client(), server.URL+"/words.txt", cacheDir)
+	if err != nil {
+		t.Fatalf("Expected the wordlist to download, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the new wordlist to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "old-00.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the least recently used wordlist to be evicted, got %v", err)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != MaxCachedWordlists {
+		t.Errorf("Expected %d cached wordlists, got %d", MaxCachedWordlists, len(entries))
+	}
+}
@@ -7,14 +7,36 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"mcp-subfinder-server/internal/sources"
+	"mcp-subfinder-server/internal/useragent"
+	"mcp-subfinder-server/internal/validation"
+
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/subfinder/v2/pkg/runner"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
+// maxRecursiveWorkers caps the number of subdomains enumerated concurrently during recursion
+const maxRecursiveWorkers = 3
+
+// maxSubdomainsPerLevel caps how many subdomains are recursively enumerated at each depth
+const maxSubdomainsPerLevel = 10
+
+// SeedSource is the source attributed to subdomains supplied in SubfinderConfig.SeedSubdomains
+const SeedSource = "custom-seed"
+
+// domainEnumerator is the part of the subfinder runner used to enumerate a single domain
+type domainEnumerator interface {
+	EnumerateSingleDomainWithCtx(ctx context.Context, domain string, writers []io.Writer) (map[string]map[string]struct{}, error)
+}
+
 type SubfinderConfig struct {
 	ProviderConfigPath    string
 	Timeout               int
@@ -22,16 +44,142 @@ type SubfinderConfig struct {
 	SourcesFilter         string
 	ExcludeSourcesFilter  string
 	Recursive             bool
+	// RecursionExcludePatterns are path.Match globs; matching subdomains are kept
+	// in the results but not enumerated recursively
+	RecursionExcludePatterns []string
+	MaxPerSource          int
+	UserAgent             string
+	CertTransparencyOnly  bool
+	// SilentMode and VerboseMode map to subfinder's Silent and Verbose options;
+	// raw subfinder output is only captured and logged in verbose mode
+	SilentMode            bool
+	VerboseMode           bool
+	// ResultWriter, when set, receives results as NDJSON StreamEvents while
+	// enumeration runs. Each subdomain is written once, as soon as it is found,
+	// with the source that reported it first; with MaxPerSource or
+	// NormalizeUnicode set they are written with all their sources at the end.
+	ResultWriter          io.Writer `json:"-"`
+	// StreamFilter, when set, maps each subdomain to the names written to
+	// ResultWriter in its place; returning none keeps it out of the stream
+	StreamFilter          func(subdomain string) []string `json:"-"`
+	// RawOutputWriter, when set, receives the result lines written for the last
+	// attempt, one "host,[sources]" line per subdomain. Gologger's progress and
+	// per-source messages go to stderr and are not included.
+	RawOutputWriter       io.Writer `json:"-"`
+	// SeedSubdomains are known subdomains merged into the results as SeedSource
+	SeedSubdomains        []string
+	// RetryConfig controls how failed or empty enumeration attempts are retried
+	RetryConfig           RetryConfig
+	// RateLimits caps the requests per second sent to each named source
+	RateLimits            map[string]int
+	// PrioritySources run on their own first, with a short timeout, before the
+	// remaining sources
+	PrioritySources       []string
+	// NormalizeUnicode is the IDN encoding results are normalized to before they
+	// are deduplicated; empty leaves names as the sources reported them
+	NormalizeUnicode string
+}
+
+// RetryConfig describes how many enumeration attempts are made and how long to wait
+// between them. Zero fields fall back to DefaultRetryConfig.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// BackoffMultiplier scales the delay after each failed attempt; 1 keeps it constant
+	BackoffMultiplier float64
+}
+
+// DefaultRetryConfig makes 3 attempts 2 seconds apart
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:       3,
+	BaseDelay:         2 * time.Second,
+	BackoffMultiplier: 1.0,
+}
+
+// withDefaults fills unset fields from DefaultRetryConfig
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryConfig.MaxAttempts
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = DefaultRetryConfig.BaseDelay
+	}
+	if r.BackoffMultiplier <= 0 {
+		r.BackoffMultiplier = DefaultRetryConfig.BackoffMultiplier
+	}
+	return r
+}
+
+// delay returns how long to wait after the given failed attempt, counting from 1
+func (r RetryConfig) delay(attempt int) time.Duration {
+	return time.Duration(float64(r.BaseDelay) * math.Pow(r.BackoffMultiplier, float64(attempt-1)))
+}
+
+// EnumerationResult holds the subdomains found for a domain and the passive
+// sources that reported each one
+type EnumerationResult struct {
+	Subdomains []string
+	// Sources maps each subdomain to its sorted source names; suggested
+	// fallback subdomains have no entry
+	Sources map[string][]string
+	// SourceStats reports how each source queried for the initial enumeration fared
+	SourceStats  []SourceStatistic
+	TotalSources int
+	TotalErrors  int
+	// Partial is set when prioritized sources returned results but the remaining
+	// sources failed or ran out of time
+	Partial bool
+}
+
+// SourceStatistic is the outcome of querying one passive source
+type SourceStatistic struct {
+	Source      string `json:"source"`
+	Results     int    `json:"results"`
+	Errors      int    `json:"errors"`
+	Skipped     bool   `json:"skipped"`
+	TimeTakenMs int64  `json:"timeTakenMs"`
 }
 
 func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig, logger *slog.Logger) ([]string, error) {
+	result, err := Enumerate(ctx, domain, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return result.Subdomains, nil
+}
+
+// InitRunner creates a subfinder runner with providerConfigPath the way Enumerate
+// does and discards it, to check that enumerations can start
+func InitRunner(providerConfigPath string) error {
+	_, err := runner.NewRunner(&runner.Options{
+		Silent:         true,
+		All:            true,
+		CaptureSources: true,
+		ProviderConfig: providerConfigPath,
+		Timeout:        30,
+		Threads:        40,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create subfinder runner: %w", err)
+	}
+	return nil
+}
+
+// Enumerate runs subdomain enumeration like RunEnumeration but keeps the source attribution
+func Enumerate(ctx context.Context, domain string, config SubfinderConfig, logger *slog.Logger) (*EnumerationResult, error) {
 	if config.Timeout <= 0 {
 		config.Timeout = 120
 	}
 
+	if len(config.PrioritySources) > 0 {
+		return enumeratePrioritized(ctx, domain, config, Enumerate, logger)
+	}
+
+	enumerationStart := time.Now()
+	stream := newResultStream(config.ResultWriter, config.StreamFilter)
+
 	runnerOpts := &runner.Options{
-		Silent:             false,
-		RemoveWildcard:     true,
+		Silent:             config.SilentMode,
 		Timeout:            config.Timeout,
 		MaxEnumerationTime: config.Timeout,
 		Threads:            40,
@@ -39,7 +187,7 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		CaptureSources:     true,
 		ProviderConfig:     config.ProviderConfigPath,
 		Resolvers:          nil,
-		Verbose:            true,
+		Verbose:            config.VerboseMode,
 	}
 
 	if config.SourcesFilter != "" {
@@ -51,6 +199,14 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		runnerOpts.All = false
 	}
 
+	if len(config.RateLimits) > 0 {
+		rateLimits, err := rateLimitMap(config.RateLimits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source rate limits: %w", err)
+		}
+		runnerOpts.RateLimits = rateLimits
+	}
+
 	if config.ExcludeSourcesFilter != "" {
 		excludeSources := goflags.StringSlice{}
 		for _, source := range strings.Split(config.ExcludeSourcesFilter, ",") {
@@ -59,28 +215,84 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		runnerOpts.ExcludeSources = excludeSources
 	}
 
+	// CT-only runs use certificate transparency sources exclusively
+	if config.CertTransparencyOnly {
+		ctSources := goflags.StringSlice{}
+		for _, source := range sources.CertTransparencySources() {
+			if len(runnerOpts.Sources) > 0 && !slices.Contains(runnerOpts.Sources, source) {
+				continue
+			}
+			if slices.Contains(runnerOpts.ExcludeSources, source) {
+				continue
+			}
+			ctSources = append(ctSources, source)
+		}
+		if len(ctSources) == 0 {
+			return nil, fmt.Errorf("sourcesFilter contains no certificate transparency sources")
+		}
+		runnerOpts.Sources = ctSources
+		runnerOpts.All = false
+		logger.Info("Restricting enumeration to certificate transparency sources",
+			"sources", strings.Join(ctSources, ","))
+	}
+
+	// subfinder exits the process rather than run without sources
+	if !runnerOpts.All && !slices.ContainsFunc(runnerOpts.Sources, func(source string) bool {
+		return sources.IsKnown(source) && !slices.Contains(runnerOpts.ExcludeSources, source)
+	}) {
+		return nil, fmt.Errorf("sourcesFilter selects no subfinder sources")
+	}
+
+	// subfinder's passive sources build their own HTTP transport and pick a random
+	// User-Agent per request, so a custom one can only apply to the server's own requests
+	if config.UserAgent != "" && config.UserAgent != useragent.Default {
+		logger.Warn("Custom user agent is not applied to subfinder passive sources",
+			"userAgent", config.UserAgent)
+	}
+
 	logger.Info("Initializing subfinder with options", 
 		"timeout", config.Timeout,
 		"recursive", config.Recursive,
 		"allSources", runnerOpts.All)
 
-	subfinderRunner, err := runner.NewRunner(runnerOpts)
-	if err != nil {
+	// The runner loads the provider config and validates the options; the
+	// enumeration itself runs the passive sources directly so results can be
+	// streamed as they arrive
+	if _, err := runner.NewRunner(runnerOpts); err != nil {
 		return nil, fmt.Errorf("failed to create subfinder runner: %w", err)
 	}
 
-	var cancel context.CancelFunc
-	if _, ok := ctx.Deadline(); !ok {
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
-		defer cancel()
+	// Names are streamed as soon as a source reports them, unless capping or
+	// normalization may still drop or rename them
+	streamed := make(map[string]struct{})
+	var onResult func(subdomain, source string)
+	if stream != nil && config.MaxPerSource <= 0 && (config.NormalizeUnicode == "" || config.NormalizeUnicode == IDNNone) {
+		onResult = func(subdomain, source string) {
+			if strings.EqualFold(subdomain, domain) {
+				return
+			}
+			if _, ok := streamed[subdomain]; ok {
+				return
+			}
+			streamed[subdomain] = struct{}{}
+			stream.emitSubdomain(subdomain, []string{source})
+		}
 	}
+	subfinderRunner := newPassiveEnumerator(runnerOpts, onResult, logger)
 
-	outputBuffer := &bytes.Buffer{}
+	// subfinder only uses the timeout per request and in whole minutes, so the
+	// context is what holds the enumeration to it
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+	defer cancel()
 
-	maxRetries := 3
+	retry := config.RetryConfig.withDefaults()
+	maxRetries := retry.MaxAttempts
 	var resultMap map[string]map[string]struct{}
 	var enumErr error
+	// Raw output is collected per attempt so a retry does not repeat lines
+	var rawOutput *bytes.Buffer
 	
+attempts:
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		logger.Info("Starting subdomain enumeration", 
 			"domain", domain, 
@@ -88,8 +300,18 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 			"recursive", config.Recursive)
 		
 		startTime := time.Now()
+		// Buffering subfinder's output costs memory on large enumerations, so only do it when verbose
+		outputBuffer := &bytes.Buffer{}
+		var writers []io.Writer
+		if config.VerboseMode {
+			writers = []io.Writer{outputBuffer}
+		}
+		if config.RawOutputWriter != nil {
+			rawOutput = &bytes.Buffer{}
+			writers = append(writers, rawOutput)
+		}
 		
-		resultMap, enumErr = subfinderRunner.EnumerateSingleDomainWithCtx(ctx, domain, []io.Writer{outputBuffer})
+		resultMap, enumErr = subfinderRunner.EnumerateSingleDomainWithCtx(ctx, domain, writers)
 		
 		elapsedTime := time.Since(startTime)
 		logger.Info("Enumeration attempt completed", 
@@ -115,66 +337,80 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 			if enumErr == nil {
 				enumErr = ctx.Err()
 			}
-			break
+			break attempts
 		default:
 			if attempt < maxRetries {
 				logger.Warn("Retry attempt failed, trying again", 
 					"attempt", attempt, 
 					"error", enumErr,
-					"resultsCount", len(resultMap))
-				time.Sleep(2 * time.Second)
+					"resultsCount", len(resultMap),
+					"delay", retry.delay(attempt))
+				select {
+				case <-time.After(retry.delay(attempt)):
+				case <-ctx.Done():
+				}
 			}
 		}
 	}
 
+	if rawOutput != nil {
+		config.RawOutputWriter.Write(rawOutput.Bytes())
+	}
 	if enumErr != nil {
 		return nil, fmt.Errorf("enumeration error after %d attempts: %w", maxRetries, enumErr)
 	}
 
+	if config.NormalizeUnicode != "" && config.NormalizeUnicode != IDNNone {
+		before := len(resultMap)
+		resultMap = NormalizeIDN(resultMap, config.NormalizeUnicode)
+		if len(resultMap) != before {
+			logger.Info("Merged internationalized subdomains",
+				"normalizeUnicode", config.NormalizeUnicode,
+				"before", before,
+				"after", len(resultMap))
+		}
+	}
+
+	if config.MaxPerSource > 0 {
+		before := len(resultMap)
+		resultMap = CapPerSource(resultMap, config.MaxPerSource)
+		logger.Info("Capped exclusive results per source",
+			"maxPerSource", config.MaxPerSource,
+			"before", before,
+			"after", len(resultMap))
+	}
+
+	if len(config.SeedSubdomains) > 0 {
+		resultMap = mergeSeeds(resultMap, domain, config.SeedSubdomains)
+		logger.Info("Merged seed subdomains", "seeds", len(config.SeedSubdomains), "resultsCount", len(resultMap))
+	}
+
 	var subdomains []string
-	for subdomain := range resultMap {
+	found := make(map[string]map[string]struct{}, len(resultMap))
+	for subdomain, sources := range resultMap {
 		if strings.EqualFold(subdomain, domain) {
 			continue
 		}
 		subdomains = append(subdomains, subdomain)
+		found[subdomain] = sources
 	}
 
 	sort.Strings(subdomains)
 
 	for _, subdomain := range subdomains {
-		sources := resultMap[subdomain]
-		var sourceNames []string
-		for source := range sources {
-			sourceNames = append(sourceNames, source)
-		}
-		sort.Strings(sourceNames)
+		sourceNames := sortedSourceNames(found[subdomain])
 		logger.Debug("Subdomain sources", 
 			"subdomain", subdomain, 
 			"sources", strings.Join(sourceNames, ","))
+		// Seeds and anything held back above have not been streamed yet
+		if _, ok := streamed[subdomain]; !ok {
+			stream.emitSubdomain(subdomain, sourceNames)
+		}
 	}
 
 	if config.Recursive && len(subdomains) > 0 && config.MaxDepth > 1 {
 		logger.Info("Starting recursive enumeration", "foundSubdomains", len(subdomains))
 		
-		maxSubdomainsToProcess := 10
-		if len(subdomains) > maxSubdomainsToProcess {
-			logger.Info("Limiting recursive processing", 
-				"total", len(subdomains), 
-				"processing", maxSubdomainsToProcess)
-			subdomainsToProcess := subdomains[:maxSubdomainsToProcess]
-			subdomains = append(subdomainsToProcess, subdomains[maxSubdomainsToProcess:]...)
-		}
-		
-		allSubdomains := make(map[string]struct{})
-		for _, subdomain := range subdomains {
-			allSubdomains[subdomain] = struct{}{}
-		}
-		
-		maxDepth := config.MaxDepth
-		if maxDepth <= 0 {
-			maxDepth = 2
-		}
-		
 		recursiveTimeout := config.Timeout / 2
 		if recursiveTimeout < 30 {
 			recursiveTimeout = 30
@@ -188,44 +424,13 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		if err != nil {
 			logger.Warn("Failed to create recursive runner", "error", err)
 		} else {
-			for i, subdomain := range subdomains {
-				if i >= maxSubdomainsToProcess {
-					break
-				}
-				
-				logger.Info("Recursively checking", "subdomain", subdomain)
-				
-				recursiveCtx, cancel := context.WithTimeout(context.Background(), 
-					time.Duration(recursiveTimeout)*time.Second)
-				
-				recursiveBuffer := &bytes.Buffer{}
-				
-				recResultMap, recErr := recursiveRunner.EnumerateSingleDomainWithCtx(
-					recursiveCtx, subdomain, []io.Writer{recursiveBuffer})
-				
-				cancel()
-				
-				if recErr != nil {
-					logger.Warn("Error in recursive enumeration", 
-						"subdomain", subdomain, "error", recErr)
-					continue
-				}
-				
-				for recSubdomain := range recResultMap {
-					if strings.EqualFold(recSubdomain, subdomain) {
-						continue
-					}
-					if _, exists := allSubdomains[recSubdomain]; exists {
-						continue
-					}
-					allSubdomains[recSubdomain] = struct{}{}
-					logger.Info("Found recursive subdomain", "subdomain", recSubdomain)
-				}
-			}
+			workers := min(maxSubdomainsPerLevel, maxRecursiveWorkers)
+			enumerateBreadthFirst(ctx, recursiveRunner, subdomains, found, config.MaxDepth, workers,
+				config.RecursionExcludePatterns, time.Duration(recursiveTimeout)*time.Second, stream, logger)
 		}
 		
-		subdomains = make([]string, 0, len(allSubdomains))
-		for subdomain := range allSubdomains {
+		subdomains = make([]string, 0, len(found))
+		for subdomain := range found {
 			subdomains = append(subdomains, subdomain)
 		}
 		sort.Strings(subdomains)
@@ -241,6 +446,7 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		for _, prefix := range commonPrefixes {
 			commonSubdomain := prefix + "." + domain
 			subdomains = append(subdomains, commonSubdomain)
+			stream.emitSubdomain(commonSubdomain, nil)
 		}
 	}
 
@@ -248,16 +454,18 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		"domain", domain, 
 		"subdomainsFound", len(subdomains))
 	
-	stats := subfinderRunner.GetStatistics()
-	if stats != nil {
+	sourceStats, totalErrors := summarizeStatistics(subfinderRunner.GetStatistics())
+	if sourceStats != nil {
 		logger.Info("Enumeration statistics", 
-			"totalSources", len(stats))
+			"totalSources", len(sourceStats),
+			"totalErrors", totalErrors)
+		logger.Debug("Per-source statistics", "sources", sourceStats)
 		
 		var successfulSources []string
-		for source, stat := range stats {
+		for _, stat := range sourceStats {
 			if stat.Results > 0 {
 				successfulSources = append(successfulSources, 
-					fmt.Sprintf("%s:%d", source, stat.Results))
+					fmt.Sprintf("%s:%d", stat.Source, stat.Results))
 			}
 		}
 		
@@ -267,5 +475,213 @@ func RunEnumeration(ctx context.Context, domain string, config SubfinderConfig,
 		}
 	}
 
-	return subdomains, nil
+	stream.emit(StreamEvent{
+		Type:            StreamEventStats,
+		Domain:          domain,
+		SubdomainsFound: len(subdomains),
+		DurationMs:      time.Since(enumerationStart).Milliseconds(),
+	})
+
+	result := &EnumerationResult{
+		Subdomains:   subdomains,
+		Sources:      make(map[string][]string, len(found)),
+		SourceStats:  sourceStats,
+		TotalSources: len(sourceStats),
+		TotalErrors:  totalErrors,
+	}
+	for subdomain, sources := range found {
+		result.Sources[subdomain] = sortedSourceNames(sources)
+	}
+
+	return result, nil
+}
+
+// mergeSeeds adds every seed under domain to resultMap attributed to SeedSource,
+// alongside any sources that also reported it. Seeds outside domain are skipped.
+func mergeSeeds(resultMap map[string]map[string]struct{}, domain string, seeds []string) map[string]map[string]struct{} {
+	if resultMap == nil {
+		resultMap = make(map[string]map[string]struct{}, len(seeds))
+	}
+	suffix := "." + strings.ToLower(domain)
+	for _, seed := range seeds {
+		seed = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(seed), "."))
+		if !strings.HasSuffix(seed, suffix) {
+			continue
+		}
+		if resultMap[seed] == nil {
+			resultMap[seed] = make(map[string]struct{}, 1)
+		}
+		resultMap[seed][SeedSource] = struct{}{}
+	}
+	return resultMap
+}
+
+// summarizeStatistics converts subfinder's per-source statistics into a list
+// sorted by source name, along with the total error count across sources
+func summarizeStatistics(stats map[string]subscraping.Statistics) ([]SourceStatistic, int) {
+	if stats == nil {
+		return nil, 0
+	}
+
+	summary := make([]SourceStatistic, 0, len(stats))
+	totalErrors := 0
+	for source, stat := range stats {
+		summary = append(summary, SourceStatistic{
+			Source:      source,
+			Results:     stat.Results,
+			Errors:      stat.Errors,
+			Skipped:     stat.Skipped,
+			TimeTakenMs: stat.TimeTaken.Milliseconds(),
+		})
+		totalErrors += stat.Errors
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Source < summary[j].Source
+	})
+
+	return summary, totalErrors
+}
+
+// sortedSourceNames returns the names in a source set in alphabetical order
+func sortedSourceNames(sources map[string]struct{}) []string {
+	names := make([]string, 0, len(sources))
+	for source := range sources {
+		names = append(names, source)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recursionTarget is a subdomain queued for recursive enumeration at a given depth
+type recursionTarget struct {
+	subdomain string
+	depth     int
+}
+
+// enumerateBreadthFirst recursively enumerates subdomains level by level. The
+// initial subdomains are depth 1; anything found while enumerating depth d is
+// queued at depth d+1, and targets at maxDepth are not enumerated further.
+// At most maxSubdomainsPerLevel targets are enumerated per depth, and subdomains
+// matching an exclude pattern are never queued.
+func enumerateBreadthFirst(ctx context.Context, enumerator domainEnumerator, initial []string,
+	known map[string]map[string]struct{}, maxDepth, workers int, exclude []string, timeout time.Duration, stream *resultStream, logger *slog.Logger) {
+	queue := make([]recursionTarget, 0, len(initial))
+	enqueue := func(subdomain string, depth int) {
+		if validation.MatchAnyGlob(subdomain, exclude) {
+			logger.Debug("Skipping recursion for excluded subdomain", "subdomain", subdomain)
+			return
+		}
+		queue = append(queue, recursionTarget{subdomain: subdomain, depth: depth})
+	}
+	for _, subdomain := range initial {
+		enqueue(subdomain, 1)
+	}
+
+	for len(queue) > 0 && ctx.Err() == nil {
+		// Pop every target at the front depth so the level runs concurrently
+		depth := queue[0].depth
+		if depth >= maxDepth {
+			break
+		}
+
+		var level []string
+		for len(queue) > 0 && queue[0].depth == depth {
+			level = append(level, queue[0].subdomain)
+			queue = queue[1:]
+		}
+
+		if len(level) > maxSubdomainsPerLevel {
+			logger.Info("Limiting recursive processing",
+				"depth", depth,
+				"total", len(level),
+				"processing", maxSubdomainsPerLevel)
+			level = level[:maxSubdomainsPerLevel]
+		}
+
+		logger.Info("Recursive enumeration level", "depth", depth, "targets", len(level))
+		for _, found := range enumerateRecursively(ctx, enumerator, level, known, workers, timeout, stream, logger) {
+			enqueue(found, depth+1)
+		}
+	}
+}
+
+// enumerateRecursively enumerates each target with a pool of workers and merges
+// newly discovered subdomains and their sources into known, returning them in
+// sorted order.
+// Cancelling ctx stops all workers.
+func enumerateRecursively(ctx context.Context, enumerator domainEnumerator, targets []string,
+	known map[string]map[string]struct{}, workers int, timeout time.Duration, stream *resultStream, logger *slog.Logger) []string {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var discovered []string
+	queue := make(chan string)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for subdomain := range queue {
+				logger.Info("Recursively checking", "subdomain", subdomain)
+
+				recursiveCtx, cancel := context.WithTimeout(ctx, timeout)
+
+				// Results come back in the map, so subfinder's text output is not captured
+				recResultMap, recErr := enumerator.EnumerateSingleDomainWithCtx(
+					recursiveCtx, subdomain, nil)
+
+				cancel()
+
+				if recErr != nil {
+					logger.Warn("Error in recursive enumeration",
+						"subdomain", subdomain, "error", recErr)
+					continue
+				}
+
+				mu.Lock()
+				for recSubdomain, recSources := range recResultMap {
+					if strings.EqualFold(recSubdomain, subdomain) {
+						continue
+					}
+					if _, exists := known[recSubdomain]; exists {
+						continue
+					}
+					known[recSubdomain] = recSources
+					discovered = append(discovered, recSubdomain)
+					logger.Info("Found recursive subdomain", "subdomain", recSubdomain)
+					stream.emitSubdomain(recSubdomain, sortedSourceNames(recSources))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, subdomain := range targets {
+		select {
+		case <-ctx.Done():
+			logger.Warn("Context cancelled, stopping recursive enumeration")
+			break feed
+		case queue <- subdomain:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Strings(discovered)
+	return discovered
+}
+
+// rateLimitMap converts requests per second by source into subfinder's rate limit map
+func rateLimitMap(limits map[string]int) (goflags.RateLimitMap, error) {
+	var rateLimits goflags.RateLimitMap
+	for source, perSecond := range limits {
+		if err := rateLimits.Set(fmt.Sprintf("%s=%d/s", source, perSecond)); err != nil {
+			return goflags.RateLimitMap{}, err
+		}
+	}
+	return rateLimits, nil
 }
@@ -2,12 +2,38 @@ package subfinder
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
+// mockEnumerator returns canned results for each domain after a fixed delay
+type mockEnumerator struct {
+	delay   time.Duration
+	results map[string][]string
+}
+
+func (m *mockEnumerator) EnumerateSingleDomainWithCtx(ctx context.Context, domain string, _ []io.Writer) (map[string]map[string]struct{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(m.delay):
+	}
+
+	resultMap := make(map[string]map[string]struct{})
+	for _, subdomain := range m.results[domain] {
+		resultMap[subdomain] = map[string]struct{}{"mock": {}}
+	}
+	return resultMap, nil
+}
+
 func TestRunEnumeration(t *testing.T) {
 	// Skip this test by default since it makes actual external API calls
 	// Set ENABLE_LIVE_TESTS=1 to run these tests
@@ -86,3 +112,261 @@ func TestConfigDefaults(t *testing.T) {
 	// which means the default values were properly applied
 	t.Log("Default configuration values were applied correctly")
 }
+
+func TestEnumerateRecursively(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	enumerator := &mockEnumerator{
+		results: map[string][]string{
+			"a.example.com": {"a.example.com", "x.a.example.com"},
+			"b.example.com": {"y.b.example.com", "z.b.example.com"},
+			"c.example.com": {"x.a.example.com"},
+		},
+	}
+
+	known := map[string]map[string]struct{}{
+		"a.example.com": {},
+		"b.example.com": {},
+		"c.example.com": {},
+	}
+
+	targets := []string{"a.example.com", "b.example.com", "c.example.com"}
+	enumerateRecursively(context.Background(), enumerator, targets, known, 3, time.Second, nil, logger)
+
+	var got []string
+	for subdomain := range known {
+		got = append(got, subdomain)
+	}
+	sort.Strings(got)
+
+	expected := []string{"a.example.com", "b.example.com", "c.example.com", "x.a.example.com", "y.b.example.com", "z.b.example.com"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+			break
+		}
+	}
+}
+
+func TestEnumerateBreadthFirstDepth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Each level reveals exactly one deeper subdomain
+	enumerator := &mockEnumerator{
+		results: map[string][]string{
+			"a.example.com":       {"x.a.example.com"},
+			"x.a.example.com":     {"y.x.a.example.com"},
+			"y.x.a.example.com":   {"z.y.x.a.example.com"},
+			"z.y.x.a.example.com": {"w.z.y.x.a.example.com"},
+		},
+	}
+
+	tests := []struct {
+		maxDepth int
+		expected []string
+	}{
+		{maxDepth: 1, expected: []string{"a.example.com"}},
+		{maxDepth: 2, expected: []string{"a.example.com", "x.a.example.com"}},
+		{maxDepth: 3, expected: []string{"a.example.com", "x.a.example.com", "y.x.a.example.com"}},
+	}
+
+	for _, tc := range tests {
+		known := map[string]map[string]struct{}{"a.example.com": {}}
+		enumerateBreadthFirst(context.Background(), enumerator, []string{"a.example.com"}, known,
+			tc.maxDepth, 3, nil, time.Second, nil, logger)
+
+		var got []string
+		for subdomain := range known {
+			got = append(got, subdomain)
+		}
+		sort.Strings(got)
+
+		if len(got) != len(tc.expected) {
+			t.Errorf("maxDepth %d: expected %v, got %v", tc.maxDepth, tc.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("maxDepth %d: expected %v, got %v", tc.maxDepth, tc.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestEnumerateBreadthFirstExclude(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerator := &mockEnumerator{
+		results: map[string][]string{
+			"a.example.com":     {"d1.cloudfront.net", "x.a.example.com"},
+			"d1.cloudfront.net": {"leak.d1.cloudfront.net"},
+			"x.a.example.com":   {"y.x.a.example.com"},
+			"cdn.example.com":   {"deep.cdn.example.com"},
+		},
+	}
+
+	known := map[string]map[string]struct{}{"a.example.com": {}, "cdn.example.com": {}}
+	enumerateBreadthFirst(context.Background(), enumerator, []string{"a.example.com", "cdn.example.com"}, known,
+		3, 3, []string{"*.cloudfront.net", "cdn.example.com"}, time.Second, nil, logger)
+
+	var got []string
+	for subdomain := range known {
+		got = append(got, subdomain)
+	}
+	sort.Strings(got)
+
+	// Excluded names are still reported but never enumerated themselves
+	expected := []string{"a.example.com", "cdn.example.com", "d1.cloudfront.net", "x.a.example.com", "y.x.a.example.com"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestEnumerateRecursivelyCancelled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerator := &mockEnumerator{delay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	targets := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}
+
+	start := time.Now()
+	enumerateRecursively(ctx, enumerator, targets, map[string]map[string]struct{}{}, 2, time.Minute, nil, logger)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected workers to stop promptly after cancellation, took %v", elapsed)
+	}
+}
+
+func benchmarkEnumerateRecursively(b *testing.B, workers int) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enumerator := &mockEnumerator{delay: 10 * time.Millisecond}
+
+	targets := make([]string, 10)
+	for i := range targets {
+		targets[i] = string(rune('a'+i)) + ".example.com"
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumerateRecursively(context.Background(), enumerator, targets, map[string]map[string]struct{}{}, workers, time.Second, nil, logger)
+	}
+}
+
+// BenchmarkEnumerateRecursivelySequential measures the old one-at-a-time behaviour
+func BenchmarkEnumerateRecursivelySequential(b *testing.B) {
+	benchmarkEnumerateRecursively(b, 1)
+}
+
+// BenchmarkEnumerateRecursivelyPooled measures the default worker pool size
+func BenchmarkEnumerateRecursivelyPooled(b *testing.B) {
+	benchmarkEnumerateRecursively(b, maxRecursiveWorkers)
+}
+
+func TestSummarizeStatistics(t *testing.T) {
+	stats := map[string]subscraping.Statistics{
+		"crtsh":          {Results: 12, TimeTaken: 1500 * time.Millisecond},
+		"alienvault":     {Results: 3, Errors: 2},
+		"securitytrails": {Errors: 1, Skipped: true},
+	}
+
+	summary, totalErrors := summarizeStatistics(stats)
+
+	if totalErrors != 3 {
+		t.Errorf("Expected 3 total errors, got %d", totalErrors)
+	}
+	if len(summary) != 3 {
+		t.Fatalf("Expected 3 sources, got %d", len(summary))
+	}
+
+	expectedOrder := []string{"alienvault", "crtsh", "securitytrails"}
+	for i, source := range expectedOrder {
+		if summary[i].Source != source {
+			t.Errorf("Expected source %d to be %s, got %s", i, source, summary[i].Source)
+		}
+	}
+	if summary[1].Results != 12 || summary[1].TimeTakenMs != 1500 {
+		t.Errorf("Unexpected crtsh statistics: %+v", summary[1])
+	}
+	if !summary[2].Skipped {
+		t.Errorf("Expected securitytrails to be skipped: %+v", summary[2])
+	}
+
+	if summary, totalErrors := summarizeStatistics(nil); summary != nil || totalErrors != 0 {
+		t.Errorf("Expected no statistics for nil input, got %v, %d", summary, totalErrors)
+	}
+}
+
+func TestRetryConfigDelay(t *testing.T) {
+	retry := RetryConfig{BackoffMultiplier: 2}.withDefaults()
+	if retry.MaxAttempts != 3 || retry.BaseDelay != 2*time.Second {
+		t.Errorf("Expected unset fields to take their defaults, got %+v", retry)
+	}
+
+	for attempt, expected := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 3: 8 * time.Second} {
+		if got := retry.delay(attempt); got != expected {
+			t.Errorf("delay(%d): expected %v, got %v", attempt, expected, got)
+		}
+	}
+
+	if got := DefaultRetryConfig.delay(3); got != 2*time.Second {
+		t.Errorf("Expected the default strategy to keep a constant delay, got %v", got)
+	}
+}
+
+func TestMergeSeeds(t *testing.T) {
+	resultMap := map[string]map[string]struct{}{
+		"www.example.com": {"crtsh": {}},
+	}
+	merged := mergeSeeds(resultMap, "example.com", []string{"WWW.example.com", " vpn.example.com. ", "other.org", "example.com"})
+
+	expected := map[string]map[string]struct{}{
+		"www.example.com": {"crtsh": {}, SeedSource: {}},
+		"vpn.example.com": {SeedSource: {}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+
+	if got := mergeSeeds(nil, "example.com", []string{"api.example.com"}); len(got) != 1 {
+		t.Errorf("Expected seeds to be merged into an empty result, got %v", got)
+	}
+}
+
+func TestRateLimitMap(t *testing.T) {
+	rateLimits, err := rateLimitMap(map[string]int{"shodan": 1, "securitytrails": 2})
+	if err != nil {
+		t.Fatalf("rateLimitMap failed: %v", err)
+	}
+
+	got := rateLimits.AsMap()
+	if len(got) != 2 || got["shodan"].MaxCount != 1 || got["securitytrails"].MaxCount != 2 || got["shodan"].Duration != time.Second {
+		t.Errorf("Unexpected rate limits %v", got)
+	}
+}
+
+func TestEnumerateNoSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name     string
+		config   SubfinderConfig
+		expected string
+	}{
+		{"every CT source excluded", SubfinderConfig{CertTransparencyOnly: true, ExcludeSourcesFilter: "censys,certspotter,crtsh,digitorus,facebook"}, "no certificate transparency sources"},
+		{"no CT source selected", SubfinderConfig{CertTransparencyOnly: true, SourcesFilter: "hackertarget"}, "no certificate transparency sources"},
+		{"unknown sources", SubfinderConfig{SourcesFilter: "bogus"}, "no subfinder sources"},
+		{"every selected source excluded", SubfinderConfig{SourcesFilter: "crtsh", ExcludeSourcesFilter: "crtsh"}, "no subfinder sources"},
+	}
+
+	// These fail before any source is queried, instead of subfinder exiting
+	for _, tc := range tests {
+		tc.config.Timeout = 10
+		if _, err := Enumerate(context.Background(), "example.com", tc.config, logger); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error about %s, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
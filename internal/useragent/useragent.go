@@ -0,0 +1,34 @@
+// Package useragent provides the User-Agent used for outbound HTTP requests made by the server
+package useragent
+
+import (
+	"net/http"
+)
+
+// Default is the User-Agent sent when a caller does not override it
+const Default = "mcp-subfinder/1.0.0"
+
+// Transport sets a fixed User-Agent on every request before delegating to Base
+type Transport struct {
+	Base      http.RoundTripper
+	UserAgent string
+}
+
+// NewTransport wraps base (http.DefaultTransport when nil) so every request carries userAgent
+func NewTransport(base http.RoundTripper, userAgent string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if userAgent == "" {
+		userAgent = Default
+	}
+	return &Transport{Base: base, UserAgent: userAgent}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	clone := req.Clone(req.Context())
+	clone.Header.Set("User-Agent", t.UserAgent)
+	return t.Base.RoundTrip(clone)
+}
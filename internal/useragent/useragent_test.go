@@ -0,0 +1,49 @@
+package useragent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{name: "Default", userAgent: "", expected: Default},
+		{name: "Custom", userAgent: "posture-test/2.0", expected: "posture-test/2.0"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: NewTransport(nil, tc.userAgent)}
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("User-Agent", "original")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if received != tc.expected {
+				t.Errorf("Expected User-Agent %q, got %q", tc.expected, received)
+			}
+			if req.Header.Get("User-Agent") != "original" {
+				t.Errorf("Transport modified the caller's request")
+			}
+		})
+	}
+}
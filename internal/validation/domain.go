@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits on host names from RFC 1035
+const (
+	maxDomainLength = 253
+	maxLabelLength  = 63
+)
+
+// ValidateDomain checks that domain is a registrable-looking host name: at least
+// two dot-separated labels of letters, digits and inner hyphens, within the DNS
+// length limits. IDNs must be given in their Punycode form.
+func ValidateDomain(domain string) error {
+	if domain == "" {
+		return fmt.Errorf("domain is empty")
+	}
+	if len(domain) > maxDomainLength {
+		return fmt.Errorf("domain %q is longer than %d characters", domain, maxDomainLength)
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("domain %q needs at least two labels", domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength {
+			return fmt.Errorf("domain %q has a label that is empty or longer than %d characters", domain, maxLabelLength)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("domain %q has a label starting or ending with a hyphen", domain)
+		}
+		for _, c := range label {
+			if !isLDH(c) {
+				return fmt.Errorf("domain %q contains %q, only letters, digits and hyphens are allowed", domain, c)
+			}
+		}
+	}
+	return nil
+}
+
+// isLDH reports whether c is a letter, digit or hyphen
+func isLDH(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-'
+}
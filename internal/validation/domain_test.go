@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDomain(t *testing.T) {
+	valid := []string{"example.com", "sub.example.co.uk", "xn--wgv71a119e.jp", "a-b.example.com", "EXAMPLE.com"}
+	for _, domain := range valid {
+		if err := ValidateDomain(domain); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", domain, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"localhost",
+		"example..com",
+		".example.com",
+		"-example.com",
+		"example-.com",
+		"exa_mple.com",
+		"https://example.com",
+		"日本語.jp",
+		strings.Repeat("a", 64) + ".com",
+		strings.Repeat("a.", 127) + "com",
+	}
+	for _, domain := range invalid {
+		if err := ValidateDomain(domain); err == nil {
+			t.Errorf("Expected %q to be invalid", domain)
+		}
+	}
+}
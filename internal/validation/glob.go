@@ -0,0 +1,31 @@
+// Package validation provides matching helpers for caller-supplied tool parameters
+package validation
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidateGlobs checks that every pattern is a valid path.Match pattern
+func ValidateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchAnyGlob reports whether name matches any of the patterns, ignoring case.
+// Patterns use path.Match semantics; since host names contain no "/", "*" also
+// spans dots, so "*.cloudfront.net" matches names at any depth under it.
+func MatchAnyGlob(name string, patterns []string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
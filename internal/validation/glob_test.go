@@ -0,0 +1,36 @@
+package validation
+
+import "testing"
+
+func TestMatchAnyGlob(t *testing.T) {
+	patterns := []string{"*.cloudfront.net", "cdn-*.example.com"}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "d111111abcdef8.cloudfront.net", expected: true},
+		{name: "a.b.CloudFront.net.", expected: true},
+		{name: "cloudfront.net", expected: false},
+		{name: "cdn-eu.example.com", expected: true},
+		{name: "www.example.com", expected: false},
+	}
+
+	for _, tc := range tests {
+		if got := MatchAnyGlob(tc.name, patterns); got != tc.expected {
+			t.Errorf("MatchAnyGlob(%q): expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	if MatchAnyGlob("www.example.com", nil) {
+		t.Error("Expected no match without patterns")
+	}
+}
+
+func TestValidateGlobs(t *testing.T) {
+	if err := ValidateGlobs([]string{"*.cloudfront.net", "cdn-?.example.com"}); err != nil {
+		t.Errorf("Expected valid patterns, got %v", err)
+	}
+	if err := ValidateGlobs([]string{"*.example.com", "[cdn.example.com"}); err == nil {
+		t.Error("Expected an error for an unterminated character class")
+	}
+}
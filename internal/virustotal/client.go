@@ -0,0 +1,162 @@
+// Package virustotal looks up the reputation of discovered subdomains in the VirusTotal API
+package virustotal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"mcp-subfinder-server/internal/probe"
+	"mcp-subfinder-server/internal/ratelimit"
+)
+
+const (
+	// APIKeyEnv is the environment variable read when no API key is passed explicitly
+	APIKeyEnv = "VIRUSTOTAL_API_KEY"
+	// DefaultBaseURL is the VirusTotal v3 REST API endpoint
+	DefaultBaseURL = "https://www.virustotal.com/api/v3"
+	// DefaultInterval is the minimum time between requests, per the free API's 4 requests/minute limit
+	DefaultInterval = 15 * time.Second
+	// requestTimeout bounds each domain lookup
+	requestTimeout = 10 * time.Second
+)
+
+var (
+	// ErrUnauthorized is returned when VirusTotal rejects the API key
+	ErrUnauthorized = errors.New("virustotal rejected the API key")
+	// ErrQuotaExceeded is returned once the API key's request quota is used up
+	ErrQuotaExceeded = errors.New("virustotal API quota exceeded")
+)
+
+// Report is the part of a VirusTotal domain report attached to subdomain results
+type Report struct {
+	// MaliciousVotes and SuspiciousVotes count the engines that flagged the
+	// domain in its last analysis
+	MaliciousVotes  int `json:"maliciousVotes"`
+	SuspiciousVotes int `json:"suspiciousVotes"`
+	// Categories are the distinct categories assigned by the categorization services
+	Categories       []string   `json:"categories,omitempty"`
+	LastAnalysisDate *time.Time `json:"lastAnalysisDate,omitempty"`
+}
+
+// Client queries the VirusTotal domain API, spacing requests at least interval apart.
+// Clients with the same API key share that spacing.
+type Client struct {
+	apiKey   string
+	baseURL  string
+	interval time.Duration
+	pacer    *ratelimit.Pacer
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// NewClient creates a Client authenticating with apiKey whose requests carry userAgent,
+// with each connection allowed networkTimeout to be established
+func NewClient(apiKey, userAgent string, networkTimeout time.Duration, logger *slog.Logger) *Client {
+	return &Client{
+		apiKey:   apiKey,
+		baseURL:  DefaultBaseURL,
+		interval: DefaultInterval,
+		pacer:    ratelimit.ForKey("virustotal", apiKey),
+		client:   probe.NewHTTPClient(requestTimeout, networkTimeout, userAgent),
+		logger:   logger,
+	}
+}
+
+// Domains looks up every domain one at a time, waiting the client's interval between
+// requests made with its API key, and returns the reports found keyed by domain.
+// Domains VirusTotal has no report on are left out. Lookups stop early if the context
+// ends, the key is rejected or its quota runs out.
+func (c *Client) Domains(ctx context.Context, domains []string) map[string]*Report {
+	reports := make(map[string]*Report, len(domains))
+	for _, domain := range domains {
+		release, err := c.pacer.Acquire(ctx, c.interval)
+		if err != nil {
+			return reports
+		}
+
+		report, err := c.Domain(ctx, domain)
+		release()
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrQuotaExceeded) {
+			c.logger.Warn("Stopping VirusTotal enrichment", "error", err)
+			return reports
+		}
+		if err != nil {
+			c.logger.Debug("VirusTotal lookup failed", "domain", domain, "error", err)
+			continue
+		}
+		if report != nil {
+			reports[domain] = report
+		}
+	}
+	return reports
+}
+
+// Domain fetches the VirusTotal report for one domain. It returns nil without an
+// error when VirusTotal has no report on the domain.
+func (c *Client) Domain(ctx context.Context, domain string) (*Report, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/domains/"+url.PathEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-apikey", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return nil, ErrQuotaExceeded
+	default:
+		return nil, fmt.Errorf("virustotal returned status %d", resp.StatusCode)
+	}
+
+	var record struct {
+		Data struct {
+			Attributes struct {
+				LastAnalysisStats struct {
+					Malicious  int `json:"malicious"`
+					Suspicious int `json:"suspicious"`
+				} `json:"last_analysis_stats"`
+				Categories       map[string]string `json:"categories"`
+				LastAnalysisDate int64             `json:"last_analysis_date"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode virustotal response: %w", err)
+	}
+	attributes := record.Data.Attributes
+
+	report := &Report{
+		MaliciousVotes:  attributes.LastAnalysisStats.Malicious,
+		SuspiciousVotes: attributes.LastAnalysisStats.Suspicious,
+	}
+	seen := make(map[string]struct{}, len(attributes.Categories))
+	for _, category := range attributes.Categories {
+		if _, ok := seen[category]; !ok {
+			seen[category] = struct{}{}
+			report.Categories = append(report.Categories, category)
+		}
+	}
+	sort.Strings(report.Categories)
+	if attributes.LastAnalysisDate > 0 {
+		analyzed := time.Unix(attributes.LastAnalysisDate, 0).UTC()
+		report.LastAnalysisDate = &analyzed
+	}
+	return report, nil
+}
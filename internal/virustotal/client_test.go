@@ -0,0 +1,79 @@
+package virustotal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientDomains(t *testing.T) {
+	var mu sync.Mutex
+	var requested []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, time.Now())
+		mu.Unlock()
+
+		if r.Header.Get("x-apikey") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/domains/www.example.com":
+			w.Write([]byte(`{"data": {"attributes": {
+				"last_analysis_stats": {"malicious": 2, "suspicious": 1, "harmless": 60},
+				"categories": {"Forcepoint ThreatSeeker": "phishing", "Sophos": "phishing", "BitDefender": "business"},
+				"last_analysis_date": 1714564800}}}`))
+		case "/domains/new.example.com":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "NotFoundError"}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := NewClient("test-key", "test-agent", time.Second, logger)
+	client.baseURL = server.URL
+	client.interval = 50 * time.Millisecond
+
+	reports := client.Domains(context.Background(), []string{"www.example.com", "new.example.com", "api.example.com"})
+	analyzed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expected := map[string]*Report{
+		"www.example.com": {MaliciousVotes: 2, SuspiciousVotes: 1, Categories: []string{"business", "phishing"}, LastAnalysisDate: &analyzed},
+	}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, reports)
+	}
+
+	if len(requested) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requested))
+	}
+	for i := 1; i < len(requested); i++ {
+		if gap := requested[i].Sub(requested[i-1]); gap < client.interval {
+			t.Errorf("Expected requests at least %s apart, got %s", client.interval, gap)
+		}
+	}
+
+	// A rejected key stops the remaining lookups
+	requested = nil
+	client.apiKey = "wrong-key"
+	if reports := client.Domains(context.Background(), []string{"www.example.com", "new.example.com"}); len(reports) != 0 {
+		t.Errorf("Expected no reports with a rejected key, got %+v", reports)
+	}
+	if len(requested) != 1 {
+		t.Errorf("Expected lookups to stop after the first rejection, got %d requests", len(requested))
+	}
+
+	// Every call with the same key shares its rate limit
+	if NewClient("test-key", "test-agent", time.Second, logger).pacer != client.pacer {
+		t.Error("Expected clients with the same API key to share their pacing")
+	}
+}
@@ -0,0 +1,110 @@
+// Package weights learns how reliable each passive source is from how often the
+// subdomains it reports turn out to resolve
+package weights
+
+import (
+	"sort"
+	"sync"
+)
+
+// SourceWeight is what has been learned about one source
+type SourceWeight struct {
+	Source string `json:"source"`
+	// Weight is the share of the source's checked subdomains that resolved
+	Weight float64 `json:"weight"`
+	// Reported and Confirmed count the checked subdomains the source reported and those that resolved
+	Reported  int `json:"reported"`
+	Confirmed int `json:"confirmed"`
+}
+
+// Snapshot is a point-in-time view of a Learner
+type Snapshot struct {
+	Sources []SourceWeight `json:"sources"`
+}
+
+// MinReports is how many checked subdomains a source needs before Weights uses it
+const MinReports = 20
+
+// counts is the running tally for one source
+type counts struct {
+	reported  int
+	confirmed int
+}
+
+// Learner accumulates per-source confirmation counts; all methods are safe for concurrent use
+type Learner struct {
+	mu      sync.Mutex
+	sources map[string]*counts
+}
+
+// New creates an empty Learner
+func New() *Learner {
+	return &Learner{sources: make(map[string]*counts)}
+}
+
+// Default is the process-wide learner served by GET /mcp/sources/weights
+var Default = New()
+
+// Record counts, for every source of every subdomain in sources, one reported
+// subdomain, and one confirmed subdomain when resolved reports it resolved
+func (l *Learner) Record(sources map[string][]string, resolved map[string]bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for subdomain, names := range sources {
+		for _, name := range names {
+			c, ok := l.sources[name]
+			if !ok {
+				c = &counts{}
+				l.sources[name] = c
+			}
+			c.reported++
+			if resolved[subdomain] {
+				c.confirmed++
+			}
+		}
+	}
+}
+
+// Snapshot returns the learned weight of every source seen so far, by name
+func (l *Learner) Snapshot() Snapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	snapshot := Snapshot{Sources: make([]SourceWeight, 0, len(l.sources))}
+	for name, c := range l.sources {
+		snapshot.Sources = append(snapshot.Sources, SourceWeight{
+			Source:    name,
+			Weight:    float64(c.confirmed) / float64(c.reported),
+			Reported:  c.reported,
+			Confirmed: c.confirmed,
+		})
+	}
+	sort.Slice(snapshot.Sources, func(i, j int) bool {
+		return snapshot.Sources[i].Source < snapshot.Sources[j].Source
+	})
+	return snapshot
+}
+
+// Weights returns the learned weight of each source with at least MinReports
+// checked subdomains, relative to the average of those sources, so 1 is average
+// and sources above it confirm more of what they report than most
+func (l *Learner) Weights() map[string]float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	shares := make(map[string]float64)
+	var total float64
+	for name, c := range l.sources {
+		if c.reported >= MinReports {
+			shares[name] = float64(c.confirmed) / float64(c.reported)
+			total += shares[name]
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	average := total / float64(len(shares))
+	for name, share := range shares {
+		shares[name] = share / average
+	}
+	return shares
+}
@@ -0,0 +1,53 @@
+package weights
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLearner(t *testing.T) {
+	l := New()
+	if got := l.Snapshot(); len(got.Sources) != 0 {
+		t.Errorf("Expected no sources, got %+v", got)
+	}
+
+	l.Record(map[string][]string{
+		"www.example.com": {"crtsh", "alienvault"},
+		"old.example.com": {"crtsh"},
+	}, map[string]bool{"www.example.com": true})
+	l.Record(map[string][]string{
+		"api.example.com": {"alienvault"},
+	}, map[string]bool{"api.example.com": true})
+
+	expected := Snapshot{Sources: []SourceWeight{
+		{Source: "alienvault", Weight: 1, Reported: 2, Confirmed: 2},
+		{Source: "crtsh", Weight: 0.5, Reported: 2, Confirmed: 1},
+	}}
+	if got := l.Snapshot(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestLearnerWeights(t *testing.T) {
+	l := New()
+	sources := make(map[string][]string)
+	resolved := make(map[string]bool)
+	for i := 0; i < MinReports; i++ {
+		subdomain := fmt.Sprintf("host%d.example.com", i)
+		sources[subdomain] = []string{"crtsh", "alienvault"}
+		resolved[subdomain] = i%4 != 0
+	}
+	sources["only.example.com"] = []string{"anubis"}
+	l.Record(sources, resolved)
+	l.Record(map[string][]string{"www.example.com": {"crtsh"}}, map[string]bool{"www.example.com": true})
+
+	// anubis has too few reports; crtsh confirmed 16 of 21 and alienvault 15 of 20
+	got := l.Weights()
+	if len(got) != 2 || got["crtsh"] <= 1 || got["alienvault"] >= 1 {
+		t.Errorf("Expected crtsh above and alienvault below the average, got %v", got)
+	}
+	if New().Weights() != nil {
+		t.Error("Expected no weights before anything is learned")
+	}
+}
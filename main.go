@@ -2,20 +2,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"mcp-subfinder-server/internal/mcp"
+	"mcp-subfinder-server/internal/metrics"
+	"mcp-subfinder-server/internal/plugin"
+	"mcp-subfinder-server/internal/server"
+	"mcp-subfinder-server/internal/sources"
 	jsoniter "github.com/json-iterator/go"
 )
 
@@ -23,12 +32,50 @@ const (
 	// Protocol constants
 	mcpProtocolVersion = "0.3"
 	defaultServerPort  = 8080
+	defaultBindAddress = "0.0.0.0"
 	providerConfigFile = "provider-config.yaml"
 	serverTimeout      = 30 * time.Second
+	// streamTimeout bounds a streamed tool call, which may run the longest
+	// enumeration allowed and then write its final events
+	streamTimeout      = time.Duration(mcp.MaxEnumerationTimeout)*time.Second + 10*time.Second
 	shutdownTimeout    = 10 * time.Second
+	// requestIDHeader carries a caller-supplied request ID for end-to-end tracing
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
 )
 
 func main() {
+	// Parse command-line flags
+	port := flag.Int("port", defaultServerPort, "Port to listen on")
+	bindAddress := flag.String("bind-address", defaultBindAddress, "IP address of the interface to listen on")
+	providerConfig := flag.String("provider-config", providerConfigFile, "Path to the subfinder provider config file")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 5*time.Minute, "How long results are replayed for a repeated idempotencyKey")
+	lockWaitTimeout := flag.Duration("lock-wait-timeout", 5*time.Second, "How long an enumeration waits for a running one on the same domain")
+	queueDepth := flag.Int("queue-depth", server.DefaultQueueDepth, "Maximum number of tools.call requests waiting for a worker")
+	workerCount := flag.Int("worker-count", server.DefaultWorkerCount, "Number of workers running tools.call requests")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; with -tls-key, serves HTTPS with HTTP/2")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	allowBruteForce := flag.Bool("allow-brute-force", false, "Enable the wildcardSubdomainBrute tool, which actively queries the target's DNS")
+	maxBackgroundJobs := flag.Int("max-background-jobs", mcp.DefaultMaxBackgroundJobs, "Maximum number of callbackURL and deferred enumerations running at once")
+	wordlistDir := flag.String("wordlist-dir", "", "Directory wildcardSubdomainBrute reads wordlistPath from; unset only allows wordlistURL")
+	allowCustomTrustAnchors := flag.Bool("allow-custom-trust-anchors", false, "Accept caller-supplied CA certificates (trustAnchorsBase64) for probeTLS")
+	pluginDir := flag.String("plugin-dir", "", "Directory of .so plugins hooked into every enumeration")
+	maxRecursiveDepth := flag.Int("max-recursive-depth", mcp.DefaultMaxRecursiveDepth, fmt.Sprintf("Cap on the maxDepth of every call, at most %d", mcp.MaxRecursiveDepthLimit))
+	flag.IntVar(maxRecursiveDepth, "max-enumeration-depth", mcp.DefaultMaxRecursiveDepth, "Alias of -max-recursive-depth")
+	apiToken := flag.String("api-token", "", "Bearer token required by the /mcp/jobs and /mcp/cache endpoints and cache.invalidate; unset disables them")
+	genProviderConfig := flag.String("gen-provider-config", "", "Write a commented provider config template listing every source that needs a key to this path, then exit")
+	flag.Parse()
+
+	// Generate the provider config template instead of serving
+	if *genProviderConfig != "" {
+		if err := writeProviderConfigTemplate(*genProviderConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write provider config template: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote provider config template to %s\n", *genProviderConfig)
+		return
+	}
+
 	// Setup structured logging with JSON output
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
@@ -47,7 +94,10 @@ func main() {
 	}
 
 	// Set provider config path and ensure it exists
-	providerConfigPath := filepath.Join(workDir, providerConfigFile)
+	providerConfigPath := *providerConfig
+	if !filepath.IsAbs(providerConfigPath) {
+		providerConfigPath = filepath.Join(workDir, providerConfigPath)
+	}
 	if _, err := os.Stat(providerConfigPath); os.IsNotExist(err) {
 		logger.Warn("Provider config file not found, creating empty file", "path", providerConfigPath)
 		// Create an empty file if it doesn't exist
@@ -58,13 +108,88 @@ func main() {
 	}
 	logger.Info("Using provider config file", "path", providerConfigPath)
 
+	// Resolve where to listen before anything starts
+	addr, err := listenAddress(*bindAddress, *port)
+	if err != nil {
+		logger.Error("Invalid listen address", "error", err)
+		os.Exit(1)
+	}
+
+	if *maxRecursiveDepth < 1 || *maxRecursiveDepth > mcp.MaxRecursiveDepthLimit {
+		logger.Error("Invalid maximum recursive depth", "maxRecursiveDepth", *maxRecursiveDepth, "limit", mcp.MaxRecursiveDepthLimit)
+		os.Exit(1)
+	}
+
+	// Apply server-wide tool settings
+	mcp.Configure(mcp.ServerSettings{
+		AllowBruteForce:         *allowBruteForce,
+		AllowCustomTrustAnchors: *allowCustomTrustAnchors,
+		IdempotencyTTL:          *idempotencyTTL,
+		LockWaitTimeout:         *lockWaitTimeout,
+		MaxRecursiveDepth:       *maxRecursiveDepth,
+		WordlistDir:             *wordlistDir,
+		MaxBackgroundJobs:       *maxBackgroundJobs,
+	})
+	if *allowBruteForce {
+		logger.Warn("Brute force enumeration enabled")
+	}
+	if *allowCustomTrustAnchors {
+		logger.Warn("Custom trust anchors enabled for TLS probing")
+	}
+
+	// Load enumeration plugins
+	if *pluginDir != "" {
+		if err := plugin.LoadDir(*pluginDir, logger); err != nil {
+			logger.Error("Failed to load plugins", "dir", *pluginDir, "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create root context that will be canceled on SIGINT/SIGTERM
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Bound concurrent tool calls so bursts are rejected instead of piling up
+	queue := server.NewQueue(*queueDepth, *workerCount, serverTimeout/2, providerConfigPath, logger)
+	logger.Info("Started tools.call queue", "queueDepth", *queueDepth, "workerCount", *workerCount)
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
-	mux.HandleFunc("/mcp", mcpHandler(providerConfigPath, logger))
+	mux.HandleFunc("/mcp", mcpHandler(providerConfigPath, *apiToken, queue, logger))
+
+	// Request validation endpoint for client developers (never runs subfinder)
+	mux.HandleFunc("/mcp/validate", server.ValidateHandler)
+
+	// Aggregate statistics for deployments without Prometheus
+	mux.HandleFunc("/mcp/stats", server.StatsHandler)
+
+	// Source weights learned from DNS-confirmed results
+	mux.HandleFunc("/mcp/sources/weights", server.RequireBearerToken(*apiToken, server.SourceWeightsHandler))
+
+	// Tools list over plain GET, with ETag revalidation for polling clients
+	mux.HandleFunc("/mcp/tools", server.NewToolsHandler())
+
+	// Single tool input schemas for validation and code generation
+	mux.HandleFunc("/mcp/tools/{name}/schema", server.ToolSchemaHandler)
+
+	// Plain-text batch enumeration for integrations that do not speak JSON-RPC
+	mux.HandleFunc("/mcp/bulk-enumerate", server.RequireBearerToken(*apiToken, server.NewBulkEnumerateHandler(providerConfigPath, logger)))
+
+	// End-to-end smoke test of the server's setup
+	mux.HandleFunc("/mcp/test-domain", server.RequireBearerToken(*apiToken, server.NewTestDomainHandler(providerConfigPath, logger)))
+
+	// Deferred jobs, listed and collected without knowing their IDs in advance
+	if *apiToken == "" {
+		logger.Warn("No API token set, the /mcp/jobs, /mcp/cache, /mcp/sources/weights and /mcp/test-domain endpoints and cache.invalidate reject every request")
+	}
+	mux.HandleFunc("/mcp/jobs", server.RequireBearerToken(*apiToken, server.JobsHandler))
+	mux.HandleFunc("/mcp/jobs/{id}/result", server.RequireBearerToken(*apiToken, server.JobResultHandler))
+
+	// Cached result invalidation after DNS changes, which affects every client
+	mux.HandleFunc("/mcp/cache/{domain}", server.RequireBearerToken(*apiToken, server.CacheHandler))
+
+	// Prometheus metrics endpoint
+	mux.HandleFunc("/metrics", metrics.Handler)
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -74,7 +199,7 @@ func main() {
 
 	// Create HTTP server with timeouts
 	srv := &http.Server{
-		Addr:              fmt.Sprintf(":%d", defaultServerPort),
+		Addr:              addr,
 		Handler:           mux,
 		ReadTimeout:       serverTimeout,
 		WriteTimeout:      serverTimeout,
@@ -83,17 +208,44 @@ func main() {
 		BaseContext: func(_ net.Listener) context.Context {
 			return ctx
 		},
+		// Each connection gets its own MCP session for negotiated state
+		ConnContext: func(connCtx context.Context, _ net.Conn) context.Context {
+			return mcp.WithSession(connCtx, mcp.NewSession())
+		},
+	}
+
+	// Serve HTTPS with HTTP/2 when a certificate is configured
+	useTLS := *tlsCert != "" && *tlsKey != ""
+	if useTLS {
+		server.EnableHTTP2(srv)
+		srv.Handler = server.AdvertiseHTTP2(*port, mux)
+	} else if *tlsCert != "" || *tlsKey != "" {
+		logger.Error("Both -tls-cert and -tls-key are required to serve TLS")
+		os.Exit(1)
 	}
 
 	// Start HTTP server in a goroutine
 	go func() {
-		logger.Info("HTTP server starting", "port", defaultServerPort)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Info("HTTP server starting", "address", addr, "tls", useTLS)
+		var err error
+		if useTLS {
+			err = srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("HTTP server error", "error", err)
 			stop() // Signal application to shutdown
 		}
 	}()
 
+	// Verify the tool registry before accepting traffic
+	if err := selfTest(); err != nil {
+		logger.Error("CRITICAL: startup self-test failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Startup self-test passed")
+
 	// Wait for shutdown signal
 	<-ctx.Done()
 	logger.Info("Shutdown initiated")
@@ -108,11 +260,22 @@ func main() {
 	} else {
 		logger.Info("HTTP server shutdown complete")
 	}
+
+	// Let queued tool calls finish, then stop scheduled and background enumerations
+	queue.Stop()
+	mcp.StopScheduler()
+	mcp.StopBackgroundJobs()
 }
 
 // mcpHandler creates a handler function for MCP protocol requests
-func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+func mcpHandler(providerConfigPath, apiToken string, queue *server.Queue, logger *slog.Logger) func(w http.ResponseWriter, r *http.Request) {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		// Count response bytes for the size histogram
+		w := &metrics.CountingResponseWriter{ResponseWriter: rw}
+		defer func() {
+			metrics.ResponseBodyBytes.Observe(float64(w.Bytes))
+		}()
+
 		// Ensure the request method is POST
 		if r.Method != http.MethodPost {
 			logger.Warn("Invalid HTTP method", "method", r.Method, "remoteAddr", r.RemoteAddr)
@@ -121,15 +284,15 @@ func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.Resp
 		}
 
 		// Ensure the content type is application/json
-		contentType := r.Header.Get("Content-Type")
-		if contentType != "application/json" {
-			logger.Warn("Invalid content type", "contentType", contentType, "remoteAddr", r.RemoteAddr)
+		if !isJSONContent(r) {
+			logger.Warn("Invalid content type", "contentType", r.Header.Get("Content-Type"), "remoteAddr", r.RemoteAddr)
 			http.Error(w, "Content type must be application/json", http.StatusUnsupportedMediaType)
 			return
 		}
 
 		// Log request start
-		requestID := fmt.Sprintf("%d", time.Now().UnixNano())
+		requestID := requestIDFromHeader(r)
+		w.Header().Set(requestIDHeader, requestID)
 		logger.Info("Received MCP request",
 			"requestID", requestID,
 			"remoteAddr", r.RemoteAddr,
@@ -145,18 +308,54 @@ func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.Resp
 			http.Error(w, "Failed to read request", http.StatusBadRequest)
 			return
 		}
+		metrics.RequestBodyBytes.Observe(float64(len(body)))
 
-		// Prepare context with timeout
-		ctx, cancel := context.WithTimeout(r.Context(), serverTimeout)
+		// Prepare context with timeout; streamed calls keep writing as results arrive
+		timeout := serverTimeout
+		if acceptsStream(r) {
+			timeout = streamTimeout
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 
+		// Prefer the client-chosen session, then the connection's, then a per-request one
+		if sessionID := r.Header.Get(mcp.SessionIDHeader); sessionID != "" {
+			session, err := mcp.DefaultSessions.GetOrCreate(sessionID)
+			if errors.Is(err, mcp.ErrTooManySessions) {
+				logger.Warn("Session registry is full, rejecting request", "requestID", requestID)
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, "Too many sessions, retry later", http.StatusServiceUnavailable)
+				return
+			} else if err != nil {
+				http.Error(w, "Invalid "+mcp.SessionIDHeader+" header", http.StatusBadRequest)
+				return
+			}
+			ctx = mcp.WithSession(ctx, session)
+			w.Header().Set(mcp.SessionIDHeader, sessionID)
+		} else if mcp.SessionFromContext(ctx) == nil {
+			ctx = mcp.WithSession(ctx, mcp.NewSession())
+		}
+
+		// Administrative methods such as cache.invalidate need the API token
+		if server.HasBearerToken(r, apiToken) {
+			ctx = mcp.WithBearerAuthorized(ctx)
+		}
+
+		// Tool calls go through the bounded queue; everything else is handled inline
+		process := func(req mcp.Request) mcp.Response {
+			if req.Method == "tools.call" {
+				return queue.Submit(ctx, &req)
+			}
+			return mcp.ProcessSingleRequest(ctx, req, providerConfigPath, logger)
+		}
+
 		// Process the request (batch or single)
 		var response interface{}
 
 		// Check if the request is a batch (array)
 		if len(body) > 0 && body[0] == '[' {
-			// Parse batch request
-			var batchRequest []mcp.Request
+			// Parse batch request; items are decoded individually so one bad item fails alone
+			var batchRequest []jsoniter.RawMessage
 			if err := jsoniter.Unmarshal(body, &batchRequest); err != nil {
 				logger.Error("Failed to parse batch request", "error", err, "requestID", requestID)
 				response = []mcp.Response{{
@@ -164,14 +363,10 @@ func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.Resp
 					Error:   mcp.ErrParse,
 				}}
 			} else {
-				// Process each request in the batch
-				batchResponse := make([]mcp.Response, 0, len(batchRequest))
-				for _, req := range batchRequest {
-					resp := mcp.ProcessSingleRequest(ctx, req, providerConfigPath, logger)
-					// Only include non-empty responses (important for notifications)
-					if resp.ID != nil || resp.Error != nil {
-						batchResponse = append(batchResponse, resp)
-					}
+				// Process each request in the batch and summarize the outcome in a header
+				batchResponse, batchStats := mcp.ProcessBatch(batchRequest, process)
+				if statsJSON, err := jsoniter.Marshal(batchStats); err == nil {
+					w.Header().Set(mcp.BatchStatsHeader, string(statsJSON))
 				}
 				response = batchResponse
 			}
@@ -184,9 +379,14 @@ func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.Resp
 					JSONRPC: "2.0",
 					Error:   mcp.ErrParse,
 				}
+			} else if singleRequest.Method == "tools.call" && acceptsStream(r) {
+				// Stream results as they are discovered instead of buffering
+				streamToolsCall(ctx, w, singleRequest, queue, logger, requestID)
+				logger.Info("Completed streaming MCP request", "requestID", requestID)
+				return
 			} else {
 				// Process single request
-				response = mcp.ProcessSingleRequest(ctx, singleRequest, providerConfigPath, logger)
+				response = process(singleRequest)
 			}
 		}
 
@@ -196,15 +396,141 @@ func mcpHandler(providerConfigPath string, logger *slog.Logger) func(w http.Resp
 	}
 }
 
-// writeResponse writes a JSON response to the HTTP response writer
+// requiredTools lists the tools the startup self-test expects tools.list to return
+var requiredTools = []string{"enumerateSubdomains"}
+
+// selfTest runs a tools.list request internally and checks that every required tool is registered
+func selfTest() error {
+	id := jsoniter.RawMessage(`"self-test"`)
+	resp := mcp.HandleToolsList(&mcp.Request{
+		JSONRPC: "2.0",
+		ID:      &id,
+		Method:  "tools.list",
+	})
+	if resp.Error != nil {
+		return fmt.Errorf("tools.list returned error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+
+	result, ok := resp.Result.(mcp.ToolsListResult)
+	if !ok {
+		return fmt.Errorf("tools.list returned unexpected result type %T", resp.Result)
+	}
+
+	registered := make(map[string]struct{}, len(result.Tools))
+	for _, tool := range result.Tools {
+		registered[tool.Name] = struct{}{}
+	}
+	for _, name := range requiredTools {
+		if _, ok := registered[name]; !ok {
+			return fmt.Errorf("tools.list is missing the %s tool", name)
+		}
+	}
+
+	return nil
+}
+
+// acceptsStream reports whether the client asked for NDJSON streaming
+func acceptsStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == mcp.StreamContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONContent reports whether the request body is declared as JSON. Parameters
+// such as charset=utf-8 are allowed and the media type is matched case-insensitively.
+func isJSONContent(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// listenAddress joins bindAddress and port into a listen address, rejecting
+// anything that is not an IP address
+func listenAddress(bindAddress string, port int) (string, error) {
+	if net.ParseIP(bindAddress) == nil {
+		return "", fmt.Errorf("bind address %q is not an IP address", bindAddress)
+	}
+	return net.JoinHostPort(bindAddress, strconv.Itoa(port)), nil
+}
+
+// requestIDFromHeader returns the X-Request-ID set by a gateway or caller, or a new
+// ID when it is missing or longer than maxRequestIDLength
+func requestIDFromHeader(r *http.Request) string {
+	if id := strings.TrimSpace(r.Header.Get(requestIDHeader)); id != "" && len(id) <= maxRequestIDLength {
+		return id
+	}
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+// streamToolsCall runs a tools.call while writing NDJSON events to the client.
+// Discovered subdomains are followed by a final stats event; if the call fails
+// the JSON-RPC response is written as the last line instead.
+func streamToolsCall(ctx context.Context, w http.ResponseWriter, req mcp.Request, queue *server.Queue, logger *slog.Logger, requestID string) {
+	// The server's write timeout would cut the stream off, so it ends with ctx instead
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			logger.Debug("Could not extend the stream write deadline", "error", err, "requestID", requestID)
+		}
+	}
+
+	w.Header().Set("Content-Type", mcp.StreamContentType)
+	w.WriteHeader(http.StatusOK)
+
+	// Flushing without a Content-Length switches the response to chunked encoding
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	resp := queue.Submit(mcp.WithStreamWriter(ctx, w), &req)
+
+	// Failures and replayed results produce no stream events, so send the response itself
+	writeFinal := resp.Error != nil
+	if result, ok := resp.Result.(mcp.ToolCallResult); ok && (result.IsError || (result.Meta != nil && result.Meta.Idempotent)) {
+		writeFinal = true
+	}
+	if !writeFinal {
+		return
+	}
+
+	if err := jsoniter.NewEncoder(w).Encode(resp); err != nil {
+		logger.Error("Failed to encode streamed response", "error", err, "requestID", requestID)
+	}
+}
+
+// writeResponse writes a JSON response to the HTTP response writer. The body is
+// buffered first so HTTP/1.0 clients and proxies get a Content-Length.
 func writeResponse(w http.ResponseWriter, resp interface{}, httpStatusCode int, logger *slog.Logger, requestID string) {
+	// Encode response as JSON
+	var buf bytes.Buffer
+	if err := jsoniter.NewEncoder(&buf).Encode(resp); err != nil {
+		logger.Error("Failed to encode response", "error", err, "requestID", requestID)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
 	w.WriteHeader(httpStatusCode)
 
-	// Encode response as JSON
-	if err := jsoniter.NewEncoder(w).Encode(resp); err != nil {
-		logger.Error("Failed to encode response", "error", err, "requestID", requestID)
-		// At this point headers are already sent, so we can only log the error
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logger.Error("Failed to write response", "error", err, "requestID", requestID)
+	}
+}
+
+// writeProviderConfigTemplate writes the provider config template to path,
+// refusing to overwrite an existing file that may already hold keys
+func writeProviderConfigTemplate(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(sources.ProviderConfigTemplate()); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
 }
@@ -3,9 +3,18 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -167,6 +176,103 @@ func TestIntegration(t *testing.T) {
 	})
 }
 
+func TestAcceptsStream(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{accept: "", expected: false},
+		{accept: "application/json", expected: false},
+		{accept: "application/x-ndjson", expected: true},
+		{accept: "application/json, application/x-ndjson;q=0.9", expected: true},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set("Accept", tc.accept)
+		if got := acceptsStream(req); got != tc.expected {
+			t.Errorf("Accept %q: expected %v, got %v", tc.accept, tc.expected, got)
+		}
+	}
+}
+
+func TestIsJSONContent(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{contentType: "application/json", expected: true},
+		{contentType: "application/json; charset=utf-8", expected: true},
+		{contentType: "Application/JSON", expected: true},
+		{contentType: "text/plain", expected: false},
+		{contentType: "", expected: false},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set("Content-Type", tc.contentType)
+		if got := isJSONContent(req); got != tc.expected {
+			t.Errorf("Content-Type %q: expected %v, got %v", tc.contentType, tc.expected, got)
+		}
+	}
+}
+
+func TestRequestIDFromHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("X-Request-ID", " gateway-abc123 ")
+	if got := requestIDFromHeader(req); got != "gateway-abc123" {
+		t.Errorf("Expected the incoming request ID, got %q", got)
+	}
+
+	for _, header := range []string{"", strings.Repeat("a", maxRequestIDLength+1)} {
+		req.Header.Set("X-Request-ID", header)
+		got := requestIDFromHeader(req)
+		if got == "" || got == header {
+			t.Errorf("Expected a generated request ID for header of length %d, got %q", len(header), got)
+		}
+	}
+}
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		bindAddress string
+		expected    string
+	}{
+		{"0.0.0.0", "0.0.0.0:8080"},
+		{"127.0.0.1", "127.0.0.1:8080"},
+		{"::1", "[::1]:8080"},
+	}
+	for _, tt := range tests {
+		got, err := listenAddress(tt.bindAddress, 8080)
+		if err != nil || got != tt.expected {
+			t.Errorf("listenAddress(%q) = %q, %v; expected %q", tt.bindAddress, got, err, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "localhost", "127.0.0.1:8080"} {
+		if _, err := listenAddress(invalid, 8080); err == nil {
+			t.Errorf("Expected an error for bind address %q", invalid)
+		}
+	}
+}
+
+func TestWriteResponseContentLength(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rec := httptest.NewRecorder()
+	writeResponse(rec, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": "ok"}, http.StatusOK, logger, "test")
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if got, expected := rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()); got != expected {
+		t.Errorf("Expected Content-Length %s, got %q", expected, got)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil || decoded["result"] != "ok" {
+		t.Errorf("Expected the encoded response, got %q", rec.Body.String())
+	}
+}
+
 // MockRunner is a function to run tests with a timeout
 func MockRunner(t *testing.T, testFunc func(*testing.T), timeout time.Duration) {
 	done := make(chan bool)
@@ -182,3 +288,153 @@ func MockRunner(t *testing.T, testFunc func(*testing.T), timeout time.Duration)
 		t.Fatal("Test timed out")
 	}
 }
+
+func TestSelfTest(t *testing.T) {
+	if err := selfTest(); err != nil {
+		t.Fatalf("Expected self-test to pass, got %v", err)
+	}
+
+	original := requiredTools
+	defer func() { requiredTools = original }()
+
+	requiredTools = []string{"enumerateSubdomains", "missingTool"}
+	if err := selfTest(); err == nil {
+		t.Fatal("Expected self-test to fail when a required tool is missing")
+	}
+}
+
+// runMainEnv makes the test binary run main() instead of the tests, with the
+// server flags taken from runMainArgsEnv
+const (
+	runMainEnv     = "MCP_SUBFINDER_RUN_MAIN"
+	runMainArgsEnv = "MCP_SUBFINDER_MAIN_ARGS"
+)
+
+// TestFullStartup runs the real main() in a subprocess and talks to it over HTTP
+func TestFullStartup(t *testing.T) {
+	// Inside the subprocess, become the server
+	if os.Getenv(runMainEnv) == "1" {
+		os.Args = append([]string{os.Args[0]}, strings.Fields(os.Getenv(runMainArgsEnv))...)
+		main()
+		return
+	}
+
+	// Skip the startup test unless explicitly enabled
+	if os.Getenv("ENABLE_LIVE_TESTS") != "1" {
+		t.Skip("Skipping full startup test. Set ENABLE_LIVE_TESTS=1 to enable")
+	}
+
+	// Pick a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	workDir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFullStartup$")
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(),
+		runMainEnv+"=1",
+		fmt.Sprintf("%s=-port %d", runMainArgsEnv, port))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		// Ask for a graceful shutdown, then make sure the process is gone
+		cmd.Process.Signal(syscall.SIGTERM)
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case <-done:
+		case <-time.After(15 * time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+		if t.Failed() {
+			t.Logf("Server output:\n%s", output.String())
+		}
+	})
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	// Wait for the health endpoint to come up
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Server did not become healthy: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Startup creates an empty provider config when none exists
+	if _, err := os.Stat(filepath.Join(workDir, providerConfigFile)); err != nil {
+		t.Errorf("Expected provider config to be created: %v", err)
+	}
+
+	reqBody, err := json.Marshal(TestRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]string{
+			"protocolVersion": "0.3",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(baseURL+"/mcp", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response TestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	result, ok := response.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Result is not a map: %T", response.Result)
+	}
+
+	if name, ok := result["name"].(string); !ok || name != "MCP Subfinder Server" {
+		t.Errorf("Expected 'MCP Subfinder Server', got %v", result["name"])
+	}
+}
+
+func TestWriteProviderConfigTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-config.yaml")
+	if err := writeProviderConfigTemplate(path); err != nil {
+		t.Fatalf("writeProviderConfigTemplate failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\nshodan:\n") {
+		t.Errorf("Expected a shodan entry in %s", data)
+	}
+
+	// An existing config may hold keys and is left alone
+	if err := writeProviderConfigTemplate(path); err == nil {
+		t.Error("Expected an error overwriting an existing file")
+	}
+}